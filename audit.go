@@ -0,0 +1,96 @@
+package pkce
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditOutcome provides the outcome of an audited verification attempt.
+type AuditOutcome string
+
+const (
+	// AuditOutcomeVerified specifies the code verifier was verified.
+	AuditOutcomeVerified AuditOutcome = "verified"
+	// AuditOutcomeFailed specifies the verification attempt failed.
+	AuditOutcomeFailed AuditOutcome = "failed"
+)
+
+// fingerprintLen provides the number of bytes of the code challenge hash
+// used as a fingerprint.
+const fingerprintLen = 8
+
+// AuditEvent provides a record of a verification attempt.
+type AuditEvent struct {
+	// Time provides when the attempt was made.
+	Time time.Time `json:"timestamp"`
+	// Fingerprint identifies the registered code challenge without revealing
+	// it, enabling attempts against the same code challenge to be correlated.
+	// Empty if no key was registered.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Method provides the registered code challenge method. Empty if no key
+	// was registered.
+	Method Method `json:"method,omitempty"`
+	// Outcome provides the outcome of the attempt.
+	Outcome AuditOutcome `json:"outcome"`
+	// Reason provides the reason a failed attempt failed.
+	Reason FailureReason `json:"reason,omitempty"`
+	// Age provides the time elapsed since the registered key was created,
+	// enabling stale flows to be debugged. Zero if no key was registered, or
+	// its creation time is unknown.
+	Age time.Duration `json:"age_ns,omitempty"`
+}
+
+// Auditor receives an event for every verification attempt made by a
+// KeyManager, enabling a forensic trail to be kept.
+//
+// Implementations must be safe for concurrent use.
+type Auditor interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// AuditWriter provides an Auditor which streams events to an io.Writer as JSON
+// Lines.
+type AuditWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewAuditWriter returns an auditor writing events to w.
+func NewAuditWriter(w io.Writer) *AuditWriter {
+	return &AuditWriter{
+		enc: json.NewEncoder(w),
+	}
+}
+
+// Audit implements Auditor.
+func (a *AuditWriter) Audit(_ context.Context, event AuditEvent) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.enc.Encode(event); err != nil && a.err == nil {
+		a.err = err
+	}
+}
+
+// Err returns the first error encountered writing an event, as Audit has no
+// means of reporting one.
+func (a *AuditWriter) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.err
+}
+
+// fingerprint returns a short, non-reversible identifier of the code
+// challenge.
+func fingerprint(codeChallenge string) string {
+	sum := sha256.Sum256([]byte(codeChallenge))
+
+	return hex.EncodeToString(sum[:fingerprintLen])
+}
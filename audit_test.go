@@ -0,0 +1,84 @@
+package pkce
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditWriter(t *testing.T) {
+	clock := newTestClock()
+	var buf bytes.Buffer
+	m := NewKeyManager(NewMemoryStore(), WithManagerClock(clock), WithManagerAuditor(NewAuditWriter(&buf)))
+
+	for _, id := range []string{"a", "b"} {
+		if err := m.Register(context.Background(), id, S256, testCodeChallenge); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	_ = m.Verify(context.Background(), "a", testCodeVerifier)
+	_ = m.Verify(context.Background(), "b", testCodeChallenge)
+	_ = m.Verify(context.Background(), "c", testCodeVerifier)
+
+	want := []string{
+		`{"timestamp":"2022-01-27T00:00:00Z","fingerprint":"` + fingerprint(testCodeChallenge) + `","method":"S256","outcome":"verified"}`,
+		`{"timestamp":"2022-01-27T00:00:00Z","fingerprint":"` + fingerprint(testCodeChallenge) + `","method":"S256","outcome":"failed","reason":"downgrade"}`,
+		`{"timestamp":"2022-01-27T00:00:00Z","outcome":"failed","reason":"not_found"}`,
+	}
+	if got := strings.Split(strings.TrimSpace(buf.String()), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Audit() wrote:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event AuditEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Errorf("Audit() wrote an invalid JSON line: %v", err)
+		}
+	}
+}
+
+func TestAuditWriter_age(t *testing.T) {
+	clock := newTestClock()
+	var buf bytes.Buffer
+	m := NewKeyManager(NewMemoryStore(), WithManagerClock(clock), WithManagerAuditor(NewAuditWriter(&buf)))
+
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	_ = m.Verify(context.Background(), "code", testCodeVerifier)
+
+	var event AuditEvent
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatalf("Audit() wrote an invalid JSON line: %v", err)
+	}
+
+	if event.Age != time.Minute {
+		t.Errorf("Audit() age = %v, want %v", event.Age, time.Minute)
+	}
+}
+
+// errWriter provides a writer which always fails.
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("yolo")
+}
+
+func TestAuditWriter_Err(t *testing.T) {
+	a := NewAuditWriter(errWriter{})
+	if err := a.Err(); err != nil {
+		t.Fatalf("Err() unexpected error: %v", err)
+	}
+
+	a.Audit(context.Background(), AuditEvent{Outcome: AuditOutcomeVerified})
+	if err := a.Err(); err == nil || err.Error() != "yolo" {
+		t.Errorf("Err() error type not expected\ngot:  %v, want: %v\n", err, "yolo")
+	}
+}
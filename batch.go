@@ -0,0 +1,121 @@
+package pkce
+
+import (
+	"runtime"
+	"sync"
+)
+
+// VerificationInput provides a recorded authorization request code challenge
+// and token request code verifier to be verified offline, such as when
+// auditing logs.
+type VerificationInput struct {
+	// ID optionally identifies the entry, such as the authorization code or
+	// a log line reference.
+	ID string
+	// Method provides the code challenge method of the authorization
+	// request. Defaults to "plain" if empty, as specified in RFC 7636, 4.3.
+	Method Method
+	// CodeChallenge provides the code challenge of the authorization request.
+	CodeChallenge string
+	// CodeVerifier provides the code verifier of the token request.
+	CodeVerifier string
+}
+
+// VerificationResult provides the outcome of verifying a VerificationInput.
+type VerificationResult struct {
+	// ID provides the ID of the verified entry.
+	ID string
+	// Verified reports whether the code verifier proves the code challenge.
+	Verified bool
+	// Err provides the reason verification failed, such as
+	// ErrVerifierMismatch, ErrMethodDowngrade, ErrChallengeEncoding or a code
+	// verifier validation error.
+	Err error
+	// Reason classifies why verification failed. Empty if verified.
+	Reason FailureReason
+	// Code provides the stable, machine-readable result code classifying
+	// why verification failed. Empty if verified.
+	Code ResultCode
+}
+
+// batchChunkSize provides the number of entries verified by a worker at a
+// time, amortising synchronisation over many cheap verifications.
+const batchChunkSize = 256
+
+// VerifyBatch verifies each entry, returning a result for each in the same
+// order. Entries are verified concurrently across a pool of GOMAXPROCS
+// workers.
+func VerifyBatch(entries []VerificationInput) []VerificationResult {
+	results := make([]VerificationResult, len(entries))
+
+	workers := runtime.GOMAXPROCS(0)
+	if chunks := (len(entries) + batchChunkSize - 1) / batchChunkSize; chunks < workers {
+		workers = chunks
+	}
+
+	chunks := make(chan int, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for start := range chunks {
+				end := start + batchChunkSize
+				if end > len(entries) {
+					end = len(entries)
+				}
+
+				// each worker writes a distinct range of results.
+				for j := start; j < end; j++ {
+					results[j] = NewVerificationResult(verifyInput(entries[j]))
+					results[j].ID = entries[j].ID
+				}
+			}
+		}()
+	}
+
+	for start := 0; start < len(entries); start += batchChunkSize {
+		chunks <- start
+	}
+	close(chunks)
+	wg.Wait()
+
+	return results
+}
+
+// verifyInput verifies the entry, returning why verification failed.
+func verifyInput(entry VerificationInput) error {
+	method := entry.Method
+	if method == "" {
+		method = Plain
+	}
+
+	if !isKeyMethod(method) {
+		return ErrMethodNotSupported
+	}
+
+	codeVerifier := []byte(entry.CodeVerifier)
+	if err := validateCodeVerifier(codeVerifier); err != nil {
+		return err
+	}
+
+	want := generateCodeChallenge(method, codeVerifier)
+	if ChallengesEqual(want, entry.CodeChallenge) {
+		return nil
+	}
+
+	if method != Plain {
+		// RFC 7636, 7.2. Presenting the hashed code challenge as the code
+		// verifier is an attempt to prove possession using "plain".
+		if ChallengesEqual(entry.CodeVerifier, entry.CodeChallenge) {
+			return ErrMethodDowngrade
+		}
+
+		if ChallengesEqual(want, NormalizeCodeChallenge(entry.CodeChallenge)) {
+			return ErrChallengeEncoding
+		}
+	}
+
+	return ErrVerifierMismatch
+}
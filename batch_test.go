@@ -0,0 +1,158 @@
+package pkce
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		entry      VerificationInput
+		wantErr    error
+		wantReason FailureReason
+		wantCode   ResultCode
+	}{
+		{
+			name: "should verify a S256 code verifier",
+			entry: VerificationInput{
+				Method:        S256,
+				CodeChallenge: testCodeChallenge,
+				CodeVerifier:  testCodeVerifier,
+			},
+		},
+		{
+			name: "should verify a S384 code verifier",
+			entry: VerificationInput{
+				Method:        S384,
+				CodeChallenge: testHashedChallenge(S384),
+				CodeVerifier:  testCodeVerifier,
+			},
+		},
+		{
+			name: "should default to plain",
+			entry: VerificationInput{
+				CodeChallenge: testCodeVerifier,
+				CodeVerifier:  testCodeVerifier,
+			},
+		},
+		{
+			name: "should error on a mismatched code verifier",
+			entry: VerificationInput{
+				Method:        S256,
+				CodeChallenge: testCodeChallenge,
+				CodeVerifier:  strings.Repeat("a", verifierMinLen),
+			},
+			wantErr:    ErrVerifierMismatch,
+			wantReason: FailureMismatch,
+			wantCode:   ResultVerifierMismatch,
+		},
+		{
+			name: "should error on a downgraded code verifier",
+			entry: VerificationInput{
+				Method:        S256,
+				CodeChallenge: testCodeChallenge,
+				CodeVerifier:  testCodeChallenge,
+			},
+			wantErr:    ErrMethodDowngrade,
+			wantReason: FailureDowngrade,
+			wantCode:   ResultMethodDowngrade,
+		},
+		{
+			name: "should error on a downgraded S512 code verifier",
+			entry: VerificationInput{
+				Method:        S512,
+				CodeChallenge: testHashedChallenge(S512),
+				CodeVerifier:  testHashedChallenge(S512),
+			},
+			wantErr:    ErrMethodDowngrade,
+			wantReason: FailureDowngrade,
+			wantCode:   ResultMethodDowngrade,
+		},
+		{
+			name: "should error on a padded code challenge",
+			entry: VerificationInput{
+				Method:        S256,
+				CodeChallenge: testCodeChallenge + "=",
+				CodeVerifier:  testCodeVerifier,
+			},
+			wantErr:    ErrChallengeEncoding,
+			wantReason: FailureMalformed,
+			wantCode:   ResultMalformed,
+		},
+		{
+			name: "should error on an invalid code verifier",
+			entry: VerificationInput{
+				Method:        S256,
+				CodeChallenge: testCodeChallenge,
+				CodeVerifier:  "yolo",
+			},
+			wantErr:    ErrVerifierLength,
+			wantReason: FailureMalformed,
+			wantCode:   ResultMalformed,
+		},
+		{
+			name: "should error on an unsupported method",
+			entry: VerificationInput{
+				Method:        "yolo",
+				CodeChallenge: testCodeChallenge,
+				CodeVerifier:  testCodeVerifier,
+			},
+			wantErr:    ErrMethodNotSupported,
+			wantReason: FailureMalformed,
+			wantCode:   ResultMethodUnsupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.entry.ID = "entry"
+			got := VerifyBatch([]VerificationInput{tt.entry})
+			if len(got) != 1 {
+				t.Fatalf("VerifyBatch() returned %d results, want 1", len(got))
+			}
+
+			if got[0].Err != tt.wantErr {
+				t.Errorf("VerifyBatch() error type not expected\ngot:  %v, want: %v\n", got[0].Err, tt.wantErr)
+			}
+
+			if got[0].Reason != tt.wantReason {
+				t.Errorf("VerifyBatch() reason = %v, want %v", got[0].Reason, tt.wantReason)
+			}
+
+			if got[0].Code != tt.wantCode {
+				t.Errorf("VerifyBatch() code = %v, want %v", got[0].Code, tt.wantCode)
+			}
+
+			if got[0].Verified != (tt.wantErr == nil) || got[0].ID != "entry" {
+				t.Errorf("VerifyBatch() = %+v, want verified %v", got[0], tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestVerifyBatch_order(t *testing.T) {
+	entries := make([]VerificationInput, 3*batchChunkSize+1)
+	for i := range entries {
+		entries[i] = VerificationInput{
+			ID:            fmt.Sprint(i),
+			Method:        S256,
+			CodeChallenge: testCodeChallenge,
+			CodeVerifier:  testCodeVerifier,
+		}
+		if i%2 == 1 {
+			entries[i].CodeVerifier = testCodeChallenge
+		}
+	}
+
+	for i, result := range VerifyBatch(entries) {
+		if result.ID != entries[i].ID || result.Verified != (i%2 == 0) {
+			t.Fatalf("VerifyBatch() result #%d = %+v, not in entry order", i, result)
+		}
+	}
+
+	if got := VerifyBatch(nil); len(got) != 0 {
+		t.Errorf("VerifyBatch() = %v, want no results", got)
+	}
+}
@@ -0,0 +1,239 @@
+package pkce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func BenchmarkNew(b *testing.B) {
+	for _, n := range []int{verifierMinLen, 64, 96, verifierMaxLen} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				key, err := New(WithCodeVerifierLength(n))
+				if err != nil {
+					b.Fatalf("New() unexpected error: %v", err)
+				}
+				key.getCodeVerifier()
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateCodeVerifier(b *testing.B) {
+	for _, n := range []int{verifierMinLen, 64, 96, verifierMaxLen} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateCodeVerifier(n); err != nil {
+					b.Fatalf("GenerateCodeVerifier() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateCodeChallenge(b *testing.B) {
+	for _, method := range []Method{Plain, S256} {
+		b.Run(method.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateCodeChallenge(method, testCodeVerifier); err != nil {
+					b.Fatalf("GenerateCodeChallenge() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkKey_CodeChallenge(b *testing.B) {
+	for _, encoding := range []ChallengeEncoding{Base64URL, Base64URLPadded, Hex} {
+		b.Run(encoding.String(), func(b *testing.B) {
+			key, err := New(WithChallengeEncoding(encoding), WithCodeVerifierString(testCodeVerifier))
+			if err != nil {
+				b.Fatalf("New() unexpected error: %v", err)
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				key.CodeChallenge()
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyCodeVerifier(b *testing.B) {
+	challenges := map[Method]string{
+		Plain: testCodeVerifier,
+		S256:  testCodeChallenge,
+	}
+
+	for _, method := range []Method{Plain, S256} {
+		b.Run(method.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if !VerifyCodeVerifier(method, testCodeVerifier, challenges[method]) {
+					b.Fatal("VerifyCodeVerifier() should verify the code verifier")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyCodeVerifierBytes(b *testing.B) {
+	codeVerifier, codeChallenge := []byte(testCodeVerifier), []byte(testCodeChallenge)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !VerifyCodeVerifierBytes(S256, codeVerifier, codeChallenge) {
+			b.Fatal("VerifyCodeVerifierBytes() should verify the code verifier")
+		}
+	}
+}
+
+func BenchmarkKey_VerifyCodeVerifier(b *testing.B) {
+	key, err := New(WithChallengeEncoding(Hex))
+	if err != nil {
+		b.Fatalf("New() unexpected error: %v", err)
+	}
+	codeVerifier := key.CodeVerifier()
+	key, err = New(WithChallengeEncoding(Hex), WithCodeChallenge(key.CodeChallenge()))
+	if err != nil {
+		b.Fatalf("New() unexpected error: %v", err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !key.VerifyCodeVerifier(codeVerifier) {
+			b.Fatal("VerifyCodeVerifier() should verify the code verifier")
+		}
+	}
+}
+
+func BenchmarkKey_MarshalBinary(b *testing.B) {
+	key, err := New(WithCodeVerifierString(testCodeVerifier), WithExpiry(time.Minute))
+	if err != nil {
+		b.Fatalf("New() unexpected error: %v", err)
+	}
+	data, err := key.MarshalBinary()
+	if err != nil {
+		b.Fatalf("MarshalBinary() unexpected error: %v", err)
+	}
+
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := key.MarshalBinary(); err != nil {
+				b.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+		}
+	})
+
+	b.Run("unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := (&Key{}).UnmarshalBinary(data); err != nil {
+				b.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkStore(b *testing.B) {
+	fileStore, cleanup := newTestFileStore(b)
+	defer cleanup()
+
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{name: "memory", store: NewMemoryStore()},
+		{name: "file", store: fileStore},
+		{name: "encrypted", store: NewEncryptedStore(NewMemoryStore(), newTestKeyring(b, "kid"))},
+	}
+
+	ctx := context.Background()
+	data := []byte(testCodeChallenge)
+	for _, tt := range stores {
+		b.Run(tt.name+"/put", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := tt.store.Put(ctx, "code", data, time.Minute); err != nil {
+					b.Fatalf("Put() unexpected error: %v", err)
+				}
+			}
+		})
+
+		b.Run(tt.name+"/get", func(b *testing.B) {
+			if err := tt.store.Put(ctx, "code", data, time.Minute); err != nil {
+				b.Fatalf("Put() unexpected error: %v", err)
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := tt.store.Get(ctx, "code"); err != nil {
+					b.Fatalf("Get() unexpected error: %v", err)
+				}
+			}
+		})
+
+		b.Run(tt.name+"/put+consume", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := tt.store.Put(ctx, "code", data, time.Minute); err != nil {
+					b.Fatalf("Put() unexpected error: %v", err)
+				}
+				if _, err := tt.store.Consume(ctx, "code"); err != nil {
+					b.Fatalf("Consume() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkKeyManager(b *testing.B) {
+	ctx := context.Background()
+	m := NewKeyManager(NewMemoryStore())
+
+	b.Run("register+verify", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := m.Register(ctx, "code", S256, testCodeChallenge); err != nil {
+				b.Fatalf("Register() unexpected error: %v", err)
+			}
+			if err := m.Verify(ctx, "code", testCodeVerifier); err != nil {
+				b.Fatalf("Verify() unexpected error: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkKeyManager_Middleware(b *testing.B) {
+	m := NewKeyManager(NewMemoryStore())
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"code"},
+		"code_verifier": {testCodeVerifier},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+			b.Fatalf("Register() unexpected error: %v", err)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newTokenRequest(form))
+		if rec.Code != http.StatusOK {
+			b.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package pkce
+
+import (
+	"net/http"
+)
+
+// Binding namespaces the correlation identifiers a key can be stored under,
+// so that identifiers of different kinds sharing a store can not collide.
+type Binding string
+
+const (
+	// BindingCode binds keys to the authorization code issued in response to
+	// the authorization request.
+	BindingCode Binding = "code"
+	// BindingState binds keys to the state parameter of the authorization
+	// request.
+	BindingState Binding = "state"
+	// BindingRequestURI binds keys to the request_uri issued for a pushed
+	// authorization request, as specified in RFC 9126.
+	BindingRequestURI Binding = "request_uri"
+	// BindingSession binds keys to the user agent's session identifier.
+	BindingSession Binding = "session"
+)
+
+// Binder correlates the authorization request with the token request, enabling
+// authorization servers to store keys under the identifier their architecture
+// already tracks the flow by.
+type Binder interface {
+	// ID returns the store id the key correlated by value is stored under.
+	ID(value string) string
+
+	// TokenRequestValue returns the correlation value of a token request, or
+	// an empty string if the request can not be correlated. The request form
+	// has been parsed.
+	TokenRequestValue(r *http.Request) string
+}
+
+// binder provides a Binder namespacing ids by binding.
+type binder struct {
+	binding Binding
+	value   func(r *http.Request) string
+}
+
+// ID implements Binder.
+func (b binder) ID(value string) string {
+	// Keys bound to authorization codes are stored under the code itself, as
+	// they were prior to the introduction of binders.
+	if b.binding == BindingCode {
+		return value
+	}
+
+	return string(b.binding) + ":" + value
+}
+
+// TokenRequestValue implements Binder.
+func (b binder) TokenRequestValue(r *http.Request) string {
+	return b.value(r)
+}
+
+// CodeBinder returns a binder storing keys under the authorization code, which
+// is read from the code parameter of the token request. This is the default
+// binding.
+func CodeBinder() Binder {
+	return binder{
+		binding: BindingCode,
+		value: func(r *http.Request) string {
+			return r.PostForm.Get(paramCode)
+		},
+	}
+}
+
+// StateBinder returns a binder storing keys under the state of the
+// authorization request. As the state is not sent in the token request, value
+// resolves it, such as from the server's record of the authorization code.
+func StateBinder(value func(r *http.Request) string) Binder {
+	return newBinder(BindingState, value)
+}
+
+// RequestURIBinder returns a binder storing keys under the request_uri issued
+// for a pushed authorization request. As the request_uri is not sent in the
+// token request, value resolves it, such as from the server's record of the
+// authorization code.
+func RequestURIBinder(value func(r *http.Request) string) Binder {
+	return newBinder(BindingRequestURI, value)
+}
+
+// SessionBinder returns a binder storing keys under the user agent's session
+// identifier, which value resolves from the token request, such as from a
+// cookie.
+func SessionBinder(value func(r *http.Request) string) Binder {
+	return newBinder(BindingSession, value)
+}
+
+// newBinder returns a binder for binding, resolving nothing if value is nil.
+func newBinder(binding Binding, value func(r *http.Request) string) Binder {
+	if value == nil {
+		value = func(*http.Request) string { return "" }
+	}
+
+	return binder{
+		binding: binding,
+		value:   value,
+	}
+}
@@ -0,0 +1,119 @@
+package pkce
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestBinder_ID(t *testing.T) {
+	tests := []struct {
+		name   string
+		binder Binder
+		want   string
+	}{
+		{
+			name:   "should store code bound keys under the code",
+			binder: CodeBinder(),
+			want:   "value",
+		},
+		{
+			name:   "should namespace state bound keys",
+			binder: StateBinder(nil),
+			want:   "state:value",
+		},
+		{
+			name:   "should namespace request_uri bound keys",
+			binder: RequestURIBinder(nil),
+			want:   "request_uri:value",
+		},
+		{
+			name:   "should namespace session bound keys",
+			binder: SessionBinder(nil),
+			want:   "session:value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.binder.ID("value"); got != tt.want {
+				t.Errorf("ID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinder_TokenRequestValue(t *testing.T) {
+	session := func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}
+
+	tests := []struct {
+		name   string
+		binder Binder
+		want   string
+	}{
+		{
+			name:   "should read the code from the token request",
+			binder: CodeBinder(),
+			want:   "code",
+		},
+		{
+			name:   "should resolve the value with the provided function",
+			binder: SessionBinder(session),
+			want:   "session",
+		},
+		{
+			name:   "should resolve nothing without a function",
+			binder: StateBinder(nil),
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTokenRequest(url.Values{"code": {"code"}})
+			r.Header.Set("X-Session", "session")
+			if err := r.ParseForm(); err != nil {
+				t.Fatalf("ParseForm() unexpected error: %v", err)
+			}
+
+			if got := tt.binder.TokenRequestValue(r); got != tt.want {
+				t.Errorf("TokenRequestValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithManagerBinder(t *testing.T) {
+	store := NewMemoryStore()
+	m := NewKeyManager(store, WithManagerBinder(StateBinder(func(r *http.Request) string {
+		return r.Header.Get("X-State")
+	})))
+	if err := m.Register(context.Background(), "state", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if _, err := store.Get(context.Background(), "state:state"); err != nil {
+		t.Fatalf("Get() should find the key under the bound id, got: %v", err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r := newTokenRequest(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"code"},
+		"code_verifier": {testCodeVerifier},
+	})
+	r.Header.Set("X-State", "state")
+
+	rec := httptest.NewRecorder()
+	m.Middleware(next).ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
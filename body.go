@@ -0,0 +1,185 @@
+package pkce
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+)
+
+const (
+	// Content types of token request bodies negotiated by parseTokenBody.
+	contentTypeForm      = "application/x-www-form-urlencoded"
+	contentTypeJSON      = "application/json"
+	contentTypeMultipart = "multipart/form-data"
+)
+
+// parseForm parses the request's form as http.Request.ParseForm, limiting the
+// size of a form encoded body to the configured maximum with
+// http.MaxBytesReader. Other bodies are left unread for the next handler.
+func parseForm(r *http.Request, config parseConfig) error {
+	if r.PostForm != nil || r.Body == nil || !hasBody(r) || mediaType(r) != contentTypeForm {
+		return r.ParseForm()
+	}
+
+	body, limited, err := limitBody(r, config)
+	if err != nil {
+		return err
+	}
+
+	err = r.ParseForm()
+	r.Body = body
+
+	return limited.err(err)
+}
+
+// parseTokenBody parses the request's form as parseForm, selecting how the
+// body is decoded by its content type, as some gateways translate form encoded
+// token requests to JSON or multipart bodies. JSON bodies must be an object,
+// of which string members are added to the form, and are restored so they can
+// be read by the next handler.
+//
+// ErrUnsupportedContentType is returned for other content types.
+func parseTokenBody(r *http.Request, config parseConfig) error {
+	if r.PostForm != nil {
+		return nil
+	}
+
+	switch mediaType(r) {
+	case contentTypeForm:
+		return parseForm(r, config)
+
+	case contentTypeJSON:
+		return parseJSONForm(r, config)
+
+	case contentTypeMultipart:
+		return parseMultipartForm(r, config)
+
+	default:
+		return ErrUnsupportedContentType
+	}
+}
+
+// parseJSONForm parses a JSON object body into the request's form.
+func parseJSONForm(r *http.Request, config parseConfig) error {
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	body, limited, err := limitBody(r, config)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	body.Close()
+	if err = limited.err(err); err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for name, field := range fields {
+		if value, ok := field.(string); ok {
+			r.PostForm.Set(name, value)
+			r.Form[name] = append([]string{value}, r.Form[name]...)
+		}
+	}
+
+	return nil
+}
+
+// parseMultipartForm parses a multipart body into the request's form, holding
+// at most the configured maximum body size in memory.
+func parseMultipartForm(r *http.Request, config parseConfig) error {
+	body, limited, err := limitBody(r, config)
+	if err != nil {
+		return err
+	}
+
+	err = r.ParseMultipartForm(config.maxBodyBytes)
+	r.Body = body
+
+	return limited.err(err)
+}
+
+// limitBody replaces the request body with one limited to the configured
+// maximum size by http.MaxBytesReader, returning the original body to be
+// restored once read.
+func limitBody(r *http.Request, config parseConfig) (io.ReadCloser, *limitedBody, error) {
+	if r.ContentLength > config.maxBodyBytes {
+		return nil, nil, ErrRequestTooLarge
+	}
+
+	body := r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+
+	limited := &limitedBody{
+		ReadCloser: http.MaxBytesReader(nil, body, config.maxBodyBytes),
+		limit:      config.maxBodyBytes,
+	}
+	r.Body = limited
+
+	return body, limited, nil
+}
+
+// hasBody returns whether the request method has a body which would be read
+// by http.Request.ParseForm.
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+
+	return false
+}
+
+// mediaType returns the media type of the request's content type, or an empty
+// string if it is missing or malformed.
+func mediaType(r *http.Request) string {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+
+	return mediaType
+}
+
+// limitedBody records whether reading a body wrapped by http.MaxBytesReader
+// failed due to exceeding the limit, as older versions of Go do not return a
+// typed error.
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+// Read implements io.Reader.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// err returns ErrRequestTooLarge if err was caused by the body exceeding the
+// limit, otherwise err.
+func (b *limitedBody) err(err error) error {
+	if err != nil && b.exceeded {
+		return ErrRequestTooLarge
+	}
+
+	return err
+}
@@ -0,0 +1,23 @@
+package pkce
+
+// metaClientID provides the metadata name the client_id of a flow is recorded
+// under.
+const metaClientID = "client_id"
+
+// VerifyFor verifies the code verifier as VerifyResult, additionally enforcing
+// that the client_id presented in the token request is identical to the
+// client_id recorded with SetMeta("client_id", clientID) at authorization
+// time, as required by RFC 6749, 4.1.3.
+//
+// ErrClientMismatch is returned if the client_id differs, or if no client_id
+// was recorded, so that a misconfigured server fails closed. The client is
+// checked before the code verifier, so a mismatched client never learns
+// whether its code verifier was correct.
+func (k *Key) VerifyFor(codeVerifier, clientID string) error {
+	registered, ok := k.meta[metaClientID]
+	if !ok || registered == "" || registered != clientID {
+		return ErrClientMismatch
+	}
+
+	return k.verify(codeVerifier)
+}
@@ -0,0 +1,66 @@
+package pkce
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKey_VerifyFor(t *testing.T) {
+	tests := []struct {
+		name         string
+		registered   map[string]string
+		codeVerifier string
+		clientID     string
+		wantErr      error
+	}{
+		{
+			name:         "should verify the code verifier for the registered client",
+			registered:   map[string]string{"client_id": "client"},
+			codeVerifier: testCodeVerifier,
+			clientID:     "client",
+		},
+		{
+			name:         "should error on a different client",
+			registered:   map[string]string{"client_id": "client"},
+			codeVerifier: testCodeVerifier,
+			clientID:     "attacker",
+			wantErr:      ErrClientMismatch,
+		},
+		{
+			name:         "should check the client before the code verifier",
+			registered:   map[string]string{"client_id": "client"},
+			codeVerifier: strings.Repeat("a", verifierMinLen),
+			clientID:     "attacker",
+			wantErr:      ErrClientMismatch,
+		},
+		{
+			name:         "should error on a mismatched code verifier",
+			registered:   map[string]string{"client_id": "client"},
+			codeVerifier: strings.Repeat("a", verifierMinLen),
+			clientID:     "client",
+			wantErr:      ErrVerifierMismatch,
+		},
+		{
+			name:         "should fail closed if no client was recorded",
+			codeVerifier: testCodeVerifier,
+			clientID:     "",
+			wantErr:      ErrClientMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(WithChallengeMethod(S256), WithCodeChallenge(testCodeChallenge))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			for name, value := range tt.registered {
+				key.SetMeta(name, value)
+			}
+
+			if err = key.VerifyFor(tt.codeVerifier, tt.clientID); err != tt.wantErr {
+				t.Errorf("VerifyFor() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package pkce
+
+import (
+	"time"
+)
+
+// Clock provides the current time to expiry logic, enabling expiration to be
+// tested deterministically and skewed clocks to be compensated for.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock returns a Clock reporting the system's current time.
+func SystemClock() Clock {
+	return ClockFunc(time.Now)
+}
+
+// OffsetClock returns a Clock reporting the time of clock adjusted by offset,
+// enabling a known skew against the authoritative time source to be
+// compensated for.
+func OffsetClock(clock Clock, offset time.Duration) Clock {
+	return ClockFunc(func() time.Time {
+		return clock.Now().Add(offset)
+	})
+}
+
+// now returns the current time of the clock, falling back to the system clock
+// if nil.
+func now(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+
+	return clock.Now()
+}
@@ -0,0 +1,65 @@
+package pkce
+
+import (
+	"testing"
+	"time"
+)
+
+// testClock provides a manually advanced clock for deterministic tests.
+type testClock struct {
+	now time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{
+		now: time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Now implements Clock.
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestOffsetClock(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+	}{
+		{
+			name:   "should report the clock's time without an offset",
+			offset: 0,
+		},
+		{
+			name:   "should compensate for a clock running slow",
+			offset: 30 * time.Second,
+		},
+		{
+			name:   "should compensate for a clock running fast",
+			offset: -30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			want := clock.Now().Add(tt.offset)
+			if got := OffsetClock(clock, tt.offset).Now(); !got.Equal(want) {
+				t.Errorf("Now() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSystemClock(t *testing.T) {
+	before := time.Now()
+	got := SystemClock().Now()
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("Now() = %v, should report the system time", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// generated provides a minted code verifier and code challenge pair.
+type generated struct {
+	CodeVerifier        string      `json:"code_verifier"`
+	CodeChallenge       string      `json:"code_challenge"`
+	CodeChallengeMethod pkce.Method `json:"code_challenge_method"`
+}
+
+// generate prints a code verifier and code challenge pair in the requested
+// format.
+func generate(args []string, stdout io.Writer, stderr io.Writer) error {
+	var method pkce.Method = pkce.S256
+
+	fs := newFlagSet("generate", stderr)
+	length := fs.Int("length", 43, "length of the code verifier, between 43 and 128")
+	fs.Var(methodFlag{&method}, "method", "code challenge method, either S256 or plain, or the non-standard S384 or S512")
+	format := fs.String("format", "plain", "output format, one of plain, env or json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	key, err := pkce.New(
+		pkce.WithChallengeMethod(method),
+		pkce.WithCodeVerifierLength(*length),
+	)
+	if err != nil {
+		return err
+	}
+
+	out := generated{
+		CodeVerifier:        key.CodeVerifier(),
+		CodeChallenge:       key.CodeChallenge(),
+		CodeChallengeMethod: key.ChallengeMethod(),
+	}
+
+	switch *format {
+	case "plain":
+		_, err = fmt.Fprintf(stdout, "%s\n%s\n%s\n", out.CodeVerifier, out.CodeChallenge, out.CodeChallengeMethod)
+
+	case "env":
+		_, err = fmt.Fprintf(stdout, "CODE_VERIFIER=%s\nCODE_CHALLENGE=%s\nCODE_CHALLENGE_METHOD=%s\n", out.CodeVerifier, out.CodeChallenge, out.CodeChallengeMethod)
+
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(out)
+
+	default:
+		err = fmt.Errorf("output format must be one of plain, env or json, got %q", *format)
+	}
+
+	return err
+}
+
+// methodFlag parses a code challenge method flag.
+type methodFlag struct {
+	method *pkce.Method
+}
+
+func (f methodFlag) String() string {
+	if f.method == nil {
+		return ""
+	}
+
+	return f.method.String()
+}
+
+func (f methodFlag) Set(value string) error {
+	if value == "" {
+		return pkce.ErrMethodNotSupported
+	}
+
+	return f.method.UnmarshalText([]byte(value))
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantMethod pkce.Method
+		wantLen    int
+		wantErr    bool
+	}{
+		{
+			name:       "should generate an S256 pair by default",
+			args:       []string{"generate"},
+			wantMethod: pkce.S256,
+			wantLen:    43,
+		},
+		{
+			name:       "should generate a plain pair",
+			args:       []string{"generate", "-method", "plain", "-length", "128"},
+			wantMethod: pkce.Plain,
+			wantLen:    128,
+		},
+		{
+			name:       "should generate an S512 pair",
+			args:       []string{"generate", "-method", "S512"},
+			wantMethod: pkce.S512,
+			wantLen:    43,
+		},
+		{
+			name:       "should generate an env pair",
+			args:       []string{"generate", "-format", "env"},
+			wantMethod: pkce.S256,
+			wantLen:    43,
+		},
+		{
+			name:       "should generate a json pair",
+			args:       []string{"generate", "-format", "json"},
+			wantMethod: pkce.S256,
+			wantLen:    43,
+		},
+		{
+			name:    "should error on an unsupported method",
+			args:    []string{"generate", "-method", "S224"},
+			wantErr: true,
+		},
+		{
+			name:    "should error on a non-compliant length",
+			args:    []string{"generate", "-length", "42"},
+			wantErr: true,
+		},
+		{
+			name:    "should error on an unknown format",
+			args:    []string{"generate", "-format", "yaml"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			err := run(tt.args, &stdout, ioutil.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			got := parseGenerated(t, stdout.String())
+			if got.CodeChallengeMethod != tt.wantMethod {
+				t.Errorf("run() method = %v, want %v", got.CodeChallengeMethod, tt.wantMethod)
+			}
+
+			if len(got.CodeVerifier) != tt.wantLen {
+				t.Errorf("run() code verifier length = %v, want %v", len(got.CodeVerifier), tt.wantLen)
+			}
+
+			if !pkce.VerifyCodeVerifier(got.CodeChallengeMethod, got.CodeVerifier, got.CodeChallenge) {
+				t.Errorf("run() code challenge %q does not verify %q", got.CodeChallenge, got.CodeVerifier)
+			}
+		})
+	}
+}
+
+// parseGenerated parses generate output in any format.
+func parseGenerated(t *testing.T, out string) (got generated) {
+	t.Helper()
+
+	if strings.HasPrefix(out, "{") {
+		if err := json.Unmarshal([]byte(out), &got); err != nil {
+			t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+		}
+
+		return got
+	}
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("run() printed %d lines, want 3", len(lines))
+	}
+	for i, line := range lines {
+		if j := strings.IndexByte(line, '='); j != -1 {
+			lines[i] = line[j+1:]
+		}
+	}
+
+	return generated{
+		CodeVerifier:        lines[0],
+		CodeChallenge:       lines[1],
+		CodeChallengeMethod: pkce.Method(lines[2]),
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "should error on a missing command",
+		},
+		{
+			name: "should error on an unknown command",
+			args: []string{"yolo"},
+		},
+		{
+			name: "should error on missing login flags",
+			args: []string{"login", "-client-id", "client"},
+		},
+		{
+			name: "should error on unexpected arguments",
+			args: []string{"generate", "yolo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(tt.args, ioutil.Discard, ioutil.Discard); err != errUsage {
+				t.Errorf("run() error type not expected\ngot:  %v, want: %v\n", err, errUsage)
+			}
+		})
+	}
+}
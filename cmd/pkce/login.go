@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matthewhartstonge/pkce"
+	"github.com/matthewhartstonge/pkce/keychain"
+)
+
+const (
+	// callbackPath provides the path of the loopback redirect uri.
+	callbackPath = "/callback"
+	// keychainService provides the keychain service token responses are
+	// stored under.
+	keychainService = "pkce"
+)
+
+// loginConfig provides the configuration of a native app login flow.
+type loginConfig struct {
+	authorizeURL string
+	tokenURL     string
+	clientID     string
+	issuer       string
+	scope        string
+	port         int
+	out          string
+	timeout      time.Duration
+	// keychain stores the token response, under the keychainService and the
+	// client identifier, if non-nil.
+	keychain keychain.Store
+	// open directs the user agent to the authorization url.
+	open func(authorizeURL string) error
+}
+
+// parseOptions returns the options to parse the authorization response with.
+func (c loginConfig) parseOptions() []pkce.ParseOption {
+	if c.issuer == "" {
+		return nil
+	}
+
+	return []pkce.ParseOption{pkce.WithIssuer(c.issuer)}
+}
+
+// callback provides the result of an authorization response.
+type callback struct {
+	code string
+	err  error
+}
+
+// login runs the RFC 8252 native app authorization code flow, printing the
+// token response.
+func login(args []string, stdout io.Writer, stderr io.Writer) error {
+	cfg := loginConfig{
+		open: openBrowser,
+	}
+
+	fs := newFlagSet("login", stderr)
+	fs.StringVar(&cfg.authorizeURL, "authorize-url", "", "url of the authorization endpoint")
+	fs.StringVar(&cfg.tokenURL, "token-url", "", "url of the token endpoint")
+	fs.StringVar(&cfg.clientID, "client-id", "", "client identifier")
+	fs.StringVar(&cfg.issuer, "issuer", "", "issuer identifier of the authorization server, validated against the response iss parameter")
+	fs.StringVar(&cfg.scope, "scope", "", "space separated scopes to request")
+	fs.IntVar(&cfg.port, "port", 0, "loopback redirect port, defaults to a random port")
+	fs.StringVar(&cfg.out, "out", "", "file to write the token response to with 0600 permissions, defaults to stdout")
+	fs.DurationVar(&cfg.timeout, "timeout", 5*time.Minute, "time to wait for the authorization response")
+	useKeychain := fs.Bool("keychain", false, "store the token response in the operating system's keychain")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *useKeychain {
+		store, err := keychain.New()
+		if err != nil {
+			return err
+		}
+		cfg.keychain = store
+	}
+
+	if cfg.authorizeURL == "" || cfg.tokenURL == "" || cfg.clientID == "" {
+		fmt.Fprintln(stderr, "flags -authorize-url, -token-url and -client-id are required")
+		fs.Usage()
+		return errUsage
+	}
+
+	return runLogin(context.Background(), cfg, stdout, stderr)
+}
+
+// runLogin performs the authorization request via the user agent, receives
+// the authorization response on a loopback redirect uri, and exchanges the
+// authorization code using the code verifier.
+func runLogin(ctx context.Context, cfg loginConfig, stdout io.Writer, stderr io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.timeout)
+	defer cancel()
+
+	key, err := pkce.New()
+	if err != nil {
+		return err
+	}
+
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+
+	// RFC 8252, 7.3. Loopback Interface Redirection.
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(cfg.port)))
+	if err != nil {
+		return err
+	}
+	redirectURI := "http://" + ln.Addr().String() + callbackPath
+
+	callbacks := make(chan callback, 1)
+	srv := &http.Server{
+		Handler:           callbackHandler(state, callbacks, cfg.parseOptions()...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	authorizeURL, err := url.Parse(cfg.authorizeURL)
+	if err != nil {
+		return err
+	}
+	query := authorizeURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", cfg.clientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("state", state)
+	query.Set(pkce.ParamCodeChallenge, key.CodeChallenge())
+	query.Set(pkce.ParamCodeChallengeMethod, key.ChallengeMethod().String())
+	if cfg.scope != "" {
+		query.Set("scope", cfg.scope)
+	}
+	authorizeURL.RawQuery = query.Encode()
+
+	fmt.Fprintf(stderr, "complete the login in your browser, or open:\n\n  %s\n\n", authorizeURL)
+	if err = cfg.open(authorizeURL.String()); err != nil {
+		fmt.Fprintf(stderr, "unable to open a browser: %v\n", err)
+	}
+
+	var cb callback
+	select {
+	case <-ctx.Done():
+		return errors.New("timed out waiting for the authorization response")
+
+	case cb = <-callbacks:
+		if cb.err != nil {
+			return cb.err
+		}
+	}
+
+	tokens, err := exchange(ctx, cfg, cb.code, redirectURI, key)
+	if errors.Is(err, pkce.ErrPossibleDowngradeAttack) {
+		fmt.Fprintln(stderr, "warning: the code verifier was rejected in a manner consistent with a downgrade attack, the authorization request may have been tampered with")
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.keychain != nil:
+		if err = cfg.keychain.Set(keychainService, cfg.clientID, tokens); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "tokens stored in the keychain for service %q and account %q\n", keychainService, cfg.clientID)
+
+		return nil
+
+	case cfg.out != "":
+		return writePrivateFile(cfg.out, tokens)
+
+	default:
+		_, err = stdout.Write(tokens)
+		return err
+	}
+}
+
+// callbackHandler handles the authorization response, in either the query or
+// form_post response mode, sending the issued code or the error to callbacks.
+func callbackHandler(state string, callbacks chan<- callback, opts ...pkce.ParseOption) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackPath, func(w http.ResponseWriter, r *http.Request) {
+		var cb callback
+
+		resp, err := pkce.ParseAuthorizationResponse(r, opts...)
+		switch {
+		case err == pkce.ErrIssuerMismatch || err == pkce.ErrIssuerMissing:
+			// RFC 9207, 2.4. The flow must be aborted on a mix-up.
+			cb.err = err
+
+		case resp == nil || resp.State != state:
+			http.Error(w, "invalid authorization response", http.StatusBadRequest)
+			return
+
+		default:
+			cb.code, cb.err = resp.Code, err
+		}
+		if err != nil {
+			fmt.Fprintln(w, "login failed, you may close this window.")
+		} else {
+			fmt.Fprintln(w, "login complete, you may close this window.")
+		}
+
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})
+
+	return mux
+}
+
+// exchange exchanges the authorization code for tokens using the key's code
+// verifier, returning the token response indented.
+func exchange(ctx context.Context, cfg loginConfig, code string, redirectURI string, key *pkce.Key) ([]byte, error) {
+	form := url.Values{
+		"grant_type":           {"authorization_code"},
+		"code":                 {code},
+		"redirect_uri":         {redirectURI},
+		"client_id":            {cfg.clientID},
+		pkce.ParamCodeVerifier: {key.CodeVerifier()},
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, pkce.ParseTokenError(key, res)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err = json.Indent(&out, body, "", "  "); err != nil {
+		return nil, fmt.Errorf("token response is not JSON: %v", err)
+	}
+	out.WriteByte('\n')
+
+	return out.Bytes(), nil
+}
+
+// writePrivateFile writes data to the named file, ensuring only the owner can
+// read it, even if it already exists.
+func writePrivateFile(name string, data []byte) (err error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+
+	return err
+}
+
+// openBrowser opens the url in the system browser.
+func openBrowser(u string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+
+	return cmd.Start()
+}
+
+// randomState returns a random state value, for binding the authorization
+// response to the request.
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matthewhartstonge/pkce"
+	"github.com/matthewhartstonge/pkce/keychain"
+	"github.com/matthewhartstonge/pkce/pkcetest"
+)
+
+// followAuthorize returns a user agent which follows the authorization
+// server's redirect to the loopback redirect uri, optionally rewriting the
+// authorization response.
+func followAuthorize(rewrite func(query url.Values)) func(string) error {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return func(authorizeURL string) error {
+		res, err := client.Get(authorizeURL)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		if err != nil {
+			return err
+		}
+		if rewrite != nil {
+			query := location.Query()
+			rewrite(query)
+			location.RawQuery = query.Encode()
+		}
+
+		res, err = client.Get(location.String())
+		if err != nil {
+			return err
+		}
+
+		return res.Body.Close()
+	}
+}
+
+func TestRunLogin(t *testing.T) {
+	s := pkcetest.NewServer()
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		tokenURL string
+		issuer   string
+		open     func(string) error
+		wantErr  bool
+	}{
+		{
+			name:     "should print the token response",
+			tokenURL: s.TokenURL(),
+			open:     followAuthorize(nil),
+		},
+		{
+			name:     "should error on an authorization error response",
+			tokenURL: s.TokenURL(),
+			open: followAuthorize(func(query url.Values) {
+				query.Del("code")
+				query.Set("error", "access_denied")
+			}),
+			wantErr: true,
+		},
+		{
+			name:     "should ignore an authorization response with a mismatched state",
+			tokenURL: s.TokenURL(),
+			open: followAuthorize(func(query url.Values) {
+				query.Set("state", "yolo")
+			}),
+			wantErr: true,
+		},
+		{
+			name:     "should error on a mismatched issuer",
+			tokenURL: s.TokenURL(),
+			issuer:   s.URL,
+			open: followAuthorize(func(query url.Values) {
+				query.Set("iss", "https://attacker.example.com")
+			}),
+			wantErr: true,
+		},
+		{
+			name:     "should accept a matching issuer",
+			tokenURL: s.TokenURL(),
+			issuer:   s.URL,
+			open: followAuthorize(func(query url.Values) {
+				query.Set("iss", s.URL)
+			}),
+		},
+		{
+			name:     "should error on a token error response",
+			tokenURL: s.TokenURL(),
+			open: followAuthorize(func(query url.Values) {
+				query.Set("code", "yolo")
+			}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loginConfig{
+				authorizeURL: s.AuthorizeURL(),
+				tokenURL:     tt.tokenURL,
+				clientID:     "client",
+				issuer:       tt.issuer,
+				timeout:      time.Second,
+				open:         tt.open,
+			}
+
+			var stdout bytes.Buffer
+			err := runLogin(context.Background(), cfg, &stdout, ioutil.Discard)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runLogin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var tokens map[string]interface{}
+			if err = json.Unmarshal(stdout.Bytes(), &tokens); err != nil || tokens["access_token"] == nil {
+				t.Errorf("runLogin() should print the token response, got: %s", stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunLogin_downgrade(t *testing.T) {
+	s := pkcetest.NewServer()
+	defer s.Close()
+
+	follow := followAuthorize(nil)
+	cfg := loginConfig{
+		authorizeURL: s.AuthorizeURL(),
+		tokenURL:     s.TokenURL(),
+		clientID:     "client",
+		timeout:      time.Second,
+		open: func(authorizeURL string) error {
+			// a MITM strips the code challenge method, so the S256 code
+			// challenge is registered as plain.
+			u, err := url.Parse(authorizeURL)
+			if err != nil {
+				return err
+			}
+			query := u.Query()
+			query.Del(pkce.ParamCodeChallengeMethod)
+			u.RawQuery = query.Encode()
+
+			return follow(u.String())
+		},
+	}
+
+	var stderr bytes.Buffer
+	err := runLogin(context.Background(), cfg, ioutil.Discard, &stderr)
+	if !errors.Is(err, pkce.ErrPossibleDowngradeAttack) {
+		t.Fatalf("runLogin() error = %v, want %v", err, pkce.ErrPossibleDowngradeAttack)
+	}
+	if !strings.Contains(stderr.String(), "downgrade attack") {
+		t.Errorf("runLogin() should warn of a downgrade attack, got: %s", stderr.String())
+	}
+}
+
+func TestRunLogin_out(t *testing.T) {
+	s := pkcetest.NewServer()
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "pkce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "tokens.json")
+	if err = ioutil.WriteFile(out, []byte("stale"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loginConfig{
+		authorizeURL: s.AuthorizeURL(),
+		tokenURL:     s.TokenURL(),
+		clientID:     "client",
+		out:          out,
+		timeout:      time.Second,
+		open:         followAuthorize(nil),
+	}
+	if err = runLogin(context.Background(), cfg, ioutil.Discard, ioutil.Discard); err != nil {
+		t.Fatalf("runLogin() unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("runLogin() file permissions = %v, want %v", perm, os.FileMode(0600))
+	}
+
+	data, _ := ioutil.ReadFile(out)
+	var tokens map[string]interface{}
+	if err = json.Unmarshal(data, &tokens); err != nil || tokens["access_token"] == nil {
+		t.Errorf("runLogin() should write the token response, got: %s", data)
+	}
+}
+
+func TestRunLogin_keychain(t *testing.T) {
+	s := pkcetest.NewServer()
+	defer s.Close()
+
+	store := keychain.NewMemoryStore()
+	cfg := loginConfig{
+		authorizeURL: s.AuthorizeURL(),
+		tokenURL:     s.TokenURL(),
+		clientID:     "client",
+		timeout:      time.Second,
+		open:         followAuthorize(nil),
+		keychain:     store,
+	}
+
+	var stdout bytes.Buffer
+	if err := runLogin(context.Background(), cfg, &stdout, ioutil.Discard); err != nil {
+		t.Fatalf("runLogin() unexpected error: %v", err)
+	}
+
+	if stdout.Len() != 0 {
+		t.Errorf("runLogin() should not print the token response, got: %s", stdout.String())
+	}
+
+	data, err := store.Get(keychainService, "client")
+	if err != nil {
+		t.Fatalf("keychain.Get() unexpected error: %v", err)
+	}
+
+	var tokens map[string]interface{}
+	if err = json.Unmarshal(data, &tokens); err != nil || tokens["access_token"] == nil {
+		t.Errorf("runLogin() should store the token response, got: %s", data)
+	}
+}
@@ -0,0 +1,102 @@
+// Command pkce mints and checks PKCE material, for testing OAuth 2.0 flows
+// with tools such as curl without writing Go.
+//
+// Usage:
+//
+//	pkce generate [-length 43] [-method S256] [-format plain|env|json]
+//	pkce verify [-method S256] -verifier <code verifier> -challenge <code challenge>
+//	pkce login -authorize-url <url> -token-url <url> -client-id <id> [-issuer <issuer>] [-scope <scopes>] [-out <file>] [-keychain]
+//
+// verify exits with a non-zero status, printing the reason, if the code
+// verifier does not match the code challenge.
+//
+// login runs the native app authorization code flow, opening the browser and
+// receiving the authorization response on a loopback redirect uri, then
+// prints the token response, writes it to a file readable only by the owner,
+// or stores it in the operating system's keychain.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// usage describes the available commands.
+const usage = `usage: pkce <command> [flags]
+
+commands:
+  generate  print a code verifier and code challenge pair
+  verify    check a code verifier matches a code challenge
+  login     run the native app flow and print the resulting tokens
+`
+
+// errUsage is returned when the command line can not be parsed, once the
+// usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+// command provides a subcommand, run with its arguments.
+type command func(args []string, stdout io.Writer, stderr io.Writer) error
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, os.Stderr)
+	switch err {
+	case nil:
+
+	case errUsage:
+		os.Exit(2)
+
+	default:
+		fmt.Fprintf(os.Stderr, "pkce: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the subcommand named by the first argument.
+func run(args []string, stdout io.Writer, stderr io.Writer) error {
+	commands := map[string]command{
+		"generate": generate,
+		"login":    login,
+		"verify":   verify,
+	}
+
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return errUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "pkce: unknown command %q\n\n%s", args[0], usage)
+		return errUsage
+	}
+
+	return cmd(args[1:], stdout, stderr)
+}
+
+// newFlagSet returns a flag set for the named subcommand, writing errors and
+// usage to stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("pkce "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	return fs
+}
+
+// parseFlags parses the subcommand's flags, mapping parse failures to
+// errUsage as the flag set reports them.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %q\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
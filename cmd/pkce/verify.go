@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// verify checks a code verifier against a code challenge, returning the reason
+// verification failed.
+func verify(args []string, stdout io.Writer, stderr io.Writer) error {
+	var method pkce.Method = pkce.S256
+
+	fs := newFlagSet("verify", stderr)
+	fs.Var(methodFlag{&method}, "method", "code challenge method, either S256 or plain, or the non-standard S384 or S512")
+	codeVerifier := fs.String("verifier", "", "code verifier sent in the token request")
+	codeChallenge := fs.String("challenge", "", "code challenge sent in the authorization request")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *codeVerifier == "" || *codeChallenge == "" {
+		fmt.Fprintln(stderr, "flags -verifier and -challenge are required")
+		fs.Usage()
+		return errUsage
+	}
+
+	if err := verifyCodeVerifier(method, *codeVerifier, *codeChallenge); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(stdout, "ok: code verifier matches the code challenge")
+
+	return err
+}
+
+// verifyCodeVerifier returns an error describing why the code verifier does
+// not verify against the code challenge.
+func verifyCodeVerifier(method pkce.Method, codeVerifier string, codeChallenge string) error {
+	if method != pkce.Plain && pkce.ChallengesEqual(codeVerifier, codeChallenge) {
+		return pkce.ErrMethodDowngrade
+	}
+
+	want, err := pkce.GenerateCodeChallenge(method, codeVerifier)
+	if err != nil {
+		return err
+	}
+
+	if pkce.ChallengesEqual(want, codeChallenge) {
+		return nil
+	}
+
+	// report the common client mistake of encoding the hashed code challenge
+	// with padded, or standard alphabet, base64.
+	if method != pkce.Plain && pkce.ChallengesEqual(want, pkce.NormalizeCodeChallenge(codeChallenge)) {
+		return pkce.ErrChallengeEncoding
+	}
+
+	return pkce.ErrVerifierMismatch
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestVerify(t *testing.T) {
+	const (
+		codeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		codeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{
+			name: "should verify an S256 code verifier",
+			args: []string{"verify", "-verifier", codeVerifier, "-challenge", codeChallenge},
+		},
+		{
+			name: "should verify a plain code verifier",
+			args: []string{"verify", "-method", "plain", "-verifier", codeVerifier, "-challenge", codeVerifier},
+		},
+		{
+			name:    "should error on a mismatched code verifier",
+			args:    []string{"verify", "-method", "plain", "-verifier", codeVerifier, "-challenge", codeChallenge},
+			wantErr: pkce.ErrVerifierMismatch,
+		},
+		{
+			name:    "should error on a padded code challenge",
+			args:    []string{"verify", "-verifier", codeVerifier, "-challenge", codeChallenge + "="},
+			wantErr: pkce.ErrChallengeEncoding,
+		},
+		{
+			name:    "should error on the code challenge presented as a code verifier",
+			args:    []string{"verify", "-verifier", codeChallenge, "-challenge", codeChallenge},
+			wantErr: pkce.ErrMethodDowngrade,
+		},
+		{
+			name:    "should error on a non-compliant code verifier",
+			args:    []string{"verify", "-verifier", "yolo", "-challenge", codeChallenge},
+			wantErr: pkce.ErrVerifierLength,
+		},
+		{
+			name:    "should error on a missing code challenge",
+			args:    []string{"verify", "-verifier", codeVerifier},
+			wantErr: errUsage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := run(tt.args, ioutil.Discard, ioutil.Discard); err != tt.wantErr {
+				t.Errorf("run() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
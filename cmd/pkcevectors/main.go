@@ -0,0 +1,48 @@
+// Command pkcevectors writes JSON test vectors of code verifier, transform
+// method and code challenge triples, for validating other PKCE
+// implementations, such as JavaScript, Swift or Kotlin clients, against this
+// package.
+//
+// Usage:
+//
+//	go run github.com/matthewhartstonge/pkce/cmd/pkcevectors -o vectors.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/matthewhartstonge/pkce/pkcetest"
+)
+
+func main() {
+	out := flag.String("o", "", "file to write the vectors to, defaults to stdout")
+	flag.Parse()
+
+	if err := run(*out); err != nil {
+		fmt.Fprintf(os.Stderr, "pkcevectors: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run writes the vectors to the named file, or stdout if name is empty.
+func run(name string) (err error) {
+	var w io.Writer = os.Stdout
+	if name != "" {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+
+		w = f
+	}
+
+	return pkcetest.WriteVectors(w)
+}
@@ -0,0 +1,149 @@
+package pkce
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugReport provides a redacted description of a key, safe to paste into a
+// support ticket when an authorization server rejects a token exchange. The
+// code verifier is never included, only its length.
+type DebugReport struct {
+	// Method provides the code challenge method.
+	Method Method `json:"method"`
+	// Encoding provides the encoding of the code challenge.
+	Encoding ChallengeEncoding `json:"encoding"`
+	// VerifierLength provides the length of the held code verifier, or the
+	// length that will be generated if one has not been generated yet.
+	VerifierLength int `json:"verifier_length"`
+	// Challenge provides the code challenge, which is public, being sent in
+	// the authorization request. Empty if a code verifier has not been
+	// generated yet.
+	Challenge string `json:"challenge,omitempty"`
+	// ChallengeLength provides the length of the code challenge.
+	ChallengeLength int `json:"challenge_length"`
+	// Fingerprint provides a short, non-reversible identifier of the code
+	// challenge, as recorded in audit events.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// CreatedAt provides the time the key was created, if known.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt provides the time the key expires, if it expires.
+	ExpiresAt time.Time `json:"expires_at"`
+	// VerifiedAt provides the time the key was first verified, if verified.
+	VerifiedAt time.Time `json:"verified_at"`
+	// MetaKeys provides the names of the metadata attached to the key. Values
+	// are omitted, as they may identify the user or client.
+	MetaKeys []string `json:"meta_keys,omitempty"`
+
+	// HasVerifier reports whether the key holds a code verifier.
+	HasVerifier bool `json:"has_verifier"`
+	// ServerSide reports whether the key holds a code challenge received from
+	// a client, rather than one derived from its own code verifier.
+	ServerSide bool `json:"server_side"`
+	// Expired reports whether the key had expired when the report was made.
+	Expired bool `json:"expired"`
+	// Pinned reports whether the code challenge has been pinned.
+	Pinned bool `json:"pinned"`
+	// FIPS reports whether the FIPS compliance profile is enforced.
+	FIPS bool `json:"fips"`
+	// Invalid provides the error returned by Validate, if any.
+	Invalid string `json:"invalid,omitempty"`
+	// Inconsistent provides the error returned by SelfCheck, if any.
+	Inconsistent string `json:"inconsistent,omitempty"`
+}
+
+// DebugReport returns a redacted description of the key for diagnosing
+// failed token exchanges. Unlike CodeChallenge, a code verifier is not
+// generated if one has not been generated yet.
+func (k *Key) DebugReport() DebugReport {
+	report := DebugReport{
+		Method:         k.ChallengeMethod(),
+		Encoding:       k.ChallengeEncoding(),
+		VerifierLength: k.codeVerifierLen,
+		CreatedAt:      k.createdAt,
+		ExpiresAt:      k.expiresAt,
+		VerifiedAt:     k.verifiedAt,
+		HasVerifier:    len(k.codeVerifier) > 0,
+		ServerSide:     k.codeChallenge != "",
+		Expired:        k.Expired(),
+		Pinned:         k.Pinned(),
+		FIPS:           k.fips || fipsEnabled(),
+	}
+	if report.HasVerifier {
+		report.VerifierLength = len(k.codeVerifier)
+	}
+
+	switch {
+	case k.codeChallenge != "":
+		report.Challenge = k.codeChallenge
+	case k.pinnedChallenge != "":
+		report.Challenge = k.pinnedChallenge
+	case report.HasVerifier && isKeyMethod(report.Method):
+		report.Challenge = encodeCodeChallenge(report.Method, report.Encoding, k.codeVerifier)
+	}
+	if report.Challenge != "" {
+		report.ChallengeLength = len(report.Challenge)
+		report.Fingerprint = fingerprint(report.Challenge)
+	}
+
+	for name := range k.meta {
+		report.MetaKeys = append(report.MetaKeys, name)
+	}
+	sort.Strings(report.MetaKeys)
+
+	if err := k.Validate(); err != nil {
+		report.Invalid = err.Error()
+	}
+	if err := k.SelfCheck(); err != nil {
+		report.Inconsistent = err.Error()
+	}
+
+	return report
+}
+
+// String renders the report as aligned "name: value" lines.
+func (r DebugReport) String() string {
+	timestamp := func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	orNone := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+
+		return s
+	}
+
+	lines := [][2]string{
+		{"method", r.Method.String()},
+		{"encoding", r.Encoding.String()},
+		{"verifier length", fmt.Sprint(r.VerifierLength)},
+		{"challenge", orNone(r.Challenge)},
+		{"challenge length", fmt.Sprint(r.ChallengeLength)},
+		{"fingerprint", orNone(r.Fingerprint)},
+		{"created at", timestamp(r.CreatedAt)},
+		{"expires at", timestamp(r.ExpiresAt)},
+		{"verified at", timestamp(r.VerifiedAt)},
+		{"meta keys", orNone(strings.Join(r.MetaKeys, ", "))},
+		{"has verifier", fmt.Sprint(r.HasVerifier)},
+		{"server side", fmt.Sprint(r.ServerSide)},
+		{"expired", fmt.Sprint(r.Expired)},
+		{"pinned", fmt.Sprint(r.Pinned)},
+		{"fips", fmt.Sprint(r.FIPS)},
+		{"invalid", orNone(r.Invalid)},
+		{"inconsistent", orNone(r.Inconsistent)},
+	}
+
+	var sb strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&sb, "%-17s %s\n", line[0]+":", line[1])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package pkce
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKey_DebugReport(t *testing.T) {
+	clock := newTestClock()
+	key, err := New(WithClock(clock), WithExpiry(time.Minute), WithCodeVerifierLength(64))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	report := key.DebugReport()
+	if report.HasVerifier || report.Challenge != "" || report.VerifierLength != 64 {
+		t.Errorf("DebugReport() before generation got = %+v", report)
+	}
+	if len(key.codeVerifier) != 0 {
+		t.Fatal("DebugReport() must not generate a code verifier")
+	}
+
+	codeVerifier := key.CodeVerifier()
+	key.SetMeta("client_id", "secret-client")
+	clock.Advance(2 * time.Minute)
+
+	report = key.DebugReport()
+	if !report.HasVerifier || report.VerifierLength != 64 {
+		t.Errorf("DebugReport() verifier got = %v (%d)", report.HasVerifier, report.VerifierLength)
+	}
+	if report.Challenge != key.CodeChallenge() || report.ChallengeLength != 43 {
+		t.Errorf("DebugReport() challenge got = %q (%d)", report.Challenge, report.ChallengeLength)
+	}
+	if report.Fingerprint != fingerprint(key.CodeChallenge()) {
+		t.Errorf("DebugReport() fingerprint got = %q", report.Fingerprint)
+	}
+	if !report.Expired || report.ExpiresAt != key.ExpiresAt() {
+		t.Errorf("DebugReport() expiry got = %v, %v", report.Expired, report.ExpiresAt)
+	}
+	if len(report.MetaKeys) != 1 || report.MetaKeys[0] != "client_id" {
+		t.Errorf("DebugReport() meta keys got = %v", report.MetaKeys)
+	}
+	if report.Invalid != "" || report.Inconsistent != "" {
+		t.Errorf("DebugReport() errors got = %q, %q", report.Invalid, report.Inconsistent)
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	for name, out := range map[string]string{"String()": report.String(), "JSON": string(encoded)} {
+		if strings.Contains(out, codeVerifier) {
+			t.Errorf("%s leaks the code verifier:\n%s", name, out)
+		}
+		if strings.Contains(out, "secret-client") {
+			t.Errorf("%s leaks metadata values:\n%s", name, out)
+		}
+	}
+
+	if text := report.String(); !strings.Contains(text, "fingerprint:      "+report.Fingerprint+"\n") {
+		t.Errorf("String() got:\n%s", text)
+	}
+}
+
+func TestKey_DebugReport_Inconsistent(t *testing.T) {
+	key, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	_ = key.CodeChallenge()
+	if key.codeVerifier[0] == 'a' {
+		key.codeVerifier[0] = 'b'
+	} else {
+		key.codeVerifier[0] = 'a'
+	}
+
+	if got := key.DebugReport().Inconsistent; got != ErrKeyInconsistent.Error() {
+		t.Errorf("DebugReport().Inconsistent got = %q, want %q", got, ErrKeyInconsistent.Error())
+	}
+}
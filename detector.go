@@ -0,0 +1,197 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FailureReason classifies a failed verification attempt.
+type FailureReason string
+
+const (
+	// FailureMalformed specifies the token request was malformed.
+	FailureMalformed FailureReason = "malformed"
+	// FailureNotFound specifies no key was registered for the code.
+	FailureNotFound FailureReason = "not_found"
+	// FailureExpired specifies the registered key had expired.
+	FailureExpired FailureReason = "expired"
+	// FailureMismatch specifies the code verifier did not match.
+	FailureMismatch FailureReason = "mismatch"
+	// FailureDowngrade specifies the code verifier was presented under a
+	// weaker method than the one registered.
+	FailureDowngrade FailureReason = "downgrade"
+	// FailureSession specifies the code verifier was presented from a
+	// different session than the one the code challenge was bound to.
+	FailureSession FailureReason = "session_mismatch"
+	// FailureRedirectURI specifies the token request's redirect uri did not
+	// match the one recorded for the flow.
+	FailureRedirectURI FailureReason = "redirect_uri_mismatch"
+)
+
+// failureReason returns the failure reason for a verification error.
+func failureReason(err error) FailureReason {
+	switch err {
+	case ErrKeyNotFound:
+		return FailureNotFound
+
+	case ErrKeyExpired:
+		return FailureExpired
+
+	case ErrVerifierMismatch:
+		return FailureMismatch
+
+	case ErrMethodDowngrade:
+		return FailureDowngrade
+
+	case ErrSessionMismatch:
+		return FailureSession
+
+	case ErrRedirectURIMismatch:
+		return FailureRedirectURI
+
+	default:
+		return FailureMalformed
+	}
+}
+
+// FailureStats provides a client's verification failure statistics over a
+// sliding window.
+type FailureStats struct {
+	// Client provides the key identifying the client, as keyed by the
+	// middleware.
+	Client string
+	// Window provides the duration statistics are aggregated over.
+	Window time.Duration
+	// Code provides the authorization code of the latest failed attempt,
+	// enabling the embedding server to revoke it.
+	Code string
+	// Reason provides the reason of the latest failed attempt.
+	Reason FailureReason
+	// Failures provides the number of failed attempts within the window.
+	Failures int
+	// DistinctCodes provides the number of distinct authorization codes
+	// failed against within the window.
+	DistinctCodes int
+	// Reasons provides the number of failed attempts per reason within the
+	// window.
+	Reasons map[FailureReason]int
+	// FirstFailure provides the time of the earliest failure in the window.
+	FirstFailure time.Time
+	// LastFailure provides the time of the latest failure in the window.
+	LastFailure time.Time
+}
+
+// SuspectedDowngrade reports whether any attempt within the window tried to
+// prove possession using a weaker method than registered, which per RFC 7636,
+// 7.2 can only mean a faulty client or a MITM attempting a downgrade attack.
+func (s FailureStats) SuspectedDowngrade() bool {
+	return s.Reasons[FailureDowngrade] > 0
+}
+
+// SuspectedGuessing reports whether the number of mismatched code verifiers,
+// or distinct authorization codes failed against, within the window has
+// reached threshold, indicating code verifiers are being guessed.
+func (s FailureStats) SuspectedGuessing(threshold int) bool {
+	return s.Reasons[FailureMismatch] >= threshold || s.DistinctCodes >= threshold
+}
+
+// Detector inspects a client's verification failure statistics after each
+// failed attempt, enabling the embedding server to flag suspected guessing or
+// downgrade attacks and take action, such as revoking codes or alerting.
+//
+// Implementations must be safe for concurrent use.
+type Detector interface {
+	Detect(ctx context.Context, stats FailureStats)
+}
+
+// DetectorFunc adapts a function to a Detector.
+type DetectorFunc func(ctx context.Context, stats FailureStats)
+
+// Detect implements Detector.
+func (f DetectorFunc) Detect(ctx context.Context, stats FailureStats) {
+	f(ctx, stats)
+}
+
+// failureSweepInterval provides the number of recorded failures between
+// sweeps of idle clients.
+const failureSweepInterval = 1024
+
+// failureTracker aggregates verification failures per client over a sliding
+// window.
+type failureTracker struct {
+	window time.Duration
+	clock  Clock
+
+	mu       sync.Mutex
+	clients  map[string][]failureEvent
+	recorded int
+}
+
+// failureEvent provides a recorded verification failure.
+type failureEvent struct {
+	at     time.Time
+	code   string
+	reason FailureReason
+}
+
+// newFailureTracker returns a tracker aggregating failures over window.
+func newFailureTracker(window time.Duration, clock Clock) *failureTracker {
+	return &failureTracker{
+		window:  window,
+		clock:   clock,
+		clients: map[string][]failureEvent{},
+	}
+}
+
+// record records a failure for the client, returning the client's statistics
+// over the window.
+func (t *failureTracker) record(client string, code string, reason FailureReason) FailureStats {
+	at := now(t.clock)
+	cutoff := at.Add(-t.window)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.recorded++
+	if t.recorded%failureSweepInterval == 0 {
+		for id, events := range t.clients {
+			if len(events) == 0 || !events[len(events)-1].at.After(cutoff) {
+				delete(t.clients, id)
+			}
+		}
+	}
+
+	events := append(t.clients[client], failureEvent{
+		at:     at,
+		code:   code,
+		reason: reason,
+	})
+
+	i := 0
+	for i < len(events) && !events[i].at.After(cutoff) {
+		i++
+	}
+	events = events[i:]
+	t.clients[client] = events
+
+	stats := FailureStats{
+		Client:       client,
+		Window:       t.window,
+		Code:         code,
+		Reason:       reason,
+		Failures:     len(events),
+		Reasons:      map[FailureReason]int{},
+		FirstFailure: events[0].at,
+		LastFailure:  at,
+	}
+
+	codes := map[string]struct{}{}
+	for _, event := range events {
+		stats.Reasons[event.reason]++
+		codes[event.code] = struct{}{}
+	}
+	stats.DistinctCodes = len(codes)
+
+	return stats
+}
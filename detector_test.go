@@ -0,0 +1,153 @@
+package pkce
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_failureTracker(t *testing.T) {
+	clock := newTestClock()
+	tracker := newFailureTracker(time.Minute, clock)
+
+	tracker.record("client", "code-1", FailureMismatch)
+	clock.Advance(30 * time.Second)
+	tracker.record("client", "code-2", FailureNotFound)
+	clock.Advance(20 * time.Second)
+	stats := tracker.record("client", "code-2", FailureMismatch)
+
+	if stats.Failures != 3 || stats.DistinctCodes != 2 || stats.Reasons[FailureMismatch] != 2 {
+		t.Errorf("record() should aggregate failures within the window\ngot: %+v", stats)
+	}
+
+	// the first failure slides out of the window.
+	clock.Advance(20 * time.Second)
+	stats = tracker.record("client", "code-3", FailureDowngrade)
+
+	want := FailureStats{
+		Client:        "client",
+		Window:        time.Minute,
+		Code:          "code-3",
+		Reason:        FailureDowngrade,
+		Failures:      3,
+		DistinctCodes: 2,
+		FirstFailure:  clock.Now().Add(-40 * time.Second),
+		LastFailure:   clock.Now(),
+	}
+	if stats.Client != want.Client ||
+		stats.Window != want.Window ||
+		stats.Code != want.Code ||
+		stats.Reason != want.Reason ||
+		stats.Failures != want.Failures ||
+		stats.DistinctCodes != want.DistinctCodes ||
+		!stats.FirstFailure.Equal(want.FirstFailure) ||
+		!stats.LastFailure.Equal(want.LastFailure) {
+		t.Errorf("record()\ngot:  %+v\nwant: %+v\n", stats, want)
+	}
+
+	if other := tracker.record("other", "code-4", FailureExpired); other.Failures != 1 {
+		t.Errorf("record() should aggregate per client\ngot: %+v", other)
+	}
+}
+
+func TestFailureStats_Suspected(t *testing.T) {
+	tests := []struct {
+		name          string
+		stats         FailureStats
+		wantGuessing  bool
+		wantDowngrade bool
+	}{
+		{
+			name: "should not flag a single mismatch",
+			stats: FailureStats{
+				DistinctCodes: 1,
+				Reasons:       map[FailureReason]int{FailureMismatch: 1},
+			},
+		},
+		{
+			name: "should flag repeated mismatches as guessing",
+			stats: FailureStats{
+				DistinctCodes: 1,
+				Reasons:       map[FailureReason]int{FailureMismatch: 5},
+			},
+			wantGuessing: true,
+		},
+		{
+			name: "should flag failures against many codes as guessing",
+			stats: FailureStats{
+				DistinctCodes: 5,
+				Reasons:       map[FailureReason]int{FailureNotFound: 5},
+			},
+			wantGuessing: true,
+		},
+		{
+			name: "should flag a downgrade attempt",
+			stats: FailureStats{
+				DistinctCodes: 1,
+				Reasons:       map[FailureReason]int{FailureDowngrade: 1},
+			},
+			wantDowngrade: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.SuspectedGuessing(5); got != tt.wantGuessing {
+				t.Errorf("SuspectedGuessing() = %v, want %v", got, tt.wantGuessing)
+			}
+			if got := tt.stats.SuspectedDowngrade(); got != tt.wantDowngrade {
+				t.Errorf("SuspectedDowngrade() = %v, want %v", got, tt.wantDowngrade)
+			}
+		})
+	}
+}
+
+func TestWithDetector(t *testing.T) {
+	var got []FailureStats
+	detector := DetectorFunc(func(_ context.Context, stats FailureStats) {
+		got = append(got, stats)
+	})
+	handler := newTestMiddleware(t, WithDetector(detector, time.Minute))
+
+	for _, codeVerifier := range []string{testCodeVerifier, strings.Repeat("a", verifierMinLen), testCodeChallenge} {
+		handler.ServeHTTP(httptest.NewRecorder(), newTokenRequest(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"client"},
+			"code":          {"code"},
+			"code_verifier": {codeVerifier},
+		}))
+	}
+
+	// the first request succeeds and consumes the code.
+	if len(got) != 2 {
+		t.Fatalf("Detect() should be called for each failure\ngot:  %v, want: %v", len(got), 2)
+	}
+
+	if got[1].Client != "client" || got[1].Failures != 2 || got[1].Reason != FailureNotFound {
+		t.Errorf("Detect() stats not expected\ngot: %+v", got[1])
+	}
+}
+
+func Test_failureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want FailureReason
+	}{
+		{err: ErrKeyNotFound, want: FailureNotFound},
+		{err: ErrKeyExpired, want: FailureExpired},
+		{err: ErrVerifierMismatch, want: FailureMismatch},
+		{err: ErrMethodDowngrade, want: FailureDowngrade},
+		{err: ErrVerifierLength, want: FailureMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.want), func(t *testing.T) {
+			if got := failureReason(tt.err); got != tt.want {
+				t.Errorf("failureReason() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
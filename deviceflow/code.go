@@ -0,0 +1,58 @@
+package deviceflow
+
+import (
+	"strings"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	// UserCodeCharset provides the RFC 8628, 6.1 recommended user code
+	// character set of upper case consonants, which avoids vowels so codes
+	// never spell words, and is unambiguous when read aloud.
+	UserCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+
+	// UserCodeLength provides the number of characters in a generated user
+	// code, providing 20^8, or roughly 34 bits, of entropy as recommended in
+	// RFC 8628, 6.1.
+	UserCodeLength = 8
+
+	// deviceCodeLength provides the length of generated device codes, which
+	// are never shown to users, so are generated with the same entropy as a
+	// default code verifier.
+	deviceCodeLength = 43
+)
+
+// GenerateUserCode generates a cryptographically secure user code, formatted
+// in two dash separated halves for readability, such as "WDJB-MJHT".
+func GenerateUserCode() (string, error) {
+	code, err := pkce.RandomString(UserCodeLength, UserCodeCharset)
+	if err != nil {
+		return "", err
+	}
+
+	return code[:UserCodeLength/2] + "-" + code[UserCodeLength/2:], nil
+}
+
+// GenerateDeviceCode generates a cryptographically secure device code.
+func GenerateDeviceCode() (string, error) {
+	return pkce.GenerateCodeVerifier(deviceCodeLength)
+}
+
+// NormalizeUserCode normalizes a user code as entered by a user, for
+// comparison with an issued user code. As recommended by RFC 8628, 6.1, case
+// and any characters outside of the user code character set, such as
+// dashes and spaces, are ignored.
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+
+		if strings.IndexRune(UserCodeCharset, r) == -1 {
+			return -1
+		}
+
+		return r
+	}, code)
+}
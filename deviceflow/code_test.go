@@ -0,0 +1,64 @@
+package deviceflow
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateUserCode(t *testing.T) {
+	code, err := GenerateUserCode()
+	if err != nil {
+		t.Fatalf("GenerateUserCode() unexpected error: %v", err)
+	}
+
+	if len(code) != UserCodeLength+1 || code[UserCodeLength/2] != '-' {
+		t.Fatalf("GenerateUserCode() = %q, want two dash separated halves", code)
+	}
+
+	if got := NormalizeUserCode(code); len(got) != UserCodeLength || strings.Trim(got, UserCodeCharset) != "" {
+		t.Errorf("GenerateUserCode() = %q, want characters from %q", code, UserCodeCharset)
+	}
+}
+
+func TestGenerateDeviceCode(t *testing.T) {
+	a, err := GenerateDeviceCode()
+	if err != nil {
+		t.Fatalf("GenerateDeviceCode() unexpected error: %v", err)
+	}
+
+	if b, _ := GenerateDeviceCode(); a == b || len(a) != deviceCodeLength {
+		t.Errorf("GenerateDeviceCode() = %q, want unique %d character codes", a, deviceCodeLength)
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{
+			name: "should remove the dash",
+			code: "WDJB-MJHT",
+			want: "WDJBMJHT",
+		},
+		{
+			name: "should ignore case and spaces",
+			code: " wdjb mjht ",
+			want: "WDJBMJHT",
+		},
+		{
+			name: "should remove characters outside of the charset",
+			code: "WDJB_MJHT!",
+			want: "WDJBMJHT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeUserCode(tt.code); got != tt.want {
+				t.Errorf("NormalizeUserCode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Package deviceflow provides an RFC 8628 OAuth 2.0 device authorization
+// grant client, for CLI tools which need to fall back from the authorization
+// code flow with PKCE on devices without a browser, along with user code and
+// device code generation for authorization servers.
+package deviceflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// GrantType provides the RFC 8628, 3.4 device code grant type.
+	GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+	// DefaultInterval provides the RFC 8628, 3.2 polling interval used when
+	// the authorization server does not specify one.
+	DefaultInterval = 5 * time.Second
+
+	// slowDownInterval provides the RFC 8628, 3.5 increase of the polling
+	// interval on receiving a slow_down error.
+	slowDownInterval = 5 * time.Second
+
+	// maxResponseSize limits the size of responses read from the
+	// authorization server.
+	maxResponseSize = 1 << 20
+)
+
+var (
+	// ErrAccessDenied is returned when the user denies the authorization
+	// request, as specified in RFC 8628, 3.5.
+	ErrAccessDenied = errors.New("the authorization request was denied")
+
+	// ErrExpiredToken is returned when the device code expires before the
+	// user completes the authorization request, as specified in RFC 8628,
+	// 3.5.
+	ErrExpiredToken = errors.New("the device code has expired")
+)
+
+// Error provides an RFC 6749, 5.2 error response returned by the device
+// authorization or token endpoint, which is not otherwise handled.
+type Error struct {
+	// Code provides the error code, such as "invalid_client".
+	Code string `json:"error"`
+	// Description provides the optional human-readable error description.
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return "deviceflow: " + e.Code
+	}
+
+	return "deviceflow: " + e.Code + ": " + e.Description
+}
+
+// Authorization provides an RFC 8628, 3.2 device authorization response.
+type Authorization struct {
+	// DeviceCode provides the device verification code.
+	DeviceCode string `json:"device_code"`
+	// UserCode provides the code the user enters at the verification uri.
+	UserCode string `json:"user_code"`
+	// VerificationURI provides the uri the user visits to authorize the
+	// device.
+	VerificationURI string `json:"verification_uri"`
+	// VerificationURIComplete optionally provides a verification uri which
+	// includes the user code, such as for rendering as a QR code.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// ExpiresIn provides the lifetime, in seconds, of the device code.
+	ExpiresIn int `json:"expires_in"`
+	// Interval optionally provides the minimum time, in seconds, to wait
+	// between polling requests.
+	Interval int `json:"interval,omitempty"`
+}
+
+// Token provides an RFC 6749, 5.1 successful token response.
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Client provides an RFC 8628 device authorization grant client.
+type Client struct {
+	// DeviceAuthorizationURL provides the url of the device authorization
+	// endpoint.
+	DeviceAuthorizationURL string
+	// TokenURL provides the url of the token endpoint.
+	TokenURL string
+	// ClientID provides the client identifier.
+	ClientID string
+	// HTTPClient optionally provides the client used to make requests.
+	// Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// sleep waits between polling requests, returning the context's error if
+	// the context is done first.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Authorize requests a device code and user code for the scopes, which
+// should be displayed to the user along with the verification uri before
+// calling Poll.
+func (c *Client) Authorize(ctx context.Context, scopes ...string) (*Authorization, error) {
+	form := url.Values{
+		"client_id": {c.ClientID},
+	}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+
+	var auth Authorization
+	if err := c.post(ctx, c.DeviceAuthorizationURL, form, &auth); err != nil {
+		return nil, err
+	}
+
+	return &auth, nil
+}
+
+// Poll polls the token endpoint at the interval requested by the
+// authorization server until the user has completed the authorization
+// request, returning ErrAccessDenied if the user denies it, ErrExpiredToken
+// if the device code expires, or the context's error if the context is done
+// first.
+func (c *Client) Poll(ctx context.Context, auth *Authorization) (*Token, error) {
+	interval := DefaultInterval
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+
+	form := url.Values{
+		"grant_type":  {GrantType},
+		"device_code": {auth.DeviceCode},
+		"client_id":   {c.ClientID},
+	}
+
+	for {
+		if err := c.wait(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		var token Token
+		err := c.post(ctx, c.TokenURL, form, &token)
+		if err == nil {
+			return &token, nil
+		}
+
+		e, ok := err.(*Error)
+		if !ok {
+			return nil, err
+		}
+
+		switch e.Code {
+		case "authorization_pending":
+			continue
+
+		case "slow_down":
+			interval += slowDownInterval
+
+		case "access_denied":
+			return nil, ErrAccessDenied
+
+		case "expired_token":
+			return nil, ErrExpiredToken
+
+		default:
+			return nil, err
+		}
+	}
+}
+
+// wait waits for the duration, returning the context's error if the context
+// is done first.
+func (c *Client) wait(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		return c.sleep(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+
+	case <-timer.C:
+		return nil
+	}
+}
+
+// post posts the form to the endpoint, decoding a successful response into
+// out, or returning an *Error for an error response.
+func (c *Client) post(ctx context.Context, endpoint string, form url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		e := &Error{}
+		if err = json.Unmarshal(body, e); err != nil || e.Code == "" {
+			return &Error{Code: "invalid_response", Description: res.Status}
+		}
+
+		return e
+	}
+
+	return json.Unmarshal(body, out)
+}
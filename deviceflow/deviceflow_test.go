@@ -0,0 +1,153 @@
+package deviceflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testServer provides a device authorization server which responds to token
+// requests with the queued error codes, before issuing a token.
+func testServer(t *testing.T, errs ...string) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("client_id") != "client" || r.PostFormValue("scope") != "openid profile" {
+			t.Errorf("device authorization request not expected, got: %v", r.PostForm)
+		}
+
+		_ = json.NewEncoder(w).Encode(Authorization{
+			DeviceCode:      "device",
+			UserCode:        "WDJB-MJHT",
+			VerificationURI: "https://as.example.com/device",
+			ExpiresIn:       600,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != GrantType || r.PostFormValue("device_code") != "device" {
+			t.Errorf("token request not expected, got: %v", r.PostForm)
+		}
+
+		if len(errs) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(Error{Code: errs[0]})
+			errs = errs[1:]
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(Token{AccessToken: "token", TokenType: "Bearer"})
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		errs      []string
+		want      *Token
+		wantWaits []time.Duration
+		wantErr   error
+	}{
+		{
+			name:      "should issue a token",
+			want:      &Token{AccessToken: "token", TokenType: "Bearer"},
+			wantWaits: []time.Duration{DefaultInterval},
+		},
+		{
+			name:      "should keep polling while authorization is pending",
+			errs:      []string{"authorization_pending", "authorization_pending"},
+			want:      &Token{AccessToken: "token", TokenType: "Bearer"},
+			wantWaits: []time.Duration{DefaultInterval, DefaultInterval, DefaultInterval},
+		},
+		{
+			name:      "should slow down",
+			errs:      []string{"slow_down"},
+			want:      &Token{AccessToken: "token", TokenType: "Bearer"},
+			wantWaits: []time.Duration{DefaultInterval, DefaultInterval + slowDownInterval},
+		},
+		{
+			name:      "should error on access denied",
+			errs:      []string{"authorization_pending", "access_denied"},
+			wantWaits: []time.Duration{DefaultInterval, DefaultInterval},
+			wantErr:   ErrAccessDenied,
+		},
+		{
+			name:      "should error on an expired device code",
+			errs:      []string{"expired_token"},
+			wantWaits: []time.Duration{DefaultInterval},
+			wantErr:   ErrExpiredToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testServer(t, tt.errs...)
+			defer s.Close()
+
+			var waits []time.Duration
+			c := &Client{
+				DeviceAuthorizationURL: s.URL + "/device",
+				TokenURL:               s.URL + "/token",
+				ClientID:               "client",
+				sleep: func(ctx context.Context, d time.Duration) error {
+					waits = append(waits, d)
+					return nil
+				},
+			}
+
+			auth, err := c.Authorize(context.Background(), "openid", "profile")
+			if err != nil {
+				t.Fatalf("Authorize() unexpected error: %v", err)
+			}
+			if auth.UserCode != "WDJB-MJHT" {
+				t.Errorf("Authorize() user code = %q, want %q", auth.UserCode, "WDJB-MJHT")
+			}
+
+			got, err := c.Poll(context.Background(), auth)
+			if err != tt.wantErr {
+				t.Fatalf("Poll() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Poll() = %+v, want %+v", got, tt.want)
+			}
+
+			if !reflect.DeepEqual(waits, tt.wantWaits) {
+				t.Errorf("Poll() waits = %v, want %v", waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestClient_Poll_error(t *testing.T) {
+	s := testServer(t, "invalid_client")
+	defer s.Close()
+
+	c := &Client{
+		TokenURL: s.URL + "/token",
+		sleep: func(ctx context.Context, d time.Duration) error {
+			return nil
+		},
+	}
+
+	_, err := c.Poll(context.Background(), &Authorization{DeviceCode: "device", Interval: 1})
+	if e, ok := err.(*Error); !ok || e.Code != "invalid_client" {
+		t.Errorf("Poll() error type not expected\ngot:  %v, want: %v\n", err, "invalid_client")
+	}
+}
+
+func TestClient_Poll_context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := &Client{}
+	if _, err := c.Poll(ctx, &Authorization{Interval: 1}); err != context.Canceled {
+		t.Errorf("Poll() error type not expected\ngot:  %v, want: %v\n", err, context.Canceled)
+	}
+}
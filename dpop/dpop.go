@@ -0,0 +1,191 @@
+// Package dpop provides OAuth 2.0 Demonstrating Proof of Possession (DPoP)
+// utilities, as specified in RFC 9449, for clients pairing DPoP with PKCE to
+// harden public client flows.
+//
+// A Flow carries both the PKCE proof key and the DPoP key pair of a single
+// authorization flow, binding the authorization request, via dpop_jkt, and
+// the token request, via the DPoP proof, to the same key pair.
+package dpop
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderDPoP provides the HTTP header a DPoP proof is sent in.
+	HeaderDPoP = "DPoP"
+
+	// ParamJKT provides the authorization request parameter binding the
+	// authorization code to the DPoP key pair, as specified in RFC 9449, 10.
+	ParamJKT = "dpop_jkt"
+
+	// proofType provides the JWT typ of DPoP proofs.
+	proofType = "dpop+jwt"
+
+	// coordinateLen provides the length in bytes of P-256 coordinates and
+	// signature components.
+	coordinateLen = 32
+)
+
+// ErrKeyInvalid is returned when a DPoP key pair is not an ECDSA P-256 key.
+var ErrKeyInvalid = errors.New("dpop: key must be an ECDSA P-256 private key")
+
+// Key provides a DPoP key pair, signing ES256 proofs.
+type Key struct {
+	private *ecdsa.PrivateKey
+}
+
+// GenerateKey returns a new ECDSA P-256 DPoP key pair.
+func GenerateKey() (*Key, error) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{private: private}, nil
+}
+
+// NewKey returns a DPoP key pair from an existing ECDSA P-256 private key,
+// such as one loaded from a client's secure storage.
+func NewKey(private *ecdsa.PrivateKey) (*Key, error) {
+	if private == nil || private.Curve != elliptic.P256() {
+		return nil, ErrKeyInvalid
+	}
+
+	return &Key{private: private}, nil
+}
+
+// Signer returns the key pair as a crypto.Signer.
+func (k *Key) Signer() crypto.Signer {
+	return k.private
+}
+
+// jwk provides the public key as a JSON Web Key, with members in the
+// lexicographic order required to compute its RFC 7638 thumbprint.
+type jwk struct {
+	Crv string `json:"crv"`
+	Kty string `json:"kty"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwk returns the public key as a JSON Web Key.
+func (k *Key) jwk() jwk {
+	return jwk{
+		Crv: "P-256",
+		Kty: "EC",
+		X:   base64.RawURLEncoding.EncodeToString(padCoordinate(k.private.X)),
+		Y:   base64.RawURLEncoding.EncodeToString(padCoordinate(k.private.Y)),
+	}
+}
+
+// Thumbprint returns the RFC 7638 JWK SHA-256 thumbprint of the public key,
+// as sent in the dpop_jkt authorization request parameter.
+func (k *Key) Thumbprint() string {
+	data, _ := json.Marshal(k.jwk())
+	sum := sha256.Sum256(data)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// proofHeader provides the JOSE header of a DPoP proof.
+type proofHeader struct {
+	Type      string `json:"typ"`
+	Algorithm string `json:"alg"`
+	JWK       jwk    `json:"jwk"`
+}
+
+// proofClaims provides the claims of a DPoP proof.
+type proofClaims struct {
+	JWTID    string `json:"jti"`
+	Method   string `json:"htm"`
+	URI      string `json:"htu"`
+	IssuedAt int64  `json:"iat"`
+	Nonce    string `json:"nonce,omitempty"`
+	ATH      string `json:"ath,omitempty"`
+}
+
+// Proof returns a DPoP proof for a request with the HTTP method to the uri,
+// as specified in RFC 9449, 4.2. The query and fragment are removed from the
+// uri. The nonce, if non-empty, provides the server supplied DPoP-Nonce.
+func (k *Key) Proof(method string, uri string, nonce string) (string, error) {
+	return k.proof(proofClaims{
+		Method: method,
+		URI:    uri,
+		Nonce:  nonce,
+	})
+}
+
+// ResourceProof returns a DPoP proof for a protected resource request with
+// the HTTP method to the uri, bound to the access token with the ath claim.
+func (k *Key) ResourceProof(method string, uri string, nonce string, accessToken string) (string, error) {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return k.proof(proofClaims{
+		Method: method,
+		URI:    uri,
+		Nonce:  nonce,
+		ATH:    base64.RawURLEncoding.EncodeToString(sum[:]),
+	})
+}
+
+// proof signs a DPoP proof with the claims.
+func (k *Key) proof(claims proofClaims) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	claims.JWTID = hex.EncodeToString(jti)
+	claims.IssuedAt = time.Now().Unix()
+	if i := strings.IndexAny(claims.URI, "?#"); i != -1 {
+		claims.URI = claims.URI[:i]
+	}
+
+	header, err := json.Marshal(proofHeader{
+		Type:      proofType,
+		Algorithm: "ES256",
+		JWK:       k.jwk(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, sum[:])
+	if err != nil {
+		return "", err
+	}
+
+	// RFC 7518, 3.4. The signature is the concatenation of R and S.
+	signature := append(padCoordinate(r), padCoordinate(s)...)
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// padCoordinate returns the big-endian bytes of n, left padded to the length
+// of a P-256 coordinate.
+func padCoordinate(n *big.Int) []byte {
+	out := make([]byte, coordinateLen)
+	b := n.Bytes()
+
+	return append(out[:coordinateLen-len(b)], b...)
+}
@@ -0,0 +1,157 @@
+package dpop
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+// decodeProof verifies the proof's signature against its embedded jwk,
+// returning its decoded header and claims.
+func decodeProof(t *testing.T, proof string) (header proofHeader, claims map[string]interface{}) {
+	t.Helper()
+
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		t.Fatalf("proof should be a compact JWS, got: %s", proof)
+	}
+
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatalf("proof header is not JSON: %v", err)
+	}
+
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("proof claims are not JSON: %v", err)
+	}
+
+	x, _ := base64.RawURLEncoding.DecodeString(header.JWK.X)
+	y, _ := base64.RawURLEncoding.DecodeString(header.JWK.Y)
+	public := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if len(signature) != 2*coordinateLen {
+		t.Fatalf("proof signature length = %d, want %d", len(signature), 2*coordinateLen)
+	}
+
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:coordinateLen])
+	s := new(big.Int).SetBytes(signature[coordinateLen:])
+	if !ecdsa.Verify(public, sum[:], r, s) {
+		t.Fatal("proof signature does not verify")
+	}
+
+	return header, claims
+}
+
+func TestKey_Proof(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	proof, err := key.Proof("POST", "https://as.example.com/token?yolo=1#frag", "nonce")
+	if err != nil {
+		t.Fatalf("Proof() unexpected error: %v", err)
+	}
+
+	header, claims := decodeProof(t, proof)
+	if header.Type != "dpop+jwt" || header.Algorithm != "ES256" {
+		t.Errorf("Proof() header not expected, got: %+v", header)
+	}
+
+	want := map[string]interface{}{
+		"htm":   "POST",
+		"htu":   "https://as.example.com/token",
+		"nonce": "nonce",
+	}
+	for name, value := range want {
+		if claims[name] != value {
+			t.Errorf("Proof() claim %q = %v, want %v", name, claims[name], value)
+		}
+	}
+
+	if claims["jti"] == "" || claims["iat"] == nil || claims["ath"] != nil {
+		t.Errorf("Proof() claims not expected, got: %v", claims)
+	}
+
+	other, _ := key.Proof("POST", "https://as.example.com/token", "")
+	if _, otherClaims := decodeProof(t, other); otherClaims["jti"] == claims["jti"] {
+		t.Error("Proof() should generate a unique jti")
+	}
+}
+
+func TestKey_ResourceProof(t *testing.T) {
+	key, _ := GenerateKey()
+
+	proof, err := key.ResourceProof("GET", "https://rs.example.com/resource", "", "token")
+	if err != nil {
+		t.Fatalf("ResourceProof() unexpected error: %v", err)
+	}
+
+	_, claims := decodeProof(t, proof)
+	sum := sha256.Sum256([]byte("token"))
+	if claims["ath"] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Errorf("ResourceProof() ath = %v", claims["ath"])
+	}
+	if _, ok := claims["nonce"]; ok {
+		t.Error("ResourceProof() should omit an empty nonce")
+	}
+}
+
+func TestKey_Thumbprint(t *testing.T) {
+	// RFC 7638, 3.1 defines the thumbprint input for an EC key as its
+	// required members in lexicographic order.
+	key, _ := GenerateKey()
+	jwk := key.jwk()
+	input := `{"crv":"P-256","kty":"EC","x":"` + jwk.X + `","y":"` + jwk.Y + `"}`
+	sum := sha256.Sum256([]byte(input))
+
+	if got, want := key.Thumbprint(), base64.RawURLEncoding.EncodeToString(sum[:]); got != want {
+		t.Errorf("Thumbprint() = %v, want %v", got, want)
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	tests := []struct {
+		name    string
+		private *ecdsa.PrivateKey
+		wantErr error
+	}{
+		{
+			name:    "should accept a P-256 key",
+			private: p256,
+		},
+		{
+			name:    "should error on a P-384 key",
+			private: p384,
+			wantErr: ErrKeyInvalid,
+		},
+		{
+			name:    "should error on a nil key",
+			wantErr: ErrKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKey(tt.private); err != tt.wantErr {
+				t.Errorf("NewKey() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
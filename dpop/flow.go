@@ -0,0 +1,78 @@
+package dpop
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// Flow carries the PKCE proof key and the DPoP key pair of a single
+// authorization code flow.
+type Flow struct {
+	// Key provides the PKCE proof key.
+	Key *pkce.Key
+	// DPoP provides the DPoP key pair.
+	DPoP *Key
+}
+
+// NewFlow returns a flow with a new PKCE proof key, configured with opts, and
+// a new DPoP key pair.
+func NewFlow(opts ...pkce.Option) (*Flow, error) {
+	key, err := pkce.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dpopKey, err := GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow{
+		Key:  key,
+		DPoP: dpopKey,
+	}, nil
+}
+
+// AuthorizationParams returns the authorization request parameters of the
+// flow: the code challenge, the code challenge method and the DPoP key
+// thumbprint binding the authorization code to the key pair.
+func (f *Flow) AuthorizationParams() url.Values {
+	return url.Values{
+		pkce.ParamCodeChallenge:       {f.Key.CodeChallenge()},
+		pkce.ParamCodeChallengeMethod: {f.Key.ChallengeMethod().String()},
+		ParamJKT:                      {f.DPoP.Thumbprint()},
+	}
+}
+
+// TokenRequest returns a token request to tokenURL with the form parameters,
+// such as the code, redirect_uri and client_id, adding the code verifier and
+// a DPoP proof. The grant type defaults to authorization_code. The nonce, if
+// non-empty, provides the server supplied DPoP-Nonce, for retrying a request
+// rejected with use_dpop_nonce.
+func (f *Flow) TokenRequest(tokenURL string, form url.Values, nonce string) (*http.Request, error) {
+	proof, err := f.DPoP.Proof(http.MethodPost, tokenURL, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	body := url.Values{}
+	for name, values := range form {
+		body[name] = append([]string(nil), values...)
+	}
+	if body.Get("grant_type") == "" {
+		body.Set("grant_type", "authorization_code")
+	}
+	body.Set(pkce.ParamCodeVerifier, f.Key.CodeVerifier())
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(body.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(HeaderDPoP, proof)
+
+	return req, nil
+}
@@ -0,0 +1,51 @@
+package dpop
+
+import (
+	"io/ioutil"
+	"net/url"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestFlow(t *testing.T) {
+	flow, err := NewFlow(pkce.WithChallengeMethod(pkce.S256))
+	if err != nil {
+		t.Fatalf("NewFlow() unexpected error: %v", err)
+	}
+
+	params := flow.AuthorizationParams()
+	if params.Get(pkce.ParamCodeChallenge) != flow.Key.CodeChallenge() ||
+		params.Get(pkce.ParamCodeChallengeMethod) != pkce.S256.String() ||
+		params.Get(ParamJKT) != flow.DPoP.Thumbprint() {
+		t.Errorf("AuthorizationParams() not expected, got: %v", params)
+	}
+
+	tokenURL := "https://as.example.com/token"
+	req, err := flow.TokenRequest(tokenURL, url.Values{"code": {"code"}}, "nonce")
+	if err != nil {
+		t.Fatalf("TokenRequest() unexpected error: %v", err)
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	form, _ := url.ParseQuery(string(body))
+	if form.Get("grant_type") != "authorization_code" ||
+		form.Get("code") != "code" ||
+		form.Get(pkce.ParamCodeVerifier) != flow.Key.CodeVerifier() {
+		t.Errorf("TokenRequest() form not expected, got: %v", form)
+	}
+
+	header, claims := decodeProof(t, req.Header.Get(HeaderDPoP))
+	if header.JWK != flow.DPoP.jwk() {
+		t.Error("TokenRequest() proof should be signed by the flow's DPoP key")
+	}
+	if claims["htm"] != "POST" || claims["htu"] != tokenURL || claims["nonce"] != "nonce" {
+		t.Errorf("TokenRequest() proof claims not expected, got: %v", claims)
+	}
+}
+
+func TestNewFlow(t *testing.T) {
+	if _, err := NewFlow(pkce.WithChallengeMethod("yolo")); err != pkce.ErrMethodNotSupported {
+		t.Errorf("NewFlow() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrMethodNotSupported)
+	}
+}
@@ -0,0 +1,111 @@
+package pkce
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultDuplicateCacheSize provides the default number of code challenges
+// remembered by a DuplicateCache.
+const DefaultDuplicateCacheSize = 10000
+
+// DuplicateCache remembers recently registered code challenges, detecting the
+// same code challenge being registered for different authorization requests.
+//
+// A code verifier must be freshly generated for every authorization request,
+// so a duplicate code challenge indicates either a faulty client reusing code
+// verifiers, or an attacker replaying an authorization request. Code
+// challenges are remembered for a TTL, with the least recently seen evicted
+// once the cache is full.
+type DuplicateCache struct {
+	config storeConfig
+	size   int
+	ttl    time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// duplicateEntry provides a remembered code challenge.
+type duplicateEntry struct {
+	codeChallenge string
+	id            string
+	expiresAt     time.Time
+}
+
+// NewDuplicateCache returns a cache remembering up to size code challenges,
+// each for ttl. A non-positive size defaults to DefaultDuplicateCacheSize, and
+// a non-positive ttl to DefaultKeyTTL.
+//
+// Detected duplicates are reported to a configured metrics hook as
+// StoreEventDuplicate.
+func NewDuplicateCache(size int, ttl time.Duration, opts ...StoreOption) *DuplicateCache {
+	if size < 1 {
+		size = DefaultDuplicateCacheSize
+	}
+	if ttl <= 0 {
+		ttl = DefaultKeyTTL
+	}
+
+	return &DuplicateCache{
+		config:  newStoreConfig(opts),
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Observe records the code challenge as registered for the authorization
+// request identified by id, returning whether the code challenge has already
+// been seen for a different authorization request.
+func (c *DuplicateCache) Observe(id string, codeChallenge string) bool {
+	at := now(c.config.clock)
+
+	c.mu.Lock()
+	duplicate := false
+	if el, ok := c.entries[codeChallenge]; ok {
+		entry := el.Value.(*duplicateEntry)
+		if at.Before(entry.expiresAt) {
+			duplicate = entry.id != id
+		} else {
+			entry.id = id
+		}
+		entry.expiresAt = at.Add(c.ttl)
+		c.order.MoveToFront(el)
+	} else {
+		c.entries[codeChallenge] = c.order.PushFront(&duplicateEntry{
+			codeChallenge: codeChallenge,
+			id:            id,
+			expiresAt:     at.Add(c.ttl),
+		})
+	}
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	c.mu.Unlock()
+
+	if duplicate {
+		c.config.metrics(StoreEventDuplicate)
+	}
+
+	return duplicate
+}
+
+// Len returns the number of code challenges remembered, including those that
+// have expired but are yet to be evicted.
+func (c *DuplicateCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// remove removes the element from the cache. The caller must hold the lock.
+func (c *DuplicateCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*duplicateEntry).codeChallenge)
+}
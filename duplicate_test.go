@@ -0,0 +1,119 @@
+package pkce
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDuplicateCache_Observe(t *testing.T) {
+	type observation struct {
+		id            string
+		codeChallenge string
+		wait          time.Duration
+		want          bool
+	}
+
+	tests := []struct {
+		name         string
+		size         int
+		observations []observation
+	}{
+		{
+			name: "should flag a code challenge seen for another request",
+			observations: []observation{
+				{id: "a", codeChallenge: "challenge"},
+				{id: "b", codeChallenge: "challenge", want: true},
+			},
+		},
+		{
+			name: "should not flag a code challenge seen for the same request",
+			observations: []observation{
+				{id: "a", codeChallenge: "challenge"},
+				{id: "a", codeChallenge: "challenge"},
+			},
+		},
+		{
+			name: "should not flag different code challenges",
+			observations: []observation{
+				{id: "a", codeChallenge: "challenge"},
+				{id: "b", codeChallenge: "other"},
+			},
+		},
+		{
+			name: "should forget code challenges after the ttl",
+			observations: []observation{
+				{id: "a", codeChallenge: "challenge"},
+				{id: "b", codeChallenge: "challenge", wait: time.Minute},
+				{id: "c", codeChallenge: "challenge", want: true},
+			},
+		},
+		{
+			name: "should evict the least recently seen code challenge",
+			size: 2,
+			observations: []observation{
+				{id: "a", codeChallenge: "challenge"},
+				{id: "b", codeChallenge: "other"},
+				{id: "a", codeChallenge: "challenge"},
+				{id: "c", codeChallenge: "another"},
+				{id: "d", codeChallenge: "challenge", want: true},
+				{id: "e", codeChallenge: "other"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			events := 0
+			c := NewDuplicateCache(tt.size, time.Minute, WithStoreClock(clock), WithMetricsHook(func(event StoreEvent) {
+				if event == StoreEventDuplicate {
+					events++
+				}
+			}))
+
+			wantEvents := 0
+			for i, o := range tt.observations {
+				clock.Advance(o.wait)
+				if got := c.Observe(o.id, o.codeChallenge); got != o.want {
+					t.Errorf("Observe() #%d = %v, want %v", i, got, o.want)
+				}
+				if o.want {
+					wantEvents++
+				}
+			}
+
+			if events != wantEvents {
+				t.Errorf("Observe() duplicate events = %d, want %d", events, wantEvents)
+			}
+
+			if tt.size > 0 && c.Len() > tt.size {
+				t.Errorf("Len() = %d, want at most %d", c.Len(), tt.size)
+			}
+		})
+	}
+}
+
+func TestWithManagerDuplicateCache(t *testing.T) {
+	events := 0
+	cache := NewDuplicateCache(0, 0, WithMetricsHook(func(event StoreEvent) {
+		if event == StoreEventDuplicate {
+			events++
+		}
+	}))
+	m := NewKeyManager(NewMemoryStore(), WithManagerDuplicateCache(cache))
+
+	for _, id := range []string{"a", "b"} {
+		if err := m.Register(context.Background(), id, S256, testCodeChallenge); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+
+	if events != 1 {
+		t.Errorf("Register() duplicate events = %d, want %d", events, 1)
+	}
+
+	if err := m.Verify(context.Background(), "b", testCodeVerifier); err != nil {
+		t.Errorf("Verify() should verify a duplicate code challenge, got: %v", err)
+	}
+}
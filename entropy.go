@@ -0,0 +1,56 @@
+package pkce
+
+import (
+	"math"
+)
+
+// EstimateVerifierEntropy estimates the entropy, in bits, of a code verifier
+// based on the frequency of each character it contains.
+//
+// RFC 7636, 7.1. The security model relies on the fact that the code verifier
+// is not learned or guessed by the attacker. It is vitally important to adhere
+// to this principle. As such, the code verifier has to be created in such a
+// manner that it is cryptographically random and has high entropy that it is
+// not practical for the attacker to guess.
+//
+// The estimate can not prove a code verifier was randomly generated, but
+// enables detecting pathologically weak code verifiers, such as a single
+// repeated character, which are otherwise specification compliant.
+func EstimateVerifierEntropy(v string) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+
+	counts := make(map[byte]int, len(unreserved))
+	for i := 0; i < len(v); i++ {
+		counts[v[i]]++
+	}
+
+	n := float64(len(v))
+
+	var bitsPerChar float64
+	for _, count := range counts {
+		p := float64(count) / n
+		bitsPerChar -= p * math.Log2(p)
+	}
+
+	return bitsPerChar * n
+}
+
+// generatedEntropyBits returns the entropy, in bits, of a code verifier of
+// length n generated uniformly at random from charset.
+func generatedEntropyBits(charset string, n int) float64 {
+	return float64(n) * math.Log2(float64(len(charset)))
+}
+
+// verifierLenForEntropy returns the length of code verifier required to be
+// generated from charset to provide at least bits of entropy.
+func verifierLenForEntropy(charset string, bits int) int {
+	bitsPerChar := generatedEntropyBits(charset, 1)
+	if bitsPerChar == 0 {
+		// a single character charset can never provide entropy.
+		return verifierMaxLen + 1
+	}
+
+	return int(math.Ceil(float64(bits) / bitsPerChar))
+}
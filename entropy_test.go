@@ -0,0 +1,44 @@
+package pkce
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEstimateVerifierEntropy(t *testing.T) {
+	tests := []struct {
+		name string
+		v    string
+		want float64
+	}{
+		{
+			name: "should have no entropy if empty",
+			v:    "",
+			want: 0,
+		},
+		{
+			name: "should have no entropy for a repeated character",
+			v:    strings.Repeat("a", verifierMinLen),
+			want: 0,
+		},
+		{
+			name: "should have one bit per character for two equally frequent characters",
+			v:    strings.Repeat("ab", 22),
+			want: 44,
+		},
+		{
+			name: "should have the maximum entropy for all distinct characters",
+			v:    unreserved,
+			want: float64(len(unreserved)) * math.Log2(float64(len(unreserved))),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateVerifierEntropy(tt.v); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EstimateVerifierEntropy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
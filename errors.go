@@ -5,7 +5,250 @@ import (
 	"fmt"
 )
 
+// Error categories, wrapped by every error returned by the package, enabling
+// callers to match errors coarsely with errors.Is, while still being able to
+// match the specific errors below.
 var (
+	// ErrValidation categorises errors caused by malformed or non-compliant
+	// input, such as an invalid code verifier or unsupported option.
+	ErrValidation = newError(nil, "pkce.validation", "validation failed")
+
+	// ErrSecurityPolicy categorises errors caused by a request violating a
+	// security requirement, such as a code verifier not matching its code
+	// challenge, or a method downgrade.
+	ErrSecurityPolicy = newError(nil, "pkce.security_policy", "security policy violated")
+
+	// ErrStorage categorises errors caused by persisting or retrieving keys,
+	// such as a key not being found, or being undecodable.
+	ErrStorage = newError(nil, "pkce.storage", "storage failed")
+)
+
+// Identifier is implemented by errors providing a stable identifier, such as
+// "pkce.verifier_length", which will not change between releases.
+//
+// Products localizing user facing messages should key translations off the
+// identifier, rather than the English error string, which may be reworded.
+type Identifier interface {
+	error
+
+	// ID returns the stable identifier of the error.
+	ID() string
+}
+
+// ErrorID returns the stable identifier of the first error in err's chain
+// implementing Identifier, or an empty string if there is none.
+func ErrorID(err error) string {
+	var identifier Identifier
+	if !errors.As(err, &identifier) {
+		return ""
+	}
+
+	return identifier.ID()
+}
+
+// Error provides an error returned by the package, wrapping the category of
+// the error, being one of ErrValidation, ErrSecurityPolicy or ErrStorage.
+type Error struct {
+	id       string
+	msg      string
+	category error
+}
+
+// newError returns an error identified by id, wrapping the category.
+func newError(category error, id string, msg string) error {
+	return &Error{
+		id:       id,
+		msg:      msg,
+		category: category,
+	}
+}
+
+// newErrorf returns an error identified by id, wrapping the category,
+// formatted as fmt.Sprintf.
+func newErrorf(category error, id string, format string, args ...interface{}) error {
+	return newError(category, id, fmt.Sprintf(format, args...))
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// ID implements Identifier.
+func (e *Error) ID() string {
+	return e.id
+}
+
+// Category returns the category of the error, being one of ErrValidation,
+// ErrSecurityPolicy or ErrStorage. Categories themselves have no category.
+func (e *Error) Category() error {
+	return e.category
+}
+
+// Unwrap returns the category of the error, enabling matching the category
+// with errors.Is.
+func (e *Error) Unwrap() error {
+	return e.category
+}
+
+// StoreError is returned by a key manager when its store fails with an error
+// not returned by the package, such as a network error, categorising the
+// error as ErrStorage.
+type StoreError struct {
+	// Err provides the error returned by the store.
+	Err error
+}
+
+// newStoreError wraps err as a StoreError, unless it is nil or has already
+// been categorised, such as ErrKeyNotFound.
+func newStoreError(err error) error {
+	var categorised *Error
+	if err == nil || errors.As(err, &categorised) {
+		return err
+	}
+
+	return &StoreError{Err: err}
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+// ID implements Identifier.
+func (e *StoreError) ID() string {
+	return "pkce.store"
+}
+
+// Is reports whether target is ErrStorage, enabling store errors to be
+// matched by category with errors.Is.
+func (e *StoreError) Is(target error) bool {
+	return target == ErrStorage
+}
+
+// Unwrap returns the error returned by the store.
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrChallengeConflict is returned when an authorization request
+	// referencing a pushed authorization request carries a code challenge
+	// conflicting with the pushed code challenge.
+	ErrChallengeConflict = newError(ErrSecurityPolicy, "pkce.challenge_conflict", "code challenge conflicts with the pushed authorization request")
+
+	// ErrChallengePinned is returned when a key's code challenge has been
+	// pinned with PinChallenge, and a mutation would change it.
+	ErrChallengePinned = newError(ErrSecurityPolicy, "pkce.challenge_pinned", "code challenge has been pinned and can not be changed")
+
+	// ErrChallengeDigest is returned when a hashed code challenge does not
+	// decode to exactly one digest of the method's hash, being 32 bytes for
+	// S256, such as when it has been truncated or had data appended.
+	ErrChallengeDigest = newError(ErrValidation, "pkce.challenge_digest", "code challenge must be the base64url encoding of a single digest of the method's hash")
+
+	// ErrChallengeEncoding is returned when a hashed code challenge, such as
+	// S256, has been encoded using base64 padding or the standard base64
+	// alphabet, rather than unpadded base64url as specified in RFC 7636, 4.2.
+	ErrChallengeEncoding = newError(ErrValidation, "pkce.challenge_encoding", "code challenge must be base64url encoded without padding")
+
+	// ErrCharsetInvalid is returned when a code verifier character set is
+	// empty, contains duplicate characters, or contains characters outside of
+	// the unreserved character set as specified in RFC 7636, 4.1.
+	ErrCharsetInvalid = newError(ErrValidation, "pkce.charset_invalid", "code verifier character set must be a non-empty subset of the unreserved characters")
+
+	// ErrChallengeInvalid enforces compliance with the code challenge ABNF as
+	// specified in RFC 7636, 4.2.
+	ErrChallengeInvalid = newErrorf(
+		ErrValidation,
+		"pkce.challenge_invalid",
+		"code challenge must be between %d and %d characters long, containing only unreserved characters",
+		verifierMinLen,
+		verifierMaxLen,
+	)
+
+	// ErrChallengeMissing is returned when an authorization request does not
+	// contain a code challenge.
+	ErrChallengeMissing = newError(ErrValidation, "pkce.challenge_missing", "code challenge is missing from the authorization request")
+
+	// ErrClientMismatch is returned when the client_id of a token request is
+	// not identical to the client_id of the authorization request, as required
+	// by RFC 6749, 4.1.3.
+	ErrClientMismatch = newError(ErrSecurityPolicy, "pkce.client_mismatch", "client_id does not match the authorization request")
+
+	// ErrCodeMissing is returned when an authorization response contains
+	// neither an authorization code nor an error.
+	ErrCodeMissing = newError(ErrValidation, "pkce.code_missing", "authorization code is missing from the authorization response")
+
+	// ErrEncodingNotSupported is returned when an unknown code challenge
+	// encoding is specified.
+	ErrEncodingNotSupported = newError(ErrValidation, "pkce.encoding_not_supported", "code challenge encoding must be one of 'base64url', 'base64url-padded' or 'hex'")
+
+	// ErrEntropyInvalid is returned when the requested minimum entropy can not
+	// be provided by a code verifier generated from the configured character
+	// set within the lengths specified in RFC 7636, 4.1.
+	ErrEntropyInvalid = newErrorf(
+		ErrValidation,
+		"pkce.entropy_invalid",
+		"minimum entropy must be between 1 and %d bits, less for restricted character sets",
+		int(generatedEntropyBits(unreserved, verifierMaxLen)),
+	)
+
+	// ErrEnvNotSet is returned when an environment variable a value is
+	// configured to be read from is not set.
+	ErrEnvNotSet = newError(ErrValidation, "pkce.env_not_set", "environment variable is not set")
+
+	// ErrExpiryInvalid is returned when a negative expiry is specified.
+	ErrExpiryInvalid = newError(ErrValidation, "pkce.expiry_invalid", "expiry must not be negative")
+
+	// ErrFormatVersion is returned when encoded data has been written using a
+	// format version unknown to this version of the library.
+	ErrFormatVersion = newError(ErrStorage, "pkce.format_version", "encoded format version is not supported")
+
+	// ErrHandoffSecret is returned when a secret used to seal or unseal a key
+	// handoff blob is too short to be secure.
+	ErrHandoffSecret = newError(ErrSecurityPolicy, "pkce.handoff_secret", "handoff secret must be at least 32 bytes")
+
+	// ErrHashNotSupported is returned when a hash other than SHA-256, SHA-384
+	// or SHA-512 is specified for the code challenge transform.
+	ErrHashNotSupported = newError(ErrValidation, "pkce.hash_not_supported", "code challenge hash must be one of SHA-256, SHA-384 or SHA-512")
+
+	// ErrHashWeak is returned when a cryptographically weak hash, such as MD5
+	// or SHA-1, is specified for the code challenge transform.
+	ErrHashWeak = newError(ErrSecurityPolicy, "pkce.hash_weak", "code challenge hash must not be a weak hash such as MD5 or SHA-1")
+
+	// ErrIssuerMismatch is returned when the iss parameter of an authorization
+	// response does not match the expected issuer, as specified in RFC 9207.
+	ErrIssuerMismatch = newError(ErrSecurityPolicy, "pkce.issuer_mismatch", "authorization response issuer does not match the expected issuer")
+
+	// ErrIssuerMissing is returned when an authorization response does not
+	// contain a required iss parameter, as specified in RFC 9207.
+	ErrIssuerMissing = newError(ErrSecurityPolicy, "pkce.issuer_missing", "issuer is missing from the authorization response")
+
+	// ErrJSONModeNotSupported is returned when an unknown JSON encoding mode
+	// is specified.
+	ErrJSONModeNotSupported = newError(ErrValidation, "pkce.json_mode_not_supported", "json mode must be either public or full")
+
+	// ErrKeyEncoding is returned when an encoded key is malformed.
+	ErrKeyEncoding = newError(ErrStorage, "pkce.key_encoding", "encoded key is malformed")
+
+	// ErrKeyExpired is returned when a key is used after it has expired.
+	ErrKeyExpired = newError(ErrSecurityPolicy, "pkce.key_expired", "key has expired")
+
+	// ErrKeyInconsistent is returned when a key's state is internally
+	// inconsistent, such as holding a code verifier that does not prove its
+	// code challenge.
+	ErrKeyInconsistent = newError(ErrValidation, "pkce.key_inconsistent", "key state is internally inconsistent")
+
+	// ErrKeyNotFound is returned when a key does not exist in a store, or has
+	// expired.
+	ErrKeyNotFound = newError(ErrStorage, "pkce.key_not_found", "key not found")
+
+	// ErrKeyringKeyNotFound is returned when a keyring does not hold the key
+	// encryption key required to unwrap a data encryption key.
+	ErrKeyringKeyNotFound = newError(ErrStorage, "pkce.keyring_key_not_found", "keyring does not contain the requested key encryption key")
+
+	// ErrLengthInvalid is returned when a random string of less than one
+	// character is requested.
+	ErrLengthInvalid = newError(ErrValidation, "pkce.length_invalid", "random string length must be positive")
+
 	// ErrMethodDowngrade enforces compliance with RFC 7636, 7.2.
 	//
 	// Clients MUST NOT downgrade to "plain" after trying the "S256" method.
@@ -14,23 +257,121 @@ var (
 	// "code_verifier".  Because of this, an error when "S256" is presented
 	// can only mean that the server is faulty or that a MITM attacker is
 	// trying a downgrade attack.
-	ErrMethodDowngrade = errors.New("clients must not downgrade to 'plain' after trying the 'S256' method")
+	ErrMethodDowngrade = newError(ErrSecurityPolicy, "pkce.method_downgrade", "clients must not downgrade to 'plain' after trying the 'S256' method")
+
+	// ErrMethodNotAllowed is returned when a supported transform method has
+	// been disallowed by the configured provider or policy.
+	ErrMethodNotAllowed = newError(ErrSecurityPolicy, "pkce.method_not_allowed", "the transform method is not allowed by the configured policy")
+
+	// ErrMethodNotSupported enforces the use of compliant transform methods,
+	// being plain and S256, as specified in RFC 7636, 4.2, or the S384 and
+	// S512 extensions.
+	ErrMethodNotSupported = newError(ErrValidation, "pkce.method_not_supported", "clients must use one of 'plain', 'S256', 'S384' or 'S512' as a transform method")
+
+	// ErrPolicyInvalid is returned when a policy is internally inconsistent,
+	// such as requiring a method it does not allow.
+	ErrPolicyInvalid = newError(ErrValidation, "pkce.policy_invalid", "policy is invalid")
 
-	// ErrMethodNotSupported enforces the use of compliant transform methods
-	ErrMethodNotSupported = errors.New("clients must use either 'plain' or 'S256' as a transform method")
+	// ErrPossibleDowngradeAttack is wrapped by a *TokenError when a token
+	// request for a key using a hashed method is rejected in a manner
+	// consistent with a downgrade attack, as described in RFC 7636, 7.2, such
+	// as a MITM having stripped the code challenge method from the
+	// authorization request.
+	ErrPossibleDowngradeAttack = newError(ErrSecurityPolicy, "pkce.possible_downgrade_attack", "token request rejection suggests a code challenge method downgrade attack")
+
+	// ErrProviderNotSupported is returned when a provider preset is unknown.
+	ErrProviderNotSupported = newError(ErrValidation, "pkce.provider_not_supported", "provider preset is not supported")
+
+	// ErrRandomCharset is returned when a random string character set is
+	// empty, longer than 256 characters, or contains duplicate characters.
+	ErrRandomCharset = newError(ErrValidation, "pkce.random_charset", "random string character set must contain between 1 and 256 unique characters")
+
+	// ErrRedirectURIMismatch is returned when the redirect uri of a token
+	// request is not identical to the redirect uri of the authorization
+	// request, as required by RFC 6749, 4.1.3.
+	ErrRedirectURIMismatch = newError(ErrSecurityPolicy, "pkce.redirect_uri_mismatch", "redirect uri does not match the authorization request")
+
+	// ErrRequestTooLarge is returned when the body of a request being parsed
+	// exceeds the maximum size configured by WithMaxBodyBytes.
+	ErrRequestTooLarge = newError(ErrValidation, "pkce.request_too_large", "request body exceeds the maximum size")
+
+	// ErrSealedPayload is returned when a sealed payload is malformed, or
+	// fails authentication.
+	ErrSealedPayload = newError(ErrSecurityPolicy, "pkce.sealed_payload", "sealed payload is malformed or has been tampered with")
+
+	// ErrSelfTest is returned when the known-answer self-test produces an
+	// unexpected result, indicating the cryptographic functionality of the
+	// process can not be relied upon.
+	ErrSelfTest = newError(ErrSecurityPolicy, "pkce.self_test", "known-answer self-test failed")
+
+	// ErrSessionMismatch is returned when a code challenge bound to a session
+	// is verified from a different session.
+	ErrSessionMismatch = newError(ErrSecurityPolicy, "pkce.session_mismatch", "code challenge is bound to a different session")
+
+	// ErrSessionSecret is returned when a secret used to bind code challenges
+	// to sessions is too short to be secure.
+	ErrSessionSecret = newError(ErrSecurityPolicy, "pkce.session_secret", "session binding secret must be at least 32 bytes")
+
+	// ErrSignerAlgorithm is returned when a request object signer does not
+	// specify a signing algorithm, as unsigned request objects provide no
+	// integrity protection.
+	ErrSignerAlgorithm = newError(ErrSecurityPolicy, "pkce.signer_algorithm", "request object signer must specify a signing algorithm other than 'none'")
+
+	// ErrStoreInsecure is returned when a FileStore directory, or the secret
+	// sealing its entries, could be read or modified by another user, as it
+	// is not owned by the current user with owner only permissions, or is a
+	// symbolic link.
+	ErrStoreInsecure = newError(ErrSecurityPolicy, "pkce.store_insecure", "file store must be owned by the current user with owner only permissions")
+
+	// ErrStoreSecret is returned when a secret used to seal file store
+	// entries is too short to be secure.
+	ErrStoreSecret = newError(ErrSecurityPolicy, "pkce.store_secret", "file store secret must be at least 32 bytes")
+
+	// ErrStoreUnavailable is returned when a store is failing fast, as its
+	// circuit breaker has been opened by repeated failures.
+	ErrStoreUnavailable = newError(ErrStorage, "pkce.store_unavailable", "store is temporarily unavailable")
+
+	// ErrUnsupportedContentType is returned when a token request body is not
+	// form encoded, JSON or multipart.
+	ErrUnsupportedContentType = newError(ErrValidation, "pkce.unsupported_content_type", "token request content type must be one of 'application/x-www-form-urlencoded', 'application/json' or 'multipart/form-data'")
 
 	// ErrVerifierCharacters enforces character compliance with the unreserved
 	// character set as specified in RFC 7636, 4.1.
-	ErrVerifierCharacters = fmt.Errorf(
+	ErrVerifierCharacters = newErrorf(
+		ErrValidation,
+		"pkce.verifier_characters",
 		"code verifier must only contain unreserved characters from the set: {'%s'}",
 		unreserved,
 	)
 
+	// ErrVerifierEncoding is returned when a code verifier contains
+	// percent-encoded characters, as sent by clients that have encoded the
+	// code verifier twice.
+	ErrVerifierEncoding = newError(ErrValidation, "pkce.verifier_encoding", "code verifier must not contain percent-encoded characters")
+
+	// ErrVerifierEntropy is returned when a code verifier does not meet the
+	// configured minimum estimated entropy, as required by RFC 7636, 7.1.
+	ErrVerifierEntropy = newError(ErrSecurityPolicy, "pkce.verifier_entropy", "code verifier does not have sufficient entropy")
+
 	// ErrVerifierLength enforces compliance with the minimum and maximum
 	// lengths as specified in RFC 7636, 4.1.
-	ErrVerifierLength = fmt.Errorf(
+	ErrVerifierLength = newErrorf(
+		ErrValidation,
+		"pkce.verifier_length",
 		"code verifier must be between %d and %d characters long",
 		verifierMinLen,
 		verifierMaxLen,
 	)
+
+	// ErrVerifierReused is returned when a code verifier supplied to a key has
+	// already been supplied to another key, as detected by a ReuseCache.
+	ErrVerifierReused = newError(ErrSecurityPolicy, "pkce.verifier_reused", "code verifier has already been used by another key")
+
+	// ErrVerifierMismatch is returned when a received code verifier does not
+	// match the registered code challenge.
+	ErrVerifierMismatch = newError(ErrSecurityPolicy, "pkce.verifier_mismatch", "code verifier does not match the code challenge")
+
+	// ErrVerifierMissing is returned when a token request does not contain a
+	// code verifier.
+	ErrVerifierMissing = newError(ErrValidation, "pkce.verifier_missing", "code verifier is missing from the token request")
 )
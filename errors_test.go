@@ -0,0 +1,109 @@
+package pkce
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError_category(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "should categorise a verifier length error as validation", err: ErrVerifierLength, want: ErrValidation},
+		{name: "should categorise an unsupported method as validation", err: ErrMethodNotSupported, want: ErrValidation},
+		{name: "should categorise a mismatch as a security policy violation", err: ErrVerifierMismatch, want: ErrSecurityPolicy},
+		{name: "should categorise a downgrade as a security policy violation", err: ErrMethodDowngrade, want: ErrSecurityPolicy},
+		{name: "should categorise a missing key as storage", err: ErrKeyNotFound, want: ErrStorage},
+		{name: "should categorise an unavailable store as storage", err: ErrStoreUnavailable, want: ErrStorage},
+		{name: "should categorise a wrapped error", err: fmt.Errorf("verifying: %w", ErrKeyExpired), want: ErrSecurityPolicy},
+		{name: "should categorise a compliance error as a security policy violation", err: &ComplianceError{Reason: "yolo"}, want: ErrSecurityPolicy},
+		{name: "should categorise a store error as storage", err: &StoreError{Err: errors.New("yolo")}, want: ErrStorage},
+	}
+
+	categories := []error{ErrValidation, ErrSecurityPolicy, ErrStorage}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, category := range categories {
+				if got := errors.Is(tt.err, category); got != (category == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.err, category, got, category == tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestError_as(t *testing.T) {
+	err := fmt.Errorf("parsing: %w", ErrVerifierCharacters)
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatalf("errors.As() = false, want true")
+	}
+
+	if e.Category() != ErrValidation {
+		t.Errorf("Error.Category() = %v, want %v", e.Category(), ErrValidation)
+	}
+
+	if !errors.Is(err, ErrVerifierCharacters) {
+		t.Errorf("errors.Is() = false, want the specific error to still match")
+	}
+
+	if e.Error() != ErrVerifierCharacters.Error() {
+		t.Errorf("Error.Error() = %q, want %q", e.Error(), ErrVerifierCharacters.Error())
+	}
+}
+
+func Test_newStoreError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantWrapped bool
+	}{
+		{name: "should wrap a store error", err: errors.New("yolo"), wantWrapped: true},
+		{name: "should not wrap a package error", err: ErrKeyNotFound},
+		{name: "should not wrap a wrapped package error", err: fmt.Errorf("decrypting: %w", ErrSealedPayload)},
+		{name: "should not wrap nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newStoreError(tt.err)
+
+			var storeErr *StoreError
+			if errors.As(got, &storeErr) != tt.wantWrapped {
+				t.Errorf("newStoreError() wrapped = %v, want %v", !tt.wantWrapped, tt.wantWrapped)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("newStoreError() error type not expected\ngot:  %v, want: %v\n", got, tt.err)
+			}
+		})
+	}
+}
+
+func TestErrorID(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "should identify a package error", err: ErrVerifierLength, want: "pkce.verifier_length"},
+		{name: "should identify a category", err: ErrValidation, want: "pkce.validation"},
+		{name: "should identify a wrapped error", err: fmt.Errorf("verifying: %w", ErrRedirectURIMismatch), want: "pkce.redirect_uri_mismatch"},
+		{name: "should identify a compliance error", err: &ComplianceError{Reason: "yolo"}, want: "pkce.fips_compliance"},
+		{name: "should identify a store error", err: &StoreError{Err: errors.New("yolo")}, want: "pkce.store"},
+		{name: "should identify an authorization error", err: &AuthorizationError{Code: "access_denied"}, want: "pkce.authorization.access_denied"},
+		{name: "should not identify a foreign error", err: errors.New("yolo"), want: ""},
+		{name: "should not identify nil", err: nil, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ErrorID(tt.err); got != tt.want {
+				t.Errorf("ErrorID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
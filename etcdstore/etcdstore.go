@@ -0,0 +1,118 @@
+// Package etcdstore provides a pkce.Store backed by etcd, enabling code
+// challenges to be shared between authorization server instances.
+//
+// The package is a separate module, so that the pkce module remains free of
+// external dependencies.
+package etcdstore
+
+import (
+	"context"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// defaultPrefix provides the prefix entries are stored under if a prefix is
+// not specified.
+const defaultPrefix = "pkce/"
+
+// Store provides a pkce.Store persisting each entry as an etcd key:
+//
+//	client, err := clientv3.New(clientv3.Config{Endpoints: endpoints})
+//	manager := pkce.NewKeyManager(etcdstore.New(client))
+//
+// Entries which expire are attached to a lease granted with their ttl,
+// rounded up to whole seconds, so that expiry is handled by etcd. Consume
+// deletes the entry, returning its previous value, so each entry is returned
+// to only one of any concurrent callers.
+type Store struct {
+	client *clientv3.Client
+	prefix string
+}
+
+// Option enables variadic store options to be configured.
+type Option func(*Store)
+
+// WithPrefix enables specifying the prefix entries are stored under, such as
+// to share an etcd cluster between applications. Defaults to "pkce/".
+func WithPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// New returns a store persisting entries using client.
+func New(client *clientv3.Client, opts ...Option) *Store {
+	s := &Store{
+		client: client,
+		prefix: defaultPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Put implements pkce.Store.
+func (s *Store) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	var opts []clientv3.OpOption
+	if ttl > 0 {
+		// leases have second resolution, so the ttl is rounded up to ensure
+		// entries do not expire early.
+		lease, err := s.client.Grant(ctx, int64((ttl+time.Second-1)/time.Second))
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, clientv3.WithLease(lease.ID))
+	}
+
+	_, err := s.client.Put(ctx, s.key(id), string(data), opts...)
+
+	return err
+}
+
+// Get implements pkce.Store.
+func (s *Store) Get(ctx context.Context, id string) ([]byte, error) {
+	resp, err := s.client.Get(ctx, s.key(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Kvs) == 0 {
+		return nil, pkce.ErrKeyNotFound
+	}
+
+	return resp.Kvs[0].Value, nil
+}
+
+// Delete implements pkce.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.client.Delete(ctx, s.key(id))
+
+	return err
+}
+
+// Consume implements pkce.Store.
+func (s *Store) Consume(ctx context.Context, id string) ([]byte, error) {
+	// deletes are serialized by etcd, so only the caller whose delete removed
+	// the entry receives its previous value.
+	resp, err := s.client.Delete(ctx, s.key(id), clientv3.WithPrevKV())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.PrevKvs) == 0 {
+		return nil, pkce.ErrKeyNotFound
+	}
+
+	return resp.PrevKvs[0].Value, nil
+}
+
+// key returns the etcd key the entry with id is stored under.
+func (s *Store) key(id string) string {
+	return s.prefix + id
+}
@@ -0,0 +1,74 @@
+package etcdstore
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+
+	"github.com/matthewhartstonge/pkce"
+	"github.com/matthewhartstonge/pkce/storetest"
+)
+
+func TestStore(t *testing.T) {
+	client := newTestClient(t)
+
+	storetest.TestStore(t, func(t *testing.T, _ pkce.Clock) (pkce.Store, func()) {
+		prefix, err := pkce.RandomString(16, pkce.AlphanumericCharset)
+		if err != nil {
+			t.Fatalf("RandomString() unexpected error: %v", err)
+		}
+
+		return New(client, WithPrefix(prefix+"/")), nil
+	}, storetest.WithRealTime(time.Second))
+}
+
+// newTestClient starts an embedded etcd server for the duration of the test,
+// returning a client connected to it.
+func newTestClient(t *testing.T) *clientv3.Client {
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	cfg.ListenClientUrls = []url.URL{freeURL(t)}
+	cfg.AdvertiseClientUrls = cfg.ListenClientUrls
+	cfg.ListenPeerUrls = []url.URL{freeURL(t)}
+	cfg.AdvertisePeerUrls = cfg.ListenPeerUrls
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	server, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatalf("StartEtcd() unexpected error: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("StartEtcd() server not ready")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{cfg.ListenClientUrls[0].String()},
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+// freeURL returns a URL on a free local port.
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() unexpected error: %v", err)
+	}
+	defer l.Close()
+
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
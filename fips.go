@@ -0,0 +1,55 @@
+package pkce
+
+// ComplianceError is returned when a configuration would violate the FIPS
+// compliance profile, which only permits the S256 transform method computed
+// with an approved source of randomness.
+type ComplianceError struct {
+	// Reason describes the violation.
+	Reason string
+}
+
+func (e *ComplianceError) Error() string {
+	return "configuration violates the FIPS compliance profile: " + e.Reason
+}
+
+// ID implements Identifier.
+func (e *ComplianceError) ID() string {
+	return "pkce.fips_compliance"
+}
+
+// Unwrap returns ErrSecurityPolicy, enabling compliance violations to be
+// matched with errors.Is.
+func (e *ComplianceError) Unwrap() error {
+	return ErrSecurityPolicy
+}
+
+// FIPSEnabled reports whether the binary has been built with a FIPS 140
+// validated cryptographic module, such as BoringCrypto, which is in use. If
+// so, the FIPS compliance profile is enforced for all keys and key managers.
+func FIPSEnabled() bool {
+	return fipsEnabled()
+}
+
+// validateFIPSMethod ensures the transform method is permitted by the FIPS
+// compliance profile.
+func validateFIPSMethod(method Method) error {
+	if method != S256 {
+		return &ComplianceError{Reason: "transform method must be 'S256'"}
+	}
+
+	return nil
+}
+
+// validateFIPS ensures the key is configured as permitted by the FIPS
+// compliance profile.
+func validateFIPS(key *Key) error {
+	if err := validateFIPSMethod(key.challengeMethod); err != nil {
+		return err
+	}
+
+	if key.random != nil {
+		return &ComplianceError{Reason: "code verifiers must be generated using crypto/rand"}
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build boringcrypto
+// +build boringcrypto
+
+package pkce
+
+import (
+	"crypto/boring"
+)
+
+// fipsEnabled reports whether BoringCrypto is in use.
+func fipsEnabled() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,10 @@
+//go:build !go1.24 && !boringcrypto
+// +build !go1.24,!boringcrypto
+
+package pkce
+
+// fipsEnabled reports false, as this toolchain provides no FIPS 140 validated
+// cryptographic module.
+func fipsEnabled() bool {
+	return false
+}
@@ -0,0 +1,14 @@
+//go:build go1.24 && !boringcrypto
+// +build go1.24,!boringcrypto
+
+package pkce
+
+import (
+	"crypto/fips140"
+)
+
+// fipsEnabled reports whether the Go Cryptographic Module is running in FIPS
+// 140-3 mode.
+func fipsEnabled() bool {
+	return fips140.Enabled()
+}
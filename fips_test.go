@@ -0,0 +1,85 @@
+package pkce
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestWithFIPSMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantErr bool
+	}{
+		{
+			name: "should create an S256 key",
+			opts: []Option{WithFIPSMode()},
+		},
+		{
+			name:    "should error on the plain method",
+			opts:    []Option{WithFIPSMode(), WithChallengeMethod(Plain)},
+			wantErr: true,
+		},
+		{
+			name:    "should error on a custom source of randomness",
+			opts:    []Option{WithFIPSMode(), WithRandReader(strings.NewReader(strings.Repeat("a", verifierMaxLen)))},
+			wantErr: true,
+		},
+		{
+			name: "should not enforce the profile by default",
+			opts: []Option{WithChallengeMethod(Plain)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if FIPSEnabled() && !tt.wantErr {
+				t.Skip("the FIPS compliance profile is enforced by the build")
+			}
+
+			_, err := New(tt.opts...)
+			if _, ok := err.(*ComplianceError); ok != tt.wantErr {
+				t.Errorf("New() error type not expected\ngot:  %v, wantErr: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithManagerFIPSMode(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        Method
+		codeChallenge string
+		wantErr       bool
+	}{
+		{
+			name:          "should register an S256 code challenge",
+			method:        S256,
+			codeChallenge: testCodeChallenge,
+		},
+		{
+			name:          "should error on a plain code challenge",
+			method:        Plain,
+			codeChallenge: testCodeVerifier,
+			wantErr:       true,
+		},
+		{
+			name:          "should error on a missing method",
+			method:        "",
+			codeChallenge: testCodeVerifier,
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore(), WithManagerFIPSMode())
+
+			err := m.Register(context.Background(), "code", tt.method, tt.codeChallenge)
+			if _, ok := err.(*ComplianceError); ok != tt.wantErr {
+				t.Errorf("Register() error type not expected\ngot:  %v, wantErr: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
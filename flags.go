@@ -0,0 +1,140 @@
+package pkce
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by RegisterFlags, providing the defaults of the
+// registered flags.
+const (
+	EnvMethod         = "PKCE_METHOD"
+	EnvVerifierLength = "PKCE_VERIFIER_LENGTH"
+	EnvExpiry         = "PKCE_EXPIRY"
+)
+
+// Names of the flags registered by RegisterFlags.
+const (
+	FlagMethod         = "pkce-method"
+	FlagVerifierLength = "pkce-verifier-length"
+	FlagExpiry         = "pkce-expiry"
+)
+
+// Flags provides the key configuration exposed as command line flags by
+// RegisterFlags, so tools embedding the package configure PKCE consistently.
+type Flags struct {
+	// Method provides the code challenge method. Defaults to S256.
+	Method Method
+	// VerifierLength provides the length of the code verifier generated.
+	// Defaults to 43.
+	VerifierLength int
+	// Expiry provides the duration the key is valid for, where zero specifies
+	// the key does not expire.
+	Expiry time.Duration
+
+	fs *flag.FlagSet
+	// envErrs provides the errors encountered reading the environment, keyed
+	// by flag name.
+	envErrs map[string]error
+}
+
+// RegisterFlags registers the -pkce-method, -pkce-verifier-length and
+// -pkce-expiry flags on fs, defaulting to the values of the PKCE_METHOD,
+// PKCE_VERIFIER_LENGTH and PKCE_EXPIRY environment variables if set.
+//
+// Once fs has been parsed, Flags.Options returns the configured options.
+// Invalid environment variables are reported by Flags.Options, unless
+// overridden by setting the flag.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	return registerFlags(fs, os.LookupEnv)
+}
+
+// registerFlags registers the flags on fs, reading defaults using lookup.
+func registerFlags(fs *flag.FlagSet, lookup func(key string) (string, bool)) *Flags {
+	f := &Flags{
+		Method:         S256,
+		VerifierLength: verifierMinLen,
+		fs:             fs,
+		envErrs:        map[string]error{},
+	}
+
+	if v, ok := lookup(EnvMethod); ok && v != "" {
+		if err := f.Method.UnmarshalText([]byte(v)); err != nil {
+			f.envErrs[FlagMethod] = fmt.Errorf("%s: %v", EnvMethod, err)
+		}
+	}
+
+	if v, ok := lookup(EnvVerifierLength); ok && v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			f.envErrs[FlagVerifierLength] = fmt.Errorf("%s: %v", EnvVerifierLength, err)
+		} else {
+			f.VerifierLength = n
+		}
+	}
+
+	if v, ok := lookup(EnvExpiry); ok && v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			f.envErrs[FlagExpiry] = fmt.Errorf("%s: %v", EnvExpiry, err)
+		} else {
+			f.Expiry = ttl
+		}
+	}
+
+	fs.Var(methodValue{&f.Method}, FlagMethod, "code challenge method, either S256 or plain, or the non-standard S384 or S512 (env "+EnvMethod+")")
+	fs.IntVar(&f.VerifierLength, FlagVerifierLength, f.VerifierLength, "length of the code verifier, between 43 and 128 (env "+EnvVerifierLength+")")
+	fs.DurationVar(&f.Expiry, FlagExpiry, f.Expiry, "duration the key is valid for, such as 10m, or 0 to never expire (env "+EnvExpiry+")")
+
+	return f
+}
+
+// Options returns the options configuring a key as specified by the flags,
+// returning an error if an environment variable which has not been
+// overridden by its flag is invalid.
+func (f *Flags) Options() ([]Option, error) {
+	if len(f.envErrs) > 0 {
+		set := map[string]bool{}
+		if f.fs != nil {
+			f.fs.Visit(func(fl *flag.Flag) {
+				set[fl.Name] = true
+			})
+		}
+
+		for _, name := range []string{FlagMethod, FlagVerifierLength, FlagExpiry} {
+			if err, ok := f.envErrs[name]; ok && !set[name] {
+				return nil, err
+			}
+		}
+	}
+
+	return []Option{
+		WithChallengeMethod(f.Method),
+		WithCodeVerifierLength(f.VerifierLength),
+		WithExpiry(f.Expiry),
+	}, nil
+}
+
+// methodValue implements flag.Value for a code challenge method.
+type methodValue struct {
+	method *Method
+}
+
+func (v methodValue) String() string {
+	if v.method == nil {
+		return ""
+	}
+
+	return v.method.String()
+}
+
+func (v methodValue) Set(value string) error {
+	if value == "" {
+		return ErrMethodNotSupported
+	}
+
+	return v.method.UnmarshalText([]byte(value))
+}
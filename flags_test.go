@@ -0,0 +1,118 @@
+package pkce
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		args       []string
+		wantMethod Method
+		wantLen    int
+		wantExpiry time.Duration
+		wantErr    string
+	}{
+		{
+			name:       "defaults",
+			wantMethod: S256,
+			wantLen:    43,
+		},
+		{
+			name: "env",
+			env: map[string]string{
+				EnvMethod:         "plain",
+				EnvVerifierLength: "64",
+				EnvExpiry:         "10m",
+			},
+			wantMethod: Plain,
+			wantLen:    64,
+			wantExpiry: 10 * time.Minute,
+		},
+		{
+			name: "flags override env",
+			env: map[string]string{
+				EnvMethod:         "plain",
+				EnvVerifierLength: "64",
+			},
+			args:       []string{"-pkce-method", "S256", "-pkce-verifier-length", "128", "-pkce-expiry", "1m"},
+			wantMethod: S256,
+			wantLen:    128,
+			wantExpiry: time.Minute,
+		},
+		{
+			name:       "hashed method flag",
+			args:       []string{"-pkce-method", "S384"},
+			wantMethod: S384,
+			wantLen:    43,
+		},
+		{
+			name:    "invalid env",
+			env:     map[string]string{EnvExpiry: "soon"},
+			wantErr: EnvExpiry,
+		},
+		{
+			name:       "invalid env overridden by flag",
+			env:        map[string]string{EnvMethod: "S128"},
+			args:       []string{"-pkce-method", "plain"},
+			wantMethod: Plain,
+			wantLen:    43,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+
+			f := registerFlags(fs, func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			opts, err := f.Options()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Options() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Options() error = %v", err)
+			}
+
+			key, err := New(opts...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if got := key.ChallengeMethod(); got != tt.wantMethod {
+				t.Errorf("ChallengeMethod() got = %v, want %v", got, tt.wantMethod)
+			}
+			if got := len(key.CodeVerifier()); got != tt.wantLen {
+				t.Errorf("len(CodeVerifier()) got = %v, want %v", got, tt.wantLen)
+			}
+			if got := key.ExpiresAt().IsZero(); got != (tt.wantExpiry == 0) {
+				t.Errorf("ExpiresAt().IsZero() got = %v, want %v", got, tt.wantExpiry == 0)
+			}
+		})
+	}
+}
+
+func TestRegisterFlags_InvalidMethodFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"-pkce-method", "S128"}); err == nil {
+		t.Error("Parse() expected error for unsupported method")
+	}
+}
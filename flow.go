@@ -0,0 +1,89 @@
+package pkce
+
+import (
+	"context"
+	"errors"
+)
+
+// RetryFlow performs an authorization code flow with fn, retrying failed
+// attempts per the policy, returning the key of the last attempt.
+//
+// A new key, configured with opts, is created for every attempt, so a code
+// verifier is never reused across attempts: naive retry loops resending the
+// same code verifier leak it to every server attempted, and are rejected by
+// many authorization servers. fn must therefore perform the whole flow,
+// from the authorization request to the token request, with the key it is
+// given. If opts supply a code verifier, such as with WithCodeVerifier, the
+// second attempt fails with ErrVerifierReused.
+//
+// Each attempt is bounded by the policy's Timeout. Hedging is not applicable
+// to flows, so HedgeAfter is ignored. Attempts failing with an error
+// categorised as ErrValidation or ErrSecurityPolicy, such as a possible
+// downgrade attack, or with an *AuthorizationError, such as the user denying
+// access, are not retried, nor are attempts once ctx is done.
+func RetryFlow(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context, key *Key) error, opts ...Option) (*Key, error) {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+	backoff := policy.Backoff
+	used := NewReuseCache(policy.Attempts)
+
+	var (
+		key *Key
+		err error
+	)
+	for attempt := 0; attempt < policy.Attempts; attempt++ {
+		if attempt > 0 {
+			if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+				return key, err
+			}
+
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+
+		key, err = New(opts...)
+		if err != nil {
+			return key, err
+		}
+
+		if err = used.Observe([]byte(key.CodeVerifier())); err != nil {
+			return key, err
+		}
+
+		err = attemptFlow(ctx, policy, key, fn)
+		if !retryableFlow(ctx, err) {
+			return key, err
+		}
+	}
+
+	return key, err
+}
+
+// attemptFlow performs fn with key, bounded by the policy timeout.
+func attemptFlow(ctx context.Context, policy RetryPolicy, key *Key, fn func(ctx context.Context, key *Key) error) error {
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	return fn(ctx, key)
+}
+
+// retryableFlow returns whether a flow failing with err should be retried.
+func retryableFlow(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	var authErr *AuthorizationError
+	switch {
+	case errors.Is(err, ErrValidation), errors.Is(err, ErrSecurityPolicy), errors.As(err, &authErr):
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,126 @@
+package pkce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryFlow(t *testing.T) {
+	errTransient := errors.New("connection reset")
+
+	tests := []struct {
+		name         string
+		policy       RetryPolicy
+		opts         []Option
+		script       func(attempt int) error
+		wantErr      error
+		wantOK       bool
+		wantAttempts int
+	}{
+		{
+			name:   "should retry transient errors",
+			policy: RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+			script: func(attempt int) error {
+				if attempt < 2 {
+					return errTransient
+				}
+				return nil
+			},
+			wantOK:       true,
+			wantAttempts: 3,
+		},
+		{
+			name:         "should stop once attempts are exhausted",
+			policy:       RetryPolicy{Attempts: 2},
+			script:       func(int) error { return errTransient },
+			wantErr:      errTransient,
+			wantAttempts: 2,
+		},
+		{
+			name:         "should default to a single attempt",
+			script:       func(int) error { return errTransient },
+			wantErr:      errTransient,
+			wantAttempts: 1,
+		},
+		{
+			name:   "should not retry a possible downgrade attack",
+			policy: RetryPolicy{Attempts: 3},
+			script: func(int) error {
+				return &TokenError{Code: "invalid_grant", possibleDowngrade: true}
+			},
+			wantErr:      ErrPossibleDowngradeAttack,
+			wantAttempts: 1,
+		},
+		{
+			name:         "should not retry an authorization error",
+			policy:       RetryPolicy{Attempts: 3},
+			script:       func(int) error { return &AuthorizationError{Code: "access_denied"} },
+			wantAttempts: 1,
+		},
+		{
+			name:         "should not retry a supplied code verifier",
+			policy:       RetryPolicy{Attempts: 3},
+			opts:         []Option{WithCodeVerifierString("01234567890123456789012345678901234567890123")},
+			script:       func(int) error { return errTransient },
+			wantErr:      ErrVerifierReused,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verifiers []string
+			key, err := RetryFlow(context.Background(), tt.policy, func(ctx context.Context, key *Key) error {
+				verifiers = append(verifiers, key.CodeVerifier())
+				return tt.script(len(verifiers) - 1)
+			}, tt.opts...)
+
+			if (err == nil) != tt.wantOK {
+				t.Errorf("RetryFlow() error = %v, want ok %v", err, tt.wantOK)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("RetryFlow() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(verifiers) != tt.wantAttempts {
+				t.Errorf("RetryFlow() attempts got = %d, want %d", len(verifiers), tt.wantAttempts)
+			}
+
+			seen := map[string]bool{}
+			for _, v := range verifiers {
+				if seen[v] {
+					t.Errorf("RetryFlow() reused code verifier %q", v)
+				}
+				seen[v] = true
+			}
+			if key == nil || key.CodeVerifier() != verifiers[len(verifiers)-1] {
+				t.Error("RetryFlow() should return the key of the last attempt")
+			}
+		})
+	}
+}
+
+func TestRetryFlow_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	_, err := RetryFlow(ctx, RetryPolicy{Attempts: 3, Backoff: time.Hour}, func(ctx context.Context, key *Key) error {
+		attempts++
+		cancel()
+		return errors.New("connection reset")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("RetryFlow() got err = %v after %d attempts, want error after 1 attempt", err, attempts)
+	}
+}
+
+func TestRetryFlow_timeout(t *testing.T) {
+	_, err := RetryFlow(context.Background(), RetryPolicy{Attempts: 2, Timeout: time.Millisecond}, func(ctx context.Context, key *Key) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RetryFlow() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
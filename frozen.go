@@ -0,0 +1,125 @@
+package pkce
+
+import (
+	"time"
+)
+
+// FrozenKey provides an immutable snapshot of a proof key, produced by
+// Key.Freeze.
+//
+// Unlike Key, a FrozenKey is a value type that is safe to copy and safe for
+// concurrent use, as none of its methods mutate it, enabling it to be passed
+// through channels and caches without the risk of a mutable Key being aliased.
+// Verification does not record VerifiedAt.
+type FrozenKey struct {
+	challengeMethod   Method
+	challengeEncoding ChallengeEncoding
+	codeVerifier      string
+	codeChallenge     string
+	clock             Clock
+	expiresAt         time.Time
+	createdAt         time.Time
+	verifiedAt        time.Time
+	meta              map[string]string
+}
+
+// Freeze returns an immutable snapshot of the key, generating the code
+// verifier if it has not been already.
+func (k *Key) Freeze() FrozenKey {
+	return FrozenKey{
+		challengeMethod:   k.ChallengeMethod(),
+		challengeEncoding: k.ChallengeEncoding(),
+		codeVerifier:      k.CodeVerifier(),
+		codeChallenge:     k.CodeChallenge(),
+		clock:             k.clock,
+		expiresAt:         k.expiresAt,
+		createdAt:         k.createdAt,
+		verifiedAt:        k.verifiedAt,
+		// never mutated once frozen, so can be shared by copies.
+		meta: copyMeta(k.meta),
+	}
+}
+
+// ChallengeMethod returns the key's method for generating a code challenge.
+func (f FrozenKey) ChallengeMethod() Method {
+	return f.challengeMethod
+}
+
+// ChallengeEncoding returns the key's encoding for the output of the S256
+// transform.
+func (f FrozenKey) ChallengeEncoding() ChallengeEncoding {
+	return f.challengeEncoding
+}
+
+// CodeVerifier returns the code verifier, or an empty string if the key was
+// frozen holding a received code challenge.
+func (f FrozenKey) CodeVerifier() string {
+	return f.codeVerifier
+}
+
+// CodeChallenge returns the code challenge.
+func (f FrozenKey) CodeChallenge() string {
+	return f.codeChallenge
+}
+
+// ExpiresAt returns the time the key expires. A zero time is returned if the
+// key does not expire.
+func (f FrozenKey) ExpiresAt() time.Time {
+	return f.expiresAt
+}
+
+// CreatedAt returns the time the key was created. A zero time is returned if
+// the creation time is unknown.
+func (f FrozenKey) CreatedAt() time.Time {
+	return f.createdAt
+}
+
+// VerifiedAt returns the time a code verifier was first verified against the
+// key before it was frozen. A zero time is returned if it had not been
+// verified.
+func (f FrozenKey) VerifiedAt() time.Time {
+	return f.verifiedAt
+}
+
+// Expired returns whether the key has expired, according to the key's clock.
+func (f FrozenKey) Expired() bool {
+	return !f.expiresAt.IsZero() && !now(f.clock).Before(f.expiresAt)
+}
+
+// Meta returns a copy of the metadata attached to the key, or nil if none had
+// been attached.
+func (f FrozenKey) Meta() map[string]string {
+	return copyMeta(f.meta)
+}
+
+// VerifyCodeVerifier reports whether the code verifier proves the key's code
+// challenge, comparing in constant time.
+func (f FrozenKey) VerifyCodeVerifier(codeVerifier string) bool {
+	return isKeyMethod(f.challengeMethod) &&
+		verifyChallenge(f.challengeMethod, f.challengeEncoding, codeVerifier, f.codeChallenge)
+}
+
+// Thaw returns a new mutable Key holding the frozen key's state. Mutating the
+// returned key does not affect the frozen key.
+func (f FrozenKey) Thaw() *Key {
+	key := &Key{
+		challengeMethod:   f.challengeMethod,
+		challengeEncoding: f.challengeEncoding,
+		codeVerifierLen:   len(f.codeVerifier),
+		clock:             f.clock,
+		expiresAt:         f.expiresAt,
+		createdAt:         f.createdAt,
+		verifiedAt:        f.verifiedAt,
+		meta:              copyMeta(f.meta),
+	}
+
+	if f.codeVerifier == "" {
+		key.codeVerifierLen = verifierMinLen
+		key.codeChallenge = f.codeChallenge
+	} else {
+		key.codeVerifier = []byte(f.codeVerifier)
+		key.issuedChallenge = f.codeChallenge
+	}
+
+	return key
+}
@@ -0,0 +1,91 @@
+package pkce
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestKey_Freeze(t *testing.T) {
+	key, err := New(WithCodeVerifierString(testCodeVerifier))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	key.SetMeta("client_id", "client")
+
+	frozen := key.Freeze()
+	if frozen.CodeVerifier() != testCodeVerifier || frozen.CodeChallenge() != testCodeChallenge || frozen.ChallengeMethod() != S256 {
+		t.Fatalf("Freeze() = %+v, want the key's state", frozen)
+	}
+
+	// mutating the key must not affect the frozen key.
+	key.SetMeta("client_id", "mutated")
+	if err = key.SetChallengeMethod(Plain); err != ErrMethodDowngrade {
+		t.Fatalf("SetChallengeMethod() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+	if frozen.Meta()["client_id"] != "client" {
+		t.Errorf("Meta() = %v, should not be affected by the key", frozen.Meta())
+	}
+
+	// mutating returned metadata must not affect the frozen key.
+	frozen.Meta()["client_id"] = "mutated"
+	if frozen.Meta()["client_id"] != "client" {
+		t.Errorf("Meta() should return a copy of the frozen key's metadata")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(frozen FrozenKey) {
+			defer wg.Done()
+
+			if !frozen.VerifyCodeVerifier(testCodeVerifier) || frozen.VerifyCodeVerifier(strings.Repeat("a", verifierMinLen)) {
+				t.Errorf("VerifyCodeVerifier() did not verify the frozen code verifier")
+			}
+			_ = frozen.Meta()
+		}(frozen)
+	}
+	wg.Wait()
+
+	if !frozen.VerifiedAt().IsZero() {
+		t.Errorf("VerifiedAt() = %v, verifying a frozen key should not record the verification", frozen.VerifiedAt())
+	}
+}
+
+func TestFrozenKey_Thaw(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{
+			name: "should thaw a key holding a code verifier",
+			opts: []Option{WithCodeVerifierString(testCodeVerifier)},
+		},
+		{
+			name: "should thaw a key holding a received code challenge",
+			opts: []Option{WithCodeChallenge(testCodeChallenge)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(tt.opts...)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			thawed := key.Freeze().Thaw()
+			if err = thawed.Validate(); err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+
+			if err = thawed.SelfCheck(); err != nil {
+				t.Errorf("SelfCheck() unexpected error: %v", err)
+			}
+
+			if !thawed.Equal(key) || thawed.CodeChallenge() != testCodeChallenge {
+				t.Errorf("Thaw() = %+v, want %+v", thawed, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+//go:build go1.18
+// +build go1.18
+
+package pkce
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fuzzSeeds provides code verifiers covering valid, boundary, malformed UTF-8
+// and oversized inputs.
+func fuzzSeeds() []string {
+	return []string{
+		"",
+		testCodeVerifier,
+		"dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk",
+		strings.Repeat("a", verifierMinLen-1),
+		strings.Repeat("~", verifierMinLen),
+		strings.Repeat("Z", verifierMaxLen),
+		strings.Repeat("9", verifierMaxLen+1),
+		strings.Repeat("-._~", 11),
+		" " + testCodeVerifier + "\n",
+		strings.Replace(testCodeVerifier, "~", "%7E", 1),
+		testCodeVerifier + "\x00",
+		"\xff\xfe\xfd" + strings.Repeat("a", verifierMinLen),
+		strings.Repeat("é", verifierMinLen),
+		strings.Repeat("a", 1<<16),
+	}
+}
+
+func FuzzValidateCodeVerifier(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, codeVerifier []byte) {
+		err := validateCodeVerifier(codeVerifier)
+		switch err {
+		case nil:
+			if len(codeVerifier) < verifierMinLen || len(codeVerifier) > verifierMaxLen {
+				t.Fatalf("validateCodeVerifier(%q) accepted a length of %d", codeVerifier, len(codeVerifier))
+			}
+
+			if strings.Trim(string(codeVerifier), unreserved) != "" {
+				t.Fatalf("validateCodeVerifier(%q) accepted reserved characters", codeVerifier)
+			}
+
+			if !utf8.Valid(codeVerifier) {
+				t.Fatalf("validateCodeVerifier(%q) accepted malformed UTF-8", codeVerifier)
+			}
+
+		case ErrVerifierLength, ErrVerifierCharacters:
+
+		default:
+			t.Fatalf("validateCodeVerifier(%q) error type not expected: %v", codeVerifier, err)
+		}
+	})
+}
+
+func FuzzGenerateCodeChallenge(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add(true, seed)
+		f.Add(false, seed)
+	}
+
+	f.Fuzz(func(t *testing.T, s256 bool, codeVerifier string) {
+		method := Plain
+		if s256 {
+			method = S256
+		}
+
+		codeChallenge, err := GenerateCodeChallenge(method, codeVerifier)
+		if err != nil {
+			if codeChallenge != "" {
+				t.Fatalf("GenerateCodeChallenge(%s, %q) returned %q with error: %v", method, codeVerifier, codeChallenge, err)
+			}
+
+			return
+		}
+
+		if err = validateMethodCodeChallenge(method, codeChallenge); err != nil {
+			t.Fatalf("GenerateCodeChallenge(%s, %q) = %q, generated a non-compliant code challenge: %v", method, codeVerifier, codeChallenge, err)
+		}
+
+		if method == Plain && codeChallenge != codeVerifier {
+			t.Fatalf("GenerateCodeChallenge(%s, %q) = %q, want the code verifier", method, codeVerifier, codeChallenge)
+		}
+
+		if !VerifyCodeVerifier(method, codeVerifier, codeChallenge) {
+			t.Fatalf("VerifyCodeVerifier(%s, %q, %q) should verify the generated code challenge", method, codeVerifier, codeChallenge)
+		}
+	})
+}
+
+func FuzzVerifyCodeVerifier(f *testing.F) {
+	for _, seed := range fuzzSeeds() {
+		f.Add("S256", seed, testCodeChallenge)
+		f.Add("plain", seed, seed)
+	}
+	f.Add("S256", testCodeChallenge, testCodeChallenge)
+	f.Add("S256", testCodeVerifier, testCodeChallenge+"=")
+	f.Add("S256", testCodeVerifier, strings.ToUpper(testCodeChallenge))
+	f.Add("s256", testCodeVerifier, testCodeChallenge)
+	f.Add("", testCodeVerifier, testCodeVerifier)
+
+	f.Fuzz(func(t *testing.T, method string, codeVerifier string, codeChallenge string) {
+		if !VerifyCodeVerifier(Method(method), codeVerifier, codeChallenge) {
+			return
+		}
+
+		// a successful verification must only ever be possible for a
+		// compliant code verifier transformed into the exact code challenge.
+		want, err := GenerateCodeChallenge(Method(method), codeVerifier)
+		if err != nil {
+			t.Fatalf("VerifyCodeVerifier(%q, %q, %q) verified a non-compliant code verifier: %v", method, codeVerifier, codeChallenge, err)
+		}
+
+		if Method(method) != Plain && Method(method) != S256 {
+			t.Fatalf("VerifyCodeVerifier(%q, %q, %q) verified an unsupported method", method, codeVerifier, codeChallenge)
+		}
+
+		if want != codeChallenge {
+			t.Fatalf("VerifyCodeVerifier(%q, %q, %q) verified a code challenge other than %q", method, codeVerifier, codeChallenge, want)
+		}
+	})
+}
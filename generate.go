@@ -0,0 +1,192 @@
+package pkce
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// GenerateError is returned by GenerateKeys when entropy could not be read for
+// one or more keys.
+type GenerateError struct {
+	// Errs provides the error reading entropy for each key which could not
+	// be generated.
+	Errs []error
+}
+
+func (e *GenerateError) Error() string {
+	return fmt.Sprintf("failed to generate %d keys: %v", len(e.Errs), e.Errs[0])
+}
+
+// ID implements Identifier.
+func (e *GenerateError) ID() string {
+	return "pkce.generate"
+}
+
+// GenerateKeys returns count keys configured by opts, with code verifiers
+// generated eagerly and concurrently across a pool of GOMAXPROCS workers, such
+// as for pre-issuing flows to a fleet of kiosks.
+//
+// A source of randomness configured with WithRandReader must be safe for
+// concurrent use. If the first key can not be generated, such as due to
+// invalid options, its error is returned. If entropy can not be read for some
+// keys, the keys which were generated are returned with a *GenerateError
+// aggregating the failures. If the context is done first, the keys generated
+// so far are returned with the context's error.
+func GenerateKeys(ctx context.Context, count int, opts ...Option) ([]*Key, error) {
+	if count < 1 {
+		return nil, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// generate the first key directly, so that invalid options, or a source
+	// of randomness which always fails, are reported once rather than for
+	// every key.
+	first, err := generateKey(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if count-1 < workers {
+		workers = count - 1
+	}
+
+	keys := make([]*Key, count)
+	errs := make([]error, count)
+	keys[0] = first
+	indexes := make(chan int, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				keys[i], errs[i] = generateKey(opts)
+			}
+		}()
+	}
+
+	for i := 1; i < count && err == nil; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	var generated []*Key
+	var failed []error
+	for i := range keys {
+		switch {
+		case errs[i] != nil:
+			failed = append(failed, errs[i])
+		case keys[i] != nil:
+			generated = append(generated, keys[i])
+		}
+	}
+
+	if err == nil && len(failed) > 0 {
+		err = &GenerateError{Errs: failed}
+	}
+
+	return generated, err
+}
+
+// generateKey returns a new key with its code verifier generated eagerly, so
+// that failures to read entropy are reported.
+func generateKey(opts []Option) (*Key, error) {
+	key, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(key.codeVerifier) == 0 && key.codeChallenge == "" {
+		if key.codeVerifier, err = key.generateCodeVerifier(); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// StreamOption enables variadic key stream options to be configured.
+type StreamOption func(*streamConfig)
+
+// streamConfig provides the configuration of a key stream.
+type streamConfig struct {
+	workers int
+}
+
+// WithWorkers enables specifying the number of workers generating keys
+// concurrently. Defaults to GOMAXPROCS. Non-positive values are ignored.
+func WithWorkers(n int) StreamOption {
+	return func(config *streamConfig) {
+		if n > 0 {
+			config.workers = n
+		}
+	}
+}
+
+// GenerateStream returns a channel producing S256 keys with code verifiers of
+// the given length, generated eagerly by a pool of workers, until the context
+// is done. This suits load testing tools and high-volume brokers consuming
+// proof keys as a stream.
+//
+// The channel is closed once the context is done, or immediately if length is
+// outside of the bounds specified in RFC 7636, 4.1. If entropy can not be read,
+// the failing worker stops, and the channel is closed once all have stopped.
+func GenerateStream(ctx context.Context, length int, opts ...StreamOption) <-chan *Key {
+	config := streamConfig{
+		workers: runtime.GOMAXPROCS(0),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	out := make(chan *Key, config.workers)
+	if validateVerifierLen(length) != nil {
+		close(out)
+		return out
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(config.workers)
+	for i := 0; i < config.workers; i++ {
+		go func() {
+			defer wg.Done()
+			streamKeys(ctx, length, out)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// streamKeys generates keys onto out until the context is done, or entropy
+// can not be read.
+func streamKeys(ctx context.Context, length int, out chan<- *Key) {
+	for ctx.Err() == nil {
+		key, err := generateKey([]Option{WithCodeVerifierLength(length)})
+		if err != nil {
+			return
+		}
+
+		select {
+		case out <- key:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
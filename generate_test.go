@@ -0,0 +1,140 @@
+package pkce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// budgetReader provides a source of randomness safe for concurrent use,
+// reading zeros until its budget is exhausted, then failing.
+type budgetReader struct {
+	mu     sync.Mutex
+	budget int
+}
+
+func (r *budgetReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.budget == 0 {
+		return 0, errors.New("entropy exhausted")
+	}
+
+	n := len(p)
+	if n > r.budget {
+		n = r.budget
+	}
+	for i := range p[:n] {
+		p[i] = 0
+	}
+	r.budget -= n
+
+	return n, nil
+}
+
+func TestGenerateKeys(t *testing.T) {
+	keys, err := GenerateKeys(context.Background(), 100, WithCodeVerifierLength(64))
+	if err != nil {
+		t.Fatalf("GenerateKeys() unexpected error: %v", err)
+	}
+
+	if len(keys) != 100 {
+		t.Fatalf("GenerateKeys() generated %d keys, want 100", len(keys))
+	}
+
+	seen := map[string]bool{}
+	for _, key := range keys {
+		codeVerifier := string(key.codeVerifier)
+		if len(codeVerifier) != 64 || seen[codeVerifier] {
+			t.Errorf("GenerateKeys() should eagerly generate unique code verifiers, got: %q", codeVerifier)
+		}
+		seen[codeVerifier] = true
+	}
+}
+
+func TestGenerateKeys_errors(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		opts      []Option
+		wantErr   error
+		wantTotal bool
+	}{
+		{
+			name:    "should error on invalid options",
+			ctx:     context.Background(),
+			opts:    []Option{WithCodeVerifierLength(verifierMaxLen + 1)},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should error on a done context",
+			ctx:     cancelled,
+			wantErr: context.Canceled,
+		},
+		{
+			name:      "should aggregate failures reading entropy",
+			ctx:       context.Background(),
+			opts:      []Option{WithRandReader(&budgetReader{budget: 3 * verifierMinLen})},
+			wantTotal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := GenerateKeys(tt.ctx, 5, tt.opts...)
+			if !tt.wantTotal {
+				if err != tt.wantErr || len(keys) != 0 {
+					t.Errorf("GenerateKeys() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+				}
+				return
+			}
+
+			generateErr, ok := err.(*GenerateError)
+			if !ok {
+				t.Fatalf("GenerateKeys() error type not expected\ngot:  %T, want: %T\n", err, generateErr)
+			}
+			if len(keys) < 1 || len(keys)+len(generateErr.Errs) != 5 {
+				t.Errorf("GenerateKeys() should return each key or its failure, got: %d keys, %d errors", len(keys), len(generateErr.Errs))
+			}
+		})
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	keys := GenerateStream(ctx, 64, WithWorkers(4))
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		key, ok := <-keys
+		if !ok {
+			t.Fatalf("GenerateStream() closed before the context was done")
+		}
+
+		codeVerifier := string(key.codeVerifier)
+		if len(codeVerifier) != 64 {
+			t.Errorf("GenerateStream() code verifier length = %d, want 64", len(codeVerifier))
+		}
+		if seen[codeVerifier] {
+			t.Errorf("GenerateStream() produced a duplicate code verifier")
+		}
+		seen[codeVerifier] = true
+	}
+
+	cancel()
+	for range keys {
+		// drain keys generated before the cancellation was observed.
+	}
+}
+
+func TestGenerateStream_invalidLength(t *testing.T) {
+	if _, ok := <-GenerateStream(context.Background(), verifierMaxLen+1); ok {
+		t.Errorf("GenerateStream() should close without producing keys for an invalid length")
+	}
+}
@@ -0,0 +1,97 @@
+package pkce
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+const (
+	// handoffVersion provides the current version of the handoff format.
+	handoffVersion = 1
+
+	// handoffSecretMinLen provides the minimum length of a handoff secret.
+	handoffSecretMinLen = 32
+
+	// handoffContext binds derived handoff encryption keys to their purpose.
+	handoffContext = "github.com/matthewhartstonge/pkce handoff v1"
+)
+
+// Seal returns the key encrypted and authenticated with secret as a compact,
+// unpadded base64url encoded blob, safe for passing between processes as a
+// command line argument, environment variable or over an IPC pipe. This
+// enables a browser helper architecture to complete the token request in a
+// different process than the one that generated the code verifier.
+//
+// The code verifier is generated, if it has not been already, so the
+// unsealed key always holds the code verifier the code challenge was derived
+// from. The secret must be at least 32 bytes, and should be randomly
+// generated and shared with the receiving process out of band.
+func (k *Key) Seal(secret []byte) (string, error) {
+	aead, err := newHandoffGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	_ = k.getCodeVerifier()
+	data, err := k.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(aead, data, []byte{handoffVersion})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append([]byte{handoffVersion}, sealed...)), nil
+}
+
+// Unseal decrypts a key sealed by Key.Seal with the same secret. Blobs which
+// are malformed, or fail authentication, are rejected with ErrSealedPayload,
+// and keys which have expired are rejected with ErrKeyExpired.
+func Unseal(secret []byte, blob string) (*Key, error) {
+	aead, err := newHandoffGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(blob)
+	if err != nil || len(data) == 0 {
+		return nil, ErrSealedPayload
+	}
+
+	if data[0] != handoffVersion {
+		return nil, ErrFormatVersion
+	}
+
+	plaintext, err := open(aead, data[1:], data[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{}
+	if err = key.UnmarshalBinary(plaintext); err != nil {
+		return nil, err
+	}
+
+	if key.Expired() {
+		return nil, ErrKeyExpired
+	}
+
+	return key, nil
+}
+
+// newHandoffGCM returns an AES-256-GCM AEAD keyed by a key derived from the
+// handoff secret, so secrets longer than an AES key can be used.
+func newHandoffGCM(secret []byte) (cipher.AEAD, error) {
+	if len(secret) < handoffSecretMinLen {
+		return nil, ErrHandoffSecret
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(handoffContext))
+
+	return newGCM(mac.Sum(nil))
+}
@@ -0,0 +1,115 @@
+package pkce
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestKey_Seal(t *testing.T) {
+	secret := bytes.Repeat([]byte("s"), handoffSecretMinLen)
+
+	key, err := New(WithExpiry(time.Minute))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	blob, err := key.Seal(secret)
+	if err != nil {
+		t.Fatalf("Seal() unexpected error: %v", err)
+	}
+
+	got, err := Unseal(secret, blob)
+	if err != nil {
+		t.Fatalf("Unseal() unexpected error: %v", err)
+	}
+
+	if got.CodeVerifier() != key.CodeVerifier() || got.CodeChallenge() != key.CodeChallenge() {
+		t.Errorf("Unseal() should return the sealed key\ngot:  %v, %v\nwant: %v, %v\n", got.CodeVerifier(), got.CodeChallenge(), key.CodeVerifier(), key.CodeChallenge())
+	}
+
+	if !got.ExpiresAt().Equal(key.ExpiresAt()) {
+		t.Errorf("Unseal() expiry = %v, want %v", got.ExpiresAt(), key.ExpiresAt())
+	}
+}
+
+func TestUnseal(t *testing.T) {
+	secret := bytes.Repeat([]byte("s"), handoffSecretMinLen)
+
+	key, _ := New()
+	blob, _ := key.Seal(secret)
+
+	expiredKey, _ := New()
+	expiredKey.expiresAt = time.Now().Add(-time.Minute)
+	expiredBlob, _ := expiredKey.Seal(secret)
+
+	raw, _ := base64.RawURLEncoding.DecodeString(blob)
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 1
+	versioned := append([]byte(nil), raw...)
+	versioned[0] = handoffVersion + 1
+
+	tests := []struct {
+		name    string
+		secret  []byte
+		blob    string
+		wantErr error
+	}{
+		{
+			name:   "should unseal a blob",
+			secret: secret,
+			blob:   blob,
+		},
+		{
+			name:    "should error on a short secret",
+			secret:  secret[:handoffSecretMinLen-1],
+			blob:    blob,
+			wantErr: ErrHandoffSecret,
+		},
+		{
+			name:    "should error on the wrong secret",
+			secret:  bytes.Repeat([]byte("x"), handoffSecretMinLen),
+			blob:    blob,
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name:    "should error on a tampered blob",
+			secret:  secret,
+			blob:    base64.RawURLEncoding.EncodeToString(tampered),
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name:    "should error on a malformed blob",
+			secret:  secret,
+			blob:    "yolo!",
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name:    "should error on an empty blob",
+			secret:  secret,
+			blob:    "",
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name:    "should error on an unknown version",
+			secret:  secret,
+			blob:    base64.RawURLEncoding.EncodeToString(versioned),
+			wantErr: ErrFormatVersion,
+		},
+		{
+			name:    "should error on an expired key",
+			secret:  secret,
+			blob:    expiredBlob,
+			wantErr: ErrKeyExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Unseal(tt.secret, tt.blob); err != tt.wantErr {
+				t.Errorf("Unseal() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
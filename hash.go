@@ -0,0 +1,124 @@
+package pkce
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+)
+
+const (
+	// S384 method specifies that the code challenge has been transformed by
+	// being hashed by SHA-384 then base64url-encoded.
+	//
+	// code_challenge = BASE64URL-ENCODE(SHA384(ASCII(code_verifier)))
+	//
+	// This is not defined by RFC 7636, so must only be used with servers
+	// known to support it. Configure with WithHash(crypto.SHA384), and allow
+	// servers to register it with WithManagerMethods.
+	S384 Method = "S384"
+
+	// S512 method specifies that the code challenge has been transformed by
+	// being hashed by SHA-512 then base64url-encoded.
+	//
+	// code_challenge = BASE64URL-ENCODE(SHA512(ASCII(code_verifier)))
+	//
+	// This is not defined by RFC 7636, so must only be used with servers
+	// known to support it. Configure with WithHash(crypto.SHA512), and allow
+	// servers to register it with WithManagerMethods.
+	S512 Method = "S512"
+)
+
+// WithHash enables specifying the hash used to transform the code verifier,
+// setting the challenge method to the method advertising it, being S256 for
+// SHA-256, S384 for SHA-384 or S512 for SHA-512. Defaults to SHA-256.
+//
+// Weak hashes, such as MD5 and SHA-1, are rejected with ErrHashWeak, and any
+// other hash with ErrHashNotSupported.
+func WithHash(hash crypto.Hash) Option {
+	return func(key *Key) (err error) {
+		method, err := hashMethod(hash)
+		if err != nil {
+			return err
+		}
+
+		if err = key.checkPinned(method != key.challengeMethod); err != nil {
+			return err
+		}
+
+		key.challengeMethod = method
+
+		return nil
+	}
+}
+
+// hashMethod returns the challenge method advertising the hash.
+func hashMethod(hash crypto.Hash) (Method, error) {
+	switch hash {
+	case crypto.SHA256:
+		return S256, nil
+
+	case crypto.SHA384:
+		return S384, nil
+
+	case crypto.SHA512:
+		return S512, nil
+
+	case crypto.MD4, crypto.MD5, crypto.SHA1, crypto.MD5SHA1, crypto.RIPEMD160:
+		return "", ErrHashWeak
+
+	default:
+		return "", ErrHashNotSupported
+	}
+}
+
+// isKeyMethod returns whether method can be used by a key, being a method
+// defined by RFC 7636, or one configured by WithHash.
+func isKeyMethod(method Method) bool {
+	switch method {
+	case Plain, S256, S384, S512:
+		return true
+
+	default:
+		return false
+	}
+}
+
+// isStandardMethod returns whether method is defined by RFC 7636.
+func isStandardMethod(method Method) bool {
+	return method == Plain || method == S256
+}
+
+// keyMethods returns the methods which can be used by a key, in order of
+// preference. The non-standard hashed methods are not permitted by the FIPS
+// compliance profile, so are omitted if FIPSEnabled reports true.
+func keyMethods() []Method {
+	if fipsEnabled() {
+		return SupportedMethods()
+	}
+
+	return append([]Method{S512, S384}, SupportedMethods()...)
+}
+
+// isWeaker returns whether method's transform is weaker than other's, being
+// ranked by the size of their digest, with plain being the weakest.
+func isWeaker(method Method, other Method) bool {
+	return methodDigestSize(method) < methodDigestSize(other)
+}
+
+// methodDigestSize returns the size of the digest computed by the method's
+// hash, or zero if the method does not hash the code verifier.
+func methodDigestSize(method Method) int {
+	switch method {
+	case S256:
+		return sha256.Size
+
+	case S384:
+		return sha512.Size384
+
+	case S512:
+		return sha512.Size
+
+	default:
+		return 0
+	}
+}
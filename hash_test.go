@@ -0,0 +1,126 @@
+package pkce
+
+import (
+	"crypto"
+	"crypto/sha512"
+	"encoding/base64"
+	"testing"
+)
+
+// testHashedChallenge returns the code challenge of testCodeVerifier for the
+// non-standard hashed method.
+func testHashedChallenge(method Method) string {
+	if method == S384 {
+		sum := sha512.Sum384([]byte(testCodeVerifier))
+		return base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+
+	sum := sha512.Sum512([]byte(testCodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestWithHash(t *testing.T) {
+	sum384 := sha512.Sum384([]byte(testCodeVerifier))
+	sum512 := sha512.Sum512([]byte(testCodeVerifier))
+
+	tests := []struct {
+		name          string
+		hash          crypto.Hash
+		wantMethod    Method
+		wantChallenge string
+		wantErr       error
+	}{
+		{
+			name:          "should map SHA-256 to S256",
+			hash:          crypto.SHA256,
+			wantMethod:    S256,
+			wantChallenge: testCodeChallenge,
+		},
+		{
+			name:          "should map SHA-384 to S384",
+			hash:          crypto.SHA384,
+			wantMethod:    S384,
+			wantChallenge: base64.RawURLEncoding.EncodeToString(sum384[:]),
+		},
+		{
+			name:          "should map SHA-512 to S512",
+			hash:          crypto.SHA512,
+			wantMethod:    S512,
+			wantChallenge: base64.RawURLEncoding.EncodeToString(sum512[:]),
+		},
+		{
+			name:    "should reject MD5 as weak",
+			hash:    crypto.MD5,
+			wantErr: ErrHashWeak,
+		},
+		{
+			name:    "should reject SHA-1 as weak",
+			hash:    crypto.SHA1,
+			wantErr: ErrHashWeak,
+		},
+		{
+			name:    "should reject an unsupported hash",
+			hash:    crypto.SHA3_256,
+			wantErr: ErrHashNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(WithCodeVerifierString(testCodeVerifier), WithHash(tt.hash))
+			if err != tt.wantErr {
+				t.Fatalf("New() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if key.ChallengeMethod() != tt.wantMethod {
+				t.Errorf("ChallengeMethod() = %v, want %v", key.ChallengeMethod(), tt.wantMethod)
+			}
+
+			if got := key.CodeChallenge(); got != tt.wantChallenge {
+				t.Errorf("CodeChallenge() = %v, want %v", got, tt.wantChallenge)
+			}
+
+			if !key.VerifyCodeVerifier(testCodeVerifier) {
+				t.Errorf("VerifyCodeVerifier() = false, want true")
+			}
+
+			if err = key.Validate(); err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+
+			data, err := key.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+			decoded := &Key{}
+			if err = decoded.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+			}
+			if decoded.ChallengeMethod() != tt.wantMethod {
+				t.Errorf("UnmarshalBinary() method = %v, want %v", decoded.ChallengeMethod(), tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestWithHash_downgrade(t *testing.T) {
+	key, err := New(WithHash(crypto.SHA512))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if err = key.SetChallengeMethod(Plain); err != ErrMethodDowngrade {
+		t.Errorf("SetChallengeMethod() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+
+	if err = key.SetChallengeMethod(S256); err != ErrMethodDowngrade {
+		t.Errorf("SetChallengeMethod() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+
+	if err = key.SetChallengeMethod(S384); err != ErrMethodDowngrade {
+		t.Errorf("SetChallengeMethod() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+}
@@ -0,0 +1,132 @@
+package pkce
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+const (
+	// requestObjectType provides the JWT typ of request objects, as specified
+	// in RFC 9101, 10.8.
+	requestObjectType = "oauth-authz-req+jwt"
+
+	// requestObjectTTL provides the lifetime of signed request objects.
+	requestObjectTTL = 5 * time.Minute
+
+	// paramRequest provides the parameter a request object is passed by value
+	// with, as specified in RFC 9101, 5.
+	paramRequest = "request"
+)
+
+// RequestObjectSigner signs JWT-Secured Authorization Request (JAR) request
+// objects, as specified in RFC 9101, enabling keys to be held by a caller
+// supplied implementation, such as a KMS or HSM.
+type RequestObjectSigner interface {
+	// Algorithm returns the JWS algorithm of the signature, such as "RS256"
+	// or "ES256".
+	Algorithm() string
+	// KeyID returns the identifier of the signing key, or an empty string if
+	// it should not be included in the JWS header.
+	KeyID() string
+	// Sign returns the JWS signature of the signing input, encoded as
+	// specified for the algorithm in RFC 7518, 3.
+	Sign(signingInput []byte) ([]byte, error)
+}
+
+// requestObjectHeader provides the JOSE header of a request object.
+type requestObjectHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// requestObjectClaims provides the claims of a request object.
+type requestObjectClaims struct {
+	Issuer              string `json:"iss"`
+	Audience            string `json:"aud"`
+	IssuedAt            int64  `json:"iat"`
+	NotBefore           int64  `json:"nbf"`
+	Expiry              int64  `json:"exp"`
+	JWTID               string `json:"jti"`
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri,omitempty"`
+	Scope               string `json:"scope,omitempty"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod Method `json:"code_challenge_method"`
+}
+
+// SignRequestObject returns the authorization request parameters, including
+// the key's code challenge and code challenge method, as a request object
+// signed by signer, as specified in RFC 9101. The audience must be the issuer
+// identifier of the authorization server.
+//
+// The request object expires after 5 minutes. If the request does not specify
+// a response type, "code" is used.
+func SignRequestObject(signer RequestObjectSigner, key *Key, req AuthorizationRequest, audience string) (string, error) {
+	alg := signer.Algorithm()
+	if alg == "" || alg == "none" {
+		return "", ErrSignerAlgorithm
+	}
+
+	if req.ResponseType == "" {
+		req.ResponseType = "code"
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	issuedAt := now(key.clock)
+	header, err := json.Marshal(requestObjectHeader{
+		Algorithm: alg,
+		Type:      requestObjectType,
+		KeyID:     signer.KeyID(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(requestObjectClaims{
+		Issuer:              req.ClientID,
+		Audience:            audience,
+		IssuedAt:            issuedAt.Unix(),
+		NotBefore:           issuedAt.Unix(),
+		Expiry:              issuedAt.Add(requestObjectTTL).Unix(),
+		JWTID:               hex.EncodeToString(jti),
+		ResponseType:        req.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       key.CodeChallenge(),
+		CodeChallengeMethod: key.ChallengeMethod(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	signature, err := signer.Sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// RequestObjectParams returns the authorization request query parameters
+// passing a signed request object by value. RFC 9101, 5 requires the client
+// identifier is also sent outside of the request object.
+func RequestObjectParams(clientID string, requestObject string) url.Values {
+	return url.Values{
+		paramClientID: {clientID},
+		paramRequest:  {requestObject},
+	}
+}
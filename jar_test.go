@@ -0,0 +1,139 @@
+package pkce
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testSigner provides an HS256 request object signer.
+type testSigner struct {
+	alg    string
+	secret []byte
+	err    error
+}
+
+func (s *testSigner) Algorithm() string { return s.alg }
+
+func (s *testSigner) KeyID() string { return "kid" }
+
+func (s *testSigner) Sign(signingInput []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(signingInput)
+
+	return mac.Sum(nil), nil
+}
+
+func TestSignRequestObject(t *testing.T) {
+	signer := &testSigner{alg: "HS256", secret: []byte("secret")}
+	key, err := New(WithCodeVerifier([]byte(testCodeVerifier)), WithClock(newTestClock()))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	requestObject, err := SignRequestObject(signer, key, AuthorizationRequest{
+		ClientID:    "client",
+		RedirectURI: "https://client.example.com/cb",
+		Scope:       "openid",
+		State:       "state",
+	}, "https://as.example.com")
+	if err != nil {
+		t.Fatalf("SignRequestObject() unexpected error: %v", err)
+	}
+
+	parts := strings.Split(requestObject, ".")
+	if len(parts) != 3 {
+		t.Fatalf("SignRequestObject() should return a compact JWS, got: %s", requestObject)
+	}
+
+	signature, _ := signer.Sign([]byte(parts[0] + "." + parts[1]))
+	if parts[2] != base64.RawURLEncoding.EncodeToString(signature) {
+		t.Error("SignRequestObject() signature does not verify")
+	}
+
+	var header map[string]string
+	data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if err = json.Unmarshal(data, &header); err != nil {
+		t.Fatalf("SignRequestObject() header is not JSON: %v", err)
+	}
+	if header["alg"] != "HS256" || header["typ"] != "oauth-authz-req+jwt" || header["kid"] != "kid" {
+		t.Errorf("SignRequestObject() header not expected, got: %v", header)
+	}
+
+	var claims map[string]interface{}
+	data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+	if err = json.Unmarshal(data, &claims); err != nil {
+		t.Fatalf("SignRequestObject() claims are not JSON: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"iss":                   "client",
+		"aud":                   "https://as.example.com",
+		"client_id":             "client",
+		"response_type":         "code",
+		"redirect_uri":          "https://client.example.com/cb",
+		"scope":                 "openid",
+		"state":                 "state",
+		"code_challenge":        testCodeChallenge,
+		"code_challenge_method": "S256",
+	}
+	for name, value := range want {
+		if claims[name] != value {
+			t.Errorf("SignRequestObject() claim %q = %v, want %v", name, claims[name], value)
+		}
+	}
+
+	if claims["exp"].(float64)-claims["iat"].(float64) != requestObjectTTL.Seconds() {
+		t.Errorf("SignRequestObject() should expire after %v", requestObjectTTL)
+	}
+}
+
+func TestSignRequestObject_error(t *testing.T) {
+	signErr := errors.New("sign failed")
+
+	tests := []struct {
+		name    string
+		signer  RequestObjectSigner
+		wantErr error
+	}{
+		{
+			name:    "should error on the none algorithm",
+			signer:  &testSigner{alg: "none"},
+			wantErr: ErrSignerAlgorithm,
+		},
+		{
+			name:    "should error on a missing algorithm",
+			signer:  &testSigner{},
+			wantErr: ErrSignerAlgorithm,
+		},
+		{
+			name:    "should return signing errors",
+			signer:  &testSigner{alg: "HS256", err: signErr},
+			wantErr: signErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := New()
+			if _, err := SignRequestObject(tt.signer, key, AuthorizationRequest{ClientID: "client"}, "https://as.example.com"); err != tt.wantErr {
+				t.Errorf("SignRequestObject() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestObjectParams(t *testing.T) {
+	got := RequestObjectParams("client", "a.b.c")
+	if got.Get("client_id") != "client" || got.Get("request") != "a.b.c" {
+		t.Errorf("RequestObjectParams() = %v", got)
+	}
+}
@@ -0,0 +1,146 @@
+package pkce
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// JSONMode determines the fields of a key encoded by MarshalJSON.
+type JSONMode int
+
+const (
+	// JSONPublic encodes only the code challenge and code challenge method,
+	// which are safe to include in API responses and log payloads.
+	JSONPublic JSONMode = iota
+	// JSONFull encodes the complete state of the key, including the code
+	// verifier, for trusted server-side persistence.
+	JSONFull
+)
+
+// keyJSON provides the JSON encoding of a key.
+type keyJSON struct {
+	ChallengeMethod    string            `json:"code_challenge_method"`
+	CodeChallenge      string            `json:"code_challenge,omitempty"`
+	CodeVerifier       string            `json:"code_verifier,omitempty"`
+	CodeVerifierLength int               `json:"code_verifier_length,omitempty"`
+	ChallengeEncoding  ChallengeEncoding `json:"challenge_encoding,omitempty"`
+	VerifierCharset    string            `json:"verifier_charset,omitempty"`
+	ExpiresAt          *time.Time        `json:"expires_at,omitempty"`
+	CreatedAt          *time.Time        `json:"created_at,omitempty"`
+	VerifiedAt         *time.Time        `json:"verified_at,omitempty"`
+	Metadata           map[string]string `json:"metadata,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields selected by the
+// key's JSON mode, as configured by WithJSONMode.
+//
+// In the public mode, the code challenge is derived from the code verifier,
+// which will be generated if it has not been already. In the full mode, the
+// key is encoded as by MarshalBinary, so if a code verifier has not yet been
+// generated, only the configured verifier length is encoded.
+func (k *Key) MarshalJSON() ([]byte, error) {
+	if k.jsonMode != JSONFull {
+		return json.Marshal(keyJSON{
+			ChallengeMethod: string(k.ChallengeMethod()),
+			CodeChallenge:   k.CodeChallenge(),
+		})
+	}
+
+	out := keyJSON{
+		ChallengeMethod:    string(k.challengeMethod),
+		CodeChallenge:      k.codeChallenge,
+		CodeVerifier:       string(k.codeVerifier),
+		CodeVerifierLength: k.codeVerifierLen,
+		ChallengeEncoding:  k.challengeEncoding,
+		VerifierCharset:    k.verifierCharset,
+		Metadata:           k.meta,
+	}
+	if !k.expiresAt.IsZero() {
+		expiresAt := k.expiresAt.UTC()
+		out.ExpiresAt = &expiresAt
+	}
+	if !k.createdAt.IsZero() {
+		createdAt := k.createdAt.UTC()
+		out.CreatedAt = &createdAt
+	}
+	if !k.verifiedAt.IsZero() {
+		verifiedAt := k.verifiedAt.UTC()
+		out.VerifiedAt = &verifiedAt
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding keys encoded in either
+// mode. The decoded key is validated to ensure data loaded from storage is
+// specification compliant, and that any code verifier matches the code
+// challenge.
+func (k *Key) UnmarshalJSON(data []byte) error {
+	if err := k.checkPinned(true); err != nil {
+		return err
+	}
+
+	var in keyJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return ErrKeyEncoding
+	}
+
+	key := Key{
+		codeVerifierLen: verifierMinLen,
+	}
+	if err := WithChallengeMethod(Method(in.ChallengeMethod))(&key); err != nil {
+		return err
+	}
+
+	if in.ChallengeEncoding != "" {
+		if err := WithChallengeEncoding(in.ChallengeEncoding)(&key); err != nil {
+			return err
+		}
+	}
+
+	if in.VerifierCharset != "" {
+		if err := WithVerifierCharset(in.VerifierCharset)(&key); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case in.CodeVerifier != "":
+		if err := key.setCodeVerifier([]byte(in.CodeVerifier)); err != nil {
+			return err
+		}
+		derived := encodeCodeChallenge(key.ChallengeMethod(), key.ChallengeEncoding(), key.codeVerifier)
+		if in.CodeChallenge != "" && !ChallengesEqual(derived, in.CodeChallenge) {
+			return ErrKeyInconsistent
+		}
+
+	case in.CodeVerifierLength != 0:
+		if err := key.setCodeVerifierLength(in.CodeVerifierLength); err != nil {
+			return err
+		}
+	}
+
+	if in.CodeChallenge != "" {
+		if err := WithCodeChallenge(in.CodeChallenge)(&key); err != nil {
+			return err
+		}
+	}
+
+	if in.ExpiresAt != nil {
+		key.expiresAt = *in.ExpiresAt
+	}
+	if in.CreatedAt != nil {
+		key.createdAt = *in.CreatedAt
+	}
+	if in.VerifiedAt != nil {
+		key.verifiedAt = *in.VerifiedAt
+	}
+	key.meta = copyMeta(in.Metadata)
+
+	// retain the runtime configuration of the key being decoded into.
+	key.clock = k.clock
+	key.jsonMode = k.jsonMode
+	*k = key
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package pkce
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKey_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{
+			name: "should only encode the code challenge by default",
+			opts: []Option{WithCodeVerifierString(testCodeVerifier)},
+			want: `{"code_challenge_method":"S256","code_challenge":"` + testCodeChallenge + `"}`,
+		},
+		{
+			name: "should only encode the code challenge in public mode",
+			opts: []Option{WithCodeVerifierString(testCodeVerifier), WithJSONMode(JSONPublic)},
+			want: `{"code_challenge_method":"S256","code_challenge":"` + testCodeChallenge + `"}`,
+		},
+		{
+			name: "should encode the code verifier in full mode",
+			opts: []Option{WithCodeVerifierString(testCodeVerifier), WithJSONMode(JSONFull), WithClock(newTestClock())},
+			want: `{"code_challenge_method":"S256","code_verifier":"` + testCodeVerifier + `","code_verifier_length":43,"created_at":"2022-01-27T00:00:00Z"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(tt.opts...)
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			got, err := json.Marshal(key)
+			if err != nil {
+				t.Fatalf("MarshalJSON() unexpected error: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON()\ngot:  %s\nwant: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_MarshalJSON_roundTrip(t *testing.T) {
+	key, err := New(WithJSONMode(JSONFull), WithChallengeEncoding(Hex), WithVerifierCharset(AlphanumericCharset), WithExpiry(time.Minute))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	want := key.CodeChallenge()
+
+	data, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("MarshalJSON() unexpected error: %v", err)
+	}
+
+	got := &Key{}
+	if err = json.Unmarshal(data, got); err != nil {
+		t.Fatalf("UnmarshalJSON() unexpected error: %v", err)
+	}
+
+	if got.CodeVerifier() != key.CodeVerifier() || got.CodeChallenge() != want {
+		t.Errorf("UnmarshalJSON() should decode the code verifier\ngot:  %v, %v\nwant: %v, %v\n", got.CodeVerifier(), got.CodeChallenge(), key.CodeVerifier(), want)
+	}
+	if !got.ExpiresAt().Equal(key.ExpiresAt()) || !got.CreatedAt().Equal(key.CreatedAt()) {
+		t.Errorf("UnmarshalJSON() should decode the key's expiry and creation time")
+	}
+	if got.jsonMode != JSONPublic {
+		t.Errorf("UnmarshalJSON() should retain the receiver's JSON mode")
+	}
+}
+
+func TestKey_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr error
+	}{
+		{
+			name: "should decode a public key",
+			data: `{"code_challenge_method":"S256","code_challenge":"` + testCodeChallenge + `"}`,
+		},
+		{
+			name:    "should error on malformed JSON",
+			data:    `{"code_challenge_method":`,
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on an unsupported method",
+			data:    `{"code_challenge_method":"yolo"}`,
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name:    "should error on an invalid code challenge",
+			data:    `{"code_challenge_method":"S256","code_challenge":"yolo"}`,
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name:    "should error on a code verifier not matching the code challenge",
+			data:    `{"code_challenge_method":"S256","code_challenge":"` + testCodeChallenge + `","code_verifier":"` + strings.Repeat("a", verifierMinLen) + `"}`,
+			wantErr: ErrKeyInconsistent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &Key{}
+			if err := key.UnmarshalJSON([]byte(tt.data)); err != tt.wantErr {
+				t.Errorf("UnmarshalJSON() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithJSONMode(t *testing.T) {
+	if _, err := New(WithJSONMode(JSONMode(-1))); err != ErrJSONModeNotSupported {
+		t.Errorf("WithJSONMode() error type not expected\ngot:  %v, want: %v\n", err, ErrJSONModeNotSupported)
+	}
+}
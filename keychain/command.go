@@ -0,0 +1,68 @@
+package keychain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runner runs the named command with stdin, returning its stdout and exit
+// code. A non-nil error is only returned if the command could not be run.
+type runner func(stdin []byte, name string, args ...string) (stdout []byte, code int, err error)
+
+// execRunner runs commands using os/exec.
+func execRunner(stdin []byte, name string, args ...string) ([]byte, int, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.Bytes(), exitCode(exitErr), nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stdout.Bytes(), 0, nil
+}
+
+// commandError is returned when a credential store command exits unexpectedly.
+type commandError struct {
+	name string
+	code int
+}
+
+func (e *commandError) Error() string {
+	return "keychain: " + e.name + " exited with status " + strconv.Itoa(e.code)
+}
+
+// encodeSecret encodes a secret as text, as command line credential stores
+// only store text.
+func encodeSecret(secret []byte) string {
+	return base64.StdEncoding.EncodeToString(secret)
+}
+
+// decodeSecret decodes a secret stored as text.
+func decodeSecret(stdout []byte) ([]byte, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(stdout)))
+	if err != nil {
+		return nil, errors.New("keychain: stored secret is malformed")
+	}
+
+	return secret, nil
+}
+
+// exitCode returns the exit code of an exited command.
+func exitCode(err *exec.ExitError) int {
+	if status, ok := err.Sys().(interface{ ExitStatus() int }); ok {
+		return status.ExitStatus()
+	}
+
+	return -1
+}
@@ -0,0 +1,14 @@
+package keychain
+
+import (
+	"os/exec"
+)
+
+// newDefault returns a store backed by the macOS Keychain.
+func newDefault() (Store, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, ErrUnsupported
+	}
+
+	return &macOS{run: execRunner}, nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !windows
+// +build !darwin,!windows
+
+package keychain
+
+import (
+	"os/exec"
+)
+
+// newDefault returns a store backed by the Secret Service.
+func newDefault() (Store, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, ErrUnsupported
+	}
+
+	return &secretService{run: execRunner}, nil
+}
@@ -0,0 +1,15 @@
+package keychain
+
+import (
+	"syscall"
+)
+
+// newDefault returns a store backed by the Windows Credential Manager.
+func newDefault() (Store, error) {
+	advapi32 := syscall.NewLazyDLL("advapi32.dll")
+	if err := advapi32.Load(); err != nil {
+		return nil, ErrUnsupported
+	}
+
+	return &wincred{advapi32: advapi32}, nil
+}
@@ -0,0 +1,92 @@
+// Package keychain provides storage of secrets, such as code verifiers and
+// refresh tokens, in the operating system's credential store, so secrets
+// persisted between process invocations of native apps and command line tools
+// are never written to plaintext files.
+//
+// The macOS Keychain, Windows Credential Manager and the freedesktop.org
+// Secret Service, via libsecret, are supported.
+package keychain
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrNotFound is returned when a secret does not exist in the store.
+	ErrNotFound = errors.New("secret not found in the keychain")
+
+	// ErrUnsupported is returned when the operating system's credential store
+	// is not supported or not available.
+	ErrUnsupported = errors.New("keychain is not supported on this system")
+)
+
+// Store provides secret storage, keyed by service and account.
+type Store interface {
+	// Get returns the secret stored for the service and account, or
+	// ErrNotFound.
+	Get(service string, account string) ([]byte, error)
+	// Set stores the secret for the service and account, replacing any
+	// existing secret.
+	Set(service string, account string, secret []byte) error
+	// Delete removes the secret stored for the service and account, or
+	// returns ErrNotFound.
+	Delete(service string, account string) error
+}
+
+// New returns a store backed by the operating system's credential store, or
+// ErrUnsupported if it is not available.
+func New() (Store, error) {
+	return newDefault()
+}
+
+// MemoryStore provides an in-memory store, for testing code depending on a
+// Store. Secrets do not persist between process invocations.
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[[2]string][]byte
+}
+
+// NewMemoryStore returns a new in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		secrets: make(map[[2]string][]byte),
+	}
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(service string, account string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, ok := s.secrets[[2]string{service, account}]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]byte(nil), secret...), nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(service string, account string, secret []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.secrets[[2]string{service, account}] = append([]byte(nil), secret...)
+
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(service string, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := [2]string{service, account}
+	if _, ok := s.secrets[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.secrets, key)
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package keychain
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeTools emulates the security and secret-tool command line tools, storing
+// secrets in memory.
+type fakeTools struct {
+	secrets map[string]string
+	// fail causes every command to exit with the given code, if non-zero.
+	fail int
+}
+
+func newFakeTools() *fakeTools {
+	return &fakeTools{
+		secrets: make(map[string]string),
+	}
+}
+
+func (f *fakeTools) run(stdin []byte, name string, args ...string) ([]byte, int, error) {
+	if f.fail != 0 {
+		return nil, f.fail, nil
+	}
+
+	if name == "security" && len(args) == 1 && args[0] == "-i" {
+		fields := strings.Fields(string(stdin))
+		for i := range fields {
+			if unquoted, err := strconv.Unquote(fields[i]); err == nil {
+				fields[i] = unquoted
+			}
+		}
+		name, args = "security", fields
+	}
+
+	switch name + " " + args[0] {
+	case "security find-generic-password":
+		secret, ok := f.secrets[args[2]+":"+args[4]]
+		if !ok {
+			return nil, macOSNotFound, nil
+		}
+		return []byte(secret + "\n"), 0, nil
+
+	case "security add-generic-password":
+		f.secrets[args[3]+":"+args[5]] = args[7]
+		return nil, 0, nil
+
+	case "security delete-generic-password":
+		key := args[2] + ":" + args[4]
+		if _, ok := f.secrets[key]; !ok {
+			return nil, macOSNotFound, nil
+		}
+		delete(f.secrets, key)
+		return nil, 0, nil
+
+	case "secret-tool lookup":
+		secret, ok := f.secrets[args[2]+":"+args[4]]
+		if !ok {
+			return nil, 1, nil
+		}
+		return []byte(secret), 0, nil
+
+	case "secret-tool store":
+		f.secrets[args[3]+":"+args[5]] = string(stdin)
+		return nil, 0, nil
+
+	case "secret-tool clear":
+		delete(f.secrets, args[2]+":"+args[4])
+		return nil, 0, nil
+	}
+
+	return nil, 127, nil
+}
+
+// testStore exercises a store's behaviour.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+
+	if _, err := s.Get("pkce", "client"); err != ErrNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrNotFound)
+	}
+
+	secret := []byte("refresh token \x00\xff")
+	if err := s.Set("pkce", "client", []byte("stale")); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+	if err := s.Set("pkce", "client", secret); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+
+	got, err := s.Get("pkce", "client")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, secret) {
+		t.Errorf("Get() = %q, want %q", got, secret)
+	}
+
+	if _, err = s.Get("pkce", "other"); err != ErrNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrNotFound)
+	}
+
+	if err = s.Delete("pkce", "client"); err != nil {
+		t.Errorf("Delete() unexpected error: %v", err)
+	}
+	if err = s.Delete("pkce", "client"); err != ErrNotFound {
+		t.Errorf("Delete() error type not expected\ngot:  %v, want: %v\n", err, ErrNotFound)
+	}
+	if _, err = s.Get("pkce", "client"); err != ErrNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrNotFound)
+	}
+}
+
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name     string
+		newStore func(run runner) Store
+	}{
+		{
+			name:     "memory",
+			newStore: func(runner) Store { return NewMemoryStore() },
+		},
+		{
+			name:     "macOS",
+			newStore: func(run runner) Store { return &macOS{run: run} },
+		},
+		{
+			name:     "secret service",
+			newStore: func(run runner) Store { return &secretService{run: run} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testStore(t, tt.newStore(newFakeTools().run))
+		})
+	}
+}
+
+func TestStores_commandError(t *testing.T) {
+	tests := []struct {
+		name  string
+		store Store
+	}{
+		{
+			name:  "macOS",
+			store: &macOS{run: (&fakeTools{fail: 51}).run},
+		},
+		{
+			name:  "secret service",
+			store: &secretService{run: (&fakeTools{fail: 2}).run},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.store.Set("pkce", "client", []byte("secret")); err == nil {
+				t.Error("Set() should error on a failed command")
+			}
+
+			if _, err := tt.store.Get("pkce", "client"); err == nil || err == ErrNotFound {
+				t.Errorf("Get() should error on a failed command, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestMacOS_Set(t *testing.T) {
+	var gotArgs []string
+	s := &macOS{run: func(stdin []byte, name string, args ...string) ([]byte, int, error) {
+		gotArgs = args
+		return nil, 0, nil
+	}}
+
+	if err := s.Set("pkce", "client", []byte("secret")); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+
+	if strings.Contains(strings.Join(gotArgs, " "), encodeSecret([]byte("secret"))) {
+		t.Errorf("Set() should not pass the secret as a process argument, got: %q", gotArgs)
+	}
+}
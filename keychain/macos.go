@@ -0,0 +1,78 @@
+package keychain
+
+import (
+	"strconv"
+	"strings"
+)
+
+// macOSNotFound provides the exit code of the security tool when an item
+// could not be found in the keychain (errSecItemNotFound).
+const macOSNotFound = 44
+
+// macOS provides a store backed by the macOS Keychain, using the security
+// command line tool.
+type macOS struct {
+	run runner
+}
+
+// Get implements Store.
+func (s *macOS) Get(service string, account string) ([]byte, error) {
+	stdout, code, err := s.run(nil, "security", "find-generic-password", "-s", service, "-a", account, "-w")
+	if err != nil {
+		return nil, err
+	}
+
+	switch code {
+	case 0:
+		return decodeSecret(stdout)
+
+	case macOSNotFound:
+		return nil, ErrNotFound
+
+	default:
+		return nil, &commandError{name: "security", code: code}
+	}
+}
+
+// Set implements Store.
+//
+// The command is passed to the security tool's interactive mode via stdin, so
+// the secret is never visible in the process arguments.
+func (s *macOS) Set(service string, account string, secret []byte) error {
+	command := strings.Join([]string{
+		"add-generic-password", "-U",
+		"-s", strconv.Quote(service),
+		"-a", strconv.Quote(account),
+		"-w", strconv.Quote(encodeSecret(secret)),
+	}, " ")
+
+	_, code, err := s.run([]byte(command+"\n"), "security", "-i")
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &commandError{name: "security", code: code}
+	}
+
+	return nil
+}
+
+// Delete implements Store.
+func (s *macOS) Delete(service string, account string) error {
+	_, code, err := s.run(nil, "security", "delete-generic-password", "-s", service, "-a", account)
+	if err != nil {
+		return err
+	}
+
+	switch code {
+	case 0:
+		return nil
+
+	case macOSNotFound:
+		return ErrNotFound
+
+	default:
+		return &commandError{name: "security", code: code}
+	}
+}
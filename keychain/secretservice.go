@@ -0,0 +1,66 @@
+package keychain
+
+// secretService provides a store backed by the freedesktop.org Secret Service,
+// such as GNOME Keyring or KWallet, using the libsecret secret-tool command
+// line tool.
+type secretService struct {
+	run runner
+}
+
+// Get implements Store.
+func (s *secretService) Get(service string, account string) ([]byte, error) {
+	stdout, code, err := s.run(nil, "secret-tool", "lookup", "service", service, "account", account)
+	if err != nil {
+		return nil, err
+	}
+
+	// secret-tool exits with 1 and no output if the secret does not exist.
+	if code == 1 && len(stdout) == 0 {
+		return nil, ErrNotFound
+	}
+
+	if code != 0 {
+		return nil, &commandError{name: "secret-tool", code: code}
+	}
+
+	return decodeSecret(stdout)
+}
+
+// Set implements Store.
+//
+// The secret is passed to secret-tool via stdin, so it is never visible in the
+// process arguments.
+func (s *secretService) Set(service string, account string, secret []byte) error {
+	_, code, err := s.run(
+		[]byte(encodeSecret(secret)),
+		"secret-tool", "store", "--label="+service+" ("+account+")",
+		"service", service, "account", account,
+	)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &commandError{name: "secret-tool", code: code}
+	}
+
+	return nil
+}
+
+// Delete implements Store.
+func (s *secretService) Delete(service string, account string) error {
+	if _, err := s.Get(service, account); err != nil {
+		return err
+	}
+
+	_, code, err := s.run(nil, "secret-tool", "clear", "service", service, "account", account)
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		return &commandError{name: "secret-tool", code: code}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package keychain
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// credTypeGeneric provides CRED_TYPE_GENERIC.
+	credTypeGeneric = 1
+	// credPersistLocalMachine provides CRED_PERSIST_LOCAL_MACHINE.
+	credPersistLocalMachine = 2
+	// errorNotFound provides ERROR_NOT_FOUND.
+	errorNotFound = syscall.Errno(1168)
+)
+
+// credential provides the CREDENTIALW structure.
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        syscall.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// wincred provides a store backed by the Windows Credential Manager.
+type wincred struct {
+	advapi32 *syscall.LazyDLL
+}
+
+// target returns the credential target name for the service and account.
+func (s *wincred) target(service string, account string) (*uint16, error) {
+	return syscall.UTF16PtrFromString(service + ":" + account)
+}
+
+// Get implements Store.
+func (s *wincred) Get(service string, account string) ([]byte, error) {
+	target, err := s.target(service, account)
+	if err != nil {
+		return nil, err
+	}
+
+	var cred *credential
+	ret, _, err := s.advapi32.NewProc("CredReadW").Call(
+		uintptr(unsafe.Pointer(target)),
+		credTypeGeneric,
+		0,
+		uintptr(unsafe.Pointer(&cred)),
+	)
+	if ret == 0 {
+		if err == errorNotFound {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+	defer s.advapi32.NewProc("CredFree").Call(uintptr(unsafe.Pointer(cred))) //nolint:errcheck
+
+	if cred.CredentialBlobSize == 0 {
+		return []byte{}, nil
+	}
+
+	blob := (*[1 << 20]byte)(unsafe.Pointer(cred.CredentialBlob))[:cred.CredentialBlobSize:cred.CredentialBlobSize]
+
+	return append([]byte(nil), blob...), nil
+}
+
+// Set implements Store.
+func (s *wincred) Set(service string, account string, secret []byte) error {
+	target, err := s.target(service, account)
+	if err != nil {
+		return err
+	}
+
+	userName, err := syscall.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(secret)),
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if len(secret) > 0 {
+		cred.CredentialBlob = &secret[0]
+	}
+
+	ret, _, err := s.advapi32.NewProc("CredWriteW").Call(uintptr(unsafe.Pointer(&cred)), 0)
+	if ret == 0 {
+		return err
+	}
+
+	return nil
+}
+
+// Delete implements Store.
+func (s *wincred) Delete(service string, account string) error {
+	target, err := s.target(service, account)
+	if err != nil {
+		return err
+	}
+
+	ret, _, err := s.advapi32.NewProc("CredDeleteW").Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+	if ret == 0 {
+		if err == errorNotFound {
+			return ErrNotFound
+		}
+
+		return err
+	}
+
+	return nil
+}
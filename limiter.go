@@ -0,0 +1,86 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter throttles failed code verifier verification attempts, mitigating
+// brute-force attempts against short, or plain, code challenges.
+//
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+	// Allow reports whether a verification attempt for the given key should
+	// proceed.
+	Allow(ctx context.Context, key string) bool
+
+	// Failure records a failed verification attempt for the given key.
+	Failure(ctx context.Context, key string)
+}
+
+// MemoryLimiter provides an in-memory Limiter, blocking further attempts once
+// a key has reached the maximum number of failures within a fixed window.
+type MemoryLimiter struct {
+	maxFailures int
+	window      time.Duration
+	clock       Clock
+
+	mu      sync.Mutex
+	windows map[string]limiterWindow
+}
+
+// limiterWindow provides the failures recorded within a window.
+type limiterWindow struct {
+	start    time.Time
+	failures int
+}
+
+// NewMemoryLimiter returns a limiter which blocks a key for the remainder of
+// the window once maxFailures failed attempts have been recorded.
+func NewMemoryLimiter(maxFailures int, window time.Duration, clock Clock) *MemoryLimiter {
+	return &MemoryLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		clock:       clock,
+		windows:     map[string]limiterWindow{},
+	}
+}
+
+// Allow implements Limiter.
+func (l *MemoryLimiter) Allow(_ context.Context, key string) bool {
+	at := now(l.clock)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok {
+		return true
+	}
+
+	if at.Sub(w.start) >= l.window {
+		delete(l.windows, key)
+		return true
+	}
+
+	return w.failures < l.maxFailures
+}
+
+// Failure implements Limiter.
+func (l *MemoryLimiter) Failure(_ context.Context, key string) {
+	at := now(l.clock)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || at.Sub(w.start) >= l.window {
+		w = limiterWindow{
+			start: at,
+		}
+	}
+
+	w.failures++
+	l.windows[key] = w
+}
@@ -0,0 +1,59 @@
+package pkce
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		advance  time.Duration
+		want     bool
+	}{
+		{
+			name:     "should allow attempts without failures",
+			failures: 0,
+			want:     true,
+		},
+		{
+			name:     "should allow attempts below the maximum failures",
+			failures: 2,
+			want:     true,
+		},
+		{
+			name:     "should block attempts at the maximum failures",
+			failures: 3,
+			want:     false,
+		},
+		{
+			name:     "should allow attempts once the window has elapsed",
+			failures: 3,
+			advance:  time.Minute,
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clock := newTestClock()
+			l := NewMemoryLimiter(3, time.Minute, clock)
+
+			for i := 0; i < tt.failures; i++ {
+				l.Failure(ctx, "client")
+			}
+			clock.Advance(tt.advance)
+
+			if got := l.Allow(ctx, "client"); got != tt.want {
+				t.Errorf("Allow() = %v, want %v", got, tt.want)
+			}
+
+			if got := l.Allow(ctx, "other"); !got {
+				t.Errorf("Allow() should not limit other keys, got %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package pkce
+
+import (
+	"context"
+	"crypto/hmac"
+	"time"
+)
+
+// DefaultKeyTTL provides the default duration a registered key is valid for.
+//
+// RFC 6749, 4.1.2 recommends a maximum authorization code lifetime of 10
+// minutes, after which the code verifier can no longer be exchanged.
+const DefaultKeyTTL = 10 * time.Minute
+
+// KeyManager manages the server-side lifecycle of proof keys, persisting the
+// code challenge received in the authorization request and verifying the code
+// verifier received in the token request.
+type KeyManager struct {
+	store          Store
+	binder         Binder
+	clock          Clock
+	ttl            time.Duration
+	minEntropy     float64
+	methods        []Method
+	minVerifierLen int
+	maxVerifierLen int
+	fips           bool
+	duplicates     *DuplicateCache
+	auditor        Auditor
+	sessionSecret  []byte
+	verifier       Verifier
+}
+
+// ManagerOption enables variadic KeyManager options to be configured.
+type ManagerOption func(*KeyManager)
+
+// NewKeyManager returns a key manager persisting keys to store.
+func NewKeyManager(store Store, opts ...ManagerOption) *KeyManager {
+	m := &KeyManager{
+		store:          store,
+		binder:         CodeBinder(),
+		ttl:            DefaultKeyTTL,
+		minVerifierLen: verifierMinLen,
+		maxVerifierLen: verifierMaxLen,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// WithManagerAuditor enables recording every verification attempt, such as
+// with an AuditWriter.
+func WithManagerAuditor(auditor Auditor) ManagerOption {
+	return func(m *KeyManager) {
+		m.auditor = auditor
+	}
+}
+
+// WithManagerBinder enables specifying the correlation identifier keys are
+// registered and verified under, such as the state or a pushed authorization
+// request's request_uri. Defaults to CodeBinder.
+func WithManagerBinder(binder Binder) ManagerOption {
+	return func(m *KeyManager) {
+		if binder != nil {
+			m.binder = binder
+		}
+	}
+}
+
+// WithManagerClock enables specifying the clock used to compute key expiry.
+// Defaults to the system clock.
+func WithManagerClock(clock Clock) ManagerOption {
+	return func(m *KeyManager) {
+		m.clock = clock
+	}
+}
+
+// WithManagerDuplicateCache enables detecting code challenges registered for
+// more than one authorization request, which are reported to the cache's
+// metrics hook. Registration is not rejected, as the registering client can
+// not be distinguished from the client that first registered the code
+// challenge.
+func WithManagerDuplicateCache(cache *DuplicateCache) ManagerOption {
+	return func(m *KeyManager) {
+		m.duplicates = cache
+	}
+}
+
+// WithManagerFIPSMode enables enforcing the FIPS compliance profile, which
+// only permits registering S256 code challenges, returning a *ComplianceError
+// otherwise. The profile is always enforced if FIPSEnabled reports true.
+func WithManagerFIPSMode() ManagerOption {
+	return func(m *KeyManager) {
+		m.fips = true
+	}
+}
+
+// WithManagerMethods enables restricting the code challenge methods that can
+// be registered, such as requiring S256. Defaults to allowing both "plain" and
+// "S256". The non-standard S384 and S512 methods can only be registered if
+// allowed by this option.
+func WithManagerMethods(methods ...Method) ManagerOption {
+	return func(m *KeyManager) {
+		m.methods = methods
+	}
+}
+
+// WithManagerMinVerifierEntropy enables rejecting code verifiers with an
+// estimated entropy below bits, as computed by EstimateVerifierEntropy.
+// Defaults to accepting any specification compliant code verifier.
+func WithManagerMinVerifierEntropy(bits float64) ManagerOption {
+	return func(m *KeyManager) {
+		if bits > 0 {
+			m.minEntropy = bits
+		}
+	}
+}
+
+// WithManagerSessionBinding enables binding registered code challenges to the
+// session that initiated the flow. An HMAC of the session identifier, keyed
+// with secret, is stored alongside each code challenge registered with
+// RegisterSession, and must be reproduced with VerifySession, or the
+// verification middleware's WithSession, at token time, blocking code injection
+// across sessions.
+//
+// The secret must be at least 32 bytes, otherwise registration and
+// verification fail with ErrSessionSecret. Keys registered by Register are
+// bound to the empty session.
+func WithManagerSessionBinding(secret []byte) ManagerOption {
+	return func(m *KeyManager) {
+		m.sessionSecret = append([]byte{}, secret...)
+	}
+}
+
+// WithManagerVerifierLength enables narrowing the lengths of code verifier
+// accepted, within the bounds specified in RFC 7636, 4.1. Invalid bounds are
+// ignored.
+func WithManagerVerifierLength(min, max int) ManagerOption {
+	return func(m *KeyManager) {
+		if min >= verifierMinLen && max <= verifierMaxLen && min <= max {
+			m.minVerifierLen = min
+			m.maxVerifierLen = max
+		}
+	}
+}
+
+// WithManagerTTL enables specifying the duration a registered key is valid
+// for. Defaults to DefaultKeyTTL.
+func WithManagerTTL(ttl time.Duration) ManagerOption {
+	return func(m *KeyManager) {
+		if ttl > 0 {
+			m.ttl = ttl
+		}
+	}
+}
+
+// Register persists the code challenge and code challenge method received in
+// an authorization request under id, which is the correlation value of the
+// configured binder, by default the authorization code issued in response.
+//
+// RFC 7636, 4.3. If the code challenge method is not present in the request,
+// it defaults to "plain".
+func (m *KeyManager) Register(ctx context.Context, id string, method Method, codeChallenge string) error {
+	return m.register(ctx, registerRequest{
+		id:            m.binder.ID(id),
+		method:        method,
+		codeChallenge: codeChallenge,
+	})
+}
+
+// registerRequest provides the parameters of a registration, where id is the
+// store id.
+type registerRequest struct {
+	id            string
+	session       string
+	method        Method
+	codeChallenge string
+	redirectURI   string
+}
+
+// register validates and persists the code challenge under the store id,
+// bound to the session if session binding is enabled, and to the redirect uri
+// if provided.
+func (m *KeyManager) register(ctx context.Context, req registerRequest) error {
+	if err := m.validateSessionSecret(); err != nil {
+		return err
+	}
+
+	id, method, codeChallenge := req.id, req.method, req.codeChallenge
+
+	if method == "" {
+		method = Plain
+	}
+
+	if !isKeyMethod(method) {
+		return ErrMethodNotSupported
+	}
+
+	if m.fips || fipsEnabled() {
+		if err := validateFIPSMethod(method); err != nil {
+			return err
+		}
+	}
+
+	if !m.methodAllowed(method) {
+		return ErrMethodNotAllowed
+	}
+
+	if err := validateMethodCodeChallenge(method, codeChallenge); err != nil {
+		return err
+	}
+
+	if m.strongerMethodAllowed(method) {
+		// RFC 7636, 7.2. A flow registered using S256 must not be replaced by
+		// a "plain" registration, nor a flow registered with a stronger hash
+		// by a weaker one, such as by an intermediary rewriting a retried
+		// authorization request.
+		registered, _, err := m.getKey(ctx, id, false)
+		switch err {
+		case nil:
+			registered.clock = m.clock
+			if !registered.Expired() && isWeaker(method, registered.ChallengeMethod()) {
+				return ErrMethodDowngrade
+			}
+		case ErrKeyNotFound:
+		default:
+			return err
+		}
+	}
+
+	key, err := m.newKey(method, codeChallenge)
+	if err != nil {
+		return err
+	}
+	if req.redirectURI != "" {
+		key.SetMeta(metaRedirectURI, req.redirectURI)
+	}
+
+	if m.duplicates != nil {
+		m.duplicates.Observe(id, codeChallenge)
+	}
+
+	return m.putKey(ctx, id, key, m.sessionTag(req.session, codeChallenge))
+}
+
+// newKey returns a key holding the code challenge, expiring after the
+// manager's ttl.
+func (m *KeyManager) newKey(method Method, codeChallenge string) (*Key, error) {
+	return New(
+		WithChallengeMethod(method),
+		WithCodeChallenge(codeChallenge),
+		WithClock(m.clock),
+		WithExpiry(m.ttl),
+	)
+}
+
+// Verify verifies the code verifier received in a token request against the
+// code challenge registered under id, the correlation value of the configured
+// binder.
+//
+// The registered key is atomically consumed before verification regardless of
+// the outcome, ensuring a code challenge can only ever be proven once, even by
+// concurrent token requests.
+func (m *KeyManager) Verify(ctx context.Context, id string, codeVerifier string) error {
+	return m.VerifyMethod(ctx, id, "", codeVerifier)
+}
+
+// VerifyMethod verifies the code verifier as Verify, additionally returning
+// ErrMethodDowngrade if the token request claims a weaker code challenge method
+// than was registered for the flow, such as "plain" for an S256 code
+// challenge. An empty method, as sent by compliant clients, is not checked.
+func (m *KeyManager) VerifyMethod(ctx context.Context, id string, method Method, codeVerifier string) error {
+	return m.verifyAudited(ctx, verifyRequest{
+		id:           id,
+		method:       method,
+		codeVerifier: codeVerifier,
+	})
+}
+
+// verifyRequest provides the parameters of a verification attempt.
+type verifyRequest struct {
+	id           string
+	method       Method
+	session      string
+	codeVerifier string
+	// checkRedirectURI enables verifying the redirect uri matches the redirect
+	// uri registered for the flow, if any.
+	checkRedirectURI bool
+	redirectURI      string
+}
+
+// verifyAudited verifies the request, recording the outcome with the auditor,
+// if configured.
+func (m *KeyManager) verifyAudited(ctx context.Context, req verifyRequest) error {
+	key, err := m.verify(ctx, req)
+	if m.auditor != nil {
+		m.audit(ctx, key, err)
+	}
+
+	return err
+}
+
+// verify verifies the code verifier, returning the registered key, if found.
+func (m *KeyManager) verify(ctx context.Context, req verifyRequest) (*Key, error) {
+	if err := m.validateSessionSecret(); err != nil {
+		return nil, err
+	}
+
+	key, tag, err := m.getKey(ctx, m.binder.ID(req.id), true)
+	if err != nil {
+		return nil, err
+	}
+
+	key.clock = m.clock
+	if key.Expired() {
+		return key, ErrKeyExpired
+	}
+
+	if m.sessionSecret != nil && !hmac.Equal(tag, m.sessionTag(req.session, key.CodeChallenge())) {
+		return key, ErrSessionMismatch
+	}
+
+	if req.checkRedirectURI && !redirectURIMatches(key, req.redirectURI) {
+		return key, ErrRedirectURIMismatch
+	}
+
+	method, codeVerifier := req.method, req.codeVerifier
+	if method != "" && isDowngrade(key.ChallengeMethod(), method) {
+		return key, ErrMethodDowngrade
+	}
+
+	if n := len(codeVerifier); n < m.minVerifierLen || n > m.maxVerifierLen {
+		return key, ErrVerifierLength
+	}
+
+	if m.minEntropy > 0 && EstimateVerifierEntropy(codeVerifier) < m.minEntropy {
+		return key, ErrVerifierEntropy
+	}
+
+	ok, err := m.verifyCodeVerifier(ctx, key, codeVerifier)
+	if err != nil {
+		return key, err
+	}
+
+	if !ok {
+		// RFC 7636, 7.2. Presenting the hashed code challenge as the code
+		// verifier is an attempt to prove possession using "plain".
+		if key.ChallengeMethod() != Plain && ChallengesEqual(codeVerifier, key.CodeChallenge()) {
+			return key, ErrMethodDowngrade
+		}
+
+		return key, ErrVerifierMismatch
+	}
+
+	return key, nil
+}
+
+// audit records the outcome of a verification attempt against key.
+func (m *KeyManager) audit(ctx context.Context, key *Key, err error) {
+	event := AuditEvent{
+		Time:    now(m.clock),
+		Outcome: AuditOutcomeVerified,
+	}
+	if key != nil {
+		event.Fingerprint = fingerprint(key.CodeChallenge())
+		event.Method = key.ChallengeMethod()
+		if created := key.CreatedAt(); !created.IsZero() {
+			event.Age = event.Time.Sub(created)
+		}
+	}
+	if err != nil {
+		event.Outcome = AuditOutcomeFailed
+		event.Reason = failureReason(err)
+	}
+
+	m.auditor.Audit(ctx, event)
+}
+
+// SupportedMethods returns the code challenge methods that can be registered,
+// in order of preference, such as for advertising
+// code_challenge_methods_supported in authorization server metadata.
+func (m *KeyManager) SupportedMethods() []Method {
+	var out []Method
+	for _, method := range keyMethods() {
+		if m.fips && validateFIPSMethod(method) != nil {
+			continue
+		}
+
+		if m.methodAllowed(method) {
+			out = append(out, method)
+		}
+	}
+
+	return out
+}
+
+// isDowngrade returns whether using method for a flow registered with the
+// registered method is a downgrade, as specified in RFC 7636, 7.2. Flows
+// registered with a hashed method must continue to use the same hash.
+func isDowngrade(registered Method, method Method) bool {
+	return registered != "" && registered != Plain && method != registered
+}
+
+// methodAllowed returns whether method can be registered. The non-standard
+// hashed methods must be allowed explicitly.
+func (m *KeyManager) methodAllowed(method Method) bool {
+	if len(m.methods) == 0 {
+		return isStandardMethod(method)
+	}
+
+	return containsMethod(m.methods, method)
+}
+
+// strongerMethodAllowed returns whether a method stronger than method can be
+// registered, in which case registering method may downgrade a flow.
+func (m *KeyManager) strongerMethodAllowed(method Method) bool {
+	for _, stronger := range keyMethods() {
+		if isWeaker(method, stronger) && m.methodAllowed(stronger) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,448 @@
+package pkce
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyManager(t *testing.T) {
+	const (
+		codeVerifier  = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+		codeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+	)
+
+	tests := []struct {
+		name          string
+		method        Method
+		codeChallenge string
+		codeVerifier  string
+		wait          time.Duration
+		registerErr   error
+		wantErr       error
+	}{
+		{
+			name:          "should verify a S256 code verifier",
+			method:        S256,
+			codeChallenge: codeChallenge,
+			codeVerifier:  codeVerifier,
+		},
+		{
+			name:          "should verify a plain code verifier",
+			method:        Plain,
+			codeChallenge: codeVerifier,
+			codeVerifier:  codeVerifier,
+		},
+		{
+			name:          "should default to plain if the method is not present",
+			method:        "",
+			codeChallenge: codeVerifier,
+			codeVerifier:  codeVerifier,
+		},
+		{
+			name:          "should error on a mismatched code verifier",
+			method:        S256,
+			codeChallenge: codeChallenge,
+			codeVerifier:  strings.Repeat("a", verifierMinLen),
+			wantErr:       ErrVerifierMismatch,
+		},
+		{
+			name:          "should error on the S256 code challenge presented as a plain code verifier",
+			method:        S256,
+			codeChallenge: codeChallenge,
+			codeVerifier:  codeChallenge,
+			wantErr:       ErrMethodDowngrade,
+		},
+		{
+			name:          "should error on an expired key",
+			method:        S256,
+			codeChallenge: codeChallenge,
+			codeVerifier:  codeVerifier,
+			wait:          DefaultKeyTTL,
+			wantErr:       ErrKeyExpired,
+		},
+		{
+			name:          "should error registering a truncated S256 code challenge",
+			method:        S256,
+			codeChallenge: "yolo",
+			registerErr:   ErrChallengeDigest,
+		},
+		{
+			name:          "should error registering an invalid code challenge",
+			method:        Plain,
+			codeChallenge: "yolo",
+			registerErr:   ErrChallengeInvalid,
+		},
+		{
+			name:          "should error registering a padded S256 code challenge",
+			method:        S256,
+			codeChallenge: codeChallenge + "=",
+			registerErr:   ErrChallengeEncoding,
+		},
+		{
+			name:          "should error registering an unsupported method",
+			method:        "yolo",
+			codeChallenge: codeChallenge,
+			registerErr:   ErrMethodNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			// the store's clock is left running in real time, to exercise the
+			// key's own expiry.
+			m := NewKeyManager(NewMemoryStore(), WithManagerClock(clock))
+
+			err := m.Register(context.Background(), "code", tt.method, tt.codeChallenge)
+			if err != tt.registerErr {
+				t.Fatalf("Register() error type not expected\ngot:  %v, want: %v\n", err, tt.registerErr)
+			}
+			if err != nil {
+				return
+			}
+
+			clock.Advance(tt.wait)
+
+			if err = m.Verify(context.Background(), "code", tt.codeVerifier); err != tt.wantErr {
+				t.Errorf("Verify() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err = m.Verify(context.Background(), "code", tt.codeVerifier); err != ErrKeyNotFound {
+				t.Errorf("Verify() should only allow a single verification\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+			}
+		})
+	}
+}
+
+func TestKeyManager_VerifyMethod(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  Method
+		wantErr error
+	}{
+		{
+			name: "should verify without a claimed method",
+		},
+		{
+			name:   "should verify the registered method",
+			method: S256,
+		},
+		{
+			name:    "should error on a downgraded method",
+			method:  Plain,
+			wantErr: ErrMethodDowngrade,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore())
+			if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			if err := m.VerifyMethod(context.Background(), "code", tt.method, testCodeVerifier); err != tt.wantErr {
+				t.Errorf("VerifyMethod() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Register_downgrade(t *testing.T) {
+	clock := newTestClock()
+	m := NewKeyManager(NewMemoryStore(), WithManagerClock(clock))
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if err := m.Register(context.Background(), "code", Plain, testCodeVerifier); err != ErrMethodDowngrade {
+		t.Errorf("Register() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Errorf("Register() should allow re-registering the same method, got: %v", err)
+	}
+
+	clock.Advance(DefaultKeyTTL)
+	if err := m.Register(context.Background(), "code", Plain, testCodeVerifier); err != nil {
+		t.Errorf("Register() should allow replacing an expired flow, got: %v", err)
+	}
+}
+
+func TestKeyManager_Register_hashed(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []ManagerOption
+		method        Method
+		codeChallenge string
+		wantErr       error
+	}{
+		{
+			name:          "should register an allowed S512 code challenge",
+			opts:          []ManagerOption{WithManagerMethods(S256, S512)},
+			method:        S512,
+			codeChallenge: testHashedChallenge(S512),
+		},
+		{
+			name:          "should register an allowed S384 code challenge",
+			opts:          []ManagerOption{WithManagerMethods(S384)},
+			method:        S384,
+			codeChallenge: testHashedChallenge(S384),
+		},
+		{
+			name:          "should not allow hashed methods by default",
+			method:        S512,
+			codeChallenge: testHashedChallenge(S512),
+			wantErr:       ErrMethodNotAllowed,
+		},
+		{
+			name:          "should error on a code challenge of the wrong digest",
+			opts:          []ManagerOption{WithManagerMethods(S512)},
+			method:        S512,
+			codeChallenge: testHashedChallenge(S384),
+			wantErr:       ErrChallengeDigest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if FIPSEnabled() {
+				t.Skip("hashed methods are not supported when FIPS is enabled")
+			}
+
+			m := NewKeyManager(NewMemoryStore(), tt.opts...)
+			err := m.Register(context.Background(), "code", tt.method, tt.codeChallenge)
+			if err != tt.wantErr {
+				t.Fatalf("Register() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if err = m.Verify(context.Background(), "code", testCodeVerifier); err != nil {
+				t.Errorf("Verify() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Register_hashDowngrade(t *testing.T) {
+	if FIPSEnabled() {
+		t.Skip("hashed methods are not supported when FIPS is enabled")
+	}
+
+	m := NewKeyManager(NewMemoryStore(), WithManagerMethods(S256, S512))
+	if err := m.Register(context.Background(), "code", S512, testHashedChallenge(S512)); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != ErrMethodDowngrade {
+		t.Errorf("Register() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+
+	if err := m.VerifyMethod(context.Background(), "code", S256, testCodeVerifier); err != ErrMethodDowngrade {
+		t.Errorf("VerifyMethod() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodDowngrade)
+	}
+
+	if err := m.Register(context.Background(), "upgrade", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	if err := m.Register(context.Background(), "upgrade", S512, testHashedChallenge(S512)); err != nil {
+		t.Errorf("Register() should allow upgrading a flow, got: %v", err)
+	}
+}
+
+func TestWithManagerTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{
+			name: "should set the ttl",
+			ttl:  time.Minute,
+			want: time.Minute,
+		},
+		{
+			name: "should ignore a non-positive ttl",
+			ttl:  0,
+			want: DefaultKeyTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore(), WithManagerTTL(tt.ttl))
+			if m.ttl != tt.want {
+				t.Errorf("WithManagerTTL() = %v, want %v", m.ttl, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithManagerMinVerifierEntropy(t *testing.T) {
+	weakCodeVerifier := strings.Repeat("a", verifierMinLen)
+
+	tests := []struct {
+		name          string
+		bits          float64
+		method        Method
+		codeChallenge string
+		codeVerifier  string
+		wantErr       error
+	}{
+		{
+			name:          "should verify a random code verifier",
+			bits:          128,
+			method:        S256,
+			codeChallenge: testCodeChallenge,
+			codeVerifier:  testCodeVerifier,
+		},
+		{
+			name:          "should error on a weak code verifier",
+			bits:          128,
+			method:        Plain,
+			codeChallenge: weakCodeVerifier,
+			codeVerifier:  weakCodeVerifier,
+			wantErr:       ErrVerifierEntropy,
+		},
+		{
+			name:          "should verify a weak code verifier by default",
+			bits:          0,
+			method:        Plain,
+			codeChallenge: weakCodeVerifier,
+			codeVerifier:  weakCodeVerifier,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore(), WithManagerMinVerifierEntropy(tt.bits))
+			if err := m.Register(context.Background(), "code", tt.method, tt.codeChallenge); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			if err := m.Verify(context.Background(), "code", tt.codeVerifier); err != tt.wantErr {
+				t.Errorf("Verify() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithManagerVerifierLength(t *testing.T) {
+	tests := []struct {
+		name    string
+		min     int
+		max     int
+		wantMin int
+		wantMax int
+	}{
+		{
+			name:    "should set the bounds",
+			min:     64,
+			max:     96,
+			wantMin: 64,
+			wantMax: 96,
+		},
+		{
+			name:    "should ignore non-compliant bounds",
+			min:     32,
+			max:     96,
+			wantMin: verifierMinLen,
+			wantMax: verifierMaxLen,
+		},
+		{
+			name:    "should ignore inverted bounds",
+			min:     96,
+			max:     64,
+			wantMin: verifierMinLen,
+			wantMax: verifierMaxLen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore(), WithManagerVerifierLength(tt.min, tt.max))
+			if m.minVerifierLen != tt.wantMin || m.maxVerifierLen != tt.wantMax {
+				t.Errorf("WithManagerVerifierLength() = [%v, %v], want [%v, %v]", m.minVerifierLen, m.maxVerifierLen, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestKeyManager_SupportedMethods(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []ManagerOption
+		want []Method
+	}{
+		{
+			name: "should support S256 and plain by default",
+			want: []Method{S256, Plain},
+		},
+		{
+			name: "should only support the allowed methods",
+			opts: []ManagerOption{WithManagerMethods(S256)},
+			want: []Method{S256},
+		},
+		{
+			name: "should support allowed hashed methods in order of preference",
+			opts: []ManagerOption{WithManagerMethods(S256, S384, S512)},
+			want: []Method{S512, S384, S256},
+		},
+		{
+			name: "should only support S256 in FIPS mode",
+			opts: []ManagerOption{WithManagerFIPSMode()},
+			want: []Method{S256},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if FIPSEnabled() && (containsMethod(tt.want, Plain) || containsMethod(tt.want, S512)) {
+				t.Skip("plain and hashed methods are not supported when FIPS is enabled")
+			}
+
+			m := NewKeyManager(NewMemoryStore(), tt.opts...)
+			if got := m.SupportedMethods(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SupportedMethods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Verify_concurrent(t *testing.T) {
+	m := NewKeyManager(NewMemoryStore())
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	const requests = 16
+	errs := make(chan error, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.Verify(context.Background(), "code", testCodeVerifier)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	verified := 0
+	for err := range errs {
+		if err == nil {
+			verified++
+		}
+	}
+
+	if verified != 1 {
+		t.Errorf("Verify() verified %d concurrent token requests, want exactly 1", verified)
+	}
+}
@@ -0,0 +1,247 @@
+package pkce
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"time"
+)
+
+const (
+	// keyFormatVersion provides the current version of the binary key format,
+	// which must be incremented whenever the format changes.
+	keyFormatVersion = 4
+
+	// keyFormatV1 provides the version of the binary key format which did not
+	// record the key's creation time.
+	keyFormatV1 = 1
+
+	// keyFormatV2 provides the version of the binary key format which did not
+	// record the key's metadata.
+	keyFormatV2 = 2
+
+	// keyFormatV3 provides the version of the binary key format which did not
+	// record when the key was first verified.
+	keyFormatV3 = 3
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, enabling a key to be
+// persisted between the authorization request and the token request.
+//
+// The encoding is prefixed with the format version, ensuring keys persisted by
+// previous versions of this library can continue to be decoded.
+//
+// If a code verifier has not yet been generated, only the configured verifier
+// length is encoded and the verifier will be generated on first use after
+// decoding.
+func (k *Key) MarshalBinary() ([]byte, error) {
+	method := []byte(k.challengeMethod)
+	encoding := []byte(k.challengeEncoding)
+	charset := []byte(k.verifierCharset)
+
+	var expiresAt, createdAt, verifiedAt int64
+	if !k.expiresAt.IsZero() {
+		expiresAt = k.expiresAt.UnixNano()
+	}
+	if !k.createdAt.IsZero() {
+		createdAt = k.createdAt.UnixNano()
+	}
+	if !k.verifiedAt.IsZero() {
+		verifiedAt = k.verifiedAt.UnixNano()
+	}
+
+	out := make([]byte, 0, 1+1+len(method)+2+len(k.codeVerifier)+1+len(k.codeChallenge)+1+len(encoding)+1+len(charset)+24)
+	out = append(out, keyFormatVersion)
+	out = append(out, byte(len(method)))
+	out = append(out, method...)
+	out = append(out, byte(k.codeVerifierLen))
+	out = append(out, byte(len(k.codeVerifier)))
+	out = append(out, k.codeVerifier...)
+	out = append(out, byte(len(k.codeChallenge)))
+	out = append(out, k.codeChallenge...)
+	out = append(out, byte(len(encoding)))
+	out = append(out, encoding...)
+	out = append(out, byte(len(charset)))
+	out = append(out, charset...)
+	out = appendUint64(out, uint64(expiresAt))
+	out = appendUint64(out, uint64(createdAt))
+	out = appendUint64(out, uint64(verifiedAt))
+
+	return appendMeta(out, k.meta)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The decoded key is
+// validated to ensure data loaded from storage is specification compliant.
+//
+// Data encoded with an unknown format version is rejected with
+// ErrFormatVersion, rather than being misparsed.
+func (k *Key) UnmarshalBinary(data []byte) error {
+	if err := k.checkPinned(true); err != nil {
+		return err
+	}
+
+	if len(data) < 1 {
+		return ErrKeyEncoding
+	}
+
+	switch version := data[0]; version {
+	case keyFormatV1, keyFormatV2, keyFormatV3, keyFormatVersion:
+		return k.unmarshalBinary(data[1:], version)
+
+	default:
+		return ErrFormatVersion
+	}
+}
+
+// unmarshalBinary decodes the binary key format, which is suffixed with the
+// key's creation time from version 2, the key's metadata from version 3, and
+// the time the key was first verified from version 4.
+func (k *Key) unmarshalBinary(data []byte, version byte) error {
+	method, data, err := readBytes(data)
+	if err != nil {
+		return err
+	}
+
+	if len(data) < 1 {
+		return ErrKeyEncoding
+	}
+	codeVerifierLen := int(data[0])
+
+	codeVerifier, data, err := readBytes(data[1:])
+	if err != nil {
+		return err
+	}
+
+	codeChallenge, data, err := readBytes(data)
+	if err != nil {
+		return err
+	}
+
+	encoding, data, err := readBytes(data)
+	if err != nil {
+		return err
+	}
+
+	charset, data, err := readBytes(data)
+	if err != nil {
+		return err
+	}
+
+	// the fixed size timestamps precede the metadata.
+	timestamps := 8
+	switch {
+	case version >= keyFormatVersion:
+		timestamps = 24
+	case version >= keyFormatV2:
+		timestamps = 16
+	}
+	if len(data) < timestamps || (version < keyFormatV3 && len(data) != timestamps) {
+		return ErrKeyEncoding
+	}
+
+	var meta map[string]string
+	if version >= keyFormatV3 {
+		if meta, err = readMeta(data[timestamps:]); err != nil {
+			return err
+		}
+	}
+
+	expiresAt := int64(binary.BigEndian.Uint64(data))
+
+	var createdAt, verifiedAt int64
+	if timestamps >= 16 {
+		createdAt = int64(binary.BigEndian.Uint64(data[8:]))
+	}
+	if timestamps >= 24 {
+		verifiedAt = int64(binary.BigEndian.Uint64(data[16:]))
+	}
+
+	key := Key{}
+	if err = WithChallengeMethod(Method(method))(&key); err != nil {
+		return err
+	}
+
+	if len(encoding) > 0 {
+		if err = WithChallengeEncoding(ChallengeEncoding(encoding))(&key); err != nil {
+			return err
+		}
+	}
+
+	if len(charset) > 0 {
+		if err = WithVerifierCharset(string(charset))(&key); err != nil {
+			return err
+		}
+	}
+
+	if len(codeVerifier) > 0 {
+		err = key.setCodeVerifier(append([]byte(nil), codeVerifier...))
+	} else {
+		err = key.setCodeVerifierLength(codeVerifierLen)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(codeChallenge) > 0 {
+		if err = WithCodeChallenge(string(codeChallenge))(&key); err != nil {
+			return err
+		}
+	}
+
+	if expiresAt != 0 {
+		key.expiresAt = time.Unix(0, expiresAt)
+	}
+	if createdAt != 0 {
+		key.createdAt = time.Unix(0, createdAt)
+	}
+	if verifiedAt != 0 {
+		key.verifiedAt = time.Unix(0, verifiedAt)
+	}
+	key.meta = meta
+
+	// retain the runtime configuration of the key being decoded into.
+	key.clock = k.clock
+	*k = key
+
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder, enabling keys to be persisted in gob
+// based session stores.
+func (k *Key) GobEncode() ([]byte, error) {
+	return k.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder. The decoded key is validated to ensure
+// data loaded from storage is specification compliant.
+func (k *Key) GobDecode(data []byte) error {
+	return k.UnmarshalBinary(data)
+}
+
+// RegisterGob registers *Key with encoding/gob, which is required to store
+// keys as interface values, such as in gorilla/sessions session values.
+func RegisterGob() {
+	gob.Register(&Key{})
+}
+
+// readBytes reads a single byte length-prefixed value, returning the value and
+// the remaining data.
+func readBytes(data []byte) (value []byte, rest []byte, err error) {
+	if len(data) < 1 {
+		return nil, nil, ErrKeyEncoding
+	}
+
+	n := int(data[0])
+	if len(data) < 1+n {
+		return nil, nil, ErrKeyEncoding
+	}
+
+	return data[1 : 1+n], data[1+n:], nil
+}
+
+// appendUint64 appends the big endian encoding of v.
+func appendUint64(out []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+
+	return append(out, buf[:]...)
+}
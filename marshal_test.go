@@ -0,0 +1,277 @@
+package pkce
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKey_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{
+			name: "should round trip a key with a code verifier",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: 48,
+				codeVerifier:    []byte(strings.Repeat("a", 48)),
+			},
+		},
+		{
+			name: "should round trip a plain key with a code verifier",
+			key: &Key{
+				challengeMethod: Plain,
+				codeVerifierLen: verifierMaxLen,
+				codeVerifier:    []byte(strings.Repeat("~", verifierMaxLen)),
+			},
+		},
+		{
+			name: "should round trip a key with a received code challenge",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				expiresAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with a challenge encoding",
+			key: &Key{
+				challengeMethod:   S256,
+				challengeEncoding: Hex,
+				codeVerifierLen:   verifierMinLen,
+				codeVerifier:      []byte(strings.Repeat("a", verifierMinLen)),
+			},
+		},
+		{
+			name: "should round trip a key with a code verifier charset",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: 64,
+				verifierCharset: AlphanumericCharset,
+			},
+		},
+		{
+			name: "should round trip a key with a creation time",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				createdAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with a verification time",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				createdAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+				verifiedAt:      time.Unix(0, time.Date(2022, 1, 27, 0, 1, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with metadata",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				meta: map[string]string{
+					"client_id":    "client",
+					"redirect_uri": "https://client.example.com/cb?" + strings.Repeat("a", 512),
+					"scope":        "",
+				},
+			},
+		},
+		{
+			name: "should round trip a key without a generated code verifier",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: 64,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.key.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() unexpected error: %v", err)
+			}
+
+			got := &Key{}
+			if err = got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.key) {
+				t.Errorf("UnmarshalBinary() key\ngot:  %v\nwant: %v\n", got, tt.key)
+			}
+		})
+	}
+}
+
+func TestKey_UnmarshalBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "should error on empty data",
+			data:    nil,
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on an unknown format version",
+			data:    []byte{keyFormatVersion + 1, 4, 'S', '2', '5', '6'},
+			wantErr: ErrFormatVersion,
+		},
+		{
+			name:    "should error on a missing creation time",
+			data:    []byte{keyFormatVersion, 4, 'S', '2', '5', '6', 43, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on truncated method",
+			data:    []byte{keyFormatV1, 4, 'S', '2'},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on missing verifier length",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6'},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on truncated verifier",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 43, 'a'},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on trailing data",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 0, 0},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on invalid code challenge",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 0, 4, 'y', 'o', 'l', 'o', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name:    "should error on unsupported encoding",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 0, 0, 4, 'y', 'o', 'l', 'o', 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrEncodingNotSupported,
+		},
+		{
+			name:    "should error on invalid charset",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 0, 0, 0, 1, '!', 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrCharsetInvalid,
+		},
+		{
+			name:    "should error on unsupported method",
+			data:    []byte{keyFormatV1, 4, 'y', 'o', 'l', 'o', 43, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name:    "should error on invalid verifier length",
+			data:    []byte{keyFormatV1, 4, 'S', '2', '5', '6', 42, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should error on invalid verifier characters",
+			data:    append(append([]byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 43}, strings.Repeat("!", 43)...), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0),
+			wantErr: ErrVerifierCharacters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &Key{}
+			if err := key.UnmarshalBinary(tt.data); err != tt.wantErr {
+				t.Errorf("UnmarshalBinary() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKey_GobEncode(t *testing.T) {
+	RegisterGob()
+
+	key := &Key{
+		challengeMethod: S256,
+		codeVerifierLen: 64,
+		codeVerifier:    []byte(strings.Repeat("a", 64)),
+		expiresAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+	}
+
+	// session stores persist values as interfaces.
+	var buf bytes.Buffer
+	values := map[interface{}]interface{}{"pkce": key}
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		t.Fatalf("Encode() unexpected error: %v", err)
+	}
+
+	got := map[interface{}]interface{}{}
+	if err := gob.NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got["pkce"], key) {
+		t.Errorf("GobDecode() key\ngot:  %v\nwant: %v\n", got["pkce"], key)
+	}
+}
+
+func TestKey_GobDecode(t *testing.T) {
+	key := &Key{}
+	if err := key.GobDecode([]byte{keyFormatV1, 4, 'y', 'o', 'l', 'o'}); err != ErrKeyEncoding {
+		t.Errorf("GobDecode() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyEncoding)
+	}
+}
+
+func TestKey_UnmarshalBinary_v1(t *testing.T) {
+	data := []byte{keyFormatV1, 4, 'S', '2', '5', '6', 43, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	key := &Key{}
+	if err := key.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+	}
+
+	if !key.CreatedAt().IsZero() {
+		t.Errorf("CreatedAt() = %v, a version 1 key's creation time is unknown", key.CreatedAt())
+	}
+}
+
+func TestKey_UnmarshalBinary_v2(t *testing.T) {
+	data := []byte{keyFormatV2, 4, 'S', '2', '5', '6', 43, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+
+	key := &Key{}
+	if err := key.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+	}
+
+	if key.Meta() != nil || !key.CreatedAt().Equal(time.Unix(0, 1)) {
+		t.Errorf("UnmarshalBinary() should decode a version 2 key without metadata")
+	}
+
+	if err := key.UnmarshalBinary(append(data, 0, 1, 'a')); err != ErrKeyEncoding {
+		t.Errorf("UnmarshalBinary() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyEncoding)
+	}
+}
+
+func TestKey_UnmarshalBinary_v3(t *testing.T) {
+	data := []byte{keyFormatV3, 4, 'S', '2', '5', '6', 43, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 'a', 0, 1, 'b'}
+
+	key := &Key{}
+	if err := key.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error: %v", err)
+	}
+
+	if !key.VerifiedAt().IsZero() || !key.CreatedAt().Equal(time.Unix(0, 1)) || key.Meta()["a"] != "b" {
+		t.Errorf("UnmarshalBinary() should decode a version 3 key without a verification time")
+	}
+}
@@ -0,0 +1,102 @@
+package pkce
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// metaMaxLen provides the maximum length of a metadata name or value, as
+// encoded by MarshalBinary.
+const metaMaxLen = 1<<16 - 1
+
+// SetMeta attaches metadata to the key, such as the client_id, redirect_uri,
+// scope or tenant of the authorization request, replacing any value previously
+// set for name. Metadata is serialized with the key, so it is carried through a
+// Store alongside the code challenge.
+//
+// Metadata is not secret, but is only encoded by MarshalJSON in the full mode.
+func (k *Key) SetMeta(name string, value string) {
+	if k.meta == nil {
+		k.meta = map[string]string{}
+	}
+
+	k.meta[name] = value
+}
+
+// Meta returns a copy of the metadata attached to the key with SetMeta, or nil
+// if none has been attached.
+func (k *Key) Meta() map[string]string {
+	return copyMeta(k.meta)
+}
+
+// copyMeta returns a copy of meta, or nil if it is empty.
+func copyMeta(meta map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(meta))
+	for name, value := range meta {
+		out[name] = value
+	}
+
+	return out
+}
+
+// sortedMetaNames returns the metadata names in order, so that encodings are
+// deterministic.
+func sortedMetaNames(meta map[string]string) []string {
+	names := make([]string, 0, len(meta))
+	for name := range meta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// appendMeta appends the binary encoding of the metadata, being a sequence of
+// two byte length-prefixed name and value pairs.
+func appendMeta(out []byte, meta map[string]string) ([]byte, error) {
+	for _, name := range sortedMetaNames(meta) {
+		for _, field := range []string{name, meta[name]} {
+			if len(field) > metaMaxLen {
+				return nil, ErrKeyEncoding
+			}
+
+			var size [2]byte
+			binary.BigEndian.PutUint16(size[:], uint16(len(field)))
+			out = append(out, size[:]...)
+			out = append(out, field...)
+		}
+	}
+
+	return out, nil
+}
+
+// readMeta decodes the binary encoding of metadata written by appendMeta,
+// which must make up the remainder of data.
+func readMeta(data []byte) (map[string]string, error) {
+	var meta map[string]string
+	for len(data) > 0 {
+		var fields [2]string
+		for i := range fields {
+			if len(data) < 2 {
+				return nil, ErrKeyEncoding
+			}
+
+			n := int(binary.BigEndian.Uint16(data))
+			if len(data) < 2+n {
+				return nil, ErrKeyEncoding
+			}
+			fields[i], data = string(data[2:2+n]), data[2+n:]
+		}
+
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[fields[0]] = fields[1]
+	}
+
+	return meta, nil
+}
@@ -0,0 +1,82 @@
+package pkce
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKey_SetMeta(t *testing.T) {
+	key, err := New(WithCodeVerifierString(testCodeVerifier))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if key.Meta() != nil {
+		t.Errorf("Meta() = %v, want nil", key.Meta())
+	}
+
+	key.SetMeta("client_id", "stale")
+	key.SetMeta("client_id", "client")
+	key.SetMeta("tenant", "acme")
+	want := map[string]string{"client_id": "client", "tenant": "acme"}
+
+	got := key.Meta()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Meta()\ngot:  %v\nwant: %v\n", got, want)
+	}
+
+	got["tenant"] = "mutated"
+	if key.Meta()["tenant"] != "acme" {
+		t.Errorf("Meta() should return a copy of the key's metadata")
+	}
+
+	clone, err := key.CloneWithNewVerifier()
+	if err != nil {
+		t.Fatalf("CloneWithNewVerifier() unexpected error: %v", err)
+	}
+	if clone.Meta() != nil {
+		t.Errorf("CloneWithNewVerifier() should not copy per-flow metadata, got: %v", clone.Meta())
+	}
+}
+
+func TestKey_SetMeta_json(t *testing.T) {
+	key, err := New(WithCodeVerifierString(testCodeVerifier))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	key.SetMeta("client_id", "client")
+
+	public, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("MarshalJSON() unexpected error: %v", err)
+	}
+	if strings.Contains(string(public), "client_id") {
+		t.Errorf("MarshalJSON() should not encode metadata in public mode, got: %s", public)
+	}
+
+	if err = WithJSONMode(JSONFull)(key); err != nil {
+		t.Fatalf("WithJSONMode() unexpected error: %v", err)
+	}
+	full, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("MarshalJSON() unexpected error: %v", err)
+	}
+
+	got := &Key{}
+	if err = json.Unmarshal(full, got); err != nil {
+		t.Fatalf("UnmarshalJSON() unexpected error: %v", err)
+	}
+	if got.Meta()["client_id"] != "client" {
+		t.Errorf("UnmarshalJSON() should decode metadata in full mode, got: %v", got.Meta())
+	}
+}
+
+func TestKey_MarshalBinary_metaTooLong(t *testing.T) {
+	key := &Key{challengeMethod: S256, codeVerifierLen: verifierMinLen}
+	key.SetMeta("scope", strings.Repeat("a", metaMaxLen+1))
+
+	if _, err := key.MarshalBinary(); err != ErrKeyEncoding {
+		t.Errorf("MarshalBinary() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyEncoding)
+	}
+}
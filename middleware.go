@@ -0,0 +1,316 @@
+package pkce
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// RFC 6749, 5.2. Error Response codes.
+	errorCodeInvalidRequest = "invalid_request"
+	errorCodeInvalidGrant   = "invalid_grant"
+	errorCodeServerError    = "server_error"
+
+	// errorCodeTemporarilyUnavailable is returned when verification attempts
+	// are being throttled.
+	errorCodeTemporarilyUnavailable = "temporarily_unavailable"
+)
+
+// MiddlewareOption enables variadic verification middleware options to be
+// configured.
+type MiddlewareOption func(*middleware)
+
+// middleware verifies the code verifier of access token requests.
+type middleware struct {
+	manager   *KeyManager
+	next      http.Handler
+	clientKey func(r *http.Request) string
+	limiter   Limiter
+	detector  Detector
+	failures  *failureTracker
+	parseOpts []ParseOption
+	failOpen  func(r *http.Request, err error)
+	session   func(r *http.Request) string
+}
+
+// WithClientKey enables specifying how requests are keyed per client for rate
+// limiting and anomaly detection. Defaults to the client_id parameter, falling
+// back to the remote IP address.
+func WithClientKey(fn func(r *http.Request) string) MiddlewareOption {
+	return func(m *middleware) {
+		if fn != nil {
+			m.clientKey = fn
+		}
+	}
+}
+
+// WithDetector enables inspecting each client's verification failures,
+// aggregated over a sliding window, after every failed attempt.
+func WithDetector(detector Detector, window time.Duration) MiddlewareOption {
+	return func(m *middleware) {
+		m.detector = detector
+		m.failures = newFailureTracker(window, m.manager.clock)
+	}
+}
+
+// WithFailOpen enables passing token requests through unverified when the
+// code verifier can not be verified due to the store failing, such as
+// returning ErrStoreUnavailable or a StoreError, after reporting the error to
+// log. By default, the middleware fails closed, responding with an error.
+//
+// Entries which have been corrupted or tampered with, or which can not be
+// decrypted, and other errors, such as misconfiguration, always fail closed.
+//
+// Failing open disables PKCE protection for the duration of a store outage,
+// so should only be enabled where availability outweighs the risk of
+// authorization code interception.
+func WithFailOpen(log func(r *http.Request, err error)) MiddlewareOption {
+	return func(m *middleware) {
+		if log == nil {
+			log = func(*http.Request, error) {}
+		}
+		m.failOpen = log
+	}
+}
+
+// WithLimiter enables throttling of failed verification attempts.
+func WithLimiter(limiter Limiter) MiddlewareOption {
+	return func(m *middleware) {
+		m.limiter = limiter
+	}
+}
+
+// WithParseOptions enables specifying the leniency applied when parsing token
+// requests. Defaults to strict parsing.
+func WithParseOptions(opts ...ParseOption) MiddlewareOption {
+	return func(m *middleware) {
+		m.parseOpts = append(m.parseOpts, opts...)
+	}
+}
+
+// Middleware returns a handler which verifies the code verifier of
+// authorization code token requests against the code challenge registered
+// under the correlation value resolved by the manager's binder, by default the
+// authorization code, before passing the request on to next.
+//
+// Token requests are decoded as ParseTokenRequest, so JSON and multipart bodies
+// are verified too. JSON bodies are restored for next to read. Flows registered
+// with RegisterRedirect also have their redirect uri verified.
+//
+// Failed verifications are responded to with an RFC 6749, 5.2 error response.
+// Requests for other grant types are passed through unverified. Requests with
+// a missing or unsupported content type are rejected, as their grant type can
+// not be determined, as are requests with token request parameters in the
+// query string, as next may read them with http.Request.FormValue.
+func (m *KeyManager) Middleware(next http.Handler, opts ...MiddlewareOption) http.Handler {
+	mw := &middleware{
+		manager:   m,
+		next:      next,
+		clientKey: defaultClientKey,
+		session:   func(*http.Request) string { return "" },
+	}
+
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return mw
+}
+
+// ServeHTTP implements http.Handler.
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if hasQueryTokenParams(r) {
+		writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "token request parameters must be sent in the request body")
+		return
+	}
+
+	switch err := parseTokenBody(r, newParseConfig(m.parseOpts)); err {
+	case nil:
+
+	case ErrUnsupportedContentType:
+		// the grant type can not be determined, so the request can not be
+		// shown not to be an authorization code token request.
+		writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, err.Error())
+		return
+
+	case ErrRequestTooLarge:
+		writeError(w, http.StatusRequestEntityTooLarge, errorCodeInvalidRequest, err.Error())
+		return
+
+	default:
+		writeError(w, http.StatusBadRequest, errorCodeInvalidRequest, "the request body could not be parsed")
+		return
+	}
+
+	if r.PostForm.Get(paramGrantType) != grantTypeAuthorizationCode {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := r.Context()
+
+	var clientKey string
+	if m.limiter != nil || m.detector != nil {
+		clientKey = m.clientKey(r)
+	}
+
+	if m.limiter != nil {
+		if !m.limiter.Allow(ctx, clientKey) {
+			writeError(w, http.StatusTooManyRequests, errorCodeTemporarilyUnavailable, "too many failed verification attempts")
+			return
+		}
+	}
+
+	req, err := ParseTokenRequest(r, m.parseOpts...)
+	if err == nil {
+		err = m.manager.verifyAudited(ctx, verifyRequest{
+			id:               m.manager.binder.TokenRequestValue(r),
+			method:           req.CodeChallengeMethod,
+			session:          m.session(r),
+			codeVerifier:     req.CodeVerifier,
+			checkRedirectURI: true,
+			redirectURI:      req.RedirectURI,
+		})
+	}
+
+	if err == nil {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	switch code, description := OAuthErrorCode(err); code {
+	case errorCodeInvalidRequest, errorCodeInvalidGrant:
+		writeError(w, http.StatusBadRequest, code, description)
+
+	case errorCodeTemporarilyUnavailable:
+		if m.serveFailOpen(w, r, err) {
+			return
+		}
+
+		writeError(w, http.StatusServiceUnavailable, code, description)
+		return
+
+	default:
+		if storeFailure(err) && m.serveFailOpen(w, r, err) {
+			return
+		}
+
+		writeError(w, http.StatusInternalServerError, code, description)
+		return
+	}
+
+	if m.limiter != nil {
+		m.limiter.Failure(ctx, clientKey)
+	}
+
+	if m.detector != nil {
+		stats := m.failures.record(clientKey, r.PostForm.Get(paramCode), failureReason(err))
+		m.detector.Detect(ctx, stats)
+	}
+}
+
+// storeFailure returns whether err was caused by the store failing, such as
+// during an outage, rather than by an entry which has been corrupted or
+// tampered with, or which can not be decrypted, or by misconfiguration, which
+// must always fail closed.
+func storeFailure(err error) bool {
+	if !errors.Is(err, ErrStorage) {
+		return false
+	}
+
+	for _, closed := range []error{ErrKeyEncoding, ErrFormatVersion, ErrSealedPayload, ErrKeyringKeyNotFound} {
+		if errors.Is(err, closed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// queryTokenParams provides the token request parameters which must only be
+// sent in the request body.
+var queryTokenParams = []string{ //nolint:gochecknoglobals // read only.
+	paramGrantType,
+	paramCode,
+	paramRedirectURI,
+	ParamCodeVerifier,
+	ParamCodeChallengeMethod,
+}
+
+// hasQueryTokenParams returns whether the request's query string contains
+// token request parameters.
+//
+// RFC 6749, 3.2. Token request parameters are sent in the request body. Next
+// handlers reading parameters with http.Request.FormValue would otherwise act
+// on query parameters which have not been verified.
+func hasQueryTokenParams(r *http.Request) bool {
+	query := r.URL.Query()
+	for _, param := range queryTokenParams {
+		if _, ok := query[param]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serveFailOpen passes the request through to next if failing open, returning
+// whether the request has been served.
+func (m *middleware) serveFailOpen(w http.ResponseWriter, r *http.Request, err error) bool {
+	if m.failOpen == nil {
+		return false
+	}
+
+	m.failOpen(r, err)
+	m.next.ServeHTTP(w, r)
+
+	return true
+}
+
+// WithSession enables resolving the session identifier a token request's code
+// challenge was bound to, as registered by KeyManager.RegisterSession. Defaults
+// to the empty session.
+func WithSession(fn func(r *http.Request) string) MiddlewareOption {
+	return func(m *middleware) {
+		if fn != nil {
+			m.session = fn
+		}
+	}
+}
+
+// defaultClientKey keys requests by client_id, falling back to the remote IP
+// address for requests which do not identify the client.
+func defaultClientKey(r *http.Request) string {
+	if clientID := r.PostForm.Get(paramClientID); clientID != "" {
+		return clientID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// errorResponse provides the RFC 6749, 5.2 error response body.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeError writes an RFC 6749, 5.2 error response.
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
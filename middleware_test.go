@@ -0,0 +1,379 @@
+package pkce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testCodeVerifier  = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+	testCodeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+)
+
+// newTestMiddleware returns a verification middleware with a code challenge
+// registered under "code", wrapping a handler which responds 200 OK.
+func newTestMiddleware(t *testing.T, opts ...MiddlewareOption) http.Handler {
+	m := NewKeyManager(NewMemoryStore())
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return m.Middleware(next, opts...)
+}
+
+func TestKeyManager_Middleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		form       url.Values
+		opts       []MiddlewareOption
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name: "should pass through a verified token request",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {testCodeVerifier},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should pass through other grant types",
+			form: url.Values{
+				"grant_type": {"refresh_token"},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should reject a mismatched code verifier",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {strings.Repeat("a", verifierMinLen)},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errorCodeInvalidGrant,
+		},
+		{
+			name: "should reject an unknown authorization code",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"unknown"},
+				"code_verifier": {testCodeVerifier},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errorCodeInvalidGrant,
+		},
+		{
+			name: "should reject a downgraded code challenge method",
+			form: url.Values{
+				"grant_type":            {"authorization_code"},
+				"code":                  {"code"},
+				"code_verifier":         {testCodeVerifier},
+				"code_challenge_method": {"plain"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errorCodeInvalidGrant,
+		},
+		{
+			name: "should reject a missing code verifier",
+			form: url.Values{
+				"grant_type": {"authorization_code"},
+				"code":       {"code"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errorCodeInvalidRequest,
+		},
+		{
+			name: "should reject a percent-encoded code verifier",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {strings.Replace(testCodeVerifier, "~", "%7E", 1)},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantError:  errorCodeInvalidRequest,
+		},
+		{
+			name: "should pass through a percent-encoded code verifier when lenient",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {strings.Replace(testCodeVerifier, "~", "%7E", 1)},
+			},
+			opts:       []MiddlewareOption{WithParseOptions(WithLenientVerifierEncoding())},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "should reject an oversized token request",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {testCodeVerifier},
+			},
+			opts:       []MiddlewareOption{WithParseOptions(WithMaxBodyBytes(16))},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantError:  errorCodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newTestMiddleware(t, tt.opts...).ServeHTTP(rec, newTokenRequest(tt.form))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			if tt.wantError != "" {
+				var got errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+					t.Fatalf("ServeHTTP() unexpected error decoding response: %v", err)
+				}
+				if got.Error != tt.wantError {
+					t.Errorf("ServeHTTP() error = %v, want %v", got.Error, tt.wantError)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyManager_Middleware_query(t *testing.T) {
+	tests := []struct {
+		name  string
+		query url.Values
+		form  url.Values
+	}{
+		{
+			name: "should reject a token request sent in the query string",
+			query: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {strings.Repeat("a", verifierMinLen)},
+			},
+		},
+		{
+			name: "should reject a grant type sent in the query string",
+			query: url.Values{
+				"grant_type": {"authorization_code"},
+			},
+			form: url.Values{
+				"code":          {"code"},
+				"code_verifier": {strings.Repeat("a", verifierMinLen)},
+			},
+		},
+		{
+			name: "should reject a code verifier sent in the query string",
+			query: url.Values{
+				"code_verifier": {testCodeVerifier},
+			},
+			form: url.Values{
+				"grant_type": {"refresh_token"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/token?"+tt.query.Encode(), strings.NewReader(tt.form.Encode()))
+			if tt.form != nil {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+
+			rec := httptest.NewRecorder()
+			newTestMiddleware(t).ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusBadRequest)
+			}
+
+			var got errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("ServeHTTP() unexpected error decoding response: %v", err)
+			}
+			if got.Error != errorCodeInvalidRequest {
+				t.Errorf("ServeHTTP() error = %v, want %v", got.Error, errorCodeInvalidRequest)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Middleware_contentType(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{
+			name: "should reject a token request without a content type",
+		},
+		{
+			name:        "should reject a token request with an unsupported content type",
+			contentType: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {strings.Repeat("a", verifierMinLen)},
+			}
+			r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rec := httptest.NewRecorder()
+			newTestMiddleware(t).ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusBadRequest)
+			}
+
+			var got errorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("ServeHTTP() unexpected error decoding response: %v", err)
+			}
+			if got.Error != errorCodeInvalidRequest {
+				t.Errorf("ServeHTTP() error = %v, want %v", got.Error, errorCodeInvalidRequest)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Middleware_json(t *testing.T) {
+	tests := []struct {
+		name         string
+		codeVerifier string
+		wantStatus   int
+	}{
+		{
+			name:         "should pass through a verified JSON token request",
+			codeVerifier: testCodeVerifier,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "should reject a mismatched JSON token request",
+			codeVerifier: strings.Repeat("a", verifierMinLen),
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"grant_type":"authorization_code","code":"code","code_verifier":"` + tt.codeVerifier + `"}`
+			r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+
+			rec := httptest.NewRecorder()
+			newTestMiddleware(t).ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWithLimiter(t *testing.T) {
+	limiter := NewMemoryLimiter(1, time.Minute, nil)
+	handler := newTestMiddleware(t, WithLimiter(limiter))
+
+	attempt := func(codeVerifier string) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newTokenRequest(url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {"client"},
+			"code":          {"code"},
+			"code_verifier": {codeVerifier},
+		}))
+
+		return rec.Code
+	}
+
+	if got := attempt(strings.Repeat("a", verifierMinLen)); got != http.StatusBadRequest {
+		t.Errorf("ServeHTTP() status = %v, want %v", got, http.StatusBadRequest)
+	}
+
+	if got := attempt(testCodeVerifier); got != http.StatusTooManyRequests {
+		t.Errorf("ServeHTTP() should throttle after failures\ngot:  %v, want: %v", got, http.StatusTooManyRequests)
+	}
+}
+
+func TestWithClientKey(t *testing.T) {
+	key := ""
+	limiter := &recordingLimiter{
+		allow: func(k string) { key = k },
+	}
+	handler := newTestMiddleware(t, WithLimiter(limiter), WithClientKey(func(r *http.Request) string {
+		return r.Header.Get("X-Forwarded-For")
+	}))
+
+	r := newTokenRequest(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"code"},
+		"code_verifier": {testCodeVerifier},
+	})
+	r.Header.Set("X-Forwarded-For", "203.0.113.1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if key != "203.0.113.1" {
+		t.Errorf("WithClientKey() key = %v, want %v", key, "203.0.113.1")
+	}
+}
+
+func Test_defaultClientKey(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{
+			name: "should key by client id",
+			form: url.Values{"client_id": {"client"}},
+			want: "client",
+		},
+		{
+			name: "should fall back to the remote address",
+			form: url.Values{},
+			want: "192.0.2.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTokenRequest(tt.form)
+			_ = r.ParseForm()
+			if got := defaultClientKey(r); got != tt.want {
+				t.Errorf("defaultClientKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingLimiter provides a Limiter which records the keys it is consulted
+// with.
+type recordingLimiter struct {
+	allow func(key string)
+}
+
+// Allow implements Limiter.
+func (l *recordingLimiter) Allow(_ context.Context, key string) bool {
+	l.allow(key)
+	return true
+}
+
+// Failure implements Limiter.
+func (l *recordingLimiter) Failure(context.Context, string) {}
@@ -0,0 +1,149 @@
+// Package mongostore provides a pkce.Store backed by a MongoDB collection,
+// enabling code challenges to be shared between authorization server
+// instances.
+//
+// The package is a separate module, so that the pkce module remains free of
+// external dependencies.
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// fieldExpiresAt provides the field entries store their expiry in, which is
+// omitted for entries which do not expire.
+const fieldExpiresAt = "expiresAt"
+
+// Store provides a pkce.Store persisting each entry as a document of a
+// MongoDB collection:
+//
+//	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+//	store, err := mongostore.New(ctx, client.Database("auth").Collection("pkce"))
+//	manager := pkce.NewKeyManager(store)
+//
+// Expired documents are removed by a TTL index on their expiry, which is
+// created by New. As MongoDB only removes expired documents periodically,
+// expired documents are also excluded from lookups. Consume uses
+// findAndModify, so each entry is returned to only one of any concurrent
+// callers.
+type Store struct {
+	collection *mongo.Collection
+	clock      pkce.Clock
+}
+
+// document provides the stored representation of an entry.
+type document struct {
+	ID        string     `bson:"_id"`
+	Data      []byte     `bson:"data"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty"`
+}
+
+// Option enables variadic store options to be configured.
+type Option func(*Store)
+
+// WithClock enables specifying the clock used to compute entry expiry.
+// Defaults to the system clock.
+func WithClock(clock pkce.Clock) Option {
+	return func(s *Store) {
+		if clock != nil {
+			s.clock = clock
+		}
+	}
+}
+
+// New returns a store persisting entries to collection, creating the TTL
+// index which removes expired entries if it does not exist.
+func New(ctx context.Context, collection *mongo.Collection, opts ...Option) (*Store, error) {
+	s := &Store{
+		collection: collection,
+		clock:      pkce.SystemClock(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: fieldExpiresAt, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Put implements pkce.Store.
+func (s *Store) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	doc := document{
+		ID:   id,
+		Data: data,
+	}
+	if ttl > 0 {
+		// BSON dates have millisecond precision, so expiry is rounded up to
+		// ensure entries do not expire early.
+		expiresAt := s.clock.Now().Add(ttl + time.Millisecond - 1).Truncate(time.Millisecond)
+		doc.ExpiresAt = &expiresAt
+	}
+
+	_, err := s.collection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: id}}, doc, options.Replace().SetUpsert(true))
+
+	return err
+}
+
+// Get implements pkce.Store.
+func (s *Store) Get(ctx context.Context, id string) ([]byte, error) {
+	return decode(s.collection.FindOne(ctx, s.liveFilter(id)))
+}
+
+// Delete implements pkce.Store.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}})
+
+	return err
+}
+
+// Consume implements pkce.Store.
+func (s *Store) Consume(ctx context.Context, id string) ([]byte, error) {
+	return decode(s.collection.FindOneAndDelete(ctx, s.liveFilter(id)))
+}
+
+// liveFilter returns the filter matching the entry stored under id, if it
+// does not expire or has not yet expired.
+func (s *Store) liveFilter(id string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: fieldExpiresAt, Value: bson.D{{Key: "$exists", Value: false}}}},
+			bson.D{{Key: fieldExpiresAt, Value: bson.D{{Key: "$gt", Value: s.clock.Now()}}}},
+		}},
+	}
+}
+
+// decode returns the data of the document found by result, or
+// pkce.ErrKeyNotFound if no document matched.
+func decode(result *mongo.SingleResult) ([]byte, error) {
+	var doc document
+	if err := result.Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, pkce.ErrKeyNotFound
+		}
+
+		return nil, err
+	}
+
+	if doc.Data == nil {
+		// entries stored with empty data are decoded as nil.
+		return []byte{}, nil
+	}
+
+	return doc.Data, nil
+}
@@ -0,0 +1,48 @@
+package mongostore
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/matthewhartstonge/pkce"
+	"github.com/matthewhartstonge/pkce/storetest"
+)
+
+// envMongoDBURI provides the environment variable specifying the MongoDB
+// deployment the store is tested against.
+const envMongoDBURI = "PKCE_MONGODB_URI"
+
+func TestStore(t *testing.T) {
+	uri := os.Getenv(envMongoDBURI)
+	if uri == "" {
+		t.Skipf("%s is not set", envMongoDBURI)
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("Connect() unexpected error: %v", err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	db := client.Database("pkce_test")
+	storetest.TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+		ctx := context.Background()
+
+		name, err := pkce.RandomString(16, pkce.AlphanumericCharset)
+		if err != nil {
+			t.Fatalf("RandomString() unexpected error: %v", err)
+		}
+
+		collection := db.Collection(name)
+		store, err := New(ctx, collection, WithClock(clock))
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return store, func() { _ = collection.Drop(ctx) }
+	})
+}
@@ -0,0 +1,39 @@
+package pkce
+
+// FromOAuth2Verifier returns an S256 key for a code verifier generated by
+// golang.org/x/oauth2's GenerateVerifier, so in-flight authorization requests
+// started with x/oauth2 can be completed using a Key.
+func FromOAuth2Verifier(verifier string, opts ...Option) (*Key, error) {
+	return New(append([]Option{
+		WithChallengeMethod(S256),
+		WithCodeVerifierString(verifier),
+	}, opts...)...)
+}
+
+// AuthCodeParams returns the authorization request parameters of the key,
+// for converting to golang.org/x/oauth2 options without this package
+// depending on x/oauth2:
+//
+//	for k, v := range key.AuthCodeParams() {
+//		opts = append(opts, oauth2.SetAuthURLParam(k, v))
+//	}
+//
+// For an S256 key using the default encoding, these are equivalent to
+// oauth2.S256ChallengeOption(key.CodeVerifier()).
+func (k *Key) AuthCodeParams() map[string]string {
+	payload := k.ChallengePayload()
+
+	return map[string]string{
+		ParamCodeChallenge:       payload.Challenge,
+		ParamCodeChallengeMethod: payload.Method.String(),
+	}
+}
+
+// ExchangeParams returns the token request parameters of the key, for
+// converting to golang.org/x/oauth2 options as with AuthCodeParams. These are
+// equivalent to oauth2.VerifierOption(key.CodeVerifier()).
+func (k *Key) ExchangeParams() map[string]string {
+	return map[string]string{
+		ParamCodeVerifier: k.CodeVerifier(),
+	}
+}
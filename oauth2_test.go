@@ -0,0 +1,50 @@
+package pkce
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+// oauth2Verifier generates a code verifier as golang.org/x/oauth2's
+// GenerateVerifier does.
+func oauth2Verifier() string {
+	data := make([]byte, 32)
+	_, _ = rand.Read(data)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func TestFromOAuth2Verifier(t *testing.T) {
+	verifier := oauth2Verifier()
+
+	key, err := FromOAuth2Verifier(verifier)
+	if err != nil {
+		t.Fatalf("FromOAuth2Verifier() unexpected error: %v", err)
+	}
+
+	// x/oauth2's S256ChallengeFromVerifier.
+	sum := sha256.Sum256([]byte(verifier))
+	want := map[string]string{
+		ParamCodeChallenge:       base64.RawURLEncoding.EncodeToString(sum[:]),
+		ParamCodeChallengeMethod: "S256",
+	}
+	if got := key.AuthCodeParams(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AuthCodeParams() = %v, want %v", got, want)
+	}
+
+	want = map[string]string{
+		ParamCodeVerifier: verifier,
+	}
+	if got := key.ExchangeParams(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExchangeParams() = %v, want %v", got, want)
+	}
+}
+
+func TestFromOAuth2Verifier_invalid(t *testing.T) {
+	if _, err := FromOAuth2Verifier("yolo"); err != ErrVerifierLength {
+		t.Errorf("FromOAuth2Verifier() error type not expected\ngot:  %v, want: %v\n", err, ErrVerifierLength)
+	}
+}
@@ -0,0 +1,97 @@
+package pkce
+
+import (
+	"errors"
+)
+
+// oauthError provides the RFC 6749, 5.2 error code and safe description
+// returned for a package error.
+type oauthError struct {
+	err         error
+	code        string
+	description string
+}
+
+// oauthErrors maps package errors to RFC 6749, 5.2 error responses, in order
+// of precedence.
+var oauthErrors = []oauthError{
+	// requests which are malformed are reported with the error's description,
+	// which never contains request data.
+	{err: ErrVerifierMissing, code: errorCodeInvalidRequest},
+	{err: ErrVerifierLength, code: errorCodeInvalidRequest},
+	{err: ErrVerifierCharacters, code: errorCodeInvalidRequest},
+	{err: ErrVerifierEncoding, code: errorCodeInvalidRequest},
+	{err: ErrChallengeMissing, code: errorCodeInvalidRequest},
+	{err: ErrChallengeInvalid, code: errorCodeInvalidRequest},
+	{err: ErrChallengeEncoding, code: errorCodeInvalidRequest},
+	{err: ErrChallengeDigest, code: errorCodeInvalidRequest},
+	{err: ErrChallengeConflict, code: errorCodeInvalidRequest},
+	{err: ErrMethodNotSupported, code: errorCodeInvalidRequest},
+	{err: ErrMethodNotAllowed, code: errorCodeInvalidRequest},
+	{err: ErrRequestTooLarge, code: errorCodeInvalidRequest},
+	{err: ErrUnsupportedContentType, code: errorCodeInvalidRequest},
+
+	// failures to prove the grant are not distinguished, so as not to reveal
+	// which codes have been registered, consumed or expired.
+	{
+		err:         ErrRedirectURIMismatch,
+		code:        errorCodeInvalidGrant,
+		description: "the redirect uri does not match the authorization request",
+	},
+	{
+		err:         ErrClientMismatch,
+		code:        errorCodeInvalidGrant,
+		description: "the authorization code was not issued to this client",
+	},
+	{err: ErrKeyNotFound, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+	{err: ErrKeyExpired, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+	{err: ErrVerifierMismatch, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+	{err: ErrMethodDowngrade, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+	{err: ErrVerifierEntropy, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+	{err: ErrSessionMismatch, code: errorCodeInvalidGrant, description: invalidGrantDescription},
+
+	{
+		err:         ErrStoreUnavailable,
+		code:        errorCodeTemporarilyUnavailable,
+		description: "the code verifier can not currently be verified",
+	},
+}
+
+const (
+	// invalidGrantDescription provides the description of all failures to
+	// prove the grant.
+	invalidGrantDescription = "the code verifier is invalid, expired or has already been used"
+
+	// serverErrorDescription provides the description of errors not
+	// attributable to the request, which may contain sensitive detail.
+	serverErrorDescription = "the code verifier could not be verified"
+)
+
+// OAuthErrorCode translates an error returned by the package into an RFC
+// 6749, 5.2 error code and a description that is safe to return to clients,
+// so token endpoints return consistent error responses that never leak
+// internal detail.
+//
+// Malformed requests are reported as "invalid_request", failures to prove the
+// grant as "invalid_grant", an unavailable store as "temporarily_unavailable"
+// and any other error as "server_error". Empty strings are returned for a nil
+// error.
+func OAuthErrorCode(err error) (code, description string) {
+	if err == nil {
+		return "", ""
+	}
+
+	for _, e := range oauthErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+
+		if e.description == "" {
+			return e.code, e.err.Error()
+		}
+
+		return e.code, e.description
+	}
+
+	return errorCodeServerError, serverErrorDescription
+}
@@ -0,0 +1,78 @@
+package pkce
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOAuthErrorCode(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantCode        string
+		wantDescription string
+	}{
+		{
+			name: "should return empty strings for nil",
+			err:  nil,
+		},
+		{
+			name:            "should report a malformed code verifier as an invalid request",
+			err:             ErrVerifierLength,
+			wantCode:        "invalid_request",
+			wantDescription: ErrVerifierLength.Error(),
+		},
+		{
+			name:            "should report an unsupported method as an invalid request",
+			err:             ErrMethodNotSupported,
+			wantCode:        "invalid_request",
+			wantDescription: ErrMethodNotSupported.Error(),
+		},
+		{
+			name:            "should report a mismatch as an invalid grant",
+			err:             ErrVerifierMismatch,
+			wantCode:        "invalid_grant",
+			wantDescription: invalidGrantDescription,
+		},
+		{
+			name:            "should not distinguish a missing key from a mismatch",
+			err:             ErrKeyNotFound,
+			wantCode:        "invalid_grant",
+			wantDescription: invalidGrantDescription,
+		},
+		{
+			name:            "should report a redirect uri mismatch as an invalid grant",
+			err:             ErrRedirectURIMismatch,
+			wantCode:        "invalid_grant",
+			wantDescription: "the redirect uri does not match the authorization request",
+		},
+		{
+			name:            "should report an unavailable store as temporarily unavailable",
+			err:             ErrStoreUnavailable,
+			wantCode:        "temporarily_unavailable",
+			wantDescription: "the code verifier can not currently be verified",
+		},
+		{
+			name:            "should translate a wrapped error",
+			err:             fmt.Errorf("verifying: %w", ErrKeyExpired),
+			wantCode:        "invalid_grant",
+			wantDescription: invalidGrantDescription,
+		},
+		{
+			name:            "should not leak an unknown error",
+			err:             errors.New("dial tcp 10.0.0.1:6379: connection refused"),
+			wantCode:        "server_error",
+			wantDescription: serverErrorDescription,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, description := OAuthErrorCode(tt.err)
+			if code != tt.wantCode || description != tt.wantDescription {
+				t.Errorf("OAuthErrorCode() = (%q, %q), want (%q, %q)", code, description, tt.wantCode, tt.wantDescription)
+			}
+		})
+	}
+}
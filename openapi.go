@@ -0,0 +1,96 @@
+package pkce
+
+// PatternCodeVerifier provides a regular expression matching RFC 7636, 4.1
+// compliant code verifiers, which also describes compliant code challenges.
+const PatternCodeVerifier = "^[A-Za-z0-9._~-]{43,128}$"
+
+// Schema provides an OpenAPI, and JSON Schema, compatible schema fragment.
+type Schema struct {
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Pattern     string            `json:"pattern,omitempty"`
+	MinLength   int               `json:"minLength,omitempty"`
+	MaxLength   int               `json:"maxLength,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+	Default     string            `json:"default,omitempty"`
+	Properties  map[string]Schema `json:"properties,omitempty"`
+	Required    []string          `json:"required,omitempty"`
+}
+
+// Parameter provides an OpenAPI 3 parameter object.
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	Schema      Schema `json:"schema"`
+}
+
+// ParameterSchemas returns schemas for the code_challenge,
+// code_challenge_method and code_verifier parameters, keyed by parameter
+// name, matching the validation performed by the package.
+func ParameterSchemas() map[string]Schema {
+	methods := make([]string, 0, 2)
+	for _, method := range SupportedMethods() {
+		methods = append(methods, method.String())
+	}
+
+	return map[string]Schema{
+		ParamCodeChallenge: {
+			Type:        "string",
+			Description: "The PKCE code challenge derived from the code verifier, as specified in RFC 7636, 4.2.",
+			Pattern:     PatternCodeVerifier,
+			MinLength:   verifierMinLen,
+			MaxLength:   verifierMaxLen,
+		},
+		ParamCodeChallengeMethod: {
+			Type:        "string",
+			Description: "The method used to derive the code challenge, as specified in RFC 7636, 4.3.",
+			Enum:        methods,
+			Default:     Plain.String(),
+		},
+		ParamCodeVerifier: {
+			Type:        "string",
+			Description: "The PKCE code verifier, as specified in RFC 7636, 4.1.",
+			Pattern:     PatternCodeVerifier,
+			MinLength:   verifierMinLen,
+			MaxLength:   verifierMaxLen,
+		},
+	}
+}
+
+// AuthorizationParameters returns the OpenAPI 3 query parameters of a PKCE
+// authorization request.
+func AuthorizationParameters() []Parameter {
+	schemas := ParameterSchemas()
+
+	return []Parameter{
+		{
+			Name:        ParamCodeChallenge,
+			In:          "query",
+			Description: schemas[ParamCodeChallenge].Description,
+			Required:    true,
+			Schema:      schemas[ParamCodeChallenge],
+		},
+		{
+			Name:        ParamCodeChallengeMethod,
+			In:          "query",
+			Description: schemas[ParamCodeChallengeMethod].Description,
+			Required:    false,
+			Schema:      schemas[ParamCodeChallengeMethod],
+		},
+	}
+}
+
+// TokenRequestSchema returns the schema of the PKCE parameters of an
+// application/x-www-form-urlencoded token request body, which can be merged
+// into the schema of the token endpoint's request body.
+func TokenRequestSchema() Schema {
+	return Schema{
+		Type: "object",
+		Properties: map[string]Schema{
+			ParamCodeVerifier: ParameterSchemas()[ParamCodeVerifier],
+		},
+		Required: []string{ParamCodeVerifier},
+	}
+}
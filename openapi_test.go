@@ -0,0 +1,60 @@
+package pkce
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPatternCodeVerifier(t *testing.T) {
+	pattern := regexp.MustCompile(PatternCodeVerifier)
+
+	codeVerifiers := []string{
+		testCodeVerifier,
+		strings.Repeat("a", verifierMinLen-1),
+		strings.Repeat("a", verifierMinLen),
+		strings.Repeat("a", verifierMaxLen),
+		strings.Repeat("a", verifierMaxLen+1),
+		strings.Repeat("a", verifierMinLen) + "!",
+		strings.Repeat("a", verifierMinLen) + "=",
+		unreserved[:verifierMinLen],
+		unreserved[len(unreserved)-verifierMinLen:],
+	}
+
+	for _, codeVerifier := range codeVerifiers {
+		want := validateCodeVerifier([]byte(codeVerifier)) == nil
+		if got := pattern.MatchString(codeVerifier); got != want {
+			t.Errorf("PatternCodeVerifier matched %q = %v, want %v", codeVerifier, got, want)
+		}
+	}
+}
+
+func TestAuthorizationParameters(t *testing.T) {
+	got, err := json.Marshal(AuthorizationParameters())
+	if err != nil {
+		t.Fatalf("Marshal() unexpected error: %v", err)
+	}
+
+	want := `[{"name":"code_challenge","in":"query","description":"The PKCE code challenge derived from the code verifier, as specified in RFC 7636, 4.2.","required":true,"schema":{"type":"string","description":"The PKCE code challenge derived from the code verifier, as specified in RFC 7636, 4.2.","pattern":"^[A-Za-z0-9._~-]{43,128}$","minLength":43,"maxLength":128}},` +
+		`{"name":"code_challenge_method","in":"query","description":"The method used to derive the code challenge, as specified in RFC 7636, 4.3.","required":false,"schema":{"type":"string","description":"The method used to derive the code challenge, as specified in RFC 7636, 4.3.","enum":["S256","plain"],"default":"plain"}}]`
+	if FIPSEnabled() {
+		want = strings.Replace(want, `["S256","plain"]`, `["S256"]`, 1)
+	}
+
+	if string(got) != want {
+		t.Errorf("AuthorizationParameters() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTokenRequestSchema(t *testing.T) {
+	schema := TokenRequestSchema()
+
+	if len(schema.Required) != 1 || schema.Required[0] != ParamCodeVerifier {
+		t.Errorf("TokenRequestSchema() required = %v, want [%v]", schema.Required, ParamCodeVerifier)
+	}
+
+	if got := schema.Properties[ParamCodeVerifier].Pattern; got != PatternCodeVerifier {
+		t.Errorf("TokenRequestSchema() code verifier pattern = %v, want %v", got, PatternCodeVerifier)
+	}
+}
@@ -1,20 +1,81 @@
 package pkce
 
+import (
+	"io"
+	"os"
+	"time"
+)
+
 // Option enables variadic PKCE Key options to be configured.
 type Option func(*Key) error
 
 // WithChallengeMethod enables specifying the challenge transformation method.
 // Should only be used to downgrade to plain if required.
+//
+// The S384 and S512 methods are also accepted, so that keys configured with
+// WithHash can be decoded, but WithHash should be preferred.
 func WithChallengeMethod(method Method) Option {
 	return func(key *Key) (err error) {
-		switch method {
-		case Plain, S256:
-			key.challengeMethod = method
+		if !isKeyMethod(method) {
+			return ErrMethodNotSupported
+		}
+
+		if err = key.checkPinned(method != key.challengeMethod); err != nil {
+			return
+		}
+
+		key.challengeMethod = method
+
+		return nil
+	}
+}
+
+// WithChallengeEncoding enables specifying the encoding of the S256 transform
+// output, for interoperating with legacy servers. Defaults to the RFC 7636
+// compliant Base64URL.
+func WithChallengeEncoding(encoding ChallengeEncoding) Option {
+	return func(key *Key) (err error) {
+		switch encoding {
+		case Base64URL, Base64URLPadded, Hex:
+			if err = key.checkPinned(encoding != key.ChallengeEncoding()); err != nil {
+				return
+			}
+
+			key.challengeEncoding = encoding
 
 		default:
-			return ErrMethodNotSupported
+			return ErrEncodingNotSupported
+		}
+
+		return nil
+	}
+}
+
+// WithClock enables specifying the clock used to compute key expiry.
+// Defaults to the system clock.
+func WithClock(clock Clock) Option {
+	return func(key *Key) (err error) {
+		key.clock = clock
+
+		return nil
+	}
+}
+
+// WithCodeChallenge enables supplying the code challenge received from a
+// client, for use server-side where the code verifier is not known. Disables
+// code verifier generation.
+func WithCodeChallenge(codeChallenge string) Option {
+	return func(key *Key) (err error) {
+		if err = validateCodeChallenge(codeChallenge); err != nil {
+			return
 		}
 
+		if err = key.checkPinned(codeChallenge != key.pinnedChallenge); err != nil {
+			return
+		}
+
+		key.codeChallenge = codeChallenge
+
 		return nil
 	}
 }
@@ -30,6 +91,35 @@ func WithCodeVerifier(codeVerifier []byte) Option {
 	}
 }
 
+// WithCodeVerifierFromEnv enables supplying your own code verifier read from
+// the named environment variable, as WithCodeVerifier, for test harnesses and
+// CI pipelines injecting pre-agreed code verifiers. Disables code verifier
+// generation.
+//
+// The variable is removed from the process environment once read, even if
+// the code verifier is invalid, so that it is not inherited by child
+// processes. ErrEnvNotSet is returned if the variable is not set.
+func WithCodeVerifierFromEnv(name string) Option {
+	return func(key *Key) (err error) {
+		codeVerifier, ok := os.LookupEnv(name)
+		if !ok {
+			return ErrEnvNotSet
+		}
+
+		if err = os.Unsetenv(name); err != nil {
+			return
+		}
+
+		return key.setCodeVerifier([]byte(codeVerifier))
+	}
+}
+
+// WithCodeVerifierString enables supplying your own code verifier held as a
+// string, as WithCodeVerifier. Disables code verifier generation.
+func WithCodeVerifierString(codeVerifier string) Option {
+	return WithCodeVerifier([]byte(codeVerifier))
+}
+
 // WithCodeVerifierLength enables specifying the length of the code verifier
 // to be generated.
 func WithCodeVerifierLength(n int) Option {
@@ -39,3 +129,122 @@ func WithCodeVerifierLength(n int) Option {
 		return
 	}
 }
+
+// WithExpiry enables specifying the duration the key is valid for, measured
+// from when the key is created.
+func WithExpiry(ttl time.Duration) Option {
+	return func(key *Key) (err error) {
+		if ttl < 0 {
+			return ErrExpiryInvalid
+		}
+
+		key.ttl = ttl
+
+		return nil
+	}
+}
+
+// WithFIPSMode enables enforcing the FIPS compliance profile, which only
+// permits the S256 transform method and code verifiers generated using
+// crypto/rand, returning a *ComplianceError if the key would violate it. The
+// profile is always enforced if FIPSEnabled reports true.
+func WithFIPSMode() Option {
+	return func(key *Key) (err error) {
+		key.fips = true
+
+		return nil
+	}
+}
+
+// WithJSONMode enables specifying how the key is encoded by MarshalJSON.
+// Defaults to JSONPublic, omitting the code verifier, as keys commonly end up
+// in API responses and log payloads. JSONFull should only be used for trusted
+// server-side persistence.
+func WithJSONMode(mode JSONMode) Option {
+	return func(key *Key) (err error) {
+		switch mode {
+		case JSONPublic, JSONFull:
+			key.jsonMode = mode
+
+		default:
+			return ErrJSONModeNotSupported
+		}
+
+		return nil
+	}
+}
+
+// WithMinEntropyBits enables specifying the minimum entropy, in bits, of the
+// code verifier to be generated, which is translated into the required code
+// verifier length for the configured character set. A configured code
+// verifier length is only ever increased.
+//
+// RFC 7636, 7.1 recommends a code verifier provides at least 256 bits of
+// entropy, which the default code verifier length satisfies.
+//
+// If a code verifier is supplied, this setting will be ignored in favour of
+// using the supplied verifier.
+func WithMinEntropyBits(n int) Option {
+	return func(key *Key) (err error) {
+		if n < 1 {
+			return ErrEntropyInvalid
+		}
+
+		key.minEntropyBits = n
+
+		return nil
+	}
+}
+
+// WithProvider enables configuring the key to meet the known PKCE
+// requirements of an identity provider. The challenge method is set to S256,
+// and the key will fail to be created if subsequent options configure it in a
+// way the provider is known to reject.
+func WithProvider(provider Provider) Option {
+	return func(key *Key) (err error) {
+		preset, ok := providerPresets()[provider]
+		if !ok {
+			return ErrProviderNotSupported
+		}
+
+		if err = key.checkPinned(key.challengeMethod != S256); err != nil {
+			return
+		}
+
+		key.challengeMethod = S256
+		key.provider = &preset
+
+		return nil
+	}
+}
+
+// WithRandReader enables specifying the source of randomness used to generate
+// the code verifier, which defaults to crypto/rand.
+//
+// This is intended for reproducible tests, such as with
+// pkcetest.NewDeterministicReader, and must never be used in production with
+// a source that is not cryptographically secure.
+func WithRandReader(r io.Reader) Option {
+	return func(key *Key) (err error) {
+		key.random = r
+
+		return nil
+	}
+}
+
+// WithVerifierCharset enables restricting the characters code verifiers are
+// generated from to a subset of the unreserved character set, for
+// interoperating with servers that mishandle some unreserved characters, such
+// as AlphanumericCharset. Received code verifiers are always validated against
+// the full unreserved character set.
+func WithVerifierCharset(charset string) Option {
+	return func(key *Key) (err error) {
+		if err = validateCharset(charset); err != nil {
+			return
+		}
+
+		key.verifierCharset = charset
+
+		return nil
+	}
+}
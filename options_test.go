@@ -1,8 +1,11 @@
 package pkce
 
 import (
+	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWithChallengeMethod(t *testing.T) {
@@ -30,6 +33,52 @@ func TestWithChallengeMethod(t *testing.T) {
 	}
 }
 
+func TestWithChallengeEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding ChallengeEncoding
+		want     ChallengeEncoding
+		wantErr  error
+	}{
+		{
+			name:     "should set base64url",
+			encoding: Base64URL,
+			want:     Base64URL,
+		},
+		{
+			name:     "should set padded base64url",
+			encoding: Base64URLPadded,
+			want:     Base64URLPadded,
+		},
+		{
+			name:     "should set hex",
+			encoding: Hex,
+			want:     Hex,
+		},
+		{
+			name:     "should error on an unsupported encoding",
+			encoding: "base32",
+			wantErr:  ErrEncodingNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(WithChallengeEncoding(tt.encoding))
+			if err != tt.wantErr {
+				t.Fatalf("WithChallengeEncoding() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := key.ChallengeEncoding(); got != tt.want {
+				t.Errorf("ChallengeEncoding() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWithCodeVerifier(t *testing.T) {
 	tests := setCodeVerifierTests()
 
@@ -55,6 +104,73 @@ func TestWithCodeVerifier(t *testing.T) {
 	}
 }
 
+func TestWithCodeVerifierString(t *testing.T) {
+	tests := setCodeVerifierTests()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := WithCodeVerifierString(string(tt.codeVerifier))
+
+			err := opt(tt.gotKey)
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("WithCodeVerifierString() should error\ngot:  %v, want: %v\n", err, tt.shouldErr)
+			}
+
+			if tt.shouldErr {
+				if tt.wantErr != err {
+					t.Errorf("WithCodeVerifierString() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+				}
+			} else {
+				if !reflect.DeepEqual(tt.gotKey, tt.wantKey) {
+					t.Errorf("WithCodeVerifierString() key\ngot: %v\nwant  %v\n", tt.gotKey, tt.wantKey)
+				}
+			}
+		})
+	}
+}
+
+func TestWithCodeVerifierFromEnv(t *testing.T) {
+	const name = "PKCE_TEST_CODE_VERIFIER"
+
+	tests := setCodeVerifierTests()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Setenv(name, string(tt.codeVerifier)); err != nil {
+				t.Fatalf("Setenv() unexpected error: %v", err)
+			}
+			defer os.Unsetenv(name)
+
+			opt := WithCodeVerifierFromEnv(name)
+
+			err := opt(tt.gotKey)
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("WithCodeVerifierFromEnv() should error\ngot:  %v, want: %v\n", err, tt.shouldErr)
+			}
+
+			if _, ok := os.LookupEnv(name); ok {
+				t.Errorf("WithCodeVerifierFromEnv() should scrub the variable from the environment")
+			}
+
+			if tt.shouldErr {
+				if tt.wantErr != err {
+					t.Errorf("WithCodeVerifierFromEnv() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+				}
+			} else {
+				if !reflect.DeepEqual(tt.gotKey, tt.wantKey) {
+					t.Errorf("WithCodeVerifierFromEnv() key\ngot: %v\nwant  %v\n", tt.gotKey, tt.wantKey)
+				}
+			}
+		})
+	}
+
+	t.Run("should error if the variable is not set", func(t *testing.T) {
+		if err := WithCodeVerifierFromEnv(name)(&Key{}); err != ErrEnvNotSet {
+			t.Errorf("WithCodeVerifierFromEnv() error type not expected\ngot:  %v, want: %v\n", err, ErrEnvNotSet)
+		}
+	})
+}
+
 func TestWithCodeVerifierLength(t *testing.T) {
 	tests := setCodeVerifierLengthTests()
 
@@ -79,3 +195,258 @@ func TestWithCodeVerifierLength(t *testing.T) {
 		})
 	}
 }
+
+func TestWithClock(t *testing.T) {
+	clock := newTestClock()
+	key := &Key{}
+	if err := WithClock(clock)(key); err != nil {
+		t.Fatalf("WithClock() unexpected error: %v", err)
+	}
+
+	if key.clock != clock {
+		t.Errorf("WithClock() clock = %v, want %v", key.clock, clock)
+	}
+}
+
+func TestWithCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name          string
+		codeChallenge string
+		wantErr       error
+	}{
+		{
+			name:          "should set a valid code challenge",
+			codeChallenge: "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			wantErr:       nil,
+		},
+		{
+			name:          "should error on a short code challenge",
+			codeChallenge: "yolo",
+			wantErr:       ErrChallengeInvalid,
+		},
+		{
+			name:          "should error on invalid code challenge characters",
+			codeChallenge: strings.Repeat("=", verifierMinLen),
+			wantErr:       ErrChallengeInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &Key{}
+			err := WithCodeChallenge(tt.codeChallenge)(key)
+			if err != tt.wantErr {
+				t.Fatalf("WithCodeChallenge() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err == nil && key.codeChallenge != tt.codeChallenge {
+				t.Errorf("WithCodeChallenge() challenge = %v, want %v", key.codeChallenge, tt.codeChallenge)
+			}
+		})
+	}
+}
+
+func TestWithExpiry(t *testing.T) {
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		wantExpiresAt bool
+		wantErr       error
+	}{
+		{
+			name:          "should set an expiry",
+			ttl:           time.Minute,
+			wantExpiresAt: true,
+		},
+		{
+			name:          "should not expire without a ttl",
+			ttl:           0,
+			wantExpiresAt: false,
+		},
+		{
+			name:    "should error on a negative ttl",
+			ttl:     -time.Minute,
+			wantErr: ErrExpiryInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+
+			// the clock is specified last to ensure expiry is independent of
+			// option order.
+			key, err := New(WithExpiry(tt.ttl), WithClock(clock))
+			if err != tt.wantErr {
+				t.Fatalf("WithExpiry() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !tt.wantExpiresAt {
+				if !key.ExpiresAt().IsZero() {
+					t.Errorf("ExpiresAt() = %v, want zero time", key.ExpiresAt())
+				}
+				return
+			}
+
+			if want := clock.Now().Add(tt.ttl); !key.ExpiresAt().Equal(want) {
+				t.Errorf("ExpiresAt() = %v, want %v", key.ExpiresAt(), want)
+			}
+		})
+	}
+}
+
+func TestWithMinEntropyBits(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []Option
+		want    int
+		wantErr error
+	}{
+		{
+			name: "should satisfy the recommended entropy by default",
+			opts: []Option{WithMinEntropyBits(256)},
+			want: verifierMinLen,
+		},
+		{
+			name: "should increase the code verifier length",
+			opts: []Option{WithMinEntropyBits(512)},
+			want: 85,
+		},
+		{
+			name: "should support the maximum entropy",
+			opts: []Option{WithMinEntropyBits(773)},
+			want: verifierMaxLen,
+		},
+		{
+			name: "should account for a restricted charset",
+			opts: []Option{WithMinEntropyBits(512), WithVerifierCharset(AlphanumericCharset)},
+			want: 86,
+		},
+		{
+			name:    "should error if a single character charset is configured",
+			opts:    []Option{WithMinEntropyBits(1), WithVerifierCharset("a")},
+			wantErr: ErrEntropyInvalid,
+		},
+		{
+			name: "should not decrease a configured code verifier length",
+			opts: []Option{WithCodeVerifierLength(100), WithMinEntropyBits(256)},
+			want: 100,
+		},
+		{
+			name:    "should error if the entropy can not be met",
+			opts:    []Option{WithMinEntropyBits(774)},
+			wantErr: ErrEntropyInvalid,
+		},
+		{
+			name:    "should error on a non-positive entropy",
+			opts:    []Option{WithMinEntropyBits(0)},
+			wantErr: ErrEntropyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("WithMinEntropyBits() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := len(key.CodeVerifier()); got != tt.want {
+				t.Errorf("WithMinEntropyBits() code verifier length = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithVerifierCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		wantErr error
+	}{
+		{
+			name:    "should generate alphanumeric code verifiers",
+			charset: AlphanumericCharset,
+		},
+		{
+			name:    "should generate from a single character",
+			charset: "a",
+		},
+		{
+			name:    "should error on an empty charset",
+			charset: "",
+			wantErr: ErrCharsetInvalid,
+		},
+		{
+			name:    "should error on reserved characters",
+			charset: "abc!",
+			wantErr: ErrCharsetInvalid,
+		},
+		{
+			name:    "should error on duplicate characters",
+			charset: "abca",
+			wantErr: ErrCharsetInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(WithVerifierCharset(tt.charset))
+			if err != tt.wantErr {
+				t.Fatalf("WithVerifierCharset() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			codeVerifier := key.CodeVerifier()
+			if strings.Trim(codeVerifier, tt.charset) != "" {
+				t.Errorf("WithVerifierCharset() generated characters outside of the charset\ngot:  %s", codeVerifier)
+			}
+
+			if !key.VerifyCodeVerifier(codeVerifier) {
+				t.Errorf("VerifyCodeVerifier() = false, want true")
+			}
+		})
+	}
+}
+
+func TestWithRandReader(t *testing.T) {
+	random := strings.Repeat("\x00", 1024)
+
+	a, err := New(WithRandReader(strings.NewReader(random)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	b, err := New(WithRandReader(strings.NewReader(random)))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if a.CodeVerifier() != b.CodeVerifier() {
+		t.Errorf("WithRandReader() should generate from the reader\ngot:  %v\nwant: %v", b.CodeVerifier(), a.CodeVerifier())
+	}
+
+	key, err := New(WithRandReader(strings.NewReader("")))
+	if err == nil {
+		t.Errorf("WithRandReader() should error if the reader fails")
+	}
+	if key != nil {
+		t.Errorf("New() should not return a key without a code verifier")
+	}
+
+	// enough entropy for a single code verifier.
+	key, err = New(WithRandReader(&budgetReader{budget: verifierMinLen}))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	if _, err = key.CloneWithNewVerifier(); err == nil {
+		t.Errorf("CloneWithNewVerifier() should error if the reader fails")
+	}
+}
@@ -0,0 +1,91 @@
+package pkce
+
+import (
+	"context"
+	"net/url"
+)
+
+// pushedID returns the store id keys registered by pushed authorization
+// requests are stored under until they are bound, regardless of the manager's
+// binder.
+func pushedID(requestURI string) string {
+	return "pushed:" + requestURI
+}
+
+// RegisterPushed persists the code challenge and code challenge method
+// received in a pushed authorization request, as specified in RFC 9126, under
+// the request_uri issued in response.
+//
+// RFC 7636, 4.3. If the code challenge method is not present in the request,
+// it defaults to "plain".
+func (m *KeyManager) RegisterPushed(ctx context.Context, requestURI string, method Method, codeChallenge string) error {
+	return m.register(ctx, registerRequest{
+		id:            pushedID(requestURI),
+		method:        method,
+		codeChallenge: codeChallenge,
+	})
+}
+
+// ResolvePushed validates the parameters of a front-channel authorization
+// request referencing a pushed authorization request by requestURI, returning
+// ErrChallengeConflict if they carry a code challenge, or code challenge
+// method, differing from those pushed. If a code challenge is present without
+// a code challenge method, the method defaults to "plain", as specified in
+// RFC 7636, 4.3.
+//
+// ErrKeyNotFound is returned if no code challenge was pushed for requestURI,
+// and ErrKeyExpired if it has expired.
+func (m *KeyManager) ResolvePushed(ctx context.Context, requestURI string, params url.Values) error {
+	key, _, err := m.getKey(ctx, pushedID(requestURI), false)
+	if err != nil {
+		return err
+	}
+
+	key.clock = m.clock
+	if key.Expired() {
+		return ErrKeyExpired
+	}
+
+	codeChallenge := params.Get(ParamCodeChallenge)
+	method := Method(params.Get(ParamCodeChallengeMethod))
+	if method == "" && codeChallenge != "" {
+		method = Plain
+	}
+
+	if method != "" && method != key.ChallengeMethod() {
+		return ErrChallengeConflict
+	}
+
+	if codeChallenge != "" && !ChallengesEqual(codeChallenge, key.CodeChallenge()) {
+		return ErrChallengeConflict
+	}
+
+	return nil
+}
+
+// BindPushed atomically moves the code challenge pushed under requestURI to
+// the authorization code issued for the front-channel authorization request,
+// or the correlation value of the configured binder, so that it is verified
+// by Verify and the verification middleware. The key's lifetime restarts, as
+// the authorization code's lifetime starts once issued.
+//
+// A pushed code challenge can only be bound once, as the request_uri is one
+// time use, as specified in RFC 9126, 2.2.
+func (m *KeyManager) BindPushed(ctx context.Context, requestURI string, id string) error {
+	pushed, tag, err := m.getKey(ctx, pushedID(requestURI), true)
+	if err != nil {
+		return err
+	}
+
+	pushed.clock = m.clock
+	if pushed.Expired() {
+		return ErrKeyExpired
+	}
+
+	key, err := m.newKey(pushed.ChallengeMethod(), pushed.CodeChallenge())
+	if err != nil {
+		return err
+	}
+
+	return m.putKey(ctx, m.binder.ID(id), key, tag)
+}
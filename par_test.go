@@ -0,0 +1,96 @@
+package pkce
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testRequestURI = "urn:ietf:params:oauth:request_uri:6esc_11ACC5bwc014ltc14eY22c"
+
+func TestKeyManager_ResolvePushed(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  url.Values
+		wantErr error
+	}{
+		{
+			name:   "should resolve a request without a code challenge",
+			params: url.Values{},
+		},
+		{
+			name: "should resolve a request repeating the pushed code challenge",
+			params: url.Values{
+				ParamCodeChallenge:       {testCodeChallenge},
+				ParamCodeChallengeMethod: {string(S256)},
+			},
+		},
+		{
+			name: "should reject a conflicting code challenge",
+			params: url.Values{
+				ParamCodeChallenge:       {"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"},
+				ParamCodeChallengeMethod: {string(S256)},
+			},
+			wantErr: ErrChallengeConflict,
+		},
+		{
+			name: "should reject a conflicting code challenge method",
+			params: url.Values{
+				ParamCodeChallengeMethod: {string(Plain)},
+			},
+			wantErr: ErrChallengeConflict,
+		},
+		{
+			name: "should default the code challenge method to plain",
+			params: url.Values{
+				ParamCodeChallenge: {testCodeChallenge},
+			},
+			wantErr: ErrChallengeConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore())
+			if err := m.RegisterPushed(context.Background(), testRequestURI, S256, testCodeChallenge); err != nil {
+				t.Fatalf("RegisterPushed() unexpected error: %v", err)
+			}
+
+			if err := m.ResolvePushed(context.Background(), testRequestURI, tt.params); err != tt.wantErr {
+				t.Errorf("ResolvePushed() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyManager_BindPushed(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	m := NewKeyManager(NewMemoryStore(), WithManagerClock(clock), WithManagerTTL(time.Minute))
+	if err := m.RegisterPushed(ctx, testRequestURI, S256, testCodeChallenge); err != nil {
+		t.Fatalf("RegisterPushed() unexpected error: %v", err)
+	}
+
+	if err := m.ResolvePushed(ctx, "unknown", url.Values{}); err != ErrKeyNotFound {
+		t.Errorf("ResolvePushed() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+
+	if err := m.Verify(ctx, testRequestURI, testCodeVerifier); err != ErrKeyNotFound {
+		t.Errorf("Verify() should not verify an unbound pushed code challenge\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+
+	clock.Advance(30 * time.Second)
+	if err := m.BindPushed(ctx, testRequestURI, "code"); err != nil {
+		t.Fatalf("BindPushed() unexpected error: %v", err)
+	}
+	if err := m.BindPushed(ctx, testRequestURI, "code"); err != ErrKeyNotFound {
+		t.Errorf("BindPushed() should only bind once\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+
+	// The bound key's lifetime restarts when the authorization code is issued.
+	clock.Advance(45 * time.Second)
+	if err := m.Verify(ctx, "code", testCodeVerifier); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+}
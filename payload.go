@@ -0,0 +1,44 @@
+package pkce
+
+import (
+	"net/url"
+)
+
+// ChallengePayload provides the PKCE parameters of an authorization request,
+// being exactly what is safe to send to the authorization server over the
+// front channel. It intentionally has no field for the code verifier, so it
+// can be serialized or logged without leaking the secret.
+type ChallengePayload struct {
+	Challenge string `form:"code_challenge" json:"code_challenge" query:"code_challenge"`
+	Method    Method `form:"code_challenge_method" json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// ChallengePayload returns the front-channel parameters of the key. If a code
+// verifier has not been generated yet, one is generated in order to derive the
+// code challenge.
+func (k *Key) ChallengePayload() ChallengePayload {
+	return ChallengePayload{
+		Challenge: k.CodeChallenge(),
+		Method:    k.ChallengeMethod(),
+	}
+}
+
+// Values returns the payload as authorization request query parameters.
+func (p ChallengePayload) Values() url.Values {
+	values := url.Values{}
+	p.AddTo(values)
+
+	return values
+}
+
+// AddTo sets the payload's parameters on existing authorization request query
+// parameters, replacing any code challenge previously set.
+func (p ChallengePayload) AddTo(values url.Values) {
+	values.Set(ParamCodeChallenge, p.Challenge)
+	values.Set(ParamCodeChallengeMethod, p.Method.String())
+}
+
+// Encode returns the payload as a URL encoded query string.
+func (p ChallengePayload) Encode() string {
+	return p.Values().Encode()
+}
@@ -0,0 +1,65 @@
+package pkce
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestKey_ChallengePayload(t *testing.T) {
+	key, err := New(WithCodeVerifierString(testCodeVerifier))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	payload := key.ChallengePayload()
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{
+			name: "should encode json without the code verifier",
+			got: func() string {
+				got, err := json.Marshal(payload)
+				if err != nil {
+					t.Fatalf("Marshal() unexpected error: %v", err)
+				}
+				return string(got)
+			}(),
+			want: `{"code_challenge":"` + testCodeChallenge + `","code_challenge_method":"S256"}`,
+		},
+		{
+			name: "should encode a query without the code verifier",
+			got:  payload.Encode(),
+			want: "code_challenge=" + testCodeChallenge + "&code_challenge_method=S256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("ChallengePayload()\ngot:  %s\nwant: %s\n", tt.got, tt.want)
+			}
+			if strings.Contains(tt.got, testCodeVerifier) {
+				t.Errorf("ChallengePayload() should never contain the code verifier")
+			}
+		})
+	}
+}
+
+func TestChallengePayload_AddTo(t *testing.T) {
+	values := url.Values{
+		"client_id":        {"client"},
+		ParamCodeChallenge: {"stale"},
+	}
+	ChallengePayload{Challenge: testCodeChallenge, Method: S256}.AddTo(values)
+
+	if got := values.Get(ParamCodeChallenge); got != testCodeChallenge {
+		t.Errorf("AddTo() should replace the code challenge\ngot:  %v, want: %v\n", got, testCodeChallenge)
+	}
+	if values.Get("client_id") != "client" || values.Get(ParamCodeChallengeMethod) != "S256" {
+		t.Errorf("AddTo() should retain existing parameters and set the method\ngot:  %v\n", values)
+	}
+}
@@ -0,0 +1,42 @@
+package pkce
+
+import (
+	"crypto/subtle"
+)
+
+// PinChallenge computes the code challenge, generating the code verifier if
+// required, and freezes it, returning the pinned code challenge.
+//
+// Once pinned, any mutation which would change the code challenge, such as a
+// changed code verifier, challenge method or encoding, or decoding another key
+// into the key, fails with ErrChallengePinned. This guarantees the code
+// challenge sent in the authorization request is exactly the code challenge
+// proven by the code verifier at exchange time.
+func (k *Key) PinChallenge() string {
+	if k.pinnedChallenge == "" {
+		k.pinnedChallenge = k.CodeChallenge()
+	}
+
+	return k.pinnedChallenge
+}
+
+// Pinned reports whether the code challenge has been pinned by PinChallenge.
+func (k *Key) Pinned() bool {
+	return k.pinnedChallenge != ""
+}
+
+// checkPinned returns ErrChallengePinned if the code challenge has been pinned
+// and a mutation would change it.
+func (k *Key) checkPinned(changed bool) error {
+	if changed && k.pinnedChallenge != "" {
+		return ErrChallengePinned
+	}
+
+	return nil
+}
+
+// verifierChanged reports whether setting the code verifier would change the
+// key's code verifier, comparing in constant time.
+func (k *Key) verifierChanged(codeVerifier []byte) bool {
+	return subtle.ConstantTimeCompare(k.codeVerifier, codeVerifier) != 1
+}
@@ -0,0 +1,119 @@
+package pkce
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+)
+
+func TestKey_PinChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(key *Key) error
+		wantErr error
+	}{
+		{
+			name: "should allow setting the same method",
+			mutate: func(key *Key) error {
+				return key.SetChallengeMethod(S256)
+			},
+		},
+		{
+			name: "should allow setting the same code verifier",
+			mutate: func(key *Key) error {
+				return WithCodeVerifierString(testCodeVerifier)(key)
+			},
+		},
+		{
+			name: "should allow changing configuration not affecting the code challenge",
+			mutate: func(key *Key) error {
+				return WithJSONMode(JSONFull)(key)
+			},
+		},
+		{
+			name: "should error on changing the method",
+			mutate: func(key *Key) error {
+				return WithChallengeMethod(Plain)(key)
+			},
+			wantErr: ErrChallengePinned,
+		},
+		{
+			name: "should error on changing the hash",
+			mutate: func(key *Key) error {
+				return WithHash(crypto.SHA512)(key)
+			},
+			wantErr: ErrChallengePinned,
+		},
+		{
+			name: "should error on changing the encoding",
+			mutate: func(key *Key) error {
+				return WithChallengeEncoding(Hex)(key)
+			},
+			wantErr: ErrChallengePinned,
+		},
+		{
+			name: "should error on changing the code verifier",
+			mutate: func(key *Key) error {
+				return WithCodeVerifierString(strings.Repeat("a", verifierMinLen))(key)
+			},
+			wantErr: ErrChallengePinned,
+		},
+		{
+			name: "should error on decoding another key",
+			mutate: func(key *Key) error {
+				other, err := New()
+				if err != nil {
+					return err
+				}
+				data, err := other.MarshalBinary()
+				if err != nil {
+					return err
+				}
+
+				return key.UnmarshalBinary(data)
+			},
+			wantErr: ErrChallengePinned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(WithCodeVerifierString(testCodeVerifier))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			if got := key.PinChallenge(); got != testCodeChallenge || !key.Pinned() {
+				t.Fatalf("PinChallenge() = %v, want %v", got, testCodeChallenge)
+			}
+
+			if err = tt.mutate(key); err != tt.wantErr {
+				t.Errorf("mutation error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if got := key.CodeChallenge(); got != testCodeChallenge {
+				t.Errorf("CodeChallenge() = %v, want the pinned %v", got, testCodeChallenge)
+			}
+
+			if !key.VerifyCodeVerifier(testCodeVerifier) {
+				t.Errorf("VerifyCodeVerifier() = false, want the pinned code challenge to be proven")
+			}
+		})
+	}
+}
+
+func TestKey_PinChallenge_clone(t *testing.T) {
+	key, err := New()
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	key.PinChallenge()
+
+	clone, err := key.CloneWithNewVerifier()
+	if err != nil {
+		t.Fatalf("CloneWithNewVerifier() unexpected error: %v", err)
+	}
+	if clone.Pinned() {
+		t.Errorf("CloneWithNewVerifier() should not copy the pinned code challenge")
+	}
+}
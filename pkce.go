@@ -30,10 +30,12 @@
 package pkce
 
 import (
+	"context"
 	"crypto/rand"
-	"crypto/sha256"
-	"encoding/base64"
-	"math/big"
+	"crypto/subtle"
+	"io"
+	"strings"
+	"time"
 )
 
 // Method specifies the code challenge transformation method that was used to
@@ -45,6 +47,55 @@ func (m Method) String() string {
 	return string(m)
 }
 
+// MarshalText implements encoding.TextMarshaler, enabling methods to be used
+// directly in configuration structs.
+//
+// RFC 7636, 4.3. An empty method defaults to "plain". The non-standard S384
+// and S512 methods are also supported.
+func (m Method) MarshalText() ([]byte, error) {
+	switch m {
+	case "":
+		return []byte(Plain), nil
+
+	case Plain, S256, S384, S512:
+		return []byte(m), nil
+
+	default:
+		return nil, ErrMethodNotSupported
+	}
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, enabling methods to be
+// used directly in configuration structs and flag parsing.
+//
+// RFC 7636, 4.3. An empty method defaults to "plain". The non-standard S384
+// and S512 methods are also supported.
+func (m *Method) UnmarshalText(text []byte) error {
+	switch method := Method(text); method {
+	case "":
+		*m = Plain
+
+	case Plain, S256, S384, S512:
+		*m = method
+
+	default:
+		return ErrMethodNotSupported
+	}
+
+	return nil
+}
+
+// SupportedMethods returns the code challenge methods supported by the
+// package, in order of preference. Only S256 is returned if FIPSEnabled
+// reports true.
+func SupportedMethods() []Method {
+	if fipsEnabled() {
+		return []Method{S256}
+	}
+
+	return []Method{S256, Plain}
+}
+
 const (
 	// Plain method specifies that the code challenge has had no transformation
 	// performed on the code verifier.
@@ -70,6 +121,35 @@ const (
 	S256 Method = "S256"
 )
 
+// ChallengeEncoding specifies the encoding applied to the output of the S256
+// transform when generating a code challenge.
+type ChallengeEncoding string
+
+// String implements Stringer.
+func (e ChallengeEncoding) String() string {
+	return string(e)
+}
+
+const (
+	// Base64URL encoding specifies the code challenge is base64url-encoded
+	// without padding, as required by RFC 7636, 4.2.
+	Base64URL ChallengeEncoding = "base64url"
+
+	// Base64URLPadded encoding specifies the code challenge is
+	// base64url-encoded with trailing '=' padding.
+	//
+	// This is not specification compliant and should only be used to
+	// interoperate with legacy servers that verify padded code challenges.
+	Base64URLPadded ChallengeEncoding = "base64url-padded"
+
+	// Hex encoding specifies the code challenge is lowercase hex-encoded.
+	//
+	// This is not specification compliant and should only be used to
+	// interoperate with legacy servers that verify hex-encoded code
+	// challenges.
+	Hex ChallengeEncoding = "hex"
+)
+
 const (
 	// ABNF for "code_verifier"
 	// ALPHA = %x41-5A / %x61-7A
@@ -80,6 +160,11 @@ const (
 	unreserved = alpha + digit + "-._~"
 )
 
+// AlphanumericCharset provides the unreserved character set without the
+// punctuation characters "-", ".", "_" and "~", for generating code verifiers
+// for servers that mishandle them.
+const AlphanumericCharset = alpha + digit
+
 const (
 	// RFC 7636, 4.1
 	verifierMinLen = 43
@@ -87,6 +172,11 @@ const (
 )
 
 // New returns a Proof Key
+//
+// If a source of randomness is configured with WithRandReader, the code
+// verifier is generated eagerly, so that failing to read from it is reported,
+// in which case a nil key is returned. Otherwise, the code verifier is
+// generated from crypto/rand when first used.
 func New(opts ...Option) (key *Key, err error) {
 	key = &Key{
 		challengeMethod: S256,
@@ -99,6 +189,54 @@ func New(opts ...Option) (key *Key, err error) {
 		}
 	}
 
+	if key.minEntropyBits > 0 && len(key.codeVerifier) == 0 {
+		length := verifierLenForEntropy(key.charset(), key.minEntropyBits)
+		if length > verifierMaxLen {
+			err = ErrEntropyInvalid
+			return
+		}
+
+		if length > key.codeVerifierLen {
+			key.codeVerifierLen = length
+		}
+	}
+
+	if key.provider != nil {
+		if err = key.provider.validate(key); err != nil {
+			return
+		}
+	}
+
+	if key.fips || fipsEnabled() {
+		if err = validateFIPS(key); err != nil {
+			return
+		}
+	}
+
+	supplied := len(key.codeVerifier) > 0
+
+	// generate eagerly from a configured source of randomness, so failures
+	// can be reported.
+	if key.random != nil && len(key.codeVerifier) == 0 && key.codeChallenge == "" {
+		if key.codeVerifier, err = key.generateCodeVerifier(); err != nil {
+			// a key without a code verifier can never be used.
+			return nil, err
+		}
+	}
+
+	// observed last, so that only code verifiers of keys returned are
+	// remembered.
+	if key.reuseCache != nil && supplied {
+		if err = key.reuseCache.Observe(key.codeVerifier); err != nil {
+			return
+		}
+	}
+
+	key.createdAt = now(key.clock)
+	if key.ttl > 0 {
+		key.expiresAt = key.createdAt.Add(key.ttl)
+	}
+
 	return
 }
 
@@ -109,18 +247,63 @@ func GenerateCodeVerifier(n int) (string, error) {
 		return "", err
 	}
 
-	return string(generateCodeVerifier(n)), nil
+	codeVerifier, err := generateCodeVerifier(n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(codeVerifier), nil
+}
+
+// GenerateCodeVerifierContext generates an RFC7636 compliant, cryptographically
+// secure code verifier, returning the context's error if the context is done
+// before sufficient entropy has been read.
+func GenerateCodeVerifierContext(ctx context.Context, n int) (string, error) {
+	if err := validateVerifierLen(n); err != nil {
+		return "", err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	type generated struct {
+		codeVerifier string
+		err          error
+	}
+
+	// buffered, so the generator never blocks if the context is done first.
+	out := make(chan generated, 1)
+	go func() {
+		codeVerifier, err := GenerateCodeVerifier(n)
+		out <- generated{codeVerifier: codeVerifier, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+
+	case res := <-out:
+		return res.codeVerifier, res.err
+	}
 }
 
 // GenerateCodeChallenge takes a code verifier and method to generate a code
 // challenge.
 func GenerateCodeChallenge(method Method, codeVerifier string) (out string, err error) {
-	in := []byte(codeVerifier)
-	if err = validateCodeVerifier(in); err != nil {
+	if err = validateVerifierLen(len(codeVerifier)); err != nil {
+		return
+	}
+
+	scratch := getScratch()
+	defer scratch.release()
+
+	scratch.verifier = append(scratch.verifier, codeVerifier...)
+	if err = validateCodeVerifierCharacters(scratch.verifier); err != nil {
 		return
 	}
 
-	return generateCodeChallenge(method, in), nil
+	return string(scratch.transform(method, Base64URL, scratch.verifier)), nil
 }
 
 // VerifyCodeVerifier enables servers to verify the received code verifier.
@@ -135,43 +318,178 @@ func VerifyCodeVerifier(method Method, codeVerifier string, codeChallenge string
 	case Plain:
 		// If the "code_challenge_method" from Section 4.3 was "plain", they are
 		// compared directly, i.e.:
-		return codeVerifier == codeChallenge
+		return verifyChallenge(Plain, Base64URL, codeVerifier, codeChallenge)
 
 	case S256:
 		// If the "code_challenge_method" from Section 4.3 was "S256", the
 		// received "code_verifier" is hashed by SHA-256, base64url-encoded, and
 		// then compared to the "code_challenge", i.e.:
-		codeVerifierChallenge, err := GenerateCodeChallenge(method, codeVerifier)
-		if err != nil {
-			return false
-		}
+		return verifyChallenge(S256, Base64URL, codeVerifier, codeChallenge)
 
-		return codeVerifierChallenge == codeChallenge
+	case S384, S512:
+		// The non-standard hashed methods are verified as S256, using the
+		// method's hash.
+		return verifyChallenge(method, Base64URL, codeVerifier, codeChallenge)
 
 	default:
 		return false
 	}
 }
 
+// GenerateCodeChallengeBytes takes a code verifier and method to generate a
+// code challenge, as GenerateCodeChallenge, for callers working with byte
+// slices. The returned code challenge never aliases the code verifier.
+func GenerateCodeChallengeBytes(method Method, codeVerifier []byte) ([]byte, error) {
+	if err := validateCodeVerifier(codeVerifier); err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case Plain:
+		return append([]byte(nil), codeVerifier...), nil
+
+	case S256, S384, S512:
+		scratch := getScratch()
+		defer scratch.release()
+
+		return append([]byte(nil), scratch.transform(method, Base64URL, codeVerifier)...), nil
+
+	default:
+		return nil, ErrMethodNotSupported
+	}
+}
+
+// Transform performs only the method's transform of the code verifier into a
+// code challenge, without validating the code verifier's length or
+// characters.
+//
+// This is intended for advanced use, such as gateways which have already
+// validated the code verifier upstream, and must never be given unvalidated
+// input. Use GenerateCodeChallenge otherwise.
+func Transform(method Method, codeVerifier []byte) (string, error) {
+	if !isKeyMethod(method) {
+		return "", ErrMethodNotSupported
+	}
+
+	return generateCodeChallenge(method, codeVerifier), nil
+}
+
+// VerifyCodeVerifierBytes enables servers to verify the received code
+// verifier, as VerifyCodeVerifier, for callers working with byte slices.
+func VerifyCodeVerifierBytes(method Method, codeVerifier []byte, codeChallenge []byte) bool {
+	switch method {
+	case Plain:
+		// compare in place, avoiding copying the code verifier.
+		return validateCodeVerifier(codeVerifier) == nil &&
+			subtle.ConstantTimeCompare(codeVerifier, codeChallenge) == 1
+
+	case S256, S384, S512:
+		scratch := getScratch()
+		defer scratch.release()
+
+		return scratch.verify(method, Base64URL, codeVerifier, codeChallenge)
+
+	default:
+		return false
+	}
+}
+
+// ChallengesEqual reports whether two code challenges are equal, using a
+// constant time comparison so as not to leak timing information about secret
+// material.
+func ChallengesEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// NormalizeCodeChallenge converts an S256 code challenge encoded using padded,
+// or standard alphabet, base64 into the unpadded base64url encoding required
+// by RFC 7636, 4.2. Compliant code challenges are returned unchanged.
+//
+// This enables servers to remain compatible with non-compliant clients, and
+// should not be applied to plain code challenges.
+func NormalizeCodeChallenge(codeChallenge string) string {
+	codeChallenge = strings.TrimRight(codeChallenge, "=")
+
+	return strings.NewReplacer("+", "-", "/", "_").Replace(codeChallenge)
+}
+
 // Key provides the proof key for secure code exchange.
 type Key struct {
 	// challengeMethod determines the code challenge transform method to use.
 	challengeMethod Method
+	// challengeEncoding determines the encoding of the S256 transform output.
+	// Defaults to Base64URL if empty.
+	challengeEncoding ChallengeEncoding
 	// codeVerifierLen provides the length of the code verifier to generate, if
 	// a code verifier is not supplied on key generation.
 	codeVerifierLen int
+	// verifierCharset provides the characters to generate a code verifier
+	// from. Defaults to the unreserved character set if empty.
+	verifierCharset string
+	// random provides the source of randomness used to generate a code
+	// verifier. Defaults to crypto/rand if nil.
+	random io.Reader
+	// minEntropyBits provides the minimum entropy of the code verifier to
+	// generate, if specified.
+	minEntropyBits int
 	// codeVerifier provides the code verifier data.
 	codeVerifier []byte
+	// codeChallenge provides a code challenge received from a client, for
+	// keys used server-side where the code verifier is not yet known.
+	codeChallenge string
+	// issuedChallenge provides the code challenge last derived from the code
+	// verifier by CodeChallenge, enabling SelfCheck to detect the code
+	// verifier changing after the code challenge has been sent.
+	issuedChallenge string
+	// pinnedChallenge provides the code challenge frozen by PinChallenge. Any
+	// mutation changing the code challenge is rejected once set.
+	pinnedChallenge string
+	// clock provides the time source used to compute expiry. Defaults to the
+	// system clock if nil.
+	clock Clock
+	// ttl provides the duration a key is valid for from creation.
+	ttl time.Duration
+	// expiresAt provides the time the key expires. A zero value specifies the
+	// key does not expire.
+	expiresAt time.Time
+	// createdAt provides the time the key was created, according to the key's
+	// clock. A zero value specifies the creation time is unknown, such as for
+	// keys decoded from formats which did not record it.
+	createdAt time.Time
+	// verifiedAt provides the time a code verifier was first verified against
+	// the key, according to the key's clock. A zero value specifies the key
+	// has not been verified.
+	verifiedAt time.Time
+	// meta provides the metadata attached to the key with SetMeta.
+	meta map[string]string
+	// jsonMode determines the fields encoded by MarshalJSON. Defaults to
+	// JSONPublic.
+	jsonMode JSONMode
+	// provider provides the requirements of the identity provider the key
+	// will be used with, if specified.
+	provider *providerPreset
+	// reuseCache detects a supplied code verifier having been supplied to
+	// another key, if specified.
+	reuseCache *ReuseCache
+	// fips enforces the FIPS compliance profile, regardless of whether the
+	// binary has been built with a FIPS 140 validated cryptographic module.
+	fips bool
 }
 
-// SetChallengeMethod enables upgrading code challenge generation method.
+// SetChallengeMethod enables upgrading code challenge generation method, such
+// as from S256 to the non-standard S512. Setting a weaker method returns
+// ErrMethodDowngrade.
 func (k *Key) SetChallengeMethod(method Method) error {
 	switch method {
-	case Plain, S256:
-		if k.challengeMethod == S256 && method == Plain {
+	case Plain, S256, S384, S512:
+		if isWeaker(method, k.challengeMethod) {
 			return ErrMethodDowngrade
 		}
 
+		if err := k.checkPinned(method != k.challengeMethod); err != nil {
+			return err
+		}
+
 		k.challengeMethod = method
 
 	default:
@@ -187,6 +505,16 @@ func (k *Key) ChallengeMethod() Method {
 	return k.challengeMethod
 }
 
+// ChallengeEncoding returns the configured key's encoding for the output of
+// the S256 transform.
+func (k *Key) ChallengeEncoding() ChallengeEncoding {
+	if k.challengeEncoding == "" {
+		return Base64URL
+	}
+
+	return k.challengeEncoding
+}
+
 // setCodeVerifierLength sets the length of the code verifier to be generated.
 //
 // If a code verifier is supplied, this setting will be ignored in favour of
@@ -212,6 +540,10 @@ func (k *Key) setCodeVerifier(verifier []byte) (err error) {
 		return
 	}
 
+	if err = k.checkPinned(k.verifierChanged(verifier)); err != nil {
+		return
+	}
+
 	k.codeVerifier = verifier
 	k.codeVerifierLen = len(verifier)
 
@@ -224,51 +556,293 @@ func (k *Key) CodeVerifier() string {
 }
 
 // getCodeVerifier returns a code verifier. If one has not been set, it will
-// generate one based on the configured verifier length.
+// generate one from crypto/rand based on the configured verifier length.
+//
+// Keys holding a received code challenge never generate a code verifier, as
+// the verifier is only known to the client.
+//
+// Panics if crypto/rand fails, as a key can never be used without a code
+// verifier, and the operating system's source of randomness failing is
+// unrecoverable. Keys configured with WithRandReader generate their code
+// verifier in New, reporting failures as an error instead.
 func (k *Key) getCodeVerifier() []byte {
-	if len(k.codeVerifier) == 0 {
-		k.codeVerifier = generateCodeVerifier(k.codeVerifierLen)
+	if len(k.codeVerifier) == 0 && k.codeChallenge == "" {
+		codeVerifier, err := k.generateCodeVerifier()
+		if err != nil {
+			panic("pkce: failed to generate a code verifier: " + err.Error())
+		}
+		k.codeVerifier = codeVerifier
 	}
 
 	return k.codeVerifier
 }
 
+// generateCodeVerifier generates a code verifier from the key's configured
+// character set, length and source of randomness, defaulting to crypto/rand.
+func (k *Key) generateCodeVerifier() ([]byte, error) {
+	if k.random == nil {
+		return generateCodeVerifierFrom(k.charset(), k.codeVerifierLen)
+	}
+
+	return readCodeVerifier(k.random, k.charset(), k.codeVerifierLen)
+}
+
+// charset returns the character set to generate a code verifier from.
+func (k *Key) charset() string {
+	if k.verifierCharset == "" {
+		return unreserved
+	}
+
+	return k.verifierCharset
+}
+
 // CodeChallenge returns the challenge for the configured code verifier.
 // Will generate a verifier if nil.
+//
+// If the key was created with a received code challenge, the received code
+// challenge is returned.
 func (k *Key) CodeChallenge() string {
-	return generateCodeChallenge(k.ChallengeMethod(), k.getCodeVerifier())
+	if k.codeChallenge != "" {
+		return k.codeChallenge
+	}
+
+	if k.pinnedChallenge != "" {
+		return k.pinnedChallenge
+	}
+
+	k.issuedChallenge = encodeCodeChallenge(k.ChallengeMethod(), k.ChallengeEncoding(), k.getCodeVerifier())
+
+	return k.issuedChallenge
+}
+
+// ExpiresAt returns the time the key expires. A zero time is returned if the
+// key does not expire.
+func (k *Key) ExpiresAt() time.Time {
+	return k.expiresAt
+}
+
+// CreatedAt returns the time the key was created, according to the key's
+// clock, enabling policies such as requiring a code challenge be used within a
+// number of minutes. A zero time is returned if the creation time is unknown.
+func (k *Key) CreatedAt() time.Time {
+	return k.createdAt
+}
+
+// VerifiedAt returns the time a code verifier was first successfully verified
+// against the key, according to the key's clock, enabling audit trails and
+// replay investigations to reconstruct the timeline of a flow. A zero time is
+// returned if the key has not been verified.
+//
+// The verification time is persisted with the key, so the correlation ID of
+// the flow, such as the authorization code or a request ID, can be recorded
+// alongside it with SetMeta.
+func (k *Key) VerifiedAt() time.Time {
+	return k.verifiedAt
+}
+
+// Expired returns whether the key has expired, according to the key's clock.
+func (k *Key) Expired() bool {
+	return !k.expiresAt.IsZero() && !now(k.clock).Before(k.expiresAt)
+}
+
+// Validate checks the internal consistency of the key, such as after
+// unmarshaling a key from untrusted storage. The method and encoding must be
+// supported, a held code verifier must be compliant, match the code verifier
+// length and the held code challenge, and the expiry must be sane.
+func (k *Key) Validate() error {
+	if !isKeyMethod(k.challengeMethod) {
+		return ErrMethodNotSupported
+	}
+
+	switch k.challengeEncoding {
+	case "", Base64URL, Base64URLPadded, Hex:
+	default:
+		return ErrEncodingNotSupported
+	}
+
+	if k.verifierCharset != "" {
+		if err := validateCharset(k.verifierCharset); err != nil {
+			return err
+		}
+	}
+
+	if len(k.codeVerifier) > 0 {
+		if err := validateCodeVerifier(k.codeVerifier); err != nil {
+			return err
+		}
+
+		if k.codeVerifierLen != len(k.codeVerifier) {
+			return ErrKeyInconsistent
+		}
+	} else if err := validateVerifierLen(k.codeVerifierLen); err != nil {
+		return err
+	}
+
+	if k.codeChallenge != "" {
+		if err := validateCodeChallenge(k.codeChallenge); err != nil {
+			return err
+		}
+
+		if len(k.codeVerifier) > 0 && !verifyChallenge(k.ChallengeMethod(), k.ChallengeEncoding(), string(k.codeVerifier), k.codeChallenge) {
+			return ErrKeyInconsistent
+		}
+	}
+
+	if k.ttl < 0 || (!k.expiresAt.IsZero() && k.expiresAt.UnixNano() <= 0) {
+		return ErrExpiryInvalid
+	}
+
+	if k.fips || fipsEnabled() {
+		return validateFIPS(k)
+	}
+
+	return nil
+}
+
+// SelfCheck recomputes the code challenge from the held code verifier and
+// compares it to the code challenge issued, either held by the key or last
+// returned by CodeChallenge, returning ErrKeyInconsistent if they differ.
+//
+// Clients can use SelfCheck after loading a key from storage to catch
+// corruption before the token request is sent, as a corrupted code verifier
+// would otherwise only be discovered by the authorization server rejecting it.
+// Keys without both a code verifier and an issued code challenge pass.
+func (k *Key) SelfCheck() error {
+	if len(k.codeVerifier) == 0 {
+		return nil
+	}
+
+	if err := validateCodeVerifier(k.codeVerifier); err != nil {
+		return err
+	}
+
+	issued := k.codeChallenge
+	if issued == "" {
+		issued = k.issuedChallenge
+	}
+	if issued == "" {
+		return nil
+	}
+
+	want := encodeCodeChallenge(k.ChallengeMethod(), k.ChallengeEncoding(), k.codeVerifier)
+	if !ChallengesEqual(want, issued) {
+		return ErrKeyInconsistent
+	}
+
+	return nil
 }
 
 // VerifyCodeVerifier provides a convenience function, for if you've loaded the
 // code verifier into the key. If not, this won't really be useful to use...
+//
+// The time of the first successful verification is recorded, as returned by
+// VerifiedAt.
 func (k *Key) VerifyCodeVerifier(codeVerifier string) bool {
-	return VerifyCodeVerifier(k.ChallengeMethod(), codeVerifier, k.CodeChallenge())
+	method := k.ChallengeMethod()
+	if !isKeyMethod(method) || !verifyChallenge(method, k.ChallengeEncoding(), codeVerifier, k.CodeChallenge()) {
+		return false
+	}
+
+	if k.verifiedAt.IsZero() {
+		k.verifiedAt = now(k.clock)
+	}
+
+	return true
 }
 
-// generateCodeVerifier performs the computations required to generate a
-// cryptographically random, specification compliant code verifier.
-func generateCodeVerifier(n int) (out []byte) {
-	unreservedLen := big.NewInt(int64(len(unreserved)))
+// Equal reports whether k and other hold the same proof key, comparing secret
+// material in constant time. Code verifiers are not generated by comparison,
+// so a key that has not yet generated its code verifier is only equal to
+// another key that has not either.
+func (k *Key) Equal(other *Key) bool {
+	if k == nil || other == nil {
+		return k == other
+	}
 
-	out = make([]byte, n)
-	for i := range out {
-		// ensure we use non-deterministic random ints.
-		j, _ := rand.Int(rand.Reader, unreservedLen)
-		out[i] = unreserved[j.Int64()]
+	// evaluate all comparisons to avoid short-circuiting on secret material.
+	verifierEqual := subtle.ConstantTimeCompare(k.codeVerifier, other.codeVerifier) == 1
+	challengeEqual := ChallengesEqual(k.codeChallenge, other.codeChallenge)
+
+	return verifierEqual &&
+		challengeEqual &&
+		k.ChallengeMethod() == other.ChallengeMethod() &&
+		k.ChallengeEncoding() == other.ChallengeEncoding() &&
+		k.codeVerifierLen == other.codeVerifierLen &&
+		k.expiresAt.Equal(other.expiresAt)
+}
+
+// Clone returns a deep copy of the key, including its secret material and
+// metadata.
+func (k *Key) Clone() *Key {
+	clone := *k
+	if k.codeVerifier != nil {
+		clone.codeVerifier = append([]byte(nil), k.codeVerifier...)
 	}
+	clone.meta = copyMeta(k.meta)
 
-	return out
+	return &clone
+}
+
+// CloneWithNewVerifier returns a copy of the key's configuration, such as the
+// challenge method and code verifier length, with a freshly generated code
+// verifier. This enables a configured key to be used as a template across
+// many authorization flows.
+//
+// Any received code challenge, per-flow metadata and verification time is not
+// copied. The clone is created when it was cloned, and if the key was
+// configured with an expiry, expires relative to when it was cloned. An error
+// is returned if the code verifier can not be generated, such as the source
+// of randomness configured with WithRandReader failing.
+func (k *Key) CloneWithNewVerifier() (*Key, error) {
+	codeVerifier, err := k.generateCodeVerifier()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *k
+	clone.codeChallenge = ""
+	clone.codeVerifier = codeVerifier
+	clone.issuedChallenge = ""
+	clone.pinnedChallenge = ""
+	clone.meta = nil
+	clone.verifiedAt = time.Time{}
+
+	clone.createdAt = now(k.clock)
+	if k.ttl > 0 {
+		clone.expiresAt = clone.createdAt.Add(k.ttl)
+	}
+
+	return &clone, nil
+}
+
+// generateCodeVerifier performs the computations required to generate a
+// cryptographically random, specification compliant code verifier.
+func generateCodeVerifier(n int) ([]byte, error) {
+	return generateCodeVerifierFrom(unreserved, n)
+}
+
+// generateCodeVerifierFrom generates a cryptographically random code verifier
+// using only characters from charset.
+func generateCodeVerifierFrom(charset string, n int) ([]byte, error) {
+	// ensure we use non-deterministic random ints.
+	return readCodeVerifier(rand.Reader, charset, n)
 }
 
 // generateCodeChallenge performs the transform required by the specified
 // method.
 func generateCodeChallenge(method Method, codeVerifier []byte) (out string) {
+	return encodeCodeChallenge(method, Base64URL, codeVerifier)
+}
+
+// encodeCodeChallenge performs the transform required by the specified method,
+// encoding the S256 output with the specified encoding.
+func encodeCodeChallenge(method Method, encoding ChallengeEncoding, codeVerifier []byte) (out string) {
 	if method == Plain {
 		return string(codeVerifier)
 	}
 
-	s256 := sha256.New()
-	s256.Write(codeVerifier)
+	scratch := getScratch()
+	defer scratch.release()
 
-	return base64.RawURLEncoding.EncodeToString(s256.Sum(nil))
+	return string(scratch.transform(method, encoding, codeVerifier))
 }
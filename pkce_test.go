@@ -1,20 +1,26 @@
 package pkce
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
-func TestGenerateCodeChallenge(t *testing.T) {
-	tests := []struct {
-		name         string
-		method       Method
-		codeVerifier []byte
-		want         string
-		shouldErr    bool
-		wantErr      error
-	}{
+type generateCodeChallengeTest struct {
+	name         string
+	method       Method
+	codeVerifier []byte
+	want         string
+	shouldErr    bool
+	wantErr      error
+}
+
+func generateCodeChallengeTests() []generateCodeChallengeTest {
+	return []generateCodeChallengeTest{
 		{
 			name:         "should error on invalid length plain challenge",
 			method:       Plain,
@@ -69,6 +75,10 @@ func TestGenerateCodeChallenge(t *testing.T) {
 			shouldErr:    false,
 		},
 	}
+}
+
+func TestGenerateCodeChallenge(t *testing.T) {
+	tests := generateCodeChallengeTests()
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -328,6 +338,26 @@ func TestKey_SetChallengeMethod(t *testing.T) {
 		wantKey: &Key{
 			challengeMethod: S256,
 		},
+	}, setChallengeMethodTest{
+		name:   "should upgrade from S256 to S512",
+		method: S512,
+		gotKey: &Key{
+			challengeMethod: S256,
+		},
+		wantKey: &Key{
+			challengeMethod: S512,
+		},
+	}, setChallengeMethodTest{
+		name:      "Should error on attempting downgrade from S512 to S384",
+		method:    S384,
+		shouldErr: true,
+		wantErr:   ErrMethodDowngrade,
+		gotKey: &Key{
+			challengeMethod: S512,
+		},
+		wantKey: &Key{
+			challengeMethod: S512,
+		},
 	})
 
 	for _, tt := range tests {
@@ -377,6 +407,14 @@ func verifyCodeVerifierTests() []verifyCodeVerifierTest {
 			codeChallenge:    "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj",
 			want:             false,
 		},
+		{
+			name:             "should not verify non-compliant plain code verifier",
+			method:           Plain,
+			wantCodeVerifier: "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj",
+			codeVerifier:     "yolo",
+			codeChallenge:    "yolo",
+			want:             false,
+		},
 		{
 			name:             "should verify valid plain code verifier",
 			method:           Plain,
@@ -417,6 +455,30 @@ func verifyCodeVerifierTests() []verifyCodeVerifierTest {
 			codeChallenge:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
 			want:             true,
 		},
+		{
+			name:             "should verify matching S384 code verifier",
+			method:           S384,
+			wantCodeVerifier: testCodeVerifier,
+			codeVerifier:     testCodeVerifier,
+			codeChallenge:    testHashedChallenge(S384),
+			want:             true,
+		},
+		{
+			name:             "should verify matching S512 code verifier",
+			method:           S512,
+			wantCodeVerifier: testCodeVerifier,
+			codeVerifier:     testCodeVerifier,
+			codeChallenge:    testHashedChallenge(S512),
+			want:             true,
+		},
+		{
+			name:             "should not verify non-matching S512 code verifier",
+			method:           S512,
+			wantCodeVerifier: testCodeVerifier,
+			codeVerifier:     "this-is-not-the-verifier-you-are-looking-for",
+			codeChallenge:    testHashedChallenge(S512),
+			want:             false,
+		},
 	}
 }
 
@@ -850,6 +912,12 @@ func TestNew(t *testing.T) {
 				t.Errorf("New() error = %v, shouldErr = %v", err, tt.shouldErr)
 				return
 			}
+			if err == nil {
+				if gotKey.CreatedAt().IsZero() {
+					t.Error("New() should record the key's creation time")
+				}
+				gotKey.createdAt = time.Time{}
+			}
 			if !reflect.DeepEqual(gotKey, tt.wantKey) {
 				t.Errorf("New() gotKey = %v, want %v", gotKey, tt.wantKey)
 			}
@@ -869,6 +937,105 @@ func TestVerifyCodeVerifier(t *testing.T) {
 	}
 }
 
+func TestGenerateCodeChallengeBytes(t *testing.T) {
+	tests := append(generateCodeChallengeTests(), generateCodeChallengeTest{
+		name:         "should error on an unsupported method",
+		method:       "yolo",
+		codeVerifier: []byte(strings.Repeat("a", verifierMinLen)),
+		shouldErr:    true,
+		wantErr:      ErrMethodNotSupported,
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCodeChallengeBytes(tt.method, tt.codeVerifier)
+			if err != tt.wantErr {
+				t.Fatalf("GenerateCodeChallengeBytes() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("GenerateCodeChallengeBytes() = %s, want %s", got, tt.want)
+			}
+
+			if len(got) > 0 && &got[0] == &tt.codeVerifier[0] {
+				t.Error("GenerateCodeChallengeBytes() should not alias the code verifier")
+			}
+		})
+	}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       Method
+		codeVerifier string
+		want         string
+		wantErr      error
+	}{
+		{
+			name:         "should transform a S256 code verifier",
+			method:       S256,
+			codeVerifier: testCodeVerifier,
+			want:         testCodeChallenge,
+		},
+		{
+			name:         "should transform a S384 code verifier",
+			method:       S384,
+			codeVerifier: testCodeVerifier,
+			want:         testHashedChallenge(S384),
+		},
+		{
+			name:         "should transform a S512 code verifier",
+			method:       S512,
+			codeVerifier: testCodeVerifier,
+			want:         testHashedChallenge(S512),
+		},
+		{
+			name:         "should transform a plain code verifier",
+			method:       Plain,
+			codeVerifier: testCodeVerifier,
+			want:         testCodeVerifier,
+		},
+		{
+			name:         "should not validate the code verifier",
+			method:       Plain,
+			codeVerifier: "yolo",
+			want:         "yolo",
+		},
+		{
+			name:         "should error on an unsupported method",
+			method:       "yolo",
+			codeVerifier: testCodeVerifier,
+			wantErr:      ErrMethodNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Transform(tt.method, []byte(tt.codeVerifier))
+			if err != tt.wantErr {
+				t.Fatalf("Transform() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Transform() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeVerifierBytes(t *testing.T) {
+	tests := verifyCodeVerifierTests()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyCodeVerifierBytes(tt.method, []byte(tt.codeVerifier), []byte(tt.codeChallenge)); got != tt.want {
+				t.Errorf("VerifyCodeVerifierBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_generateCodeChallenge(t *testing.T) {
 	tests := codeChallengeTests()
 
@@ -916,7 +1083,10 @@ func Test_generateCodeVerifier(t *testing.T) {
 			// values, but we can ensure all characters are valid and the
 			// requested generation length is valid
 
-			gotOut := generateCodeVerifier(tt.args.n)
+			gotOut, err := generateCodeVerifier(tt.args.n)
+			if err != nil {
+				t.Fatalf("generateCodeVerifier() unexpected error: %v", err)
+			}
 			if len(gotOut) != tt.args.n {
 				t.Errorf("generateCodeVerifier() should generate to specified length\ngot:  %v\nwant: %v\n", len(gotOut), tt.args.n)
 			}
@@ -927,12 +1097,28 @@ func Test_generateCodeVerifier(t *testing.T) {
 	}
 }
 
+func TestGenerateCodeVerifier_randFailure(t *testing.T) {
+	reader := rand.Reader
+	rand.Reader = strings.NewReader("")
+	defer func() { rand.Reader = reader }()
+
+	if got, err := GenerateCodeVerifier(verifierMinLen); err == nil || got != "" {
+		t.Errorf("GenerateCodeVerifier() = %q, %v, want an error", got, err)
+	}
+	if got, err := GenerateCodeVerifierContext(context.Background(), verifierMinLen); err == nil || got != "" {
+		t.Errorf("GenerateCodeVerifierContext() = %q, %v, want an error", got, err)
+	}
+}
+
 func Test_generateCodeVerifier_randomness(t *testing.T) {
 	const numHashes = 10000
 	hashMap := map[string]struct{}{}
 
 	for i := 0; i < numHashes; i++ {
-		out := generateCodeVerifier(10)
+		out, err := generateCodeVerifier(10)
+		if err != nil {
+			t.Fatalf("generateCodeVerifier() unexpected error: %v", err)
+		}
 		v := string(out)
 
 		if _, ok := hashMap[v]; ok {
@@ -943,3 +1129,786 @@ func Test_generateCodeVerifier_randomness(t *testing.T) {
 		hashMap[v] = struct{}{}
 	}
 }
+
+func TestKey_Expired(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		advance time.Duration
+		want    bool
+	}{
+		{
+			name:    "should never expire without an expiry",
+			ttl:     0,
+			advance: 24 * time.Hour,
+			want:    false,
+		},
+		{
+			name:    "should not be expired before expiry",
+			ttl:     time.Minute,
+			advance: time.Minute - time.Nanosecond,
+			want:    false,
+		},
+		{
+			name:    "should be expired at expiry",
+			ttl:     time.Minute,
+			advance: time.Minute,
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			key, err := New(WithClock(clock), WithExpiry(tt.ttl))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			clock.Advance(tt.advance)
+			if got := key.Expired(); got != tt.want {
+				t.Errorf("Expired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_receivedCodeChallenge(t *testing.T) {
+	const codeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+
+	key, err := New(WithCodeChallenge(codeChallenge))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if got := key.CodeChallenge(); got != codeChallenge {
+		t.Errorf("CodeChallenge() = %v, want %v", got, codeChallenge)
+	}
+
+	if got := key.CodeVerifier(); got != "" {
+		t.Errorf("CodeVerifier() should not generate a verifier for a received challenge\ngot: %v", got)
+	}
+
+	if !key.VerifyCodeVerifier("6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj") {
+		t.Error("VerifyCodeVerifier() should verify against the received challenge")
+	}
+}
+
+func TestGenerateCodeVerifierContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		n       int
+		wantErr error
+	}{
+		{
+			name:    "should generate a code verifier",
+			ctx:     context.Background(),
+			n:       verifierMinLen,
+			wantErr: nil,
+		},
+		{
+			name:    "should error on invalid length",
+			ctx:     context.Background(),
+			n:       verifierMinLen - 1,
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should error on a cancelled context",
+			ctx:     cancelled,
+			n:       verifierMinLen,
+			wantErr: context.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCodeVerifierContext(tt.ctx, tt.n)
+			if err != tt.wantErr {
+				t.Fatalf("GenerateCodeVerifierContext() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err == nil {
+				if len(got) != tt.n {
+					t.Errorf("GenerateCodeVerifierContext() length = %v, want %v", len(got), tt.n)
+				}
+				if err = validateCodeVerifier([]byte(got)); err != nil {
+					t.Errorf("GenerateCodeVerifierContext() should generate valid code verifiers\ngot:  %s", got)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name          string
+		codeChallenge string
+		want          string
+	}{
+		{
+			name:          "should not change a compliant code challenge",
+			codeChallenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw",
+			want:          "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw",
+		},
+		{
+			name:          "should strip padding",
+			codeChallenge: "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ=",
+			want:          "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+		},
+		{
+			name:          "should convert the standard base64 alphabet",
+			codeChallenge: "EF+/M9nkOE6p88FdlYXUHkBv96MeV56C/Dsqk9DGlxw=",
+			want:          "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeCodeChallenge(tt.codeChallenge); got != tt.want {
+				t.Errorf("NormalizeCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_ChallengeEncoding(t *testing.T) {
+	const codeVerifier = "dBjftJeZ4CVP-mJ92K9qDTMvmZXh0wSUe3Q1Ja4wW0s"
+
+	tests := []struct {
+		name          string
+		method        Method
+		encoding      ChallengeEncoding
+		wantChallenge string
+	}{
+		{
+			name:          "should default to base64url",
+			method:        S256,
+			encoding:      "",
+			wantChallenge: "IIbzzjlUMcbYfL6yqFEOddVetqfibzAw_ZlemA2P_mQ",
+		},
+		{
+			name:          "should encode base64url",
+			method:        S256,
+			encoding:      Base64URL,
+			wantChallenge: "IIbzzjlUMcbYfL6yqFEOddVetqfibzAw_ZlemA2P_mQ",
+		},
+		{
+			name:          "should encode padded base64url",
+			method:        S256,
+			encoding:      Base64URLPadded,
+			wantChallenge: "IIbzzjlUMcbYfL6yqFEOddVetqfibzAw_ZlemA2P_mQ=",
+		},
+		{
+			name:          "should encode hex",
+			method:        S256,
+			encoding:      Hex,
+			wantChallenge: "2086f3ce395431c6d87cbeb2a8510e75d55eb6a7e26f3030fd995e980d8ffe64",
+		},
+		{
+			name:          "should not encode plain",
+			method:        Plain,
+			encoding:      Hex,
+			wantChallenge: codeVerifier,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &Key{
+				challengeMethod:   tt.method,
+				challengeEncoding: tt.encoding,
+				codeVerifier:      []byte(codeVerifier),
+			}
+
+			if got := k.CodeChallenge(); got != tt.wantChallenge {
+				t.Errorf("CodeChallenge() = %v, want %v", got, tt.wantChallenge)
+			}
+
+			if !k.VerifyCodeVerifier(codeVerifier) {
+				t.Errorf("VerifyCodeVerifier() = false, want true")
+			}
+
+			if k.VerifyCodeVerifier(strings.Repeat("a", verifierMinLen)) {
+				t.Errorf("VerifyCodeVerifier() = true, want false")
+			}
+		})
+	}
+}
+
+func TestChallengesEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "should be equal",
+			a:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			b:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			want: true,
+		},
+		{
+			name: "should not be equal",
+			a:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			b:    "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw",
+			want: false,
+		},
+		{
+			name: "should not be equal with differing lengths",
+			a:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			b:    "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcG",
+			want: false,
+		},
+		{
+			name: "should be equal if empty",
+			a:    "",
+			b:    "",
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChallengesEqual(tt.a, tt.b); got != tt.want {
+				t.Errorf("ChallengesEqual() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_Equal(t *testing.T) {
+	codeVerifier := []byte(strings.Repeat("a", verifierMinLen))
+	expiresAt := time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC)
+
+	newKey := func() *Key {
+		return &Key{
+			challengeMethod: S256,
+			codeVerifierLen: verifierMinLen,
+			codeVerifier:    codeVerifier,
+			expiresAt:       expiresAt,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		key   *Key
+		other *Key
+		want  bool
+	}{
+		{
+			name:  "should be equal",
+			key:   newKey(),
+			other: newKey(),
+			want:  true,
+		},
+		{
+			name:  "should be equal if both nil",
+			key:   nil,
+			other: nil,
+			want:  true,
+		},
+		{
+			name:  "should not be equal to nil",
+			key:   newKey(),
+			other: nil,
+			want:  false,
+		},
+		{
+			name: "should not be equal with differing code verifiers",
+			key:  newKey(),
+			other: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeVerifier:    []byte(strings.Repeat("b", verifierMinLen)),
+				expiresAt:       expiresAt,
+			},
+			want: false,
+		},
+		{
+			name: "should not be equal with differing methods",
+			key:  newKey(),
+			other: &Key{
+				challengeMethod: Plain,
+				codeVerifierLen: verifierMinLen,
+				codeVerifier:    codeVerifier,
+				expiresAt:       expiresAt,
+			},
+			want: false,
+		},
+		{
+			name: "should not be equal with differing expiry",
+			key:  newKey(),
+			other: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeVerifier:    codeVerifier,
+			},
+			want: false,
+		},
+		{
+			name: "should be equal with the default encoding",
+			key:  newKey(),
+			other: &Key{
+				challengeMethod:   S256,
+				challengeEncoding: Base64URL,
+				codeVerifierLen:   verifierMinLen,
+				codeVerifier:      codeVerifier,
+				expiresAt:         expiresAt.In(time.Local),
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Equal(tt.other); got != tt.want {
+				t.Errorf("Equal() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_Clone(t *testing.T) {
+	key, err := New(WithCodeVerifier([]byte(strings.Repeat("a", verifierMinLen))))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	clone := key.Clone()
+	if !reflect.DeepEqual(clone, key) {
+		t.Errorf("Clone()\ngot:  %v\nwant: %v\n", clone, key)
+	}
+
+	// mutating the clone's secret material must not affect the original.
+	clone.codeVerifier[0] = 'b'
+	if key.CodeVerifier() != strings.Repeat("a", verifierMinLen) {
+		t.Errorf("Clone() should deep copy the code verifier\ngot:  %v", key.CodeVerifier())
+	}
+}
+
+func TestKey_Clone_meta(t *testing.T) {
+	key, err := New()
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	key.SetMeta("tenant", "a")
+
+	clone := key.Clone()
+	if got := clone.Meta()["tenant"]; got != "a" {
+		t.Errorf("Clone() meta = %v, want %v", got, "a")
+	}
+
+	// setting metadata on the clone must not affect the original.
+	clone.SetMeta("tenant", "b")
+	clone.SetMeta("state", "c")
+	if want := map[string]string{"tenant": "a"}; !reflect.DeepEqual(key.Meta(), want) {
+		t.Errorf("Clone() should deep copy the metadata\ngot:  %v\nwant: %v\n", key.Meta(), want)
+	}
+}
+
+func TestKey_CloneWithNewVerifier(t *testing.T) {
+	clock := newTestClock()
+	key, err := New(
+		WithChallengeMethod(Plain),
+		WithCodeVerifierLength(64),
+		WithVerifierCharset(AlphanumericCharset),
+		WithClock(clock),
+		WithExpiry(time.Minute),
+	)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	codeVerifier := key.CodeVerifier()
+
+	clock.Advance(time.Second)
+	clone, err := key.CloneWithNewVerifier()
+	if err != nil {
+		t.Fatalf("CloneWithNewVerifier() unexpected error: %v", err)
+	}
+
+	if clone.CodeVerifier() == codeVerifier {
+		t.Errorf("CloneWithNewVerifier() should generate a new code verifier")
+	}
+
+	if key.CodeVerifier() != codeVerifier {
+		t.Errorf("CloneWithNewVerifier() should not modify the original code verifier")
+	}
+
+	if got := len(clone.CodeVerifier()); got != 64 {
+		t.Errorf("CloneWithNewVerifier() code verifier length = %v, want %v", got, 64)
+	}
+
+	if strings.Trim(clone.CodeVerifier(), AlphanumericCharset) != "" {
+		t.Errorf("CloneWithNewVerifier() should generate from the configured charset\ngot:  %v", clone.CodeVerifier())
+	}
+
+	if clone.ChallengeMethod() != Plain {
+		t.Errorf("CloneWithNewVerifier() method = %v, want %v", clone.ChallengeMethod(), Plain)
+	}
+
+	if want := clock.Now().Add(time.Minute); !clone.ExpiresAt().Equal(want) {
+		t.Errorf("CloneWithNewVerifier() expires at = %v, want %v", clone.ExpiresAt(), want)
+	}
+}
+
+func TestMethod_MarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       Method
+		want    string
+		wantErr error
+	}{
+		{
+			name: "should marshal plain",
+			m:    Plain,
+			want: "plain",
+		},
+		{
+			name: "should marshal S256",
+			m:    S256,
+			want: "S256",
+		},
+		{
+			name: "should marshal S384",
+			m:    S384,
+			want: "S384",
+		},
+		{
+			name: "should marshal S512",
+			m:    S512,
+			want: "S512",
+		},
+		{
+			name: "should default to plain",
+			m:    "",
+			want: "plain",
+		},
+		{
+			name:    "should error on an unsupported method",
+			m:       "not-a-spec-based-method",
+			wantErr: ErrMethodNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.m.MarshalText()
+			if err != tt.wantErr {
+				t.Fatalf("MarshalText() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("MarshalText() = %s, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethod_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Method
+		wantErr bool
+	}{
+		{
+			name: "should unmarshal plain",
+			json: `{"method":"plain"}`,
+			want: Plain,
+		},
+		{
+			name: "should unmarshal S256",
+			json: `{"method":"S256"}`,
+			want: S256,
+		},
+		{
+			name: "should unmarshal S384",
+			json: `{"method":"S384"}`,
+			want: S384,
+		},
+		{
+			name: "should unmarshal S512",
+			json: `{"method":"S512"}`,
+			want: S512,
+		},
+		{
+			name: "should default to plain",
+			json: `{"method":""}`,
+			want: Plain,
+		},
+		{
+			name:    "should error on an unsupported method",
+			json:    `{"method":"s256"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config struct {
+				Method Method `json:"method"`
+			}
+
+			err := json.Unmarshal([]byte(tt.json), &config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if config.Method != tt.want {
+				t.Errorf("UnmarshalText() = %v, want %v", config.Method, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_Validate(t *testing.T) {
+	newKey := func(opts ...Option) *Key {
+		key, err := New(opts...)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return key
+	}
+
+	tests := []struct {
+		name    string
+		key     func() *Key
+		wantErr error
+	}{
+		{
+			name: "should validate a new key",
+			key: func() *Key {
+				return newKey()
+			},
+		},
+		{
+			name: "should validate a key holding a code verifier",
+			key: func() *Key {
+				return newKey(WithCodeVerifier([]byte(testCodeVerifier)))
+			},
+		},
+		{
+			name: "should validate a key holding a received code challenge",
+			key: func() *Key {
+				return newKey(WithCodeChallenge(testCodeChallenge), WithExpiry(time.Minute))
+			},
+		},
+		{
+			name: "should error on an unsupported method",
+			key: func() *Key {
+				key := newKey()
+				key.challengeMethod = "yolo"
+				return key
+			},
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name: "should error on an unsupported encoding",
+			key: func() *Key {
+				key := newKey()
+				key.challengeEncoding = "yolo"
+				return key
+			},
+			wantErr: ErrEncodingNotSupported,
+		},
+		{
+			name: "should error on an invalid charset",
+			key: func() *Key {
+				key := newKey()
+				key.verifierCharset = "a a"
+				return key
+			},
+			wantErr: ErrCharsetInvalid,
+		},
+		{
+			name: "should error on a non-compliant code verifier",
+			key: func() *Key {
+				key := newKey(WithCodeVerifier([]byte(testCodeVerifier)))
+				key.codeVerifier[0] = ' '
+				return key
+			},
+			wantErr: ErrVerifierCharacters,
+		},
+		{
+			name: "should error on a length not matching the code verifier",
+			key: func() *Key {
+				key := newKey(WithCodeVerifier([]byte(testCodeVerifier)))
+				key.codeVerifierLen = verifierMaxLen
+				return key
+			},
+			wantErr: ErrKeyInconsistent,
+		},
+		{
+			name: "should error on a non-compliant code verifier length",
+			key: func() *Key {
+				key := newKey()
+				key.codeVerifierLen = 0
+				return key
+			},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name: "should error on a code verifier not proving the code challenge",
+			key: func() *Key {
+				key := newKey(WithCodeChallenge(testCodeChallenge))
+				key.setCodeVerifier([]byte(strings.Repeat("a", verifierMinLen)))
+				return key
+			},
+			wantErr: ErrKeyInconsistent,
+		},
+		{
+			name: "should error on an invalid code challenge",
+			key: func() *Key {
+				key := newKey()
+				key.codeChallenge = "yolo"
+				return key
+			},
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name: "should error on an expiry before the epoch",
+			key: func() *Key {
+				key := newKey()
+				key.expiresAt = time.Unix(-1, 0)
+				return key
+			},
+			wantErr: ErrExpiryInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.key().Validate(); err != tt.wantErr {
+				t.Errorf("Validate() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSupportedMethods(t *testing.T) {
+	want := []Method{S256, Plain}
+	if FIPSEnabled() {
+		want = []Method{S256}
+	}
+
+	if got := SupportedMethods(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SupportedMethods() = %v, want %v", got, want)
+	}
+}
+
+func TestKey_SelfCheck(t *testing.T) {
+	newKey := func(opts ...Option) *Key {
+		key, err := New(opts...)
+		if err != nil {
+			t.Fatalf("New() unexpected error: %v", err)
+		}
+
+		return key
+	}
+
+	tests := []struct {
+		name    string
+		key     func() *Key
+		wantErr error
+	}{
+		{
+			name: "should pass a key without a code verifier",
+			key: func() *Key {
+				return newKey(WithCodeChallenge(testCodeChallenge))
+			},
+		},
+		{
+			name: "should pass a key whose code challenge has not been issued",
+			key: func() *Key {
+				return newKey(WithCodeVerifier([]byte(testCodeVerifier)))
+			},
+		},
+		{
+			name: "should pass a key matching the issued code challenge",
+			key: func() *Key {
+				key := newKey(WithChallengeMethod(S256), WithCodeVerifier([]byte(testCodeVerifier)))
+				_ = key.CodeChallenge()
+				return key
+			},
+		},
+		{
+			name: "should pass a key matching the held code challenge",
+			key: func() *Key {
+				return newKey(WithChallengeMethod(S256), WithCodeVerifier([]byte(testCodeVerifier)), WithCodeChallenge(testCodeChallenge))
+			},
+		},
+		{
+			name: "should error on a corrupted code verifier",
+			key: func() *Key {
+				key := newKey(WithChallengeMethod(S256), WithCodeVerifier([]byte(testCodeVerifier)))
+				_ = key.CodeChallenge()
+				key.codeVerifier[0] = 'a'
+				return key
+			},
+			wantErr: ErrKeyInconsistent,
+		},
+		{
+			name: "should error on a code verifier corrupted outside the character set",
+			key: func() *Key {
+				key := newKey(WithChallengeMethod(S256), WithCodeVerifier([]byte(testCodeVerifier)))
+				_ = key.CodeChallenge()
+				key.codeVerifier[0] = ' '
+				return key
+			},
+			wantErr: ErrVerifierCharacters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.key().SelfCheck(); err != tt.wantErr {
+				t.Errorf("SelfCheck() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKey_VerifiedAt(t *testing.T) {
+	clock := newTestClock()
+	key, err := New(WithCodeVerifierString(testCodeVerifier), WithClock(clock))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if key.VerifyCodeVerifier(strings.Repeat("a", verifierMinLen)) || !key.VerifiedAt().IsZero() {
+		t.Fatalf("VerifiedAt() = %v, a failed verification should not be recorded", key.VerifiedAt())
+	}
+
+	clock.Advance(time.Second)
+	verifiedAt := clock.Now()
+	if !key.VerifyCodeVerifier(testCodeVerifier) {
+		t.Fatalf("VerifyCodeVerifier() = false, want true")
+	}
+
+	clock.Advance(time.Second)
+	if !key.VerifyCodeVerifier(testCodeVerifier) {
+		t.Fatalf("VerifyCodeVerifier() = false, want true")
+	}
+
+	if !key.VerifiedAt().Equal(verifiedAt) {
+		t.Errorf("VerifiedAt() = %v, want the first verification at %v", key.VerifiedAt(), verifiedAt)
+	}
+
+	if err = key.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	clone, err := key.CloneWithNewVerifier()
+	if err != nil {
+		t.Fatalf("CloneWithNewVerifier() unexpected error: %v", err)
+	}
+	if !clone.VerifiedAt().IsZero() {
+		t.Errorf("CloneWithNewVerifier() should not copy the verification time, got: %v", clone.VerifiedAt())
+	}
+}
@@ -0,0 +1,51 @@
+// Package pkcehtml provides html/template helpers for server-rendered pages
+// which submit the authorization request via a POST form, rendering the PKCE
+// parameters as hidden form inputs.
+package pkcehtml
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// FuncName provides the name HiddenInputs is registered under in Funcs.
+const FuncName = "pkceHiddenInputs"
+
+// Funcs returns the template functions provided by the package, for
+// registering with template.Template.Funcs:
+//
+//	tmpl := template.Must(template.New("login").Funcs(pkcehtml.Funcs()).Parse(
+//		`<form method="post" action="/authorize">{{ pkceHiddenInputs .Key .State }}</form>`,
+//	))
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		FuncName: HiddenInputs,
+	}
+}
+
+// HiddenInputs returns hidden form inputs for the key's code challenge and
+// code challenge method, and the state if non-empty. Values are HTML escaped,
+// so the returned HTML is safe to render as is.
+func HiddenInputs(key *pkce.Key, state string) template.HTML {
+	var b strings.Builder
+	writeHiddenInput(&b, pkce.ParamCodeChallenge, key.CodeChallenge())
+	writeHiddenInput(&b, pkce.ParamCodeChallengeMethod, key.ChallengeMethod().String())
+	if state != "" {
+		writeHiddenInput(&b, "state", state)
+	}
+
+	// safe, as all attribute values have been escaped.
+	return template.HTML(b.String())
+}
+
+// writeHiddenInput writes a hidden form input with the HTML escaped name and
+// value.
+func writeHiddenInput(b *strings.Builder, name string, value string) {
+	b.WriteString(`<input type="hidden" name="`)
+	b.WriteString(template.HTMLEscapeString(name))
+	b.WriteString(`" value="`)
+	b.WriteString(template.HTMLEscapeString(value))
+	b.WriteString(`">`)
+}
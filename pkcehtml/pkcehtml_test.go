@@ -0,0 +1,73 @@
+package pkcehtml
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	testCodeVerifier  = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+	testCodeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+)
+
+func TestHiddenInputs(t *testing.T) {
+	key, err := pkce.New(pkce.WithCodeVerifierString(testCodeVerifier))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		state string
+		want  template.HTML
+	}{
+		{
+			name:  "should render the code challenge and method",
+			state: "",
+			want: `<input type="hidden" name="code_challenge" value="` + testCodeChallenge + `">` +
+				`<input type="hidden" name="code_challenge_method" value="S256">`,
+		},
+		{
+			name:  "should render the escaped state",
+			state: `"><script>`,
+			want: `<input type="hidden" name="code_challenge" value="` + testCodeChallenge + `">` +
+				`<input type="hidden" name="code_challenge_method" value="S256">` +
+				`<input type="hidden" name="state" value="&#34;&gt;&lt;script&gt;">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HiddenInputs(key, tt.state); got != tt.want {
+				t.Errorf("HiddenInputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	key, _ := pkce.New(pkce.WithCodeVerifierString(testCodeVerifier))
+	tmpl := template.Must(template.New("login").Funcs(Funcs()).Parse(
+		`<form method="post">{{ pkceHiddenInputs .Key .State }}</form>`,
+	))
+
+	var b strings.Builder
+	err := tmpl.Execute(&b, struct {
+		Key   *pkce.Key
+		State string
+	}{
+		Key:   key,
+		State: "state",
+	})
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	want := `<form method="post">` + string(HiddenInputs(key, "state")) + `</form>`
+	if got := b.String(); got != want {
+		t.Errorf("Execute() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/matthewhartstonge/pkce/pkceoidc
+
+go 1.26.0
+
+require (
+	github.com/coreos/go-oidc/v3 v3.21.0
+	github.com/go-jose/go-jose/v4 v4.1.5
+	github.com/matthewhartstonge/pkce v0.0.0-00010101000000-000000000000
+	golang.org/x/oauth2 v0.37.0
+)
+
+replace github.com/matthewhartstonge/pkce => ../
@@ -0,0 +1,149 @@
+// Package pkceoidc integrates the pkce package with github.com/coreos/go-oidc,
+// giving OpenID Connect relying parties a single Flow handling the PKCE proof
+// key, nonce and state of an authorization code flow.
+//
+// The package is a separate module, so that the pkce module remains free of
+// external dependencies.
+package pkceoidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// randomLength provides the length of generated state and nonce values,
+// providing over 256 bits of entropy from the alphanumeric character set.
+const randomLength = 43
+
+var (
+	// ErrStateMismatch is returned when the state of an authorization
+	// response does not match the state of the flow, such as a response to
+	// an authorization request forged by an attacker.
+	ErrStateMismatch = errors.New("pkceoidc: authorization response state does not match the flow")
+
+	// ErrIDTokenMissing is returned when a token response does not contain an
+	// ID token, such as when the openid scope was not requested.
+	ErrIDTokenMissing = errors.New("pkceoidc: token response does not contain an id_token")
+
+	// ErrNonceMismatch is returned when the nonce of an ID token does not
+	// match the nonce of the flow, such as an ID token replayed from another
+	// flow.
+	ErrNonceMismatch = errors.New("pkceoidc: id token nonce does not match the flow")
+)
+
+// Flow carries the PKCE proof key, state and nonce of a single OpenID Connect
+// authorization code flow.
+type Flow struct {
+	// Key provides the PKCE proof key.
+	Key *pkce.Key
+	// State provides the state sent in the authorization request, to be
+	// matched against the authorization response.
+	State string
+	// Nonce provides the nonce sent in the authorization request, to be
+	// matched against the ID token.
+	Nonce string
+}
+
+// NewFlow returns a flow with a new PKCE proof key, configured with opts, and
+// a new random state and nonce.
+func NewFlow(opts ...pkce.Option) (*Flow, error) {
+	key, err := pkce.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := pkce.RandomString(randomLength, pkce.AlphanumericCharset)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := pkce.RandomString(randomLength, pkce.AlphanumericCharset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow{
+		Key:   key,
+		State: state,
+		Nonce: nonce,
+	}, nil
+}
+
+// AuthCodeOptions returns the authorization request options of the flow: the
+// code challenge, the code challenge method and the nonce.
+func (f *Flow) AuthCodeOptions() []oauth2.AuthCodeOption {
+	opts := []oauth2.AuthCodeOption{oidc.Nonce(f.Nonce)}
+	for name, value := range f.Key.AuthCodeParams() {
+		opts = append(opts, oauth2.SetAuthURLParam(name, value))
+	}
+
+	return opts
+}
+
+// AuthCodeURL returns the authorization request URL of the flow for config,
+// adding the flow's state and AuthCodeOptions to opts.
+func (f *Flow) AuthCodeURL(config *oauth2.Config, opts ...oauth2.AuthCodeOption) string {
+	return config.AuthCodeURL(f.State, append(f.AuthCodeOptions(), opts...)...)
+}
+
+// Exchange completes the flow with the authorization response received at the
+// redirect uri, parsed as pkce.ParseAuthorizationResponse with opts.
+//
+// The response state is matched against the flow's state before the
+// authorization code is exchanged with the flow's code verifier. The ID token
+// of the token response is then verified by verifier, and its nonce matched
+// against the flow's nonce, as VerifyIDToken.
+func (f *Flow) Exchange(ctx context.Context, config *oauth2.Config, verifier *oidc.IDTokenVerifier, r *http.Request, opts ...pkce.ParseOption) (*oauth2.Token, *oidc.IDToken, error) {
+	resp, err := pkce.ParseAuthorizationResponse(r, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(resp.State), []byte(f.State)) != 1 {
+		return nil, nil, ErrStateMismatch
+	}
+
+	var exchangeOpts []oauth2.AuthCodeOption
+	for name, value := range f.Key.ExchangeParams() {
+		exchangeOpts = append(exchangeOpts, oauth2.SetAuthURLParam(name, value))
+	}
+
+	token, err := config.Exchange(ctx, resp.Code, exchangeOpts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idToken, err := f.VerifyIDToken(ctx, verifier, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return token, idToken, nil
+}
+
+// VerifyIDToken verifies the ID token of the token response with verifier,
+// ensuring its nonce matches the flow's nonce.
+func (f *Flow) VerifyIDToken(ctx context.Context, verifier *oidc.IDTokenVerifier, token *oauth2.Token) (*oidc.IDToken, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, ErrIDTokenMissing
+	}
+
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(f.Nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	return idToken, nil
+}
@@ -0,0 +1,218 @@
+package pkceoidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v4"
+	"golang.org/x/oauth2"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	testIssuer   = "https://op.example.com"
+	testClientID = "client"
+)
+
+// testProvider provides a token endpoint which verifies the code verifier of
+// a token request against the registered code challenge, responding with an
+// ID token signed by key.
+type testProvider struct {
+	t       *testing.T
+	key     *rsa.PrivateKey
+	manager *pkce.KeyManager
+	// idToken returns the claims of the issued ID token, or nil to omit it.
+	idToken func() map[string]interface{}
+}
+
+func (p *testProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		p.t.Fatalf("ParseForm() unexpected error: %v", err)
+	}
+
+	if err := p.manager.Verify(r.Context(), r.PostForm.Get("code"), r.PostForm.Get(pkce.ParamCodeVerifier)); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+	}
+	if claims := p.idToken(); claims != nil {
+		resp["id_token"] = p.sign(claims)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// sign returns claims as a compact RS256 signed JWT.
+func (p *testProvider) sign(claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: p.key}, nil)
+	if err != nil {
+		p.t.Fatalf("NewSigner() unexpected error: %v", err)
+	}
+
+	payload, _ := json.Marshal(claims)
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		p.t.Fatalf("Sign() unexpected error: %v", err)
+	}
+
+	token, err := jws.CompactSerialize()
+	if err != nil {
+		p.t.Fatalf("CompactSerialize() unexpected error: %v", err)
+	}
+
+	return token
+}
+
+func TestFlow_AuthCodeURL(t *testing.T) {
+	flow, err := NewFlow(pkce.WithChallengeMethod(pkce.S256))
+	if err != nil {
+		t.Fatalf("NewFlow() unexpected error: %v", err)
+	}
+
+	config := &oauth2.Config{
+		ClientID: testClientID,
+		Endpoint: oauth2.Endpoint{AuthURL: testIssuer + "/authorize"},
+	}
+
+	u, err := url.Parse(flow.AuthCodeURL(config, oauth2.AccessTypeOffline))
+	if err != nil {
+		t.Fatalf("AuthCodeURL() unexpected error: %v", err)
+	}
+
+	query := u.Query()
+	if query.Get("state") != flow.State ||
+		query.Get("nonce") != flow.Nonce ||
+		query.Get(pkce.ParamCodeChallenge) != flow.Key.CodeChallenge() ||
+		query.Get(pkce.ParamCodeChallengeMethod) != pkce.S256.String() ||
+		query.Get("access_type") != "offline" {
+		t.Errorf("AuthCodeURL() query not expected, got: %v", query)
+	}
+}
+
+func TestFlow_Exchange(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		state   func(flow *Flow) string
+		idToken func(flow *Flow) map[string]interface{}
+		wantErr error
+	}{
+		{
+			name:  "should exchange and verify an authorization response",
+			state: func(flow *Flow) string { return flow.State },
+			idToken: func(flow *Flow) map[string]interface{} {
+				return testClaims(flow.Nonce)
+			},
+		},
+		{
+			name:  "should reject a mismatched state",
+			state: func(*Flow) string { return "forged" },
+			idToken: func(flow *Flow) map[string]interface{} {
+				return testClaims(flow.Nonce)
+			},
+			wantErr: ErrStateMismatch,
+		},
+		{
+			name:  "should reject a mismatched nonce",
+			state: func(flow *Flow) string { return flow.State },
+			idToken: func(*Flow) map[string]interface{} {
+				return testClaims("replayed")
+			},
+			wantErr: ErrNonceMismatch,
+		},
+		{
+			name:  "should reject a missing id token",
+			state: func(flow *Flow) string { return flow.State },
+			idToken: func(*Flow) map[string]interface{} {
+				return nil
+			},
+			wantErr: ErrIDTokenMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			flow, err := NewFlow()
+			if err != nil {
+				t.Fatalf("NewFlow() unexpected error: %v", err)
+			}
+
+			manager := pkce.NewKeyManager(pkce.NewMemoryStore())
+			if err := manager.Register(ctx, "code", flow.Key.ChallengeMethod(), flow.Key.CodeChallenge()); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			srv := httptest.NewServer(&testProvider{
+				t:       t,
+				key:     key,
+				manager: manager,
+				idToken: func() map[string]interface{} { return tt.idToken(flow) },
+			})
+			defer srv.Close()
+
+			config := &oauth2.Config{
+				ClientID: testClientID,
+				Endpoint: oauth2.Endpoint{TokenURL: srv.URL, AuthStyle: oauth2.AuthStyleInParams},
+			}
+			verifier := oidc.NewVerifier(testIssuer, &oidc.StaticKeySet{PublicKeys: []crypto.PublicKey{key.Public()}}, &oidc.Config{ClientID: testClientID})
+
+			r := httptest.NewRequest(http.MethodGet, "/callback?"+url.Values{
+				"code":  {"code"},
+				"state": {tt.state(flow)},
+			}.Encode(), nil)
+
+			token, idToken, err := flow.Exchange(ctx, config, verifier, r)
+			if err != tt.wantErr {
+				t.Fatalf("Exchange() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if token.AccessToken != "access" || idToken.Subject != "subject" {
+				t.Errorf("Exchange() tokens not expected, got: %v, %v", token.AccessToken, idToken.Subject)
+			}
+		})
+	}
+}
+
+func TestNewFlow(t *testing.T) {
+	if _, err := NewFlow(pkce.WithChallengeMethod("yolo")); err != pkce.ErrMethodNotSupported {
+		t.Errorf("NewFlow() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrMethodNotSupported)
+	}
+}
+
+// testClaims returns the claims of a valid ID token carrying nonce.
+func testClaims(nonce string) map[string]interface{} {
+	now := time.Now()
+
+	return map[string]interface{}{
+		"iss":   testIssuer,
+		"sub":   "subject",
+		"aud":   testClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": nonce,
+	}
+}
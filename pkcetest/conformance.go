@@ -0,0 +1,488 @@
+package pkcetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// Client provides the interface a PKCE client implementation under test must
+// satisfy to be driven by the client conformance suite.
+type Client interface {
+	// Authorize performs an authorization request against the server,
+	// returning the authorization code issued in the redirect.
+	//
+	// Clients may discover the server's supported methods from the metadata
+	// published at the server's MetadataURL.
+	Authorize(ctx context.Context, server *Server) (code string, err error)
+
+	// Exchange performs a token request against the server, exchanging the
+	// authorization code along with the flow's code verifier.
+	Exchange(ctx context.Context, server *Server, code string) error
+}
+
+// ClientScenario provides a server behaviour a PKCE client is tested against.
+type ClientScenario struct {
+	// Name provides the name of the scenario.
+	Name string
+
+	opts         []ServerOption
+	wantExchange bool
+}
+
+// ClientScenarios returns the scenarios run by RunClientConformance.
+func ClientScenarios() []ClientScenario {
+	return []ClientScenario{
+		{
+			Name: "S256 only",
+			opts: []ServerOption{
+				WithMetadataMethods(pkce.S256),
+				WithManagerOptions(pkce.WithManagerMethods(pkce.S256)),
+			},
+			wantExchange: true,
+		},
+		{
+			// RFC 7636, 4.2. Clients capable of using S256 must use S256,
+			// even if the server advertises plain.
+			Name: "plain only",
+			opts: []ServerOption{
+				WithMetadataMethods(pkce.Plain),
+				WithManagerOptions(pkce.WithManagerMethods(pkce.Plain)),
+			},
+		},
+		{
+			// RFC 7636, 7.2. Clients must not downgrade to plain after
+			// trying S256, as the error is likely a MITM downgrade attack.
+			Name: "downgrade attempt",
+			opts: []ServerOption{
+				WithMetadataMethods(pkce.S256, pkce.Plain),
+				WithManagerOptions(pkce.WithManagerMethods(pkce.Plain)),
+			},
+		},
+		{
+			Name: "malformed metadata",
+			opts: []ServerOption{
+				WithRawMetadataMethods(json.RawMessage(`"S256 plain"`)),
+			},
+			wantExchange: true,
+		},
+		{
+			Name: "missing metadata",
+			opts: []ServerOption{
+				WithRawMetadataMethods(json.RawMessage(`null`)),
+			},
+			wantExchange: true,
+		},
+	}
+}
+
+// Run runs the scenario against the client, returning an error describing
+// the first compliance failure found.
+func (sc ClientScenario) Run(ctx context.Context, client Client) error {
+	s := NewServer(sc.opts...)
+	defer s.Close()
+
+	code, err := client.Authorize(ctx, s)
+	if err == nil && code != "" {
+		err = client.Exchange(ctx, s, code)
+	}
+
+	if sc.wantExchange && err != nil {
+		return fmt.Errorf("the code exchange should succeed: %v", err)
+	}
+
+	requests := s.AuthorizationRequests()
+	if len(requests) == 0 {
+		return errors.New("no authorization request was received")
+	}
+
+	challenges := map[string]bool{}
+	for _, params := range requests {
+		method := pkce.Method(params.Get(pkce.ParamCodeChallengeMethod))
+		if method != pkce.S256 {
+			return fmt.Errorf("authorization requests must use the S256 method, got %q", method)
+		}
+
+		challenge := params.Get(pkce.ParamCodeChallenge)
+		if _, err := pkce.New(pkce.WithCodeChallenge(challenge)); err != nil {
+			return fmt.Errorf("authorization requests must contain a compliant code challenge: %v", err)
+		}
+
+		// each flow must use a fresh code verifier.
+		if challenges[challenge] {
+			return errors.New("authorization requests must not reuse a code challenge")
+		}
+		challenges[challenge] = true
+	}
+
+	if !sc.wantExchange && err == nil {
+		return errors.New("the flow should fail, but the client reported success")
+	}
+
+	return nil
+}
+
+// RunClientConformance runs every client scenario as a subtest, reporting
+// each scenario the client is not compliant with. newClient is called to
+// provide a fresh client for each scenario.
+func RunClientConformance(t *testing.T, newClient func() Client) {
+	for _, sc := range ClientScenarios() {
+		sc := sc
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := sc.Run(context.Background(), newClient()); err != nil {
+				t.Errorf("client is not compliant: %v", err)
+			}
+		})
+	}
+}
+
+// ServerConfig provides the configuration of an authorization server under
+// test by the server conformance suite.
+type ServerConfig struct {
+	// AuthorizePath provides the path of the authorization endpoint.
+	// Defaults to AuthorizePath.
+	AuthorizePath string
+	// TokenPath provides the path of the token endpoint. Defaults to
+	// TokenPath.
+	TokenPath string
+	// ClientID provides the client identifier sent in requests. Defaults to
+	// "client".
+	ClientID string
+	// RedirectURI provides the registered redirect uri sent in requests.
+	// Defaults to "https://client.example.com/cb".
+	RedirectURI string
+	// AllowPlain specifies the server is expected to support the plain
+	// method. Otherwise, plain code challenges are expected to be rejected.
+	AllowPlain bool
+	// LenientChallengeEncoding specifies the server is expected to normalize
+	// padded S256 code challenges. Otherwise, they are expected to be
+	// rejected.
+	LenientChallengeEncoding bool
+}
+
+// withDefaults returns the config with unset fields defaulted.
+func (c ServerConfig) withDefaults() ServerConfig {
+	if c.AuthorizePath == "" {
+		c.AuthorizePath = AuthorizePath
+	}
+	if c.TokenPath == "" {
+		c.TokenPath = TokenPath
+	}
+	if c.ClientID == "" {
+		c.ClientID = "client"
+	}
+	if c.RedirectURI == "" {
+		c.RedirectURI = "https://client.example.com/cb"
+	}
+
+	return c
+}
+
+// ServerScenario provides a combination of authorization and token requests
+// an authorization server is tested with.
+type ServerScenario struct {
+	// Name provides the name of the scenario.
+	Name string
+
+	run func(h *serverHarness) error
+}
+
+// ServerScenarios returns the scenarios run by RunServerConformance.
+func ServerScenarios() []ServerScenario {
+	return []ServerScenario{
+		{
+			Name: "S256 code verifier",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.S256)
+				code, err := h.authorizeKey(key)
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeVerifier(), "")
+			},
+		},
+		{
+			Name: "plain code verifier",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.Plain)
+				code, err := h.authorizeKey(key)
+				if !h.config.AllowPlain {
+					return wantAuthorizeError(err)
+				}
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeVerifier(), "")
+			},
+		},
+		{
+			// RFC 7636, 4.3. The method defaults to plain if not present.
+			Name: "missing code challenge method",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.Plain)
+				code, err := h.authorize(url.Values{
+					pkce.ParamCodeChallenge: {key.CodeChallenge()},
+				})
+				if !h.config.AllowPlain {
+					return wantAuthorizeError(err)
+				}
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeVerifier(), "")
+			},
+		},
+		{
+			// RFC 7636, 4.4.1. Servers requiring PKCE must reject requests
+			// without a code challenge.
+			Name: "missing code challenge",
+			run: func(h *serverHarness) error {
+				_, err := h.authorize(url.Values{})
+				return wantAuthorizeError(err)
+			},
+		},
+		{
+			// RFC 7636, 4.2. S256 code challenges are base64url-encoded
+			// without padding.
+			Name: "padded code challenge",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.S256)
+				code, err := h.authorize(url.Values{
+					pkce.ParamCodeChallenge:       {key.CodeChallenge() + "="},
+					pkce.ParamCodeChallengeMethod: {pkce.S256.String()},
+				})
+				if !h.config.LenientChallengeEncoding {
+					return wantAuthorizeError(err)
+				}
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeVerifier(), "")
+			},
+		},
+		{
+			// RFC 7636, 4.6. Mismatched code verifiers are rejected with
+			// invalid_grant.
+			Name: "wrong code verifier",
+			run: func(h *serverHarness) error {
+				code, err := h.authorizeKey(h.key(pkce.S256))
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, h.key(pkce.S256).CodeVerifier(), "invalid_grant")
+			},
+		},
+		{
+			// RFC 7636, 7.2. The S256 code challenge presented as a plain
+			// code verifier must be rejected.
+			Name: "downgraded code verifier",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.S256)
+				code, err := h.authorizeKey(key)
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeChallenge(), "invalid_grant")
+			},
+		},
+		{
+			Name: "missing code verifier",
+			run: func(h *serverHarness) error {
+				code, err := h.authorizeKey(h.key(pkce.S256))
+				if err != nil {
+					return err
+				}
+
+				return h.wantToken(code, "", "invalid_request", "invalid_grant")
+			},
+		},
+		{
+			// RFC 6749, 4.1.2. Authorization codes must only be used once.
+			Name: "reused code verifier",
+			run: func(h *serverHarness) error {
+				key := h.key(pkce.S256)
+				code, err := h.authorizeKey(key)
+				if err != nil {
+					return err
+				}
+
+				if err = h.wantToken(code, key.CodeVerifier(), ""); err != nil {
+					return err
+				}
+
+				return h.wantToken(code, key.CodeVerifier(), "invalid_grant")
+			},
+		},
+	}
+}
+
+// Run runs the scenario against the authorization server handler, returning
+// an error describing the first compliance failure found.
+func (sc ServerScenario) Run(handler http.Handler, config ServerConfig) error {
+	return sc.run(&serverHarness{
+		handler: handler,
+		config:  config.withDefaults(),
+	})
+}
+
+// RunServerConformance runs every server scenario as a subtest against the
+// authorization server handler, reporting each scenario the server is not
+// compliant with.
+func RunServerConformance(t *testing.T, handler http.Handler, config ServerConfig) {
+	for _, sc := range ServerScenarios() {
+		sc := sc
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := sc.Run(handler, config); err != nil {
+				t.Errorf("server is not compliant: %v", err)
+			}
+		})
+	}
+}
+
+// authorizeError is returned when an authorization request is rejected.
+type authorizeError struct {
+	code string
+}
+
+func (e *authorizeError) Error() string {
+	return "authorization request rejected: " + e.code
+}
+
+// wantAuthorizeError returns an error unless err is an invalid_request
+// authorization error.
+func wantAuthorizeError(err error) error {
+	if e, ok := err.(*authorizeError); ok && e.code == "invalid_request" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("the authorization request should be rejected with invalid_request: %v", err)
+	}
+
+	return errors.New("the authorization request should be rejected with invalid_request")
+}
+
+// serverHarness performs requests against an authorization server handler.
+type serverHarness struct {
+	handler http.Handler
+	config  ServerConfig
+}
+
+// key returns a new proof key using method.
+func (h *serverHarness) key(method pkce.Method) *pkce.Key {
+	key, _ := pkce.New(pkce.WithChallengeMethod(method))
+
+	return key
+}
+
+// authorizeKey performs an authorization request for key.
+func (h *serverHarness) authorizeKey(key *pkce.Key) (string, error) {
+	return h.authorize(url.Values{
+		pkce.ParamCodeChallenge:       {key.CodeChallenge()},
+		pkce.ParamCodeChallengeMethod: {key.ChallengeMethod().String()},
+	})
+}
+
+// authorize performs an authorization request with params, returning the
+// issued authorization code.
+func (h *serverHarness) authorize(params url.Values) (string, error) {
+	params.Set("response_type", "code")
+	params.Set("client_id", h.config.ClientID)
+	params.Set("redirect_uri", h.config.RedirectURI)
+	params.Set("state", "state")
+
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, h.config.AuthorizePath+"?"+params.Encode(), nil))
+
+	if rec.Code == http.StatusBadRequest {
+		return "", &authorizeError{code: decodeError(rec)}
+	}
+
+	if rec.Code != http.StatusFound && rec.Code != http.StatusSeeOther {
+		return "", fmt.Errorf("authorization response status %d, want a redirect", rec.Code)
+	}
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("authorization response location: %v", err)
+	}
+
+	query := location.Query()
+	if e := query.Get("error"); e != "" {
+		return "", &authorizeError{code: e}
+	}
+
+	if query.Get("state") != "state" {
+		return "", errors.New("authorization response must return the state")
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return "", errors.New("authorization response must contain a code")
+	}
+
+	return code, nil
+}
+
+// wantToken performs a token request, returning an error unless the response
+// is successful, or wantErrors is non-empty and the response is an error
+// response with one of the error codes.
+func (h *serverHarness) wantToken(code string, codeVerifier string, wantErrors ...string) error {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"client_id":    {h.config.ClientID},
+		"redirect_uri": {h.config.RedirectURI},
+	}
+	if codeVerifier != "" {
+		form.Set(pkce.ParamCodeVerifier, codeVerifier)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, h.config.TokenPath, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, r)
+
+	if len(wantErrors) == 0 || (len(wantErrors) == 1 && wantErrors[0] == "") {
+		if rec.Code != http.StatusOK {
+			return fmt.Errorf("token response status %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		return nil
+	}
+
+	if rec.Code != http.StatusBadRequest {
+		return fmt.Errorf("token response status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	got := decodeError(rec)
+	for _, want := range wantErrors {
+		if got == want {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("token response error %q, want one of %q", got, wantErrors)
+}
+
+// decodeError returns the error code of an RFC 6749, 5.2 error response.
+func decodeError(rec *httptest.ResponseRecorder) string {
+	var res errorResponse
+	_ = json.NewDecoder(rec.Body).Decode(&res)
+
+	return res.Error
+}
@@ -0,0 +1,193 @@
+package pkcetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// testClient provides a PKCE client driven by the conformance suite, which
+// optionally retries failed authorization requests using plain.
+type testClient struct {
+	downgrade bool
+	key       *pkce.Key
+}
+
+func (c *testClient) Authorize(ctx context.Context, s *Server) (string, error) {
+	code, err := c.authorize(ctx, s, pkce.S256)
+	if err != nil && c.downgrade {
+		return c.authorize(ctx, s, pkce.Plain)
+	}
+
+	return code, err
+}
+
+func (c *testClient) authorize(ctx context.Context, s *Server, method pkce.Method) (string, error) {
+	key, err := pkce.New(pkce.WithChallengeMethod(method))
+	if err != nil {
+		return "", err
+	}
+	c.key = key
+
+	params := url.Values{
+		"response_type":               {"code"},
+		"client_id":                   {"client"},
+		"redirect_uri":                {"https://client.example.com/cb"},
+		pkce.ParamCodeChallenge:       {key.CodeChallenge()},
+		pkce.ParamCodeChallengeMethod: {key.ChallengeMethod().String()},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.AuthorizeURL()+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+
+	if e := location.Query().Get("error"); e != "" {
+		return "", errors.New(e)
+	}
+
+	return location.Query().Get("code"), nil
+}
+
+func (c *testClient) Exchange(ctx context.Context, s *Server, code string) error {
+	res, err := http.PostForm(s.TokenURL(), url.Values{
+		"grant_type":           {"authorization_code"},
+		"code":                 {code},
+		pkce.ParamCodeVerifier: {c.key.CodeVerifier()},
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request failed with status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func TestRunClientConformance(t *testing.T) {
+	RunClientConformance(t, func() Client {
+		return &testClient{}
+	})
+}
+
+func TestClientScenario_Run(t *testing.T) {
+	for _, sc := range ClientScenarios() {
+		if sc.Name != "downgrade attempt" {
+			continue
+		}
+
+		if err := sc.Run(context.Background(), &testClient{downgrade: true}); err == nil {
+			t.Errorf("Run() should report a client downgrading to plain as non-compliant")
+		}
+	}
+}
+
+func TestRunServerConformance(t *testing.T) {
+	t.Run("plain allowed", func(t *testing.T) {
+		s := NewServer()
+		defer s.Close()
+
+		RunServerConformance(t, s.Config.Handler, ServerConfig{AllowPlain: true})
+	})
+
+	t.Run("S256 only", func(t *testing.T) {
+		s := NewServer(WithManagerOptions(pkce.WithManagerMethods(pkce.S256)))
+		defer s.Close()
+
+		RunServerConformance(t, s.Config.Handler, ServerConfig{})
+	})
+}
+
+// ignoringHandler provides an authorization server which ignores PKCE.
+func ignoringHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == AuthorizePath {
+		http.Redirect(w, r, r.FormValue("redirect_uri")+"?code=code&state="+r.FormValue("state"), http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`))
+}
+
+func TestServerScenario_Run(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		config  ServerConfig
+		wantErr map[string]bool
+	}{
+		{
+			name:    "should pass a compliant server",
+			handler: s.Config.Handler,
+			config:  ServerConfig{AllowPlain: true},
+			wantErr: map[string]bool{},
+		},
+		{
+			name:    "should fail a server expected to reject plain",
+			handler: s.Config.Handler,
+			config:  ServerConfig{},
+			wantErr: map[string]bool{
+				"plain code verifier":           true,
+				"missing code challenge method": true,
+			},
+		},
+		{
+			name:    "should fail a server expected to normalize padded challenges",
+			handler: s.Config.Handler,
+			config:  ServerConfig{AllowPlain: true, LenientChallengeEncoding: true},
+			wantErr: map[string]bool{
+				"padded code challenge": true,
+			},
+		},
+		{
+			name:    "should fail a server ignoring PKCE",
+			handler: http.HandlerFunc(ignoringHandler),
+			config:  ServerConfig{AllowPlain: true, LenientChallengeEncoding: true},
+			wantErr: map[string]bool{
+				"missing code challenge":   true,
+				"wrong code verifier":      true,
+				"downgraded code verifier": true,
+				"missing code verifier":    true,
+				"reused code verifier":     true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, sc := range ServerScenarios() {
+				err := sc.Run(tt.handler, tt.config)
+				if (err != nil) != tt.wantErr[sc.Name] {
+					t.Errorf("Run(%q) error = %v, wantErr %v", sc.Name, err, tt.wantErr[sc.Name])
+				}
+			}
+		})
+	}
+}
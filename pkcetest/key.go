@@ -0,0 +1,44 @@
+package pkcetest
+
+import (
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// The reference code verifier and code challenge specified in RFC 7636,
+// Appendix B, for asserting known-good S256 expectations.
+const (
+	RFC7636CodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	RFC7636CodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// MustKey returns a new proof key configured with opts, failing the test if
+// the key can not be created.
+func MustKey(t testing.TB, opts ...pkce.Option) *pkce.Key {
+	t.Helper()
+
+	key, err := pkce.New(opts...)
+	if err != nil {
+		t.Fatalf("pkce.New() unexpected error: %v", err)
+	}
+
+	return key
+}
+
+// DeterministicKey returns a new proof key configured with opts, generating
+// the same code verifier for the same seed, failing the test if the key can
+// not be created.
+func DeterministicKey(t testing.TB, seed string, opts ...pkce.Option) *pkce.Key {
+	t.Helper()
+
+	return MustKey(t, append(opts, pkce.WithRandReader(NewDeterministicReader(seed)))...)
+}
+
+// RFC7636Key returns an S256 proof key holding the RFC 7636, Appendix B
+// reference code verifier.
+func RFC7636Key(t testing.TB) *pkce.Key {
+	t.Helper()
+
+	return MustKey(t, pkce.WithCodeVerifier([]byte(RFC7636CodeVerifier)))
+}
@@ -0,0 +1,45 @@
+package pkcetest
+
+import (
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestRFC7636Key(t *testing.T) {
+	key := RFC7636Key(t)
+
+	if got := key.CodeChallenge(); got != RFC7636CodeChallenge {
+		t.Errorf("CodeChallenge() = %v, want %v", got, RFC7636CodeChallenge)
+	}
+
+	if !pkce.VerifyCodeVerifier(pkce.S256, RFC7636CodeVerifier, RFC7636CodeChallenge) {
+		t.Errorf("VerifyCodeVerifier() should verify the RFC 7636 reference vector")
+	}
+}
+
+func TestDeterministicKey(t *testing.T) {
+	a := DeterministicKey(t, "seed", pkce.WithCodeVerifierLength(64))
+	b := DeterministicKey(t, "seed", pkce.WithCodeVerifierLength(64))
+	c := DeterministicKey(t, "other", pkce.WithCodeVerifierLength(64))
+
+	if a.CodeVerifier() != b.CodeVerifier() {
+		t.Errorf("DeterministicKey() should generate the same code verifier for the same seed\ngot:  %v\nwant: %v", b.CodeVerifier(), a.CodeVerifier())
+	}
+
+	if a.CodeVerifier() == c.CodeVerifier() {
+		t.Errorf("DeterministicKey() should generate different code verifiers for different seeds")
+	}
+
+	if got := len(a.CodeVerifier()); got != 64 {
+		t.Errorf("DeterministicKey() code verifier length = %v, want %v", got, 64)
+	}
+}
+
+func TestMustKey(t *testing.T) {
+	key := MustKey(t, pkce.WithChallengeMethod(pkce.Plain))
+
+	if key.ChallengeMethod() != pkce.Plain {
+		t.Errorf("ChallengeMethod() = %v, want %v", key.ChallengeMethod(), pkce.Plain)
+	}
+}
@@ -0,0 +1,46 @@
+package pkcetest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+)
+
+// deterministicReader provides a reproducible stream of pseudo-random bytes,
+// computed as SHA-256(seed || counter) for an incrementing counter.
+type deterministicReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+// NewDeterministicReader returns a reader producing the same pseudo-random
+// stream of bytes for the same seed, for use with pkce.WithRandReader to
+// generate reproducible code verifiers in tests.
+//
+// The stream is predictable, and must never be used outside of tests.
+func NewDeterministicReader(seed string) io.Reader {
+	return &deterministicReader{
+		seed: []byte(seed),
+	}
+}
+
+// Read implements io.Reader. Read never returns an error.
+func (r *deterministicReader) Read(p []byte) (n int, err error) {
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], r.counter)
+			r.counter++
+
+			block := sha256.Sum256(append(append([]byte(nil), r.seed...), counter[:]...))
+			r.buf = block[:]
+		}
+
+		copied := copy(p[n:], r.buf)
+		r.buf = r.buf[copied:]
+		n += copied
+	}
+
+	return n, nil
+}
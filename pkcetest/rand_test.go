@@ -0,0 +1,38 @@
+package pkcetest
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNewDeterministicReader(t *testing.T) {
+	read := func(seed string, n int) []byte {
+		out := make([]byte, n)
+		if _, err := io.ReadFull(NewDeterministicReader(seed), out); err != nil {
+			t.Fatalf("ReadFull() unexpected error: %v", err)
+		}
+
+		return out
+	}
+
+	if !bytes.Equal(read("seed", 100), read("seed", 100)) {
+		t.Errorf("NewDeterministicReader() should produce the same stream for the same seed")
+	}
+
+	if bytes.Equal(read("seed", 100), read("other", 100)) {
+		t.Errorf("NewDeterministicReader() should produce different streams for different seeds")
+	}
+
+	// reads spanning blocks must be consistent with the stream.
+	r := NewDeterministicReader("seed")
+	chunked := make([]byte, 0, 100)
+	for len(chunked) < 100 {
+		chunk := make([]byte, 7)
+		n, _ := r.Read(chunk)
+		chunked = append(chunked, chunk[:n]...)
+	}
+	if !bytes.Equal(chunked[:100], read("seed", 100)) {
+		t.Errorf("NewDeterministicReader() should not depend on read sizes")
+	}
+}
@@ -0,0 +1,221 @@
+// Package pkcetest provides utilities for testing PKCE flows, such as a fake
+// authorization server which enforces RFC 7636.
+package pkcetest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	// AuthorizePath provides the path of the authorization endpoint.
+	AuthorizePath = "/authorize"
+	// TokenPath provides the path of the token endpoint.
+	TokenPath = "/token"
+	// MetadataPath provides the path of the RFC 8414 authorization server
+	// metadata endpoint.
+	MetadataPath = "/.well-known/oauth-authorization-server"
+)
+
+// Server provides a fake OAuth 2.0 authorization server, exposing an
+// authorization endpoint and a token endpoint which faithfully enforce PKCE.
+//
+// Authorization requests are always approved, and redirect to the requested
+// redirect uri with a freshly issued authorization code. Token requests must
+// present a code verifier matching the registered code challenge, and are
+// issued an opaque access token.
+type Server struct {
+	*httptest.Server
+
+	// Manager provides the key manager used to register code challenges and
+	// verify code verifiers.
+	Manager *pkce.KeyManager
+
+	managerOpts []pkce.ManagerOption
+	methods     json.RawMessage
+
+	mu       sync.Mutex
+	requests []url.Values
+}
+
+// ServerOption enables variadic fake authorization server options to be
+// configured.
+type ServerOption func(*Server)
+
+// WithManagerOptions enables configuring the key manager enforcing PKCE, such
+// as restricting the allowed methods.
+func WithManagerOptions(opts ...pkce.ManagerOption) ServerOption {
+	return func(s *Server) {
+		s.managerOpts = append(s.managerOpts, opts...)
+	}
+}
+
+// WithMetadataMethods enables specifying the code challenge methods
+// advertised in the server's metadata. Defaults to "S256" and "plain".
+func WithMetadataMethods(methods ...pkce.Method) ServerOption {
+	return func(s *Server) {
+		s.methods, _ = json.Marshal(methods)
+	}
+}
+
+// WithRawMetadataMethods enables specifying the raw JSON value advertised as
+// the server's code_challenge_methods_supported metadata, for testing
+// clients against malformed metadata. The value must be valid JSON.
+func WithRawMetadataMethods(methods json.RawMessage) ServerOption {
+	return func(s *Server) {
+		s.methods = methods
+	}
+}
+
+// NewServer starts and returns a new fake authorization server. Callers
+// should call Close when finished, to shut it down.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		methods: json.RawMessage(`["S256","plain"]`),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.Manager = pkce.NewKeyManager(pkce.NewMemoryStore(), s.managerOpts...)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(AuthorizePath, s.authorize)
+	mux.HandleFunc(MetadataPath, s.metadata)
+	mux.Handle(TokenPath, s.Manager.Middleware(http.HandlerFunc(token)))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// AuthorizationRequests returns the parameters of every authorization request
+// received, in order.
+func (s *Server) AuthorizationRequests() []url.Values {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]url.Values(nil), s.requests...)
+}
+
+// MetadataURL returns the url of the authorization server metadata endpoint.
+func (s *Server) MetadataURL() string {
+	return s.URL + MetadataPath
+}
+
+// AuthorizeURL returns the url of the authorization endpoint.
+func (s *Server) AuthorizeURL() string {
+	return s.URL + AuthorizePath
+}
+
+// TokenURL returns the url of the token endpoint.
+func (s *Server) TokenURL() string {
+	return s.URL + TokenPath
+}
+
+// authorize handles authorization requests, registering the code challenge
+// under a newly issued authorization code.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err == nil {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Form)
+		s.mu.Unlock()
+	}
+
+	redirectURI, err := url.Parse(r.FormValue("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		writeError(w, http.StatusBadRequest, "invalid_request", "redirect_uri must be an absolute uri")
+		return
+	}
+
+	query := redirectURI.Query()
+	if state := r.FormValue("state"); state != "" {
+		query.Set("state", state)
+	}
+
+	req, err := pkce.ParseAuthorizationRequest(r)
+	if err == nil {
+		code := randomString()
+		if err = s.Manager.Register(r.Context(), code, req.CodeChallengeMethod, req.CodeChallenge); err == nil {
+			query.Set("code", code)
+		}
+	}
+
+	if err != nil {
+		// RFC 7636, 4.4.1. Error Response.
+		query.Set("error", "invalid_request")
+		query.Set("error_description", err.Error())
+	}
+
+	redirectURI.RawQuery = query.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+// metadata handles RFC 8414 authorization server metadata requests.
+func (s *Server) metadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                           s.URL,
+		"authorization_endpoint":           s.AuthorizeURL(),
+		"token_endpoint":                   s.TokenURL(),
+		"response_types_supported":         []string{"code"},
+		"code_challenge_methods_supported": s.methods,
+	})
+}
+
+// tokenResponse provides an RFC 6749, 5.1 successful token response.
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// token handles token requests which have had their code verifier verified.
+func token(w http.ResponseWriter, r *http.Request) {
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", "only the authorization_code grant is supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	_ = json.NewEncoder(w).Encode(tokenResponse{
+		AccessToken: randomString(),
+		TokenType:   "Bearer",
+		ExpiresIn:   3600,
+	})
+}
+
+// errorResponse provides an RFC 6749, 5.2 error response.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeError writes an RFC 6749, 5.2 error response.
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+// randomString returns a random, url safe, opaque string.
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
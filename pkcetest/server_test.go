@@ -0,0 +1,178 @@
+package pkcetest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// authorize performs an authorization request, returning the redirect query.
+func authorize(t *testing.T, s *Server, params url.Values) url.Values {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	params.Set("response_type", "code")
+	params.Set("client_id", "client")
+	params.Set("redirect_uri", "https://client.example.com/cb")
+	params.Set("state", "state")
+
+	res, err := client.Get(s.AuthorizeURL() + "?" + params.Encode())
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status = %v, want %v", res.StatusCode, http.StatusFound)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	query := location.Query()
+	if got := query.Get("state"); got != "state" {
+		t.Errorf("authorize state = %v, want %v", got, "state")
+	}
+
+	return query
+}
+
+// exchange performs a token request, returning the response status and error
+// code.
+func exchange(t *testing.T, s *Server, code string, codeVerifier string) (int, string) {
+	res, err := http.PostForm(s.TokenURL(), url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {"https://client.example.com/cb"},
+		"client_id":     {"client"},
+		"code_verifier": {codeVerifier},
+	})
+	if err != nil {
+		t.Fatalf("PostForm() unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		Error       string `json:"error"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatalf("Decode() unexpected error: %v", err)
+	}
+
+	if res.StatusCode == http.StatusOK && body.AccessToken == "" {
+		t.Errorf("token response should contain an access token")
+	}
+
+	return res.StatusCode, body.Error
+}
+
+func TestServer(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []ServerOption
+		method     pkce.Method
+		wrongProof bool
+		wantError  string
+		wantStatus int
+	}{
+		{
+			name:       "should exchange an S256 code verifier",
+			method:     pkce.S256,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should exchange a plain code verifier",
+			method:     pkce.Plain,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should reject a mismatched code verifier",
+			method:     pkce.S256,
+			wrongProof: true,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "invalid_grant",
+		},
+		{
+			name:      "should reject a disallowed method",
+			opts:      []ServerOption{WithManagerOptions(pkce.WithManagerMethods(pkce.S256))},
+			method:    pkce.Plain,
+			wantError: "invalid_request",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(tt.opts...)
+			defer s.Close()
+
+			key, err := pkce.New(pkce.WithChallengeMethod(tt.method))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			query := authorize(t, s, url.Values{
+				"code_challenge":        {key.CodeChallenge()},
+				"code_challenge_method": {key.ChallengeMethod().String()},
+			})
+			if tt.wantStatus == 0 {
+				if got := query.Get("error"); got != tt.wantError {
+					t.Errorf("authorize error = %v, want %v", got, tt.wantError)
+				}
+				return
+			}
+
+			codeVerifier := key.CodeVerifier()
+			if tt.wrongProof {
+				codeVerifier = strings.Repeat("a", len(codeVerifier))
+			}
+
+			status, code := exchange(t, s, query.Get("code"), codeVerifier)
+			if status != tt.wantStatus || code != tt.wantError {
+				t.Errorf("token response = %v %v, want %v %v", status, code, tt.wantStatus, tt.wantError)
+			}
+
+			// authorization codes must only be exchanged once.
+			if status, _ = exchange(t, s, query.Get("code"), key.CodeVerifier()); status != http.StatusBadRequest {
+				t.Errorf("token replay status = %v, want %v", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestServer_missingCodeChallenge(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	query := authorize(t, s, url.Values{})
+	if got := query.Get("error"); got != "invalid_request" {
+		t.Errorf("authorize error = %v, want %v", got, "invalid_request")
+	}
+	if query.Get("code") != "" {
+		t.Errorf("authorize should not issue a code without a code challenge")
+	}
+}
+
+func TestServer_unsupportedGrantType(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+
+	res, err := http.PostForm(s.TokenURL(), url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		t.Fatalf("PostForm() unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("token status = %v, want %v", res.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,108 @@
+package pkcetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// unreservedCharset provides every character permitted in a code verifier as
+// specified in RFC 7636, 4.1.
+const unreservedCharset = pkce.AlphanumericCharset + "-._~"
+
+// Vector provides a code verifier, transform method and code challenge
+// triple, for validating other PKCE implementations against this package.
+type Vector struct {
+	// Name describes the edge case the vector exercises.
+	Name string `json:"name"`
+	// Method provides the code challenge transform method.
+	Method pkce.Method `json:"method"`
+	// CodeVerifier provides the code verifier.
+	CodeVerifier string `json:"code_verifier"`
+	// CodeChallenge provides the code challenge expected for the code
+	// verifier and method.
+	CodeChallenge string `json:"code_challenge"`
+}
+
+// vectorInput provides the code verifier of a vector before transformation.
+type vectorInput struct {
+	name         string
+	codeVerifier string
+}
+
+// Vectors returns test vectors covering the RFC 7636, Appendix B reference
+// vector, the minimum and maximum code verifier lengths, and every unreserved
+// character, for each transform method. The vectors are the same on every
+// call.
+func Vectors() ([]Vector, error) {
+	inputs := []vectorInput{
+		{name: "rfc 7636 appendix b", codeVerifier: RFC7636CodeVerifier},
+		{name: "all unreserved characters", codeVerifier: unreservedCharset},
+		{name: "unreserved symbols", codeVerifier: strings.Repeat("-._~", 11)},
+	}
+
+	for _, n := range []int{43, 64, 128} {
+		inputs = append(inputs,
+			vectorInput{
+				name:         fmt.Sprintf("length %d", n),
+				codeVerifier: deterministicVerifier(unreservedCharset, n),
+			},
+			vectorInput{
+				name:         fmt.Sprintf("alphanumeric length %d", n),
+				codeVerifier: deterministicVerifier(pkce.AlphanumericCharset, n),
+			},
+		)
+	}
+
+	vectors := make([]Vector, 0, 2*len(inputs))
+	for _, method := range []pkce.Method{pkce.S256, pkce.Plain} {
+		for _, in := range inputs {
+			key, err := pkce.New(
+				pkce.WithChallengeMethod(method),
+				pkce.WithCodeVerifier([]byte(in.codeVerifier)),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("vector %q: %v", in.name, err)
+			}
+
+			vectors = append(vectors, Vector{
+				Name:          in.name,
+				Method:        method,
+				CodeVerifier:  key.CodeVerifier(),
+				CodeChallenge: key.CodeChallenge(),
+			})
+		}
+	}
+
+	return vectors, nil
+}
+
+// WriteVectors writes the test vectors to w as an indented JSON array.
+func WriteVectors(w io.Writer) error {
+	vectors, err := Vectors()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(vectors)
+}
+
+// deterministicVerifier returns a reproducible code verifier of length n,
+// seeded by the charset and length.
+func deterministicVerifier(charset string, n int) string {
+	r := NewDeterministicReader(fmt.Sprintf("%s:%d", charset, n))
+
+	buf := make([]byte, n)
+	_, _ = io.ReadFull(r, buf)
+	for i, b := range buf {
+		buf[i] = charset[int(b)%len(charset)]
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,65 @@
+package pkcetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestVectors(t *testing.T) {
+	vectors, err := Vectors()
+	if err != nil {
+		t.Fatalf("Vectors() unexpected error: %v", err)
+	}
+
+	again, _ := Vectors()
+	if !reflect.DeepEqual(vectors, again) {
+		t.Error("Vectors() should return the same vectors on every call")
+	}
+
+	var (
+		rfc7636 bool
+		chars   string
+	)
+	for _, v := range vectors {
+		if !pkce.VerifyCodeVerifier(v.Method, v.CodeVerifier, v.CodeChallenge) {
+			t.Errorf("Vectors() %s %q code challenge does not verify", v.Method, v.Name)
+		}
+
+		if v.Method == pkce.S256 && v.CodeVerifier == RFC7636CodeVerifier && v.CodeChallenge == RFC7636CodeChallenge {
+			rfc7636 = true
+		}
+		chars += v.CodeVerifier
+	}
+
+	if !rfc7636 {
+		t.Error("Vectors() should contain the RFC 7636, Appendix B vector")
+	}
+
+	for _, c := range unreservedCharset {
+		if !strings.ContainsRune(chars, c) {
+			t.Errorf("Vectors() should contain the unreserved character %q", c)
+		}
+	}
+}
+
+func TestWriteVectors(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVectors(&buf); err != nil {
+		t.Fatalf("WriteVectors() unexpected error: %v", err)
+	}
+
+	var got []Vector
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteVectors() should write JSON: %v", err)
+	}
+
+	want, _ := Vectors()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WriteVectors()\ngot:  %+v\nwant: %+v\n", got, want)
+	}
+}
@@ -0,0 +1,335 @@
+package pkce
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by PolicyFromEnv.
+const (
+	EnvRequiredMethod            = "PKCE_REQUIRED_METHOD"
+	EnvAllowedMethods            = "PKCE_ALLOWED_METHODS"
+	EnvMinVerifierLength         = "PKCE_MIN_VERIFIER_LENGTH"
+	EnvMaxVerifierLength         = "PKCE_MAX_VERIFIER_LENGTH"
+	EnvMinVerifierEntropy        = "PKCE_MIN_VERIFIER_ENTROPY"
+	EnvKeyTTL                    = "PKCE_KEY_TTL"
+	EnvLenientChallengeEncoding  = "PKCE_LENIENT_CHALLENGE_ENCODING"
+	EnvLenientMethodCase         = "PKCE_LENIENT_METHOD_CASE"
+	EnvLenientVerifierEncoding   = "PKCE_LENIENT_VERIFIER_ENCODING"
+	EnvLenientVerifierWhitespace = "PKCE_LENIENT_VERIFIER_WHITESPACE"
+)
+
+// Policy provides a declarative configuration of server-side PKCE behaviour,
+// enabling servers to configure PKCE without code changes.
+//
+// The zero value specifies the defaults of KeyManager and request parsing.
+type Policy struct {
+	// RequiredMethod, if set, requires all code challenges use the method,
+	// such as S256.
+	RequiredMethod Method `json:"required_method"`
+	// AllowedMethods, if set, restricts the code challenge methods accepted.
+	AllowedMethods []Method `json:"allowed_methods"`
+	// MinVerifierLength, if set, narrows the minimum code verifier length
+	// accepted, both when parsing token requests and during verification.
+	MinVerifierLength int `json:"min_verifier_length"`
+	// MaxVerifierLength, if set, narrows the maximum code verifier length
+	// accepted, both when parsing token requests and during verification.
+	MaxVerifierLength int `json:"max_verifier_length"`
+	// MinVerifierEntropy, if set, rejects code verifiers with an estimated
+	// entropy below the number of bits.
+	MinVerifierEntropy float64 `json:"min_verifier_entropy"`
+	// KeyTTL, if set, specifies the duration a registered key is valid for.
+	// Encoded in JSON as a duration string, such as "10m".
+	KeyTTL time.Duration `json:"-"`
+	// LenientChallengeEncoding enables WithLenientChallengeEncoding.
+	LenientChallengeEncoding bool `json:"lenient_challenge_encoding"`
+	// LenientMethodCase enables WithLenientMethodCase.
+	LenientMethodCase bool `json:"lenient_method_case"`
+	// LenientVerifierEncoding enables WithLenientVerifierEncoding.
+	LenientVerifierEncoding bool `json:"lenient_verifier_encoding"`
+	// LenientVerifierWhitespace enables WithLenientVerifierWhitespace.
+	LenientVerifierWhitespace bool `json:"lenient_verifier_whitespace"`
+}
+
+// PolicyFromEnv loads a policy from the PKCE_* environment variables. Lists
+// of methods are comma separated, and durations are specified as a duration
+// string, such as "10m".
+func PolicyFromEnv() (Policy, error) {
+	return policyFromLookup(os.LookupEnv)
+}
+
+// PolicyFromJSON loads a policy from a JSON document. Unknown fields are
+// rejected, ensuring misspelt configuration is not silently ignored.
+func PolicyFromJSON(data []byte) (Policy, error) {
+	type policyJSON Policy
+	doc := struct {
+		*policyJSON
+		// shadowed, as an empty method otherwise decodes as "plain".
+		RequiredMethod string `json:"required_method"`
+		KeyTTL         string `json:"key_ttl"`
+	}{}
+
+	policy := Policy{}
+	doc.policyJSON = (*policyJSON)(&policy)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		return Policy{}, err
+	}
+
+	if doc.RequiredMethod != "" {
+		if err := policy.RequiredMethod.UnmarshalText([]byte(doc.RequiredMethod)); err != nil {
+			return Policy{}, fmt.Errorf("required_method: %v", err)
+		}
+	}
+
+	if doc.KeyTTL != "" {
+		ttl, err := time.ParseDuration(doc.KeyTTL)
+		if err != nil {
+			return Policy{}, fmt.Errorf("key_ttl: %v", err)
+		}
+		policy.KeyTTL = ttl
+	}
+
+	return policy, policy.Validate()
+}
+
+// policyFromLookup loads a policy from environment variables using lookup.
+func policyFromLookup(lookup func(key string) (string, bool)) (Policy, error) {
+	policy := Policy{}
+
+	if v, ok := lookup(EnvRequiredMethod); ok && v != "" {
+		if err := policy.RequiredMethod.UnmarshalText([]byte(v)); err != nil {
+			return Policy{}, fmt.Errorf("%s: %v", EnvRequiredMethod, err)
+		}
+	}
+
+	if v, ok := lookup(EnvAllowedMethods); ok && v != "" {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+
+			var method Method
+			if err := method.UnmarshalText([]byte(s)); err != nil {
+				return Policy{}, fmt.Errorf("%s: %v", EnvAllowedMethods, err)
+			}
+			policy.AllowedMethods = append(policy.AllowedMethods, method)
+		}
+	}
+
+	ints := []struct {
+		env string
+		v   *int
+	}{
+		{env: EnvMinVerifierLength, v: &policy.MinVerifierLength},
+		{env: EnvMaxVerifierLength, v: &policy.MaxVerifierLength},
+	}
+	for _, field := range ints {
+		if v, ok := lookup(field.env); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Policy{}, fmt.Errorf("%s: %v", field.env, err)
+			}
+			*field.v = n
+		}
+	}
+
+	if v, ok := lookup(EnvMinVerifierEntropy); ok {
+		bits, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Policy{}, fmt.Errorf("%s: %v", EnvMinVerifierEntropy, err)
+		}
+		policy.MinVerifierEntropy = bits
+	}
+
+	if v, ok := lookup(EnvKeyTTL); ok {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return Policy{}, fmt.Errorf("%s: %v", EnvKeyTTL, err)
+		}
+		policy.KeyTTL = ttl
+	}
+
+	bools := []struct {
+		env string
+		v   *bool
+	}{
+		{env: EnvLenientChallengeEncoding, v: &policy.LenientChallengeEncoding},
+		{env: EnvLenientMethodCase, v: &policy.LenientMethodCase},
+		{env: EnvLenientVerifierEncoding, v: &policy.LenientVerifierEncoding},
+		{env: EnvLenientVerifierWhitespace, v: &policy.LenientVerifierWhitespace},
+	}
+	for _, field := range bools {
+		if v, ok := lookup(field.env); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return Policy{}, fmt.Errorf("%s: %v", field.env, err)
+			}
+			*field.v = b
+		}
+	}
+
+	return policy, policy.Validate()
+}
+
+// Validate ensures the policy is specification compliant and internally
+// consistent.
+func (p Policy) Validate() error {
+	for _, method := range append([]Method{p.RequiredMethod}, p.AllowedMethods...) {
+		if method != "" && !isKeyMethod(method) {
+			return ErrMethodNotSupported
+		}
+	}
+
+	if p.RequiredMethod != "" && len(p.AllowedMethods) > 0 && !containsMethod(p.AllowedMethods, p.RequiredMethod) {
+		return ErrPolicyInvalid
+	}
+
+	minLen, maxLen := p.verifierLength()
+	if validateVerifierLen(minLen) != nil || validateVerifierLen(maxLen) != nil {
+		return ErrVerifierLength
+	}
+	if minLen > maxLen {
+		return ErrPolicyInvalid
+	}
+
+	if p.KeyTTL < 0 {
+		return ErrExpiryInvalid
+	}
+
+	if p.MinVerifierEntropy < 0 {
+		return ErrPolicyInvalid
+	}
+
+	return nil
+}
+
+// ManagerOptions returns the KeyManager options enforcing the policy.
+func (p Policy) ManagerOptions() []ManagerOption {
+	var opts []ManagerOption
+
+	switch {
+	case p.RequiredMethod != "":
+		opts = append(opts, WithManagerMethods(p.RequiredMethod))
+	case len(p.AllowedMethods) > 0:
+		opts = append(opts, WithManagerMethods(p.AllowedMethods...))
+	}
+
+	minLen, maxLen := p.verifierLength()
+	opts = append(opts, WithManagerVerifierLength(minLen, maxLen))
+
+	if p.MinVerifierEntropy > 0 {
+		opts = append(opts, WithManagerMinVerifierEntropy(p.MinVerifierEntropy))
+	}
+
+	if p.KeyTTL > 0 {
+		opts = append(opts, WithManagerTTL(p.KeyTTL))
+	}
+
+	return opts
+}
+
+// ParseOptions returns the request parsing options enforcing the policy.
+func (p Policy) ParseOptions() []ParseOption {
+	var opts []ParseOption
+	if p.LenientChallengeEncoding {
+		opts = append(opts, WithLenientChallengeEncoding())
+	}
+	if p.LenientMethodCase {
+		opts = append(opts, WithLenientMethodCase())
+	}
+	if p.LenientVerifierEncoding {
+		opts = append(opts, WithLenientVerifierEncoding())
+	}
+	if p.LenientVerifierWhitespace {
+		opts = append(opts, WithLenientVerifierWhitespace())
+	}
+	if p.MinVerifierLength != 0 || p.MaxVerifierLength != 0 {
+		opts = append(opts, WithVerifierLength(p.verifierLength()))
+	}
+
+	return opts
+}
+
+// MetadataCodeChallengeMethodsSupported provides the RFC 8414 authorization
+// server metadata field advertising the supported code challenge methods.
+const MetadataCodeChallengeMethodsSupported = "code_challenge_methods_supported"
+
+// Metadata returns the RFC 8414 authorization server metadata fields
+// advertising the policy, keeping advertised and enforced behaviour in sync.
+func (p Policy) Metadata() map[string]interface{} {
+	return p.MergeMetadata(nil)
+}
+
+// MergeMetadata merges the RFC 8414 authorization server metadata fields
+// advertising the policy into metadata, replacing any existing values, and
+// returns it. A new map is returned if metadata is nil.
+func (p Policy) MergeMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+
+	metadata[MetadataCodeChallengeMethodsSupported] = p.SupportedMethods()
+
+	return metadata
+}
+
+// SupportedMethods returns the code challenge methods accepted under the
+// policy, in order of preference, such as for advertising
+// code_challenge_methods_supported in authorization server metadata.
+func (p Policy) SupportedMethods() []Method {
+	switch {
+	case p.RequiredMethod != "":
+		return filterMethods([]Method{p.RequiredMethod})
+	case len(p.AllowedMethods) > 0:
+		return filterMethods(p.AllowedMethods)
+	default:
+		return SupportedMethods()
+	}
+}
+
+// verifierLength returns the code verifier length bounds, defaulting to the
+// bounds specified in RFC 7636, 4.1.
+func (p Policy) verifierLength() (minLen int, maxLen int) {
+	minLen, maxLen = verifierMinLen, verifierMaxLen
+	if p.MinVerifierLength != 0 {
+		minLen = p.MinVerifierLength
+	}
+	if p.MaxVerifierLength != 0 {
+		maxLen = p.MaxVerifierLength
+	}
+
+	return minLen, maxLen
+}
+
+// containsMethod returns whether methods contains method.
+func containsMethod(methods []Method, method Method) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterMethods returns the supported methods contained in methods, in order
+// of preference.
+func filterMethods(methods []Method) []Method {
+	var out []Method
+	for _, method := range keyMethods() {
+		if containsMethod(methods, method) {
+			out = append(out, method)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,329 @@
+package pkce
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyFromJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Policy
+		wantErr bool
+	}{
+		{
+			name: "should load a policy",
+			json: `{
+				"required_method": "S256",
+				"allowed_methods": ["S256"],
+				"min_verifier_length": 64,
+				"max_verifier_length": 96,
+				"min_verifier_entropy": 128,
+				"key_ttl": "5m",
+				"lenient_challenge_encoding": true,
+				"lenient_method_case": true,
+				"lenient_verifier_encoding": true,
+				"lenient_verifier_whitespace": true
+			}`,
+			want: Policy{
+				RequiredMethod:            S256,
+				AllowedMethods:            []Method{S256},
+				MinVerifierLength:         64,
+				MaxVerifierLength:         96,
+				MinVerifierEntropy:        128,
+				KeyTTL:                    5 * time.Minute,
+				LenientChallengeEncoding:  true,
+				LenientMethodCase:         true,
+				LenientVerifierEncoding:   true,
+				LenientVerifierWhitespace: true,
+			},
+		},
+		{
+			name: "should load a policy allowing hashed methods",
+			json: `{"allowed_methods": ["S512", "S384"]}`,
+			want: Policy{
+				AllowedMethods: []Method{S512, S384},
+			},
+		},
+		{
+			name: "should load an empty policy",
+			json: `{"required_method": ""}`,
+			want: Policy{},
+		},
+		{
+			name:    "should error on unknown fields",
+			json:    `{"requried_method": "S256"}`,
+			wantErr: true,
+		},
+		{
+			name:    "should error on an unsupported method",
+			json:    `{"allowed_methods": ["S224"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "should error on an invalid ttl",
+			json:    `{"key_ttl": "10"}`,
+			wantErr: true,
+		},
+		{
+			name:    "should error on an invalid policy",
+			json:    `{"min_verifier_length": 96, "max_verifier_length": 64}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PolicyFromJSON([]byte(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PolicyFromJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PolicyFromJSON()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_policyFromLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Policy
+		wantErr bool
+	}{
+		{
+			name: "should load a policy",
+			env: map[string]string{
+				EnvRequiredMethod:            "S256",
+				EnvAllowedMethods:            "plain, S256",
+				EnvMinVerifierLength:         "64",
+				EnvMaxVerifierLength:         "96",
+				EnvMinVerifierEntropy:        "128",
+				EnvKeyTTL:                    "5m",
+				EnvLenientChallengeEncoding:  "true",
+				EnvLenientVerifierEncoding:   "1",
+				EnvLenientVerifierWhitespace: "false",
+			},
+			want: Policy{
+				RequiredMethod:           S256,
+				AllowedMethods:           []Method{Plain, S256},
+				MinVerifierLength:        64,
+				MaxVerifierLength:        96,
+				MinVerifierEntropy:       128,
+				KeyTTL:                   5 * time.Minute,
+				LenientChallengeEncoding: true,
+				LenientVerifierEncoding:  true,
+			},
+		},
+		{
+			name: "should load an empty policy",
+			env: map[string]string{
+				EnvRequiredMethod: "",
+				EnvAllowedMethods: "",
+			},
+			want: Policy{},
+		},
+		{
+			name: "should error on an invalid length",
+			env: map[string]string{
+				EnvMinVerifierLength: "sixty-four",
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error on an invalid flag",
+			env: map[string]string{
+				EnvLenientVerifierEncoding: "yes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "should error on a required method that is not allowed",
+			env: map[string]string{
+				EnvRequiredMethod: "S256",
+				EnvAllowedMethods: "plain",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policyFromLookup(func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("policyFromLookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("policyFromLookup()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr error
+	}{
+		{
+			name:    "should accept the zero value",
+			policy:  Policy{},
+			wantErr: nil,
+		},
+		{
+			name:    "should error on an unsupported method",
+			policy:  Policy{RequiredMethod: "S224"},
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name:    "should error on a required method that is not allowed",
+			policy:  Policy{RequiredMethod: S256, AllowedMethods: []Method{Plain}},
+			wantErr: ErrPolicyInvalid,
+		},
+		{
+			name:    "should error on a non-compliant verifier length",
+			policy:  Policy{MinVerifierLength: 32},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should error on inverted verifier lengths",
+			policy:  Policy{MinVerifierLength: 96, MaxVerifierLength: 64},
+			wantErr: ErrPolicyInvalid,
+		},
+		{
+			name:    "should error on a negative ttl",
+			policy:  Policy{KeyTTL: -time.Minute},
+			wantErr: ErrExpiryInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); err != tt.wantErr {
+				t.Errorf("Validate() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicy_ManagerOptions(t *testing.T) {
+	policy := Policy{
+		RequiredMethod:    S256,
+		MinVerifierLength: 64,
+	}
+	m := NewKeyManager(NewMemoryStore(), policy.ManagerOptions()...)
+
+	if err := m.Register(context.Background(), "code", Plain, strings.Repeat("a", 64)); err != ErrMethodNotAllowed {
+		t.Errorf("Register() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodNotAllowed)
+	}
+
+	if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	// the test code verifier is only 43 characters long.
+	if err := m.Verify(context.Background(), "code", testCodeVerifier); err != ErrVerifierLength {
+		t.Errorf("Verify() error type not expected\ngot:  %v, want: %v\n", err, ErrVerifierLength)
+	}
+}
+
+func TestPolicy_ParseOptions(t *testing.T) {
+	policy := Policy{LenientVerifierWhitespace: true}
+
+	r := newTokenRequest(map[string][]string{
+		"code_verifier": {" " + testCodeVerifier},
+	})
+	if _, err := ParseTokenRequest(r, policy.ParseOptions()...); err != nil {
+		t.Errorf("ParseTokenRequest() unexpected error: %v", err)
+	}
+
+	policy = Policy{MinVerifierLength: 64}
+	r = newTokenRequest(map[string][]string{
+		"code_verifier": {testCodeVerifier},
+	})
+	if _, err := ParseTokenRequest(r, policy.ParseOptions()...); err != ErrVerifierLength {
+		t.Errorf("ParseTokenRequest() error type not expected\ngot:  %v, want: %v\n", err, ErrVerifierLength)
+	}
+}
+
+func TestPolicy_SupportedMethods(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		want   []Method
+	}{
+		{
+			name:   "should default to the supported methods",
+			policy: Policy{},
+			want:   SupportedMethods(),
+		},
+		{
+			name:   "should only support the required method",
+			policy: Policy{RequiredMethod: S256},
+			want:   []Method{S256},
+		},
+		{
+			name:   "should support the allowed methods in order of preference",
+			policy: Policy{AllowedMethods: []Method{Plain, S256}},
+			want:   []Method{S256, Plain},
+		},
+		{
+			name:   "should support allowed hashed methods in order of preference",
+			policy: Policy{AllowedMethods: []Method{S256, S384, S512}},
+			want:   []Method{S512, S384, S256},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if FIPSEnabled() && (containsMethod(tt.want, Plain) || containsMethod(tt.want, S512)) {
+				t.Skip("plain and hashed methods are not supported when FIPS is enabled")
+			}
+
+			if got := tt.policy.SupportedMethods(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SupportedMethods() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_MergeMetadata(t *testing.T) {
+	policy := Policy{RequiredMethod: S256}
+
+	got, err := json.Marshal(policy.MergeMetadata(map[string]interface{}{
+		"issuer":                              "https://as.example.com",
+		MetadataCodeChallengeMethodsSupported: []string{"plain"},
+	}))
+	if err != nil {
+		t.Fatalf("MergeMetadata() unexpected error: %v", err)
+	}
+
+	want := `{"code_challenge_methods_supported":["S256"],"issuer":"https://as.example.com"}`
+	if string(got) != want {
+		t.Errorf("MergeMetadata() = %s, want %s", got, want)
+	}
+
+	if got := policy.Metadata(); !reflect.DeepEqual(got, map[string]interface{}{
+		MetadataCodeChallengeMethodsSupported: []Method{S256},
+	}) {
+		t.Errorf("Metadata() = %v", got)
+	}
+}
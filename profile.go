@@ -0,0 +1,34 @@
+package pkce
+
+// StrictProfile returns a parse option disabling all of the leniency toggles,
+// so that only requests strictly compliant with RFC 7636 are accepted. This is
+// the default posture, but applying it after other parse options, such as
+// those of a provider preset, ensures any leniency they enable is reverted.
+//
+// The code verifier length bounds and issuer expectations are unchanged.
+func StrictProfile() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientChallengeEncoding = false
+		config.lenientMethodCase = false
+		config.lenientVerifierEncoding = false
+		config.lenientVerifierSpace = false
+	}
+}
+
+// CompatProfile returns a parse option enabling all of the leniency toggles:
+// WithLenientChallengeEncoding, WithLenientMethodCase,
+// WithLenientVerifierEncoding and WithLenientVerifierWhitespace. This accepts
+// requests from non-compliant clients sending padded base64 code challenges,
+// methods such as "s256", code verifiers percent-encoding "~", or code
+// verifiers with surrounding whitespace.
+//
+// Malformed values are normalized before being validated, so verification is
+// as secure as when strict, but non-compliant clients go unnoticed.
+func CompatProfile() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientChallengeEncoding = true
+		config.lenientMethodCase = true
+		config.lenientVerifierEncoding = true
+		config.lenientVerifierSpace = true
+	}
+}
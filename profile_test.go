@@ -0,0 +1,93 @@
+package pkce
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestCompatProfile(t *testing.T) {
+	const standardCodeChallenge = "EF+/M9nkOE6p88FdlYXUHkBv96MeV56C/Dsqk9DGlxw="
+
+	tests := []struct {
+		name    string
+		opts    []ParseOption
+		wantErr error
+	}{
+		{
+			name:    "should reject a non-compliant authorization request by default",
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name: "should accept a non-compliant authorization request",
+			opts: []ParseOption{CompatProfile()},
+		},
+		{
+			name:    "should be reverted by a later strict profile",
+			opts:    []ParseOption{CompatProfile(), StrictProfile()},
+			wantErr: ErrMethodNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{
+				"code_challenge":        {standardCodeChallenge},
+				"code_challenge_method": {"s256"},
+			}
+			r := httptest.NewRequest(http.MethodGet, "/authorize?"+query.Encode(), nil)
+
+			if _, err := ParseAuthorizationRequest(r, tt.opts...); err != tt.wantErr {
+				t.Errorf("ParseAuthorizationRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStrictProfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []ParseOption
+		codeVerifier string
+		wantErr      error
+	}{
+		{
+			name:         "should accept a non-compliant token request when compatible",
+			opts:         []ParseOption{CompatProfile()},
+			codeVerifier: " " + testCodeVerifier[:10] + "%7E" + testCodeVerifier[11:] + " ",
+		},
+		{
+			name:         "should reject surrounding whitespace when strict",
+			opts:         []ParseOption{WithLenientVerifierWhitespace(), StrictProfile()},
+			codeVerifier: " " + testCodeVerifier + " ",
+			wantErr:      ErrVerifierCharacters,
+		},
+		{
+			name:         "should reject percent-encoding when strict",
+			opts:         []ParseOption{WithLenientVerifierEncoding(), StrictProfile()},
+			codeVerifier: testCodeVerifier[:10] + "%7E" + testCodeVerifier[11:],
+			wantErr:      ErrVerifierEncoding,
+		},
+		{
+			name:         "should retain the verifier length bounds",
+			opts:         []ParseOption{WithVerifierLength(64, verifierMaxLen), StrictProfile()},
+			codeVerifier: testCodeVerifier,
+			wantErr:      ErrVerifierLength,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTokenRequest(url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"code_verifier": {tt.codeVerifier},
+			})
+
+			if _, err := ParseTokenRequest(r, tt.opts...); err != tt.wantErr {
+				t.Errorf("ParseTokenRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package pkce
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Field numbers of the pkce.v1.Key message, as defined in
+// proto/pkce/v1/pkce.proto.
+const (
+	protoFieldChallengeMethod    = 1
+	protoFieldCodeChallenge      = 2
+	protoFieldCodeVerifier       = 3
+	protoFieldCodeVerifierLength = 4
+	protoFieldExpiresAt          = 5
+	protoFieldChallengeEncoding  = 6
+	protoFieldVerifierCharset    = 7
+	protoFieldFlags              = 8
+	protoFieldCreatedAt          = 9
+	protoFieldMetadata           = 10
+	protoFieldVerifiedAt         = 11
+)
+
+// Field numbers of the pkce.v1.Key.MetadataEntry map entry message.
+const (
+	protoFieldMetadataKey   = 1
+	protoFieldMetadataValue = 2
+)
+
+// protoFlagVerifierOmitted provides pkce.v1.Flag FLAG_VERIFIER_OMITTED.
+const protoFlagVerifierOmitted = 1
+
+// Protobuf wire types.
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+	protoWireFixed32 = 5
+)
+
+// MarshalProto encodes the key as a pkce.v1.Key protobuf message, enabling
+// proof keys to be passed between services over gRPC.
+//
+// The code verifier is included, and will be generated if it has not been
+// already. Keys holding a received code challenge are encoded with the code
+// verifier omitted.
+func (k *Key) MarshalProto() ([]byte, error) {
+	if k.codeChallenge != "" {
+		return k.MarshalProtoChallenge()
+	}
+
+	return k.marshalProto(k.getCodeVerifier(), 0), nil
+}
+
+// MarshalProtoChallenge encodes the key as a pkce.v1.Key protobuf message
+// with the code verifier omitted, for passing the code challenge to services
+// which verify, but must never learn, the code verifier.
+func (k *Key) MarshalProtoChallenge() ([]byte, error) {
+	return k.marshalProto(nil, protoFlagVerifierOmitted), nil
+}
+
+// marshalProto encodes the pkce.v1.Key message fields. Fields holding their
+// zero value are omitted, as specified by proto3.
+func (k *Key) marshalProto(codeVerifier []byte, flags uint64) []byte {
+	var out []byte
+	out = appendProtoBytes(out, protoFieldChallengeMethod, []byte(k.challengeMethod))
+	out = appendProtoBytes(out, protoFieldCodeChallenge, []byte(k.CodeChallenge()))
+	out = appendProtoBytes(out, protoFieldCodeVerifier, codeVerifier)
+	out = appendProtoVarint(out, protoFieldCodeVerifierLength, uint64(k.codeVerifierLen))
+	if !k.expiresAt.IsZero() {
+		out = appendProtoVarint(out, protoFieldExpiresAt, uint64(k.expiresAt.UnixNano()))
+	}
+	out = appendProtoBytes(out, protoFieldChallengeEncoding, []byte(k.challengeEncoding))
+	out = appendProtoBytes(out, protoFieldVerifierCharset, []byte(k.verifierCharset))
+	out = appendProtoVarint(out, protoFieldFlags, flags)
+	if !k.createdAt.IsZero() {
+		out = appendProtoVarint(out, protoFieldCreatedAt, uint64(k.createdAt.UnixNano()))
+	}
+	for _, name := range sortedMetaNames(k.meta) {
+		var entry []byte
+		entry = appendProtoBytes(entry, protoFieldMetadataKey, []byte(name))
+		entry = appendProtoBytes(entry, protoFieldMetadataValue, []byte(k.meta[name]))
+		out = appendProtoEntry(out, protoFieldMetadata, entry)
+	}
+	if !k.verifiedAt.IsZero() {
+		out = appendProtoVarint(out, protoFieldVerifiedAt, uint64(k.verifiedAt.UnixNano()))
+	}
+
+	return out
+}
+
+// UnmarshalProto decodes a pkce.v1.Key protobuf message. The decoded key is
+// validated to ensure data received from other services is specification
+// compliant, and that any code verifier received matches the code challenge.
+//
+// Unknown fields are ignored, enabling the message to be extended.
+func (k *Key) UnmarshalProto(data []byte) error {
+	if err := k.checkPinned(true); err != nil {
+		return err
+	}
+
+	var (
+		method, codeChallenge, encoding, charset          string
+		codeVerifier                                      []byte
+		codeVerifierLen, expiresAt, createdAt, verifiedAt uint64
+		flags                                             uint64
+		meta                                              map[string]string
+	)
+
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return ErrKeyEncoding
+		}
+		data = data[n:]
+
+		field, wireType := tag>>3, tag&0x7
+		switch wireType {
+		case protoWireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return ErrKeyEncoding
+			}
+			data = data[n:]
+
+			switch field {
+			case protoFieldCodeVerifierLength:
+				codeVerifierLen = v
+			case protoFieldExpiresAt:
+				expiresAt = v
+			case protoFieldFlags:
+				flags = v
+			case protoFieldCreatedAt:
+				createdAt = v
+			case protoFieldVerifiedAt:
+				verifiedAt = v
+			}
+
+		case protoWireBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || size > uint64(len(data)-n) {
+				return ErrKeyEncoding
+			}
+			v := data[n : n+int(size)]
+			data = data[n+int(size):]
+
+			switch field {
+			case protoFieldChallengeMethod:
+				method = string(v)
+			case protoFieldCodeChallenge:
+				codeChallenge = string(v)
+			case protoFieldCodeVerifier:
+				codeVerifier = append([]byte(nil), v...)
+			case protoFieldChallengeEncoding:
+				encoding = string(v)
+			case protoFieldVerifierCharset:
+				charset = string(v)
+			case protoFieldMetadata:
+				name, value, err := unmarshalProtoMetadataEntry(v)
+				if err != nil {
+					return err
+				}
+				if meta == nil {
+					meta = map[string]string{}
+				}
+				meta[name] = value
+			}
+
+		case protoWireFixed64:
+			if len(data) < 8 {
+				return ErrKeyEncoding
+			}
+			data = data[8:]
+
+		case protoWireFixed32:
+			if len(data) < 4 {
+				return ErrKeyEncoding
+			}
+			data = data[4:]
+
+		default:
+			return ErrKeyEncoding
+		}
+	}
+
+	key := Key{}
+	if err := WithChallengeMethod(Method(method))(&key); err != nil {
+		return err
+	}
+
+	if encoding != "" {
+		if err := WithChallengeEncoding(ChallengeEncoding(encoding))(&key); err != nil {
+			return err
+		}
+	}
+
+	if charset != "" {
+		if err := WithVerifierCharset(charset)(&key); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case flags&protoFlagVerifierOmitted != 0:
+		if err := WithCodeChallenge(codeChallenge)(&key); err != nil {
+			return err
+		}
+		if codeVerifierLen > 0 {
+			if err := setProtoCodeVerifierLength(&key, codeVerifierLen); err != nil {
+				return err
+			}
+		}
+
+	case len(codeVerifier) > 0:
+		if err := key.setCodeVerifier(codeVerifier); err != nil {
+			return err
+		}
+		if codeChallenge != "" && !ChallengesEqual(key.CodeChallenge(), codeChallenge) {
+			return ErrKeyEncoding
+		}
+
+	default:
+		if err := setProtoCodeVerifierLength(&key, codeVerifierLen); err != nil {
+			return err
+		}
+	}
+
+	if expiresAt != 0 {
+		key.expiresAt = time.Unix(0, int64(expiresAt))
+	}
+	if createdAt != 0 {
+		key.createdAt = time.Unix(0, int64(createdAt))
+	}
+	if verifiedAt != 0 {
+		key.verifiedAt = time.Unix(0, int64(verifiedAt))
+	}
+	key.meta = meta
+
+	// retain the runtime configuration of the key being decoded into.
+	key.clock = k.clock
+	*k = key
+
+	return nil
+}
+
+// unmarshalProtoMetadataEntry decodes a pkce.v1.Key.MetadataEntry map entry
+// message. Missing fields decode as empty strings, and unknown fields are
+// ignored, as specified by proto3.
+func unmarshalProtoMetadataEntry(data []byte) (name string, value string, err error) {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 || tag&0x7 != protoWireBytes {
+			return "", "", ErrKeyEncoding
+		}
+		data = data[n:]
+
+		size, n := binary.Uvarint(data)
+		if n <= 0 || size > uint64(len(data)-n) {
+			return "", "", ErrKeyEncoding
+		}
+		v := string(data[n : n+int(size)])
+		data = data[n+int(size):]
+
+		switch tag >> 3 {
+		case protoFieldMetadataKey:
+			name = v
+		case protoFieldMetadataValue:
+			value = v
+		}
+	}
+
+	return name, value, nil
+}
+
+// setProtoCodeVerifierLength sets a decoded code verifier length, guarding
+// against lengths that overflow an int.
+func setProtoCodeVerifierLength(key *Key, n uint64) error {
+	if n > verifierMaxLen {
+		return ErrVerifierLength
+	}
+
+	return key.setCodeVerifierLength(int(n))
+}
+
+// appendProtoVarint appends a varint field, omitting zero values.
+func appendProtoVarint(out []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return out
+	}
+
+	out = appendUvarint(out, uint64(field)<<3|protoWireVarint)
+
+	return appendUvarint(out, v)
+}
+
+// appendProtoBytes appends a length-delimited field, omitting empty values.
+func appendProtoBytes(out []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return out
+	}
+
+	out = appendUvarint(out, uint64(field)<<3|protoWireBytes)
+	out = appendUvarint(out, uint64(len(v)))
+
+	return append(out, v...)
+}
+
+// appendProtoEntry appends a length-delimited map entry field, which unlike
+// other fields is written even if empty.
+func appendProtoEntry(out []byte, field int, entry []byte) []byte {
+	out = appendUvarint(out, uint64(field)<<3|protoWireBytes)
+	out = appendUvarint(out, uint64(len(entry)))
+
+	return append(out, entry...)
+}
+
+// appendUvarint appends the varint encoding of v.
+func appendUvarint(out []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+
+	return append(out, buf[:n]...)
+}
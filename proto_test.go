@@ -0,0 +1,209 @@
+package pkce
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKey_MarshalProto(t *testing.T) {
+	codeVerifier := strings.Repeat("a", verifierMinLen)
+
+	key := &Key{
+		challengeMethod: Plain,
+		codeVerifierLen: verifierMinLen,
+		codeVerifier:    []byte(codeVerifier),
+	}
+
+	got, err := key.MarshalProto()
+	if err != nil {
+		t.Fatalf("MarshalProto() unexpected error: %v", err)
+	}
+
+	var want []byte
+	want = append(want, 0x0a, 5)
+	want = append(want, "plain"...)
+	want = append(want, 0x12, verifierMinLen)
+	want = append(want, codeVerifier...)
+	want = append(want, 0x1a, verifierMinLen)
+	want = append(want, codeVerifier...)
+	want = append(want, 0x20, verifierMinLen)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("MarshalProto()\ngot:  %v\nwant: %v\n", got, want)
+	}
+}
+
+func TestKey_MarshalProto_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *Key
+	}{
+		{
+			name: "should round trip a key with a code verifier",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: 48,
+				codeVerifier:    []byte(strings.Repeat("a", 48)),
+				expiresAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with a challenge encoding and charset",
+			key: &Key{
+				challengeMethod:   S256,
+				challengeEncoding: Hex,
+				verifierCharset:   AlphanumericCharset,
+				codeVerifierLen:   verifierMinLen,
+				codeVerifier:      []byte(strings.Repeat("a", verifierMinLen)),
+			},
+		},
+		{
+			name: "should round trip a key with a received code challenge",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+			},
+		},
+		{
+			name: "should round trip a key with a creation time",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				createdAt:       time.Unix(0, time.Date(2022, 1, 27, 0, 0, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with a verification time",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				verifiedAt:      time.Unix(0, time.Date(2022, 1, 27, 0, 1, 0, 0, time.UTC).UnixNano()),
+			},
+		},
+		{
+			name: "should round trip a key with metadata",
+			key: &Key{
+				challengeMethod: S256,
+				codeVerifierLen: verifierMinLen,
+				codeChallenge:   "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ",
+				meta:            map[string]string{"client_id": "client", "scope": ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.key.MarshalProto()
+			if err != nil {
+				t.Fatalf("MarshalProto() unexpected error: %v", err)
+			}
+
+			got := &Key{}
+			if err = got.UnmarshalProto(data); err != nil {
+				t.Fatalf("UnmarshalProto() unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.key) {
+				t.Errorf("UnmarshalProto() key\ngot:  %v\nwant: %v\n", got, tt.key)
+			}
+		})
+	}
+}
+
+func TestKey_MarshalProtoChallenge(t *testing.T) {
+	key, err := New()
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	data, err := key.MarshalProtoChallenge()
+	if err != nil {
+		t.Fatalf("MarshalProtoChallenge() unexpected error: %v", err)
+	}
+
+	if bytes.Contains(data, []byte(key.CodeVerifier())) {
+		t.Errorf("MarshalProtoChallenge() should omit the code verifier")
+	}
+
+	got := &Key{}
+	if err = got.UnmarshalProto(data); err != nil {
+		t.Fatalf("UnmarshalProto() unexpected error: %v", err)
+	}
+
+	if !got.VerifyCodeVerifier(key.CodeVerifier()) {
+		t.Errorf("UnmarshalProto() key should verify the original code verifier")
+	}
+}
+
+func TestKey_UnmarshalProto(t *testing.T) {
+	codeVerifier := strings.Repeat("a", verifierMinLen)
+
+	message := func(fields ...[]byte) []byte {
+		return bytes.Join(fields, nil)
+	}
+	field := func(tag byte, v string) []byte {
+		return append([]byte{tag, byte(len(v))}, v...)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "should error on a truncated tag",
+			data:    []byte{0x80},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on a truncated field",
+			data:    []byte{0x0a, 4, 'S', '2'},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on an unsupported wire type",
+			data:    []byte{0x0b},
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on an unsupported method",
+			data:    message(field(0x0a, "yolo"), []byte{0x20, verifierMinLen}),
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name:    "should error on a code challenge not matching the code verifier",
+			data:    message(field(0x0a, "plain"), field(0x12, strings.Repeat("b", verifierMinLen)), field(0x1a, codeVerifier)),
+			wantErr: ErrKeyEncoding,
+		},
+		{
+			name:    "should error on an invalid code verifier",
+			data:    message(field(0x0a, "plain"), field(0x1a, "yolo")),
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should error on an invalid code challenge",
+			data:    message(field(0x0a, "S256"), field(0x12, "yolo"), []byte{0x40, protoFlagVerifierOmitted}),
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name:    "should ignore unknown fields",
+			data:    message(field(0x0a, "plain"), field(0x1a, codeVerifier), field(0x7a, "unknown"), []byte{0x79, 0, 0, 0, 0, 0, 0, 0, 0}),
+			wantErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := &Key{}
+			if err := key.UnmarshalProto(tt.data); err != tt.wantErr {
+				t.Errorf("UnmarshalProto() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package pkce
+
+// Provider identifies an identity provider with known PKCE requirements.
+type Provider string
+
+const (
+	// ProviderAuth0 specifies Auth0, which only supports the "S256" method.
+	ProviderAuth0 Provider = "auth0"
+
+	// ProviderAzureAD specifies the Microsoft identity platform (Azure AD),
+	// which supports both the "plain" and "S256" methods, but recommends
+	// "S256".
+	ProviderAzureAD Provider = "azuread"
+
+	// ProviderOkta specifies Okta, which only supports the "S256" method.
+	ProviderOkta Provider = "okta"
+)
+
+// providerPreset provides the PKCE requirements of an identity provider.
+type providerPreset struct {
+	// allowPlain specifies whether the provider accepts the "plain" method.
+	allowPlain bool
+	// minVerifierLen provides the minimum code verifier length accepted.
+	minVerifierLen int
+	// maxVerifierLen provides the maximum code verifier length accepted.
+	maxVerifierLen int
+}
+
+// providerPresets returns the known identity provider presets.
+func providerPresets() map[Provider]providerPreset {
+	return map[Provider]providerPreset{
+		ProviderAuth0: {
+			allowPlain:     false,
+			minVerifierLen: verifierMinLen,
+			maxVerifierLen: verifierMaxLen,
+		},
+		ProviderAzureAD: {
+			allowPlain:     true,
+			minVerifierLen: verifierMinLen,
+			maxVerifierLen: verifierMaxLen,
+		},
+		ProviderOkta: {
+			allowPlain:     false,
+			minVerifierLen: verifierMinLen,
+			maxVerifierLen: verifierMaxLen,
+		},
+	}
+}
+
+// validate ensures the key's configuration is accepted by the provider.
+func (p *providerPreset) validate(key *Key) error {
+	if key.challengeMethod == Plain && !p.allowPlain {
+		return ErrMethodNotAllowed
+	}
+
+	n := key.codeVerifierLen
+	if len(key.codeVerifier) > 0 {
+		n = len(key.codeVerifier)
+	}
+
+	if n < p.minVerifierLen || n > p.maxVerifierLen {
+		return ErrVerifierLength
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package pkce
+
+import (
+	"testing"
+)
+
+func TestWithProvider(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantMethod Method
+		wantErr    error
+	}{
+		{
+			name:       "should default to S256 for Auth0",
+			opts:       []Option{WithProvider(ProviderAuth0)},
+			wantMethod: S256,
+		},
+		{
+			name:       "should default to S256 for Okta",
+			opts:       []Option{WithProvider(ProviderOkta)},
+			wantMethod: S256,
+		},
+		{
+			name:       "should default to S256 for Azure AD",
+			opts:       []Option{WithProvider(ProviderAzureAD)},
+			wantMethod: S256,
+		},
+		{
+			name:       "should override a previously configured plain method",
+			opts:       []Option{WithChallengeMethod(Plain), WithProvider(ProviderAzureAD)},
+			wantMethod: S256,
+		},
+		{
+			name:       "should allow plain for Azure AD",
+			opts:       []Option{WithProvider(ProviderAzureAD), WithChallengeMethod(Plain)},
+			wantMethod: Plain,
+		},
+		{
+			name:    "should disallow plain for Auth0",
+			opts:    []Option{WithProvider(ProviderAuth0), WithChallengeMethod(Plain)},
+			wantErr: ErrMethodNotAllowed,
+		},
+		{
+			name:    "should disallow plain for Okta",
+			opts:    []Option{WithProvider(ProviderOkta), WithChallengeMethod(Plain)},
+			wantErr: ErrMethodNotAllowed,
+		},
+		{
+			name:    "should error on an unknown provider",
+			opts:    []Option{WithProvider("yolo")},
+			wantErr: ErrProviderNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := New(tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("New() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err == nil && key.ChallengeMethod() != tt.wantMethod {
+				t.Errorf("ChallengeMethod() = %v, want %v", key.ChallengeMethod(), tt.wantMethod)
+			}
+		})
+	}
+}
+
+func Test_providerPreset_validate(t *testing.T) {
+	preset := &providerPreset{
+		allowPlain:     false,
+		minVerifierLen: 64,
+		maxVerifierLen: 96,
+	}
+
+	tests := []struct {
+		name    string
+		key     *Key
+		wantErr error
+	}{
+		{
+			name:    "should accept a configured length within bounds",
+			key:     &Key{challengeMethod: S256, codeVerifierLen: 64},
+			wantErr: nil,
+		},
+		{
+			name:    "should reject a configured length below the minimum",
+			key:     &Key{challengeMethod: S256, codeVerifierLen: 63},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should reject a supplied verifier above the maximum",
+			key:     &Key{challengeMethod: S256, codeVerifierLen: 97, codeVerifier: make([]byte, 97)},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name:    "should reject plain",
+			key:     &Key{challengeMethod: Plain, codeVerifierLen: 64},
+			wantErr: ErrMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := preset.validate(tt.key); err != tt.wantErr {
+				t.Errorf("validate() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
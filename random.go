@@ -0,0 +1,75 @@
+package pkce
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+// RandomString generates a cryptographically secure random string of n
+// characters drawn uniformly from charset, such as for generating state,
+// nonce, RFC 8628 user code or client secret values.
+//
+// Unlike a code verifier character set, charset may contain any bytes, but
+// must be non-empty, contain at most 256 characters and not repeat
+// characters, so that each character is equally likely. Callers requiring the
+// output to meet further constraints, such as a minimum of each character
+// class, must validate it separately.
+func RandomString(n int, charset string) (string, error) {
+	if err := validateRandomCharset(charset); err != nil {
+		return "", err
+	}
+
+	if n < 1 {
+		return "", ErrLengthInvalid
+	}
+
+	out, err := readCodeVerifier(rand.Reader, charset, n)
+	if err != nil {
+		return "", err
+	}
+
+	return string(out), nil
+}
+
+// validateRandomCharset ensures every character of charset can be drawn with
+// equal probability.
+func validateRandomCharset(charset string) error {
+	if len(charset) == 0 || len(charset) > 256 {
+		return ErrRandomCharset
+	}
+
+	var seen [256]bool
+	for i := 0; i < len(charset); i++ {
+		if seen[charset[i]] {
+			return ErrRandomCharset
+		}
+		seen[charset[i]] = true
+	}
+
+	return nil
+}
+
+// sampleCodeVerifier generates a code verifier using only characters from
+// charset, reading random bytes from r and rejecting those that would bias the
+// distribution of characters.
+func sampleCodeVerifier(r io.Reader, charset string, n int) (out []byte, err error) {
+	// the largest multiple of the charset length representable in a byte,
+	// at or above which bytes are rejected.
+	limit := 256 - 256%len(charset)
+
+	out = make([]byte, 0, n)
+	buf := make([]byte, n)
+	for len(out) < n {
+		if _, err = io.ReadFull(r, buf[:n-len(out)]); err != nil {
+			return nil, err
+		}
+
+		for _, b := range buf[:n-len(out)] {
+			if int(b) < limit {
+				out = append(out, charset[int(b)%len(charset)])
+			}
+		}
+	}
+
+	return out, nil
+}
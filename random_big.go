@@ -0,0 +1,27 @@
+//go:build !tinygo && !wasm
+// +build !tinygo,!wasm
+
+package pkce
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+)
+
+// readCodeVerifier generates a code verifier using only characters from
+// charset, reading uniformly distributed random ints from r.
+func readCodeVerifier(r io.Reader, charset string, n int) (out []byte, err error) {
+	charsetLen := big.NewInt(int64(len(charset)))
+
+	out = make([]byte, n)
+	for i := range out {
+		j, err := rand.Int(r, charsetLen)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = charset[j.Int64()]
+	}
+
+	return out, nil
+}
@@ -0,0 +1,15 @@
+//go:build tinygo || wasm
+// +build tinygo wasm
+
+package pkce
+
+import (
+	"io"
+)
+
+// readCodeVerifier generates a code verifier using only characters from
+// charset, using byte rejection sampling to avoid linking math/big into
+// TinyGo and WebAssembly builds.
+func readCodeVerifier(r io.Reader, charset string, n int) (out []byte, err error) {
+	return sampleCodeVerifier(r, charset, n)
+}
@@ -0,0 +1,132 @@
+package pkce
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_sampleCodeVerifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		r       io.Reader
+		charset string
+		n       int
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "should map bytes onto the charset",
+			r:       bytes.NewReader([]byte{0, 1, 2, 3}),
+			charset: "abc",
+			n:       4,
+			want:    "abca",
+		},
+		{
+			name:    "should reject biased bytes",
+			r:       bytes.NewReader([]byte{255, 0, 255, 1}),
+			charset: "abc",
+			n:       2,
+			want:    "ab",
+		},
+		{
+			name:    "should not reject bytes for a charset dividing a byte",
+			r:       bytes.NewReader([]byte{255, 0}),
+			charset: "ab",
+			n:       2,
+			want:    "ba",
+		},
+		{
+			name:    "should error on an exhausted reader",
+			r:       bytes.NewReader([]byte{255, 0}),
+			charset: "abc",
+			n:       2,
+			wantErr: io.EOF,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sampleCodeVerifier(tt.r, tt.charset, tt.n)
+			if err != tt.wantErr {
+				t.Fatalf("sampleCodeVerifier() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("sampleCodeVerifier() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sampleCodeVerifier_charset(t *testing.T) {
+	got, err := sampleCodeVerifier(strings.NewReader(strings.Repeat("\x00\x7f\xff\x42", verifierMaxLen)), unreserved, verifierMaxLen)
+	if err != nil {
+		t.Fatalf("sampleCodeVerifier() unexpected error: %v", err)
+	}
+
+	if err = validateCodeVerifier(got); err != nil {
+		t.Errorf("sampleCodeVerifier() = %q, generated a non-compliant code verifier: %v", got, err)
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		charset string
+		wantErr error
+	}{
+		{
+			name:    "should generate a string from the charset",
+			n:       16,
+			charset: "0123456789abcdef",
+		},
+		{
+			name:    "should generate a string from reserved characters",
+			n:       16,
+			charset: "!#$%&*+/=?@ ",
+		},
+		{
+			name:    "should error on an empty charset",
+			n:       16,
+			charset: "",
+			wantErr: ErrRandomCharset,
+		},
+		{
+			name:    "should error on duplicate characters",
+			n:       16,
+			charset: "abca",
+			wantErr: ErrRandomCharset,
+		},
+		{
+			name:    "should error on a charset longer than 256 characters",
+			n:       16,
+			charset: strings.Repeat(unreserved, 4),
+			wantErr: ErrRandomCharset,
+		},
+		{
+			name:    "should error on a non-positive length",
+			n:       0,
+			charset: "abc",
+			wantErr: ErrLengthInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RandomString(tt.n, tt.charset)
+			if err != tt.wantErr {
+				t.Fatalf("RandomString() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if len(got) != tt.n || strings.Trim(got, tt.charset) != "" {
+				t.Errorf("RandomString() = %q, want %d characters from %q", got, tt.n, tt.charset)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package pkce
+
+import (
+	"context"
+)
+
+// metaRedirectURI provides the metadata name the redirect uri of a flow is
+// recorded under.
+const metaRedirectURI = "redirect_uri"
+
+// RegisterRedirect persists the code challenge as Register, recording the
+// redirect uri of the authorization request, so that VerifyRedirect, and the
+// middleware, can enforce that the token request is made with the same
+// redirect uri, as specified in RFC 6749, 4.1.3.
+func (m *KeyManager) RegisterRedirect(ctx context.Context, id string, redirectURI string, method Method, codeChallenge string) error {
+	return m.register(ctx, registerRequest{
+		id:            m.binder.ID(id),
+		method:        method,
+		codeChallenge: codeChallenge,
+		redirectURI:   redirectURI,
+	})
+}
+
+// VerifyRedirect verifies the code verifier as Verify, additionally returning
+// ErrRedirectURIMismatch if a redirect uri was recorded by RegisterRedirect
+// and the token request's redirect uri is not identical to it, including if it
+// has been omitted.
+func (m *KeyManager) VerifyRedirect(ctx context.Context, id string, redirectURI string, codeVerifier string) error {
+	return m.verifyAudited(ctx, verifyRequest{
+		id:               id,
+		codeVerifier:     codeVerifier,
+		checkRedirectURI: true,
+		redirectURI:      redirectURI,
+	})
+}
+
+// redirectURIMatches returns whether the redirect uri of a token request
+// matches the redirect uri recorded for the flow. Flows registered without a
+// redirect uri match any redirect uri.
+func redirectURIMatches(key *Key, redirectURI string) bool {
+	registered, ok := key.meta[metaRedirectURI]
+
+	return !ok || registered == redirectURI
+}
@@ -0,0 +1,113 @@
+package pkce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestKeyManager_VerifyRedirect(t *testing.T) {
+	const redirectURI = "https://client.example.com/cb"
+
+	tests := []struct {
+		name        string
+		registered  string
+		redirectURI string
+		wantErr     error
+	}{
+		{
+			name:        "should verify a matching redirect uri",
+			registered:  redirectURI,
+			redirectURI: redirectURI,
+		},
+		{
+			name:        "should error on a different redirect uri",
+			registered:  redirectURI,
+			redirectURI: redirectURI + "/evil",
+			wantErr:     ErrRedirectURIMismatch,
+		},
+		{
+			name:       "should error on an omitted redirect uri",
+			registered: redirectURI,
+			wantErr:    ErrRedirectURIMismatch,
+		},
+		{
+			name:        "should ignore redirect uris of flows registered without one",
+			redirectURI: redirectURI,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			m := NewKeyManager(NewMemoryStore())
+			if err := m.RegisterRedirect(ctx, "code", tt.registered, S256, testCodeChallenge); err != nil {
+				t.Fatalf("RegisterRedirect() unexpected error: %v", err)
+			}
+
+			if err := m.VerifyRedirect(ctx, "code", tt.redirectURI, testCodeVerifier); err != tt.wantErr {
+				t.Errorf("VerifyRedirect() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyManager_Middleware_redirect(t *testing.T) {
+	const redirectURI = "https://client.example.com/cb"
+
+	tests := []struct {
+		name        string
+		redirectURI string
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "should pass through a token request with a matching redirect uri",
+			redirectURI: redirectURI,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "should reject a token request with a different redirect uri",
+			redirectURI: "https://attacker.example.com/cb",
+			wantStatus:  http.StatusBadRequest,
+			wantError:   errorCodeInvalidGrant,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore())
+			if err := m.RegisterRedirect(context.Background(), "code", redirectURI, S256, testCodeChallenge); err != nil {
+				t.Fatalf("RegisterRedirect() unexpected error: %v", err)
+			}
+			handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, newTokenRequest(url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"redirect_uri":  {tt.redirectURI},
+				"code_verifier": {testCodeVerifier},
+			}))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+
+			if tt.wantError != "" {
+				var got errorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+					t.Fatalf("ServeHTTP() unexpected error decoding response: %v", err)
+				}
+				if got.Error != tt.wantError {
+					t.Errorf("ServeHTTP() error = %v, want %v", got.Error, tt.wantError)
+				}
+			}
+		})
+	}
+}
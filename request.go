@@ -0,0 +1,340 @@
+package pkce
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// grantTypeAuthorizationCode provides the grant type identifying an access
+	// token request exchanging an authorization code.
+	grantTypeAuthorizationCode = "authorization_code"
+
+	// RFC 6749, 4.1.1. Authorization Request parameters.
+	paramResponseType = "response_type"
+	paramScope        = "scope"
+	paramState        = "state"
+
+	// RFC 6749, 4.1.3. Access Token Request parameters.
+	paramGrantType   = "grant_type"
+	paramCode        = "code"
+	paramRedirectURI = "redirect_uri"
+	paramClientID    = "client_id"
+
+	// defaultMaxBodyBytes provides the default maximum size of a request body
+	// read when parsing requests, being far larger than any compliant token
+	// request.
+	defaultMaxBodyBytes = 64 << 10
+)
+
+// ParseOption enables variadic request parsing options to be configured.
+type ParseOption func(*parseConfig)
+
+// parseConfig provides the leniency applied when parsing requests, and the
+// expectations applied when parsing responses.
+type parseConfig struct {
+	lenientChallengeEncoding bool
+	lenientMethodCase        bool
+	lenientVerifierEncoding  bool
+	lenientVerifierSpace     bool
+	minVerifierLen           int
+	maxVerifierLen           int
+	maxBodyBytes             int64
+	issuer                   string
+	issuerRequired           bool
+}
+
+// newParseConfig applies the parse options over the default, strict,
+// configuration.
+func newParseConfig(opts []ParseOption) parseConfig {
+	config := parseConfig{
+		minVerifierLen: verifierMinLen,
+		maxVerifierLen: verifierMaxLen,
+		maxBodyBytes:   defaultMaxBodyBytes,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+// WithLenientChallengeEncoding enables accepting S256 code challenges sent by
+// non-compliant clients using padded or standard alphabet base64, which are
+// normalized to unpadded base64url before being validated.
+//
+// By default, such code challenges are rejected with ErrChallengeEncoding.
+func WithLenientChallengeEncoding() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientChallengeEncoding = true
+	}
+}
+
+// WithLenientMethodCase enables accepting code challenge methods sent by
+// non-compliant clients using the wrong case, such as "s256" or "PLAIN", which
+// are normalized before being validated.
+//
+// By default, such methods are rejected with ErrMethodNotSupported.
+func WithLenientMethodCase() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientMethodCase = true
+	}
+}
+
+// WithLenientVerifierEncoding enables accepting code verifiers sent by
+// non-compliant clients that have percent-encoded unreserved characters, such
+// as "%7E" for "~", which are decoded before being validated.
+//
+// By default, such code verifiers are rejected with ErrVerifierEncoding.
+func WithLenientVerifierEncoding() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientVerifierEncoding = true
+	}
+}
+
+// WithLenientVerifierWhitespace enables accepting code verifiers sent by
+// non-compliant clients with surrounding whitespace, which is trimmed before
+// being validated.
+//
+// By default, such code verifiers are rejected with ErrVerifierCharacters.
+func WithLenientVerifierWhitespace() ParseOption {
+	return func(config *parseConfig) {
+		config.lenientVerifierSpace = true
+	}
+}
+
+// WithMaxBodyBytes enables changing the maximum size of a request body read
+// when parsing requests, which defaults to 64 KiB. Non-positive
+// limits are ignored.
+//
+// Oversized request bodies are rejected with ErrRequestTooLarge.
+func WithMaxBodyBytes(n int64) ParseOption {
+	return func(config *parseConfig) {
+		if n > 0 {
+			config.maxBodyBytes = n
+		}
+	}
+}
+
+// WithVerifierLength enables narrowing the lengths of code verifier accepted
+// in token requests, within the bounds specified in RFC 7636, 4.1, such as
+// requiring public clients generate code verifiers of at least 64 characters.
+// Invalid bounds are ignored.
+//
+// Code verifiers outside of the bounds are rejected with ErrVerifierLength.
+func WithVerifierLength(min, max int) ParseOption {
+	return func(config *parseConfig) {
+		if min >= verifierMinLen && max <= verifierMaxLen && min <= max {
+			config.minVerifierLen = min
+			config.maxVerifierLen = max
+		}
+	}
+}
+
+// AuthorizationRequest provides the parameters of an authorization request
+// relevant to registering a PKCE code challenge.
+type AuthorizationRequest struct {
+	// ResponseType provides the requested response type.
+	ResponseType string `form:"response_type" json:"response_type" query:"response_type"`
+	// ClientID provides the client identifier.
+	ClientID string `form:"client_id" json:"client_id" query:"client_id"`
+	// RedirectURI provides the uri the client requested to be redirected to.
+	RedirectURI string `form:"redirect_uri" json:"redirect_uri" query:"redirect_uri"`
+	// Scope provides the scope of the access request.
+	Scope string `form:"scope" json:"scope" query:"scope"`
+	// State provides the opaque value used by the client to maintain state.
+	State string `form:"state" json:"state" query:"state"`
+	// CodeChallenge provides the code challenge to be registered.
+	CodeChallenge string `form:"code_challenge" json:"code_challenge" query:"code_challenge"`
+	// CodeChallengeMethod provides the method used to derive the code
+	// challenge.
+	CodeChallengeMethod Method `form:"code_challenge_method" json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// ParseAuthorizationRequest parses an authorization request as specified in
+// RFC 6749, 4.1.1 and RFC 7636, 4.3, validating the received code challenge
+// and code challenge method.
+//
+// Parameters are read from both the query string and, for POST requests, the
+// form encoded body, which is limited in size as configured by WithMaxBodyBytes.
+func ParseAuthorizationRequest(r *http.Request, opts ...ParseOption) (*AuthorizationRequest, error) {
+	req := &AuthorizationRequest{}
+	if err := req.Bind(r, opts...); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Bind decodes the authorization request into req and validates it, as
+// ParseAuthorizationRequest.
+func (req *AuthorizationRequest) Bind(r *http.Request, opts ...ParseOption) error {
+	if err := parseForm(r, newParseConfig(opts)); err != nil {
+		return err
+	}
+
+	*req = AuthorizationRequest{
+		ResponseType:        r.Form.Get(paramResponseType),
+		ClientID:            r.Form.Get(paramClientID),
+		RedirectURI:         r.Form.Get(paramRedirectURI),
+		Scope:               r.Form.Get(paramScope),
+		State:               r.Form.Get(paramState),
+		CodeChallenge:       r.Form.Get(ParamCodeChallenge),
+		CodeChallengeMethod: Method(r.Form.Get(ParamCodeChallengeMethod)),
+	}
+
+	return req.Validate(opts...)
+}
+
+// Validate validates the code challenge and code challenge method of an
+// authorization request decoded by other means, such as a framework's struct
+// binding. The code challenge method is defaulted, and the code challenge
+// normalized if permitted by the parse options, in place.
+func (req *AuthorizationRequest) Validate(opts ...ParseOption) error {
+	config := newParseConfig(opts)
+
+	if req.CodeChallenge == "" {
+		return ErrChallengeMissing
+	}
+
+	// RFC 7636, 4.3. Defaults to "plain" if not present in the request.
+	if req.CodeChallengeMethod == "" {
+		req.CodeChallengeMethod = Plain
+	}
+
+	if config.lenientMethodCase {
+		req.CodeChallengeMethod = normalizeMethod(req.CodeChallengeMethod)
+	}
+
+	if !isKeyMethod(req.CodeChallengeMethod) {
+		return ErrMethodNotSupported
+	}
+
+	if config.lenientChallengeEncoding && req.CodeChallengeMethod != Plain {
+		req.CodeChallenge = NormalizeCodeChallenge(req.CodeChallenge)
+	}
+
+	return validateMethodCodeChallenge(req.CodeChallengeMethod, req.CodeChallenge)
+}
+
+// TokenRequest provides the parameters of an access token request relevant to
+// verifying a PKCE code verifier.
+type TokenRequest struct {
+	// GrantType provides the requested grant type.
+	GrantType string `form:"grant_type" json:"grant_type" query:"grant_type"`
+	// Code provides the authorization code being exchanged.
+	Code string `form:"code" json:"code" query:"code"`
+	// RedirectURI provides the redirect uri used in the authorization request.
+	RedirectURI string `form:"redirect_uri" json:"redirect_uri" query:"redirect_uri"`
+	// ClientID provides the client identifier, if the client is not
+	// authenticating with the authorization server.
+	ClientID string `form:"client_id" json:"client_id" query:"client_id"`
+	// CodeVerifier provides the code verifier to be verified.
+	CodeVerifier string `form:"code_verifier" json:"code_verifier" query:"code_verifier"`
+	// CodeChallengeMethod provides the code challenge method claimed by the
+	// client, if sent. RFC 7636 does not define the parameter for token
+	// requests, but if present it must not downgrade the registered method.
+	CodeChallengeMethod Method `form:"code_challenge_method" json:"code_challenge_method" query:"code_challenge_method"`
+}
+
+// ParseTokenRequest parses an access token request as specified in RFC 6749,
+// 4.1.3 and RFC 7636, 4.5, validating the received code verifier.
+//
+// The body is decoded according to its content type, which must be either
+// application/x-www-form-urlencoded, application/json or multipart/form-data,
+// otherwise ErrUnsupportedContentType is returned. The body is limited in size
+// as configured by WithMaxBodyBytes.
+func ParseTokenRequest(r *http.Request, opts ...ParseOption) (*TokenRequest, error) {
+	req := &TokenRequest{}
+	if err := req.Bind(r, opts...); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Bind decodes the access token request into req and validates it, as
+// ParseTokenRequest.
+func (req *TokenRequest) Bind(r *http.Request, opts ...ParseOption) error {
+	if err := parseTokenBody(r, newParseConfig(opts)); err != nil {
+		return err
+	}
+
+	*req = TokenRequest{
+		GrantType:           r.PostForm.Get(paramGrantType),
+		Code:                r.PostForm.Get(paramCode),
+		RedirectURI:         r.PostForm.Get(paramRedirectURI),
+		ClientID:            r.PostForm.Get(paramClientID),
+		CodeVerifier:        r.PostForm.Get(ParamCodeVerifier),
+		CodeChallengeMethod: Method(r.PostForm.Get(ParamCodeChallengeMethod)),
+	}
+
+	return req.Validate(opts...)
+}
+
+// Validate validates the code verifier of an access token request decoded by
+// other means, such as a framework's struct binding. The code verifier is
+// trimmed and decoded, and any code challenge method normalized, if permitted
+// by the parse options, in place.
+func (req *TokenRequest) Validate(opts ...ParseOption) error {
+	config := newParseConfig(opts)
+
+	if config.lenientMethodCase {
+		req.CodeChallengeMethod = normalizeMethod(req.CodeChallengeMethod)
+	}
+
+	if config.lenientVerifierSpace {
+		req.CodeVerifier = strings.TrimSpace(req.CodeVerifier)
+	}
+
+	if req.CodeVerifier == "" {
+		return ErrVerifierMissing
+	}
+
+	if containsPercentEncoding(req.CodeVerifier) {
+		if !config.lenientVerifierEncoding {
+			return ErrVerifierEncoding
+		}
+
+		codeVerifier, err := url.PathUnescape(req.CodeVerifier)
+		if err != nil {
+			return ErrVerifierEncoding
+		}
+		req.CodeVerifier = codeVerifier
+	}
+
+	if n := len(req.CodeVerifier); n < config.minVerifierLen || n > config.maxVerifierLen {
+		return ErrVerifierLength
+	}
+
+	return validateCodeVerifierCharacters([]byte(req.CodeVerifier))
+}
+
+// normalizeMethod returns the supported method matching method regardless of
+// case, or method if there is no match.
+func normalizeMethod(method Method) Method {
+	for _, supported := range []Method{Plain, S256, S384, S512} {
+		if strings.EqualFold(string(method), string(supported)) {
+			return supported
+		}
+	}
+
+	return method
+}
+
+// containsPercentEncoding returns whether s contains a percent-encoded octet.
+func containsPercentEncoding(s string) bool {
+	for i := 0; i+2 < len(s); i++ {
+		if s[i] == '%' && isHex(s[i+1]) && isHex(s[i+2]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isHex returns whether c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
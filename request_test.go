@@ -0,0 +1,562 @@
+package pkce
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTokenRequest returns a form encoded token request.
+func newTokenRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return r
+}
+
+func TestParseTokenRequest(t *testing.T) {
+	const codeVerifier = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+
+	tests := []struct {
+		name    string
+		form    url.Values
+		opts    []ParseOption
+		want    *TokenRequest
+		wantErr error
+	}{
+		{
+			name: "should parse a token request",
+			form: url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {"code"},
+				"redirect_uri":  {"https://client.example.com/cb"},
+				"client_id":     {"client"},
+				"code_verifier": {codeVerifier},
+			},
+			want: &TokenRequest{
+				GrantType:    "authorization_code",
+				Code:         "code",
+				RedirectURI:  "https://client.example.com/cb",
+				ClientID:     "client",
+				CodeVerifier: codeVerifier,
+			},
+		},
+		{
+			name: "should error on a missing code verifier",
+			form: url.Values{
+				"grant_type": {"authorization_code"},
+				"code":       {"code"},
+			},
+			wantErr: ErrVerifierMissing,
+		},
+		{
+			name: "should error on an invalid code verifier length",
+			form: url.Values{
+				"code_verifier": {"yolo"},
+			},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name: "should error on invalid code verifier characters",
+			form: url.Values{
+				"code_verifier": {codeVerifier + "!"},
+			},
+			wantErr: ErrVerifierCharacters,
+		},
+		{
+			name: "should error on surrounding whitespace by default",
+			form: url.Values{
+				"code_verifier": {" " + codeVerifier + "\n"},
+			},
+			wantErr: ErrVerifierCharacters,
+		},
+		{
+			name: "should trim surrounding whitespace when lenient",
+			form: url.Values{
+				"code_verifier": {" " + codeVerifier + "\n"},
+			},
+			opts: []ParseOption{WithLenientVerifierWhitespace()},
+			want: &TokenRequest{
+				CodeVerifier: codeVerifier,
+			},
+		},
+		{
+			name: "should error on a code verifier of only whitespace when lenient",
+			form: url.Values{
+				"code_verifier": {"  "},
+			},
+			opts:    []ParseOption{WithLenientVerifierWhitespace()},
+			wantErr: ErrVerifierMissing,
+		},
+		{
+			name: "should error on percent-encoded characters by default",
+			form: url.Values{
+				"code_verifier": {strings.Replace(codeVerifier, "~", "%7E", 1)},
+			},
+			wantErr: ErrVerifierEncoding,
+		},
+		{
+			name: "should decode percent-encoded characters when lenient",
+			form: url.Values{
+				"code_verifier": {strings.Replace(codeVerifier, "~", "%7E", 1)},
+			},
+			opts: []ParseOption{WithLenientVerifierEncoding()},
+			want: &TokenRequest{
+				CodeVerifier: codeVerifier,
+			},
+		},
+		{
+			name: "should validate decoded characters when lenient",
+			form: url.Values{
+				"code_verifier": {strings.Replace(codeVerifier, "~", "%21", 1)},
+			},
+			opts:    []ParseOption{WithLenientVerifierEncoding()},
+			wantErr: ErrVerifierCharacters,
+		},
+		{
+			name: "should error on a code verifier shorter than the configured length",
+			form: url.Values{
+				"code_verifier": {codeVerifier},
+			},
+			opts:    []ParseOption{WithVerifierLength(64, verifierMaxLen)},
+			wantErr: ErrVerifierLength,
+		},
+		{
+			name: "should parse a code verifier within the configured length",
+			form: url.Values{
+				"code_verifier": {codeVerifier + codeVerifier[:21]},
+			},
+			opts: []ParseOption{WithVerifierLength(64, verifierMaxLen)},
+			want: &TokenRequest{
+				CodeVerifier: codeVerifier + codeVerifier[:21],
+			},
+		},
+		{
+			name: "should ignore invalid configured lengths",
+			form: url.Values{
+				"code_verifier": {codeVerifier},
+			},
+			opts: []ParseOption{WithVerifierLength(16, verifierMaxLen)},
+			want: &TokenRequest{
+				CodeVerifier: codeVerifier,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTokenRequest(newTokenRequest(tt.form), tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("ParseTokenRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTokenRequest()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthorizationRequest(t *testing.T) {
+	const (
+		codeChallenge         = "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw"
+		standardCodeChallenge = "EF+/M9nkOE6p88FdlYXUHkBv96MeV56C/Dsqk9DGlxw="
+	)
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		opts    []ParseOption
+		want    *AuthorizationRequest
+		wantErr error
+	}{
+		{
+			name: "should parse an authorization request",
+			query: url.Values{
+				"response_type":         {"code"},
+				"client_id":             {"client"},
+				"redirect_uri":          {"https://client.example.com/cb"},
+				"scope":                 {"openid"},
+				"state":                 {"state"},
+				"code_challenge":        {codeChallenge},
+				"code_challenge_method": {"S256"},
+			},
+			want: &AuthorizationRequest{
+				ResponseType:        "code",
+				ClientID:            "client",
+				RedirectURI:         "https://client.example.com/cb",
+				Scope:               "openid",
+				State:               "state",
+				CodeChallenge:       codeChallenge,
+				CodeChallengeMethod: S256,
+			},
+		},
+		{
+			name: "should default to the plain method",
+			query: url.Values{
+				"code_challenge": {testCodeVerifier},
+			},
+			want: &AuthorizationRequest{
+				CodeChallenge:       testCodeVerifier,
+				CodeChallengeMethod: Plain,
+			},
+		},
+		{
+			name: "should error on a missing code challenge",
+			query: url.Values{
+				"code_challenge_method": {"S256"},
+			},
+			wantErr: ErrChallengeMissing,
+		},
+		{
+			name: "should error on an unsupported method",
+			query: url.Values{
+				"code_challenge":        {codeChallenge},
+				"code_challenge_method": {"S224"},
+			},
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name: "should error on an invalid code challenge",
+			query: url.Values{
+				"code_challenge":        {"yolo"},
+				"code_challenge_method": {"plain"},
+			},
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name: "should error on a truncated S256 code challenge",
+			query: url.Values{
+				"code_challenge":        {"yolo"},
+				"code_challenge_method": {"S256"},
+			},
+			wantErr: ErrChallengeDigest,
+		},
+		{
+			name: "should error on a standard base64 code challenge by default",
+			query: url.Values{
+				"code_challenge":        {standardCodeChallenge},
+				"code_challenge_method": {"S256"},
+			},
+			wantErr: ErrChallengeEncoding,
+		},
+		{
+			name: "should normalize a standard base64 code challenge when lenient",
+			query: url.Values{
+				"code_challenge":        {standardCodeChallenge},
+				"code_challenge_method": {"S256"},
+			},
+			opts: []ParseOption{WithLenientChallengeEncoding()},
+			want: &AuthorizationRequest{
+				CodeChallenge:       codeChallenge,
+				CodeChallengeMethod: S256,
+			},
+		},
+		{
+			name: "should not normalize a plain code challenge when lenient",
+			query: url.Values{
+				"code_challenge":        {standardCodeChallenge},
+				"code_challenge_method": {"plain"},
+			},
+			opts:    []ParseOption{WithLenientChallengeEncoding()},
+			wantErr: ErrChallengeInvalid,
+		},
+		{
+			name: "should error on a lowercase method by default",
+			query: url.Values{
+				"code_challenge":        {codeChallenge},
+				"code_challenge_method": {"s256"},
+			},
+			wantErr: ErrMethodNotSupported,
+		},
+		{
+			name: "should normalize a lowercase method when lenient",
+			query: url.Values{
+				"code_challenge":        {codeChallenge},
+				"code_challenge_method": {"s256"},
+			},
+			opts: []ParseOption{WithLenientMethodCase()},
+			want: &AuthorizationRequest{
+				CodeChallenge:       codeChallenge,
+				CodeChallengeMethod: S256,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/authorize?"+tt.query.Encode(), nil)
+
+			got, err := ParseAuthorizationRequest(r, tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("ParseAuthorizationRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAuthorizationRequest()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		opts    []ParseOption
+		want    AuthorizationRequest
+		wantErr error
+	}{
+		{
+			name: "should validate a bound request",
+			json: `{"client_id":"client","code_challenge":"` + testCodeChallenge + `","code_challenge_method":"S256"}`,
+			want: AuthorizationRequest{
+				ClientID:            "client",
+				CodeChallenge:       testCodeChallenge,
+				CodeChallengeMethod: S256,
+			},
+		},
+		{
+			name: "should normalize a padded code challenge if lenient",
+			json: `{"code_challenge":"` + testCodeChallenge + `=","code_challenge_method":"S256"}`,
+			opts: []ParseOption{WithLenientChallengeEncoding()},
+			want: AuthorizationRequest{
+				CodeChallenge:       testCodeChallenge,
+				CodeChallengeMethod: S256,
+			},
+		},
+		{
+			name: "should validate a S512 request",
+			json: `{"code_challenge":"` + testHashedChallenge(S512) + `","code_challenge_method":"S512"}`,
+			want: AuthorizationRequest{
+				CodeChallenge:       testHashedChallenge(S512),
+				CodeChallengeMethod: S512,
+			},
+		},
+		{
+			name:    "should error on a S384 code challenge of the wrong digest",
+			json:    `{"code_challenge":"` + testCodeChallenge + `","code_challenge_method":"S384"}`,
+			wantErr: ErrChallengeDigest,
+		},
+		{
+			name:    "should error on a missing code challenge",
+			json:    `{"client_id":"client"}`,
+			wantErr: ErrChallengeMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req AuthorizationRequest
+			if err := json.Unmarshal([]byte(tt.json), &req); err != nil {
+				t.Fatalf("Unmarshal() unexpected error: %v", err)
+			}
+
+			if err := req.Validate(tt.opts...); err != tt.wantErr {
+				t.Fatalf("Validate() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && req != tt.want {
+				t.Errorf("Validate() = %+v, want %+v", req, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenRequest_Bind(t *testing.T) {
+	r := newTokenRequest(url.Values{
+		paramGrantType:    {grantTypeAuthorizationCode},
+		paramCode:         {"code"},
+		ParamCodeVerifier: {" " + testCodeVerifier + " "},
+	})
+
+	req := TokenRequest{ClientID: "stale"}
+	if err := req.Bind(r, WithLenientVerifierWhitespace()); err != nil {
+		t.Fatalf("Bind() unexpected error: %v", err)
+	}
+
+	want := TokenRequest{
+		GrantType:    grantTypeAuthorizationCode,
+		Code:         "code",
+		CodeVerifier: testCodeVerifier,
+	}
+	if req != want {
+		t.Errorf("Bind() = %+v, want %+v", req, want)
+	}
+}
+
+func TestWithMaxBodyBytes(t *testing.T) {
+	form := url.Values{
+		paramGrantType:    {grantTypeAuthorizationCode},
+		paramCode:         {"code"},
+		ParamCodeVerifier: {testCodeVerifier},
+	}.Encode()
+
+	tests := []struct {
+		name          string
+		opts          []ParseOption
+		body          string
+		contentLength int64
+		wantErr       error
+	}{
+		{
+			name: "should parse a token request within the default limit",
+			body: form,
+		},
+		{
+			name:    "should error on a body exceeding the limit",
+			opts:    []ParseOption{WithMaxBodyBytes(int64(len(form) - 1))},
+			body:    form,
+			wantErr: ErrRequestTooLarge,
+		},
+		{
+			name:          "should error on a body of unknown length exceeding the limit",
+			opts:          []ParseOption{WithMaxBodyBytes(int64(len(form) - 1))},
+			body:          form,
+			contentLength: -1,
+			wantErr:       ErrRequestTooLarge,
+		},
+		{
+			name: "should parse a body at the limit",
+			opts: []ParseOption{WithMaxBodyBytes(int64(len(form)))},
+			body: form,
+		},
+		{
+			name: "should ignore a non-positive limit",
+			opts: []ParseOption{WithMaxBodyBytes(0)},
+			body: form,
+		},
+		{
+			name:    "should error on a body exceeding the default limit",
+			body:    form + "&padding=" + strings.Repeat("a", defaultMaxBodyBytes),
+			wantErr: ErrRequestTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.contentLength != 0 {
+				r.ContentLength = tt.contentLength
+			}
+
+			if _, err := ParseTokenRequest(r, tt.opts...); err != tt.wantErr {
+				t.Errorf("ParseTokenRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTokenRequest_contentType(t *testing.T) {
+	want := &TokenRequest{
+		GrantType:    grantTypeAuthorizationCode,
+		Code:         "code",
+		CodeVerifier: testCodeVerifier,
+	}
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	for name, value := range map[string]string{
+		paramGrantType:    grantTypeAuthorizationCode,
+		paramCode:         "code",
+		ParamCodeVerifier: testCodeVerifier,
+	} {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatalf("WriteField() unexpected error: %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		opts        []ParseOption
+		want        *TokenRequest
+		wantErr     error
+	}{
+		{
+			name:        "should parse a JSON token request",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"grant_type":"authorization_code","code":"code","code_verifier":"` + testCodeVerifier + `","expires_in":3600}`,
+			want:        want,
+		},
+		{
+			name:        "should parse a multipart token request",
+			contentType: mw.FormDataContentType(),
+			body:        multipartBody.String(),
+			want:        want,
+		},
+		{
+			name:        "should error on an oversized JSON token request",
+			contentType: "application/json",
+			body:        `{"code_verifier":"` + testCodeVerifier + `"}`,
+			opts:        []ParseOption{WithMaxBodyBytes(16)},
+			wantErr:     ErrRequestTooLarge,
+		},
+		{
+			name:        "should error on an oversized multipart token request",
+			contentType: mw.FormDataContentType(),
+			body:        multipartBody.String(),
+			opts:        []ParseOption{WithMaxBodyBytes(16)},
+			wantErr:     ErrRequestTooLarge,
+		},
+		{
+			name:        "should error on an unsupported content type",
+			contentType: "text/plain",
+			body:        "code_verifier=" + testCodeVerifier,
+			wantErr:     ErrUnsupportedContentType,
+		},
+		{
+			name:    "should error on a missing content type",
+			body:    "code_verifier=" + testCodeVerifier,
+			wantErr: ErrUnsupportedContentType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			got, err := ParseTokenRequest(r, tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("ParseTokenRequest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTokenRequest()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTokenRequest_jsonRestoresBody(t *testing.T) {
+	body := `{"grant_type":"authorization_code","code":"code","code_verifier":"` + testCodeVerifier + `"}`
+	r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+
+	if _, err := ParseTokenRequest(r); err != nil {
+		t.Fatalf("ParseTokenRequest() unexpected error: %v", err)
+	}
+
+	got, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() unexpected error: %v", err)
+	}
+	if string(got) != body {
+		t.Errorf("ParseTokenRequest() should restore the JSON body\ngot:  %s\nwant: %s\n", got, body)
+	}
+}
@@ -0,0 +1,170 @@
+package pkce
+
+import (
+	"net/http"
+)
+
+const (
+	// ResponseModeQuery specifies authorization response parameters are
+	// encoded in the query string of the redirect uri, as specified in
+	// OAuth 2.0 Multiple Response Type Encoding Practices.
+	ResponseModeQuery = "query"
+	// ResponseModeFormPost specifies authorization response parameters are
+	// encoded in a form encoded body POSTed to the redirect uri, as specified
+	// in OAuth 2.0 Form Post Response Mode.
+	ResponseModeFormPost = "form_post"
+
+	// RFC 6749, 4.1.2.1. Error Response parameters.
+	paramError            = "error"
+	paramErrorDescription = "error_description"
+	paramErrorURI         = "error_uri"
+
+	// RFC 9207, 2. Response Parameter iss.
+	paramIssuer = "iss"
+)
+
+// WithIssuer enables validating the iss parameter of authorization responses
+// against the issuer identifier of the authorization server the request was
+// sent to, as specified in RFC 9207, protecting clients of multiple
+// authorization servers from mix-up attacks.
+//
+// Responses with a mismatched iss parameter are rejected with
+// ErrIssuerMismatch. Responses without an iss parameter are accepted, unless
+// WithIssuerRequired is also specified.
+func WithIssuer(issuer string) ParseOption {
+	return func(config *parseConfig) {
+		config.issuer = issuer
+	}
+}
+
+// WithIssuerRequired enables rejecting authorization responses without an iss
+// parameter with ErrIssuerMissing, for authorization servers advertising
+// authorization_response_iss_parameter_supported in their metadata, as
+// required by RFC 9207, 2.4.
+func WithIssuerRequired() ParseOption {
+	return func(config *parseConfig) {
+		config.issuerRequired = true
+	}
+}
+
+// AuthorizationResponse provides the parameters of an authorization response
+// received at the client's redirect uri.
+type AuthorizationResponse struct {
+	// ResponseMode provides the response mode the response was received with,
+	// either ResponseModeQuery or ResponseModeFormPost.
+	ResponseMode string
+	// Code provides the authorization code to exchange.
+	Code string
+	// State provides the state value sent in the authorization request.
+	State string
+	// Issuer provides the issuer identifier of the authorization server which
+	// created the response, if provided.
+	Issuer string
+	// Error provides the error code, if the authorization request failed.
+	Error string
+	// ErrorDescription provides a description of the error, if provided.
+	ErrorDescription string
+	// ErrorURI provides a uri describing the error, if provided.
+	ErrorURI string
+}
+
+// AuthorizationError is returned when an authorization response reports the
+// authorization request failed, as specified in RFC 6749, 4.1.2.1.
+type AuthorizationError struct {
+	// Code provides the error code, such as "access_denied".
+	Code string
+	// Description provides a description of the error, if provided.
+	Description string
+	// URI provides a uri describing the error, if provided.
+	URI string
+}
+
+func (e *AuthorizationError) Error() string {
+	if e.Description == "" {
+		return "authorization request failed: " + e.Code
+	}
+
+	return "authorization request failed: " + e.Code + ": " + e.Description
+}
+
+// ID implements Identifier. The identifier is derived from the error code,
+// such as "pkce.authorization.access_denied".
+func (e *AuthorizationError) ID() string {
+	return "pkce.authorization." + e.Code
+}
+
+// ParseAuthorizationResponse parses an authorization response received at the
+// client's redirect uri, as specified in RFC 6749, 4.1.2, supporting both the
+// query and form_post response modes.
+//
+// If an issuer is configured with WithIssuer, the iss parameter is validated
+// before any other parameter is trusted, so the authorization code, and the
+// code verifier it is exchanged with, are never released to a mixed-up
+// authorization server.
+//
+// If the response reports the authorization request failed, the parsed
+// response is returned along with an *AuthorizationError. If the response does
+// not contain an authorization code, ErrCodeMissing is returned.
+func ParseAuthorizationResponse(r *http.Request, opts ...ParseOption) (*AuthorizationResponse, error) {
+	config := newParseConfig(opts)
+
+	resp := &AuthorizationResponse{
+		ResponseMode: ResponseModeQuery,
+	}
+
+	params := r.URL.Query()
+	if r.Method == http.MethodPost {
+		// parameters of a form_post response are only read from the body, so
+		// they can not be mixed with parameters injected in the query string.
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+
+		resp.ResponseMode = ResponseModeFormPost
+		params = r.PostForm
+	}
+
+	resp.Code = params.Get(paramCode)
+	resp.State = params.Get(paramState)
+	resp.Error = params.Get(paramError)
+	resp.ErrorDescription = params.Get(paramErrorDescription)
+	resp.ErrorURI = params.Get(paramErrorURI)
+	resp.Issuer = params.Get(paramIssuer)
+
+	if err := config.validateIssuer(resp.Issuer); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != "" {
+		return resp, &AuthorizationError{
+			Code:        resp.Error,
+			Description: resp.ErrorDescription,
+			URI:         resp.ErrorURI,
+		}
+	}
+
+	if resp.Code == "" {
+		return nil, ErrCodeMissing
+	}
+
+	return resp, nil
+}
+
+// validateIssuer validates the iss parameter of an authorization response
+// against the configured issuer.
+func (c parseConfig) validateIssuer(issuer string) error {
+	if issuer == "" {
+		if c.issuerRequired {
+			return ErrIssuerMissing
+		}
+
+		return nil
+	}
+
+	// RFC 9207, 2.4. Compared using simple string comparison.
+	if c.issuer != "" && issuer != c.issuer {
+		return ErrIssuerMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package pkce
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAuthorizationResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		query   url.Values
+		form    url.Values
+		want    *AuthorizationResponse
+		wantErr error
+	}{
+		{
+			name:   "should parse a query response",
+			method: http.MethodGet,
+			query: url.Values{
+				"code":  {"code"},
+				"state": {"state"},
+			},
+			want: &AuthorizationResponse{
+				ResponseMode: ResponseModeQuery,
+				Code:         "code",
+				State:        "state",
+			},
+		},
+		{
+			name:   "should parse a form_post response",
+			method: http.MethodPost,
+			form: url.Values{
+				"code":  {"code"},
+				"state": {"state"},
+			},
+			want: &AuthorizationResponse{
+				ResponseMode: ResponseModeFormPost,
+				Code:         "code",
+				State:        "state",
+			},
+		},
+		{
+			name:   "should ignore query parameters of a form_post response",
+			method: http.MethodPost,
+			query: url.Values{
+				"code": {"injected"},
+			},
+			form: url.Values{
+				"state": {"state"},
+			},
+			wantErr: ErrCodeMissing,
+		},
+		{
+			name:   "should error on a missing code",
+			method: http.MethodGet,
+			query: url.Values{
+				"state": {"state"},
+			},
+			wantErr: ErrCodeMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/cb?"+tt.query.Encode(), strings.NewReader(tt.form.Encode()))
+			if tt.method == http.MethodPost {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+
+			got, err := ParseAuthorizationResponse(r)
+			if err != tt.wantErr {
+				t.Fatalf("ParseAuthorizationResponse() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAuthorizationResponse()\ngot:  %+v\nwant: %+v\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAuthorizationResponse_error(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		t.Run(method, func(t *testing.T) {
+			params := url.Values{
+				"error":             {"access_denied"},
+				"error_description": {"the resource owner denied the request"},
+				"error_uri":         {"https://as.example.com/errors/access_denied"},
+				"state":             {"state"},
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/cb?"+params.Encode(), nil)
+			if method == http.MethodPost {
+				r = httptest.NewRequest(http.MethodPost, "/cb", strings.NewReader(params.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+
+			got, err := ParseAuthorizationResponse(r)
+			authErr, ok := err.(*AuthorizationError)
+			if !ok {
+				t.Fatalf("ParseAuthorizationResponse() error type not expected\ngot:  %v, want: *AuthorizationError\n", err)
+			}
+
+			want := &AuthorizationError{
+				Code:        "access_denied",
+				Description: "the resource owner denied the request",
+				URI:         "https://as.example.com/errors/access_denied",
+			}
+			if !reflect.DeepEqual(authErr, want) {
+				t.Errorf("ParseAuthorizationResponse() error\ngot:  %+v\nwant: %+v\n", authErr, want)
+			}
+
+			if got == nil || got.State != "state" || got.Error != "access_denied" {
+				t.Errorf("ParseAuthorizationResponse() should return the error response, got: %+v", got)
+			}
+		})
+	}
+}
+
+func TestWithIssuer(t *testing.T) {
+	const issuer = "https://as.example.com"
+
+	tests := []struct {
+		name    string
+		query   url.Values
+		opts    []ParseOption
+		wantErr error
+	}{
+		{
+			name: "should accept a matching issuer",
+			query: url.Values{
+				"code": {"code"},
+				"iss":  {issuer},
+			},
+			opts: []ParseOption{WithIssuer(issuer)},
+		},
+		{
+			name: "should error on a mismatched issuer",
+			query: url.Values{
+				"code": {"code"},
+				"iss":  {"https://attacker.example.com"},
+			},
+			opts:    []ParseOption{WithIssuer(issuer)},
+			wantErr: ErrIssuerMismatch,
+		},
+		{
+			name: "should error on an issuer differing by a trailing slash",
+			query: url.Values{
+				"code": {"code"},
+				"iss":  {issuer + "/"},
+			},
+			opts:    []ParseOption{WithIssuer(issuer)},
+			wantErr: ErrIssuerMismatch,
+		},
+		{
+			name: "should error on a mismatched issuer of an error response",
+			query: url.Values{
+				"error": {"access_denied"},
+				"iss":   {"https://attacker.example.com"},
+			},
+			opts:    []ParseOption{WithIssuer(issuer)},
+			wantErr: ErrIssuerMismatch,
+		},
+		{
+			name: "should accept a missing issuer by default",
+			query: url.Values{
+				"code": {"code"},
+			},
+			opts: []ParseOption{WithIssuer(issuer)},
+		},
+		{
+			name: "should error on a missing required issuer",
+			query: url.Values{
+				"code": {"code"},
+			},
+			opts:    []ParseOption{WithIssuer(issuer), WithIssuerRequired()},
+			wantErr: ErrIssuerMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/cb?"+tt.query.Encode(), nil)
+
+			got, err := ParseAuthorizationResponse(r, tt.opts...)
+			if err != tt.wantErr {
+				t.Fatalf("ParseAuthorizationResponse() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err == nil && got.Issuer != tt.query.Get("iss") {
+				t.Errorf("ParseAuthorizationResponse() issuer = %v, want %v", got.Issuer, tt.query.Get("iss"))
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package pkce
+
+import (
+	"errors"
+)
+
+// ResultCode provides a stable, machine-readable classification of the
+// outcome of a verification, suitable for metrics labels and API responses.
+// Unlike error strings, result codes will not change between releases.
+type ResultCode string
+
+// String implements Stringer.
+func (c ResultCode) String() string {
+	return string(c)
+}
+
+const (
+	// ResultVerifierMismatch specifies the code verifier did not prove the
+	// code challenge.
+	ResultVerifierMismatch ResultCode = "verifier_mismatch"
+	// ResultMethodUnsupported specifies the code challenge method is unknown,
+	// or not allowed by the configured policy.
+	ResultMethodUnsupported ResultCode = "method_unsupported"
+	// ResultMethodDowngrade specifies the code verifier was presented under a
+	// weaker method than the one registered, as specified in RFC 7636, 7.2.
+	ResultMethodDowngrade ResultCode = "method_downgrade"
+	// ResultExpired specifies the key had expired.
+	ResultExpired ResultCode = "expired"
+	// ResultReused specifies a code verifier or code challenge has already
+	// been used for another flow.
+	ResultReused ResultCode = "reused"
+	// ResultNotFound specifies no key was registered for the flow, or it has
+	// already been consumed.
+	ResultNotFound ResultCode = "not_found"
+	// ResultMalformed specifies the code verifier or code challenge is not
+	// compliant with RFC 7636, or is missing.
+	ResultMalformed ResultCode = "malformed"
+	// ResultSessionMismatch specifies the code verifier was presented from a
+	// different session than the one the code challenge was bound to.
+	ResultSessionMismatch ResultCode = "session_mismatch"
+	// ResultRedirectURIMismatch specifies the token request's redirect uri
+	// did not match the one recorded for the flow.
+	ResultRedirectURIMismatch ResultCode = "redirect_uri_mismatch"
+	// ResultClientMismatch specifies the token request's client_id did not
+	// match the one recorded for the flow.
+	ResultClientMismatch ResultCode = "client_mismatch"
+	// ResultUnavailable specifies the store was temporarily unavailable.
+	ResultUnavailable ResultCode = "unavailable"
+	// ResultInternal specifies verification failed for a reason not
+	// attributable to the request, such as a store error.
+	ResultInternal ResultCode = "internal_error"
+)
+
+// NewVerificationResult returns the result of a verification that failed with
+// err, or succeeded if err is nil.
+func NewVerificationResult(err error) VerificationResult {
+	if err == nil {
+		return VerificationResult{Verified: true}
+	}
+
+	return VerificationResult{
+		Err:    err,
+		Reason: failureReason(err),
+		Code:   ResultCodeOf(err),
+	}
+}
+
+// ResultCodeOf returns the result code classifying a verification error. An
+// empty code is returned for a nil error.
+func ResultCodeOf(err error) ResultCode {
+	switch {
+	case err == nil:
+		return ""
+
+	case errors.Is(err, ErrVerifierMismatch):
+		return ResultVerifierMismatch
+
+	case errors.Is(err, ErrMethodNotSupported),
+		errors.Is(err, ErrMethodNotAllowed):
+		return ResultMethodUnsupported
+
+	case errors.Is(err, ErrMethodDowngrade):
+		return ResultMethodDowngrade
+
+	case errors.Is(err, ErrKeyExpired):
+		return ResultExpired
+
+	case errors.Is(err, ErrKeyNotFound):
+		return ResultNotFound
+
+	case errors.Is(err, ErrVerifierReused):
+		return ResultReused
+
+	case errors.Is(err, ErrVerifierLength),
+		errors.Is(err, ErrVerifierCharacters),
+		errors.Is(err, ErrVerifierEncoding),
+		errors.Is(err, ErrVerifierEntropy),
+		errors.Is(err, ErrVerifierMissing),
+		errors.Is(err, ErrChallengeInvalid),
+		errors.Is(err, ErrChallengeEncoding),
+		errors.Is(err, ErrChallengeDigest),
+		errors.Is(err, ErrChallengeMissing):
+		return ResultMalformed
+
+	case errors.Is(err, ErrSessionMismatch):
+		return ResultSessionMismatch
+
+	case errors.Is(err, ErrRedirectURIMismatch):
+		return ResultRedirectURIMismatch
+
+	case errors.Is(err, ErrClientMismatch):
+		return ResultClientMismatch
+
+	case errors.Is(err, ErrStoreUnavailable):
+		return ResultUnavailable
+
+	default:
+		return ResultInternal
+	}
+}
+
+// VerifyResult verifies the code verifier as VerifyCodeVerifier, returning a
+// result classifying why verification failed. Expired keys fail with
+// ErrKeyExpired, non-compliant code verifiers with their validation error, and
+// S256 code challenges presented as the code verifier with
+// ErrMethodDowngrade.
+func (k *Key) VerifyResult(codeVerifier string) VerificationResult {
+	return NewVerificationResult(k.verify(codeVerifier))
+}
+
+// verify verifies the code verifier, returning why verification failed.
+func (k *Key) verify(codeVerifier string) error {
+	if k.Expired() {
+		return ErrKeyExpired
+	}
+
+	if !isKeyMethod(k.ChallengeMethod()) {
+		return ErrMethodNotSupported
+	}
+
+	if err := validateCodeVerifier([]byte(codeVerifier)); err != nil {
+		return err
+	}
+
+	if !k.VerifyCodeVerifier(codeVerifier) {
+		// RFC 7636, 7.2. Presenting the hashed code challenge as the code
+		// verifier is an attempt to prove possession using "plain".
+		if k.ChallengeMethod() != Plain && ChallengesEqual(codeVerifier, k.CodeChallenge()) {
+			return ErrMethodDowngrade
+		}
+
+		return ErrVerifierMismatch
+	}
+
+	return nil
+}
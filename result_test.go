@@ -0,0 +1,98 @@
+package pkce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ResultCode
+	}{
+		{name: "should return an empty code for nil", err: nil, want: ""},
+		{name: "should classify a mismatch", err: ErrVerifierMismatch, want: ResultVerifierMismatch},
+		{name: "should classify an unsupported method", err: ErrMethodNotSupported, want: ResultMethodUnsupported},
+		{name: "should classify a disallowed method", err: ErrMethodNotAllowed, want: ResultMethodUnsupported},
+		{name: "should classify a downgrade", err: ErrMethodDowngrade, want: ResultMethodDowngrade},
+		{name: "should classify an expired key", err: ErrKeyExpired, want: ResultExpired},
+		{name: "should classify a missing key", err: ErrKeyNotFound, want: ResultNotFound},
+		{name: "should classify an invalid code verifier", err: ErrVerifierLength, want: ResultMalformed},
+		{name: "should classify an unavailable store", err: ErrStoreUnavailable, want: ResultUnavailable},
+		{name: "should classify a wrapped error", err: fmt.Errorf("verifying: %w", ErrKeyExpired), want: ResultExpired},
+		{name: "should classify an unknown error as internal", err: errors.New("yolo"), want: ResultInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResultCodeOf(tt.err); got != tt.want {
+				t.Errorf("ResultCodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey_VerifyResult(t *testing.T) {
+	clock := newTestClock()
+	key, err := New(WithCodeVerifierString(testCodeVerifier), WithClock(clock), WithExpiry(time.Minute))
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		codeVerifier string
+		advance      time.Duration
+		wantErr      error
+		wantCode     ResultCode
+	}{
+		{
+			name:         "should verify the code verifier",
+			codeVerifier: testCodeVerifier,
+		},
+		{
+			name:         "should classify a mismatched code verifier",
+			codeVerifier: strings.Repeat("a", verifierMinLen),
+			wantErr:      ErrVerifierMismatch,
+			wantCode:     ResultVerifierMismatch,
+		},
+		{
+			name:         "should classify a downgraded code verifier",
+			codeVerifier: testCodeChallenge,
+			wantErr:      ErrMethodDowngrade,
+			wantCode:     ResultMethodDowngrade,
+		},
+		{
+			name:         "should classify an invalid code verifier",
+			codeVerifier: "yolo",
+			wantErr:      ErrVerifierLength,
+			wantCode:     ResultMalformed,
+		},
+		{
+			name:         "should classify an expired key",
+			codeVerifier: testCodeVerifier,
+			advance:      time.Minute,
+			wantErr:      ErrKeyExpired,
+			wantCode:     ResultExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+
+			got := key.VerifyResult(tt.codeVerifier)
+			if got.Err != tt.wantErr {
+				t.Fatalf("VerifyResult() error type not expected\ngot:  %v, want: %v\n", got.Err, tt.wantErr)
+			}
+
+			if got.Verified != (tt.wantErr == nil) || got.Code != tt.wantCode {
+				t.Errorf("VerifyResult() = %+v, want code %q", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package pkce
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultReuseCacheSize provides the default number of code verifiers
+// remembered by a ReuseCache.
+const DefaultReuseCacheSize = 10000
+
+// ReuseCache remembers the code verifiers supplied to keys within the process,
+// detecting the same code verifier being supplied to more than one key, a
+// common copy-paste bug in client code which defeats the protection PKCE
+// provides.
+//
+// Only the SHA-256 digest of each code verifier is remembered, with the least
+// recently seen evicted once the cache is full. ReuseCache is intended to
+// catch mistakes during development and testing, and is opt-in via
+// WithReuseCache.
+type ReuseCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+}
+
+// NewReuseCache returns a cache remembering up to size code verifiers. A
+// non-positive size defaults to DefaultReuseCacheSize.
+func NewReuseCache(size int) *ReuseCache {
+	if size < 1 {
+		size = DefaultReuseCacheSize
+	}
+
+	return &ReuseCache{
+		size:    size,
+		entries: map[[sha256.Size]byte]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Observe records the code verifier as supplied to a key, returning
+// ErrVerifierReused if it has already been supplied to another key.
+func (c *ReuseCache) Observe(codeVerifier []byte) error {
+	sum := sha256.Sum256(codeVerifier)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[sum]; ok {
+		c.order.MoveToFront(el)
+		return ErrVerifierReused
+	}
+
+	c.entries[sum] = c.order.PushFront(sum)
+	for c.order.Len() > c.size {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.([sha256.Size]byte))
+	}
+
+	return nil
+}
+
+// Len returns the number of code verifiers remembered.
+func (c *ReuseCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// WithReuseCache enables detecting a code verifier supplied by WithCodeVerifier
+// or WithCodeVerifierString having already been supplied to another key
+// observed by cache, in which case New returns ErrVerifierReused.
+//
+// Generated code verifiers are not observed, as they are never reused.
+func WithReuseCache(cache *ReuseCache) Option {
+	return func(key *Key) (err error) {
+		key.reuseCache = cache
+
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package pkce
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithReuseCache(t *testing.T) {
+	cache := NewReuseCache(0)
+
+	if _, err := New(WithReuseCache(cache), WithCodeVerifierString(testCodeVerifier)); err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if _, err := New(WithCodeVerifierString(testCodeVerifier), WithReuseCache(cache)); err != ErrVerifierReused {
+		t.Errorf("New() error type not expected\ngot:  %v, want: %v\n", err, ErrVerifierReused)
+	}
+
+	if _, err := New(WithCodeVerifierString(testCodeVerifier)); err != nil {
+		t.Errorf("New() unexpected error without a reuse cache: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := New(WithReuseCache(cache)); err != nil {
+			t.Fatalf("New() unexpected error generating a code verifier: %v", err)
+		}
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("Len() = %d, want only the supplied code verifier to be remembered", cache.Len())
+	}
+}
+
+func TestReuseCache_Observe(t *testing.T) {
+	cache := NewReuseCache(2)
+	a := []byte(strings.Repeat("a", verifierMinLen))
+	b := []byte(strings.Repeat("b", verifierMinLen))
+	c := []byte(strings.Repeat("c", verifierMinLen))
+
+	for _, codeVerifier := range [][]byte{a, b, c} {
+		if err := cache.Observe(codeVerifier); err != nil {
+			t.Fatalf("Observe() unexpected error: %v", err)
+		}
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d, want %d", cache.Len(), 2)
+	}
+
+	if err := cache.Observe(c); err != ErrVerifierReused {
+		t.Errorf("Observe() error type not expected\ngot:  %v, want: %v\n", err, ErrVerifierReused)
+	}
+
+	// the least recently seen code verifier has been evicted.
+	if err := cache.Observe(a); err != nil {
+		t.Errorf("Observe() unexpected error for an evicted code verifier: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package pkce
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"sync"
+)
+
+// scratchEncodedLen provides the length of the longest code challenge
+// encoding, being the hex encoding of a SHA-512 digest.
+const scratchEncodedLen = sha512.Size * 2
+
+// scratchPool provides scratch buffers reused across code challenge
+// computations, so that verification in high throughput token endpoints does
+// not produce garbage.
+var scratchPool = sync.Pool{ //nolint:gochecknoglobals // buffers are shared across calls by design.
+	New: func() interface{} {
+		return &scratch{
+			verifier:  make([]byte, 0, verifierMaxLen),
+			challenge: make([]byte, 0, verifierMaxLen),
+		}
+	},
+}
+
+// scratch provides buffers for computing and comparing a code challenge. The
+// digest itself is computed on the stack, then copied into sum so that it can
+// be sliced without escaping to the heap.
+type scratch struct {
+	verifier  []byte
+	challenge []byte
+	sum       [sha512.Size]byte
+	encoded   [scratchEncodedLen]byte
+}
+
+// getScratch returns scratch buffers from the pool, which must be returned
+// with release.
+func getScratch() *scratch {
+	return scratchPool.Get().(*scratch)
+}
+
+// release zeroes the buffers, so that code verifiers do not linger in memory,
+// and returns them to the pool.
+func (s *scratch) release() {
+	for i := range s.verifier {
+		s.verifier[i] = 0
+	}
+	for i := range s.sum {
+		s.sum[i] = 0
+	}
+	for i := range s.encoded {
+		s.encoded[i] = 0
+	}
+	s.verifier = s.verifier[:0]
+	s.challenge = s.challenge[:0]
+
+	scratchPool.Put(s)
+}
+
+// transform performs the method's transform of the code verifier, encoding
+// S256 output with the specified encoding. The returned slice is only valid
+// until the scratch buffers are released.
+func (s *scratch) transform(method Method, encoding ChallengeEncoding, codeVerifier []byte) []byte {
+	if method == Plain {
+		return codeVerifier
+	}
+
+	sum := s.digest(method, codeVerifier)
+
+	switch encoding {
+	case Base64URLPadded:
+		out := s.encoded[:base64.URLEncoding.EncodedLen(len(sum))]
+		base64.URLEncoding.Encode(out, sum)
+		return out
+
+	case Hex:
+		out := s.encoded[:hex.EncodedLen(len(sum))]
+		hex.Encode(out, sum)
+		return out
+
+	default:
+		out := s.encoded[:base64.RawURLEncoding.EncodedLen(len(sum))]
+		base64.RawURLEncoding.Encode(out, sum)
+		return out
+	}
+}
+
+// digest computes the digest of the code verifier using the method's hash.
+// The returned slice is only valid until the scratch buffers are released.
+func (s *scratch) digest(method Method, codeVerifier []byte) []byte {
+	switch method {
+	case S384:
+		sum := sha512.Sum384(codeVerifier)
+		return s.sum[:copy(s.sum[:], sum[:])]
+
+	case S512:
+		sum := sha512.Sum512(codeVerifier)
+		return s.sum[:copy(s.sum[:], sum[:])]
+
+	default:
+		sum := sha256.Sum256(codeVerifier)
+		return s.sum[:copy(s.sum[:], sum[:])]
+	}
+}
+
+// verify reports whether the code verifier is specification compliant, and
+// transforms to the code challenge, comparing in constant time.
+func (s *scratch) verify(method Method, encoding ChallengeEncoding, codeVerifier []byte, codeChallenge []byte) bool {
+	if validateCodeVerifier(codeVerifier) != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(s.transform(method, encoding, codeVerifier), codeChallenge) == 1
+}
+
+// verifyChallenge reports whether the code verifier proves the code challenge,
+// as verify, copying the strings into scratch buffers rather than allocating.
+func verifyChallenge(method Method, encoding ChallengeEncoding, codeVerifier string, codeChallenge string) bool {
+	// neither can be valid, and must not grow the pooled buffers.
+	if len(codeVerifier) > verifierMaxLen || len(codeChallenge) > verifierMaxLen {
+		return false
+	}
+
+	s := getScratch()
+	defer s.release()
+
+	s.verifier = append(s.verifier, codeVerifier...)
+	s.challenge = append(s.challenge, codeChallenge...)
+
+	return s.verify(method, encoding, s.verifier, s.challenge)
+}
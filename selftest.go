@@ -0,0 +1,46 @@
+package pkce
+
+// The known-answer code verifier and code challenge specified in RFC 7636,
+// Appendix B.
+const (
+	selfTestCodeVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	selfTestCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+// SelfTest runs the RFC 7636, Appendix B known-answer vector through the S256
+// transform and the code verifier validator, returning ErrSelfTest if either
+// produces an unexpected result.
+//
+// This is intended to be called at process start in environments requiring
+// power-on self-tests of cryptographic functionality, such as those following
+// FIPS 140 practices.
+func SelfTest() error {
+	return selfTest(selfTestCodeVerifier, selfTestCodeChallenge)
+}
+
+// selfTest runs the known-answer test for the given vector.
+func selfTest(codeVerifier string, codeChallenge string) error {
+	if validateCodeVerifier([]byte(codeVerifier)) != nil {
+		return ErrSelfTest
+	}
+
+	if !ChallengesEqual(generateCodeChallenge(S256, []byte(codeVerifier)), codeChallenge) {
+		return ErrSelfTest
+	}
+
+	if !VerifyCodeVerifier(S256, codeVerifier, codeChallenge) {
+		return ErrSelfTest
+	}
+
+	// the validator must reject a non-compliant code verifier, and
+	// verification must reject a modified code verifier.
+	if validateCodeVerifier([]byte(codeVerifier+"!")) == nil {
+		return ErrSelfTest
+	}
+
+	if VerifyCodeVerifier(S256, codeVerifier[1:]+codeVerifier[:1], codeChallenge) {
+		return ErrSelfTest
+	}
+
+	return nil
+}
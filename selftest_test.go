@@ -0,0 +1,46 @@
+package pkce
+
+import (
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Errorf("SelfTest() unexpected error: %v", err)
+	}
+}
+
+func Test_selfTest(t *testing.T) {
+	tests := []struct {
+		name          string
+		codeVerifier  string
+		codeChallenge string
+		wantErr       error
+	}{
+		{
+			name:          "should pass the known-answer vector",
+			codeVerifier:  selfTestCodeVerifier,
+			codeChallenge: selfTestCodeChallenge,
+		},
+		{
+			name:          "should fail an unexpected code challenge",
+			codeVerifier:  selfTestCodeVerifier,
+			codeChallenge: testCodeChallenge,
+			wantErr:       ErrSelfTest,
+		},
+		{
+			name:          "should fail a non-compliant code verifier",
+			codeVerifier:  "yolo",
+			codeChallenge: selfTestCodeChallenge,
+			wantErr:       ErrSelfTest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := selfTest(tt.codeVerifier, tt.codeChallenge); err != tt.wantErr {
+				t.Errorf("selfTest() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
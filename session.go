@@ -0,0 +1,109 @@
+package pkce
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// sessionSecretMinLen provides the minimum length of a session binding secret.
+const sessionSecretMinLen = 32
+
+// RegisterSession persists the code challenge as Register, bound to the
+// session that initiated the flow, such as a hash of the browser's session
+// cookie, if session binding is enabled with WithManagerSessionBinding.
+func (m *KeyManager) RegisterSession(ctx context.Context, id string, session string, method Method, codeChallenge string) error {
+	return m.register(ctx, registerRequest{
+		id:            m.binder.ID(id),
+		session:       session,
+		method:        method,
+		codeChallenge: codeChallenge,
+	})
+}
+
+// VerifySession verifies the code verifier as Verify, additionally returning
+// ErrSessionMismatch if session binding is enabled and the code challenge was
+// registered by a different session.
+func (m *KeyManager) VerifySession(ctx context.Context, id string, session string, codeVerifier string) error {
+	return m.verifyAudited(ctx, verifyRequest{
+		id:           id,
+		session:      session,
+		codeVerifier: codeVerifier,
+	})
+}
+
+// validateSessionSecret ensures a configured session binding secret is long
+// enough to be secure.
+func (m *KeyManager) validateSessionSecret() error {
+	if m.sessionSecret != nil && len(m.sessionSecret) < sessionSecretMinLen {
+		return ErrSessionSecret
+	}
+
+	return nil
+}
+
+// sessionTag returns the HMAC binding the code challenge to the session, or
+// nil if session binding is disabled. Fields are length-prefixed, so that
+// distinct pairs can not produce the same input.
+func (m *KeyManager) sessionTag(session string, codeChallenge string) []byte {
+	if m.sessionSecret == nil {
+		return nil
+	}
+
+	mac := hmac.New(sha256.New, m.sessionSecret)
+	for _, field := range []string{session, codeChallenge} {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(field)))
+		mac.Write(size[:])
+		mac.Write([]byte(field))
+	}
+
+	return mac.Sum(nil)
+}
+
+// putKey encodes and stores the key under the store id, prefixed with the
+// session tag if session binding is enabled.
+func (m *KeyManager) putKey(ctx context.Context, id string, key *Key, tag []byte) error {
+	data, err := key.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	if m.sessionSecret != nil {
+		data = append(append(make([]byte, 0, len(tag)+len(data)), tag...), data...)
+	}
+
+	return newStoreError(m.store.Put(ctx, id, data, m.ttl))
+}
+
+// getKey retrieves and decodes the key stored under the store id, returning
+// its session tag if session binding is enabled. If consume is set, the key is
+// atomically removed.
+func (m *KeyManager) getKey(ctx context.Context, id string, consume bool) (*Key, []byte, error) {
+	var data []byte
+	var err error
+	if consume {
+		data, err = m.store.Consume(ctx, id)
+	} else {
+		data, err = m.store.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, nil, newStoreError(err)
+	}
+
+	var tag []byte
+	if m.sessionSecret != nil {
+		if len(data) < sha256.Size {
+			return nil, nil, ErrKeyEncoding
+		}
+		tag, data = data[:sha256.Size], data[sha256.Size:]
+	}
+
+	key := &Key{}
+	if err = key.UnmarshalBinary(data); err != nil {
+		return nil, nil, err
+	}
+
+	return key, tag, nil
+}
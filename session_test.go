@@ -0,0 +1,131 @@
+package pkce
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestKeyManager_VerifySession(t *testing.T) {
+	secret := bytes.Repeat([]byte{1}, sessionSecretMinLen)
+
+	tests := []struct {
+		name        string
+		opts        []ManagerOption
+		session     string
+		registerErr error
+		wantErr     error
+	}{
+		{
+			name:    "should verify from the registering session",
+			opts:    []ManagerOption{WithManagerSessionBinding(secret)},
+			session: "session",
+		},
+		{
+			name:    "should error verifying from a different session",
+			opts:    []ManagerOption{WithManagerSessionBinding(secret)},
+			session: "other",
+			wantErr: ErrSessionMismatch,
+		},
+		{
+			name:    "should ignore sessions without session binding",
+			session: "other",
+		},
+		{
+			name:        "should error on a short secret",
+			opts:        []ManagerOption{WithManagerSessionBinding(secret[1:])},
+			session:     "session",
+			registerErr: ErrSessionSecret,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewKeyManager(NewMemoryStore(), tt.opts...)
+
+			err := m.RegisterSession(context.Background(), "code", "session", S256, testCodeChallenge)
+			if err != tt.registerErr {
+				t.Fatalf("RegisterSession() error type not expected\ngot:  %v, want: %v\n", err, tt.registerErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if err = m.VerifySession(context.Background(), "code", tt.session, testCodeVerifier); err != tt.wantErr {
+				t.Errorf("VerifySession() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyManager_BindPushed_session(t *testing.T) {
+	ctx := context.Background()
+	m := NewKeyManager(NewMemoryStore(), WithManagerSessionBinding(bytes.Repeat([]byte{1}, sessionSecretMinLen)))
+	if err := m.RegisterPushed(ctx, testRequestURI, S256, testCodeChallenge); err != nil {
+		t.Fatalf("RegisterPushed() unexpected error: %v", err)
+	}
+
+	if err := m.BindPushed(ctx, testRequestURI, "code"); err != nil {
+		t.Fatalf("BindPushed() unexpected error: %v", err)
+	}
+
+	if err := m.Verify(ctx, "code", testCodeVerifier); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+}
+
+func TestWithSession(t *testing.T) {
+	m := NewKeyManager(NewMemoryStore(), WithManagerSessionBinding(bytes.Repeat([]byte{1}, sessionSecretMinLen)))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := m.Middleware(next, WithSession(func(r *http.Request) string {
+		return r.Header.Get("X-Session")
+	}))
+
+	for _, session := range []string{"a", "b"} {
+		if err := m.RegisterSession(context.Background(), session, session, S256, testCodeChallenge); err != nil {
+			t.Fatalf("RegisterSession() unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		code       string
+		session    string
+		wantStatus int
+	}{
+		{
+			name:       "should pass through a token request from the registering session",
+			code:       "a",
+			session:    "a",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should reject a code injected into another session",
+			code:       "b",
+			session:    "a",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTokenRequest(url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {tt.code},
+				"code_verifier": {testCodeVerifier},
+			})
+			r.Header.Set("X-Session", tt.session)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
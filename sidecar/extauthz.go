@@ -0,0 +1,59 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// ExtAuthzHandler returns a handler implementing Envoy's HTTP external
+// authorization service protocol, enforcing PKCE on proxied token requests
+// by verifying the code verifier against code challenges registered with the
+// manager, such as by an authorization server sharing the manager's store.
+//
+// Envoy must be configured to forward the request body, using
+// with_request_body, and the Content-Type header, using allowed_headers.
+// Allowed requests are responded to with 200 OK, and denied requests with an
+// RFC 6749, 5.2 error response, which Envoy returns to the client.
+//
+// The handler fails closed: requests whose body can not be parsed, such as
+// due to a missing or unsupported content type, or which do not specify a
+// grant type, are denied, as they can not be shown not to be authorization
+// code token requests.
+func ExtAuthzHandler(manager *pkce.KeyManager, opts ...pkce.MiddlewareOption) http.Handler {
+	return manager.Middleware(http.HandlerFunc(allow), opts...)
+}
+
+// allow allows requests which have been verified, or which the middleware
+// has parsed as a token request of another grant type.
+//
+// The middleware passes requests without a grant type through as another
+// grant type, so they are denied here.
+func allow(w http.ResponseWriter, r *http.Request) {
+	if r.PostForm.Get("grant_type") == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "the request must be a token request specifying a grant type")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// errorResponse provides the RFC 6749, 5.2 error response body.
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// writeError writes an RFC 6749, 5.2 error response.
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(errorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
@@ -0,0 +1,91 @@
+package sidecar
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestExtAuthzHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		grantType    string
+		codeVerifier string
+		wantStatus   int
+	}{
+		{
+			name:         "should allow a verified token request",
+			contentType:  "application/x-www-form-urlencoded",
+			grantType:    "authorization_code",
+			codeVerifier: testCodeVerifier,
+			wantStatus:   http.StatusOK,
+		},
+		{
+			name:         "should deny a mismatched code verifier",
+			contentType:  "application/x-www-form-urlencoded",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantStatus:   http.StatusBadRequest,
+		},
+		{
+			name:        "should allow a token request of another grant type",
+			contentType: "application/x-www-form-urlencoded",
+			grantType:   "refresh_token",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:         "should deny a token request without a content type",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantStatus:   http.StatusBadRequest,
+		},
+		{
+			name:         "should deny a token request with an unsupported content type",
+			contentType:  "text/plain",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantStatus:   http.StatusBadRequest,
+		},
+		{
+			name:         "should deny a token request without a grant type",
+			contentType:  "application/x-www-form-urlencoded",
+			codeVerifier: strings.Repeat("a", 43),
+			wantStatus:   http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := pkce.NewKeyManager(pkce.NewMemoryStore())
+			if err := manager.Register(context.Background(), "code", pkce.S256, testCodeChallenge); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			form := url.Values{
+				"code":                 {"code"},
+				pkce.ParamCodeVerifier: {tt.codeVerifier},
+			}
+			if tt.grantType != "" {
+				form.Set("grant_type", tt.grantType)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(form.Encode()))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			ExtAuthzHandler(manager).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
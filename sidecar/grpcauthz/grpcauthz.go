@@ -0,0 +1,164 @@
+// Package grpcauthz implements Envoy's gRPC external authorization service,
+// enforcing PKCE on proxied token requests as sidecar.ExtAuthzHandler does
+// for the HTTP external authorization service.
+//
+// The package is a separate module, so that the pkce module remains free of
+// external dependencies.
+package grpcauthz
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+
+	"github.com/matthewhartstonge/pkce"
+	"github.com/matthewhartstonge/pkce/sidecar"
+)
+
+// Server implements Envoy's envoy.service.auth.v3.Authorization service,
+// verifying code verifiers of proxied token requests against code challenges
+// registered with a key manager, such as by an authorization server sharing
+// the manager's store.
+//
+// Envoy must be configured to forward the request body, using
+// with_request_body. Allowed requests are responded to with an OK response,
+// and denied requests with a denied response carrying the RFC 6749, 5.2 error
+// response, which Envoy returns to the client.
+//
+// Server is registered with a gRPC server using
+// authv3.RegisterAuthorizationServer.
+type Server struct {
+	authv3.UnimplementedAuthorizationServer
+
+	handler http.Handler
+}
+
+// NewServer returns an authorization server verifying token requests against
+// code challenges registered with manager, configured as
+// sidecar.ExtAuthzHandler with opts.
+func NewServer(manager *pkce.KeyManager, opts ...pkce.MiddlewareOption) *Server {
+	return &Server{
+		handler: sidecar.ExtAuthzHandler(manager, opts...),
+	}
+}
+
+// Check implements authv3.AuthorizationServer.
+func (s *Server) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	r, err := newRequest(ctx, req)
+	if err != nil {
+		return denied(http.StatusBadRequest, nil, nil), nil
+	}
+
+	rec := newResponseRecorder()
+	s.handler.ServeHTTP(rec, r)
+
+	if rec.status == http.StatusOK {
+		return &authv3.CheckResponse{
+			Status: &status.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{
+				OkResponse: &authv3.OkHttpResponse{},
+			},
+		}, nil
+	}
+
+	return denied(rec.status, rec.header, rec.body.Bytes()), nil
+}
+
+// newRequest returns the proxied HTTP request described by the attributes of
+// req.
+func newRequest(ctx context.Context, req *authv3.CheckRequest) (*http.Request, error) {
+	attrs := req.GetAttributes().GetRequest().GetHttp()
+
+	body := attrs.GetRawBody()
+	if body == nil {
+		body = []byte(attrs.GetBody())
+	}
+
+	// the path provided by Envoy includes the query string.
+	r, err := http.NewRequestWithContext(ctx, attrs.GetMethod(), attrs.GetPath(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.Host = attrs.GetHost()
+	for name, value := range attrs.GetHeaders() {
+		r.Header.Set(name, value)
+	}
+	for _, header := range attrs.GetHeaderMap().GetHeaders() {
+		value := header.GetValue()
+		if value == "" {
+			value = string(header.GetRawValue())
+		}
+		r.Header.Add(header.GetKey(), value)
+	}
+
+	// the downstream address keys the default limiter client key fallback.
+	if addr := req.GetAttributes().GetSource().GetAddress().GetSocketAddress(); addr != nil {
+		r.RemoteAddr = net.JoinHostPort(addr.GetAddress(), strconv.FormatUint(uint64(addr.GetPortValue()), 10))
+	}
+
+	return r, nil
+}
+
+// denied returns a check response denying the request, responding to the
+// client with statusCode, header and body.
+func denied(statusCode int, header http.Header, body []byte) *authv3.CheckResponse {
+	var headers []*corev3.HeaderValueOption
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, &corev3.HeaderValueOption{
+				Header: &corev3.HeaderValue{Key: name, Value: value},
+			})
+		}
+	}
+
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(codes.PermissionDenied)},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(statusCode)},
+				Headers: headers,
+				Body:    string(body),
+			},
+		},
+	}
+}
+
+// responseRecorder records the response written by the external
+// authorization handler.
+type responseRecorder struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+// newResponseRecorder returns a response recorder defaulting to 200 OK.
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		status: http.StatusOK,
+		header: make(http.Header),
+	}
+}
+
+// Header implements http.ResponseWriter.
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+// Write implements http.ResponseWriter.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.status = statusCode
+}
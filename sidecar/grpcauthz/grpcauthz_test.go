@@ -0,0 +1,139 @@
+package grpcauthz
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc/codes"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	testCodeVerifier  = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+	testCodeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+)
+
+func TestServer_Check(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		contentType  string
+		grantType    string
+		codeVerifier string
+		wantCode     codes.Code
+		wantStatus   int
+		wantBody     string
+	}{
+		{
+			name:         "should allow a verified token request",
+			path:         "/token",
+			contentType:  "application/x-www-form-urlencoded",
+			grantType:    "authorization_code",
+			codeVerifier: testCodeVerifier,
+			wantCode:     codes.OK,
+		},
+		{
+			name:        "should allow a token request of another grant type",
+			path:        "/token",
+			contentType: "application/x-www-form-urlencoded",
+			grantType:   "refresh_token",
+			wantCode:    codes.OK,
+		},
+		{
+			name:         "should deny a mismatched code verifier",
+			path:         "/token",
+			contentType:  "application/x-www-form-urlencoded",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantCode:     codes.PermissionDenied,
+			wantStatus:   400,
+			wantBody:     `"error":"invalid_grant"`,
+		},
+		{
+			name:         "should deny a token request without a content type",
+			path:         "/token",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantCode:     codes.PermissionDenied,
+			wantStatus:   400,
+			wantBody:     `"error":"invalid_request"`,
+		},
+		{
+			name:         "should deny token request parameters sent in the query string",
+			path:         "/token?code_verifier=" + testCodeVerifier,
+			contentType:  "application/x-www-form-urlencoded",
+			grantType:    "authorization_code",
+			codeVerifier: strings.Repeat("a", 43),
+			wantCode:     codes.PermissionDenied,
+			wantStatus:   400,
+			wantBody:     `"error":"invalid_request"`,
+		},
+		{
+			name:       "should deny a request without a path",
+			wantCode:   codes.PermissionDenied,
+			wantStatus: 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := pkce.NewKeyManager(pkce.NewMemoryStore())
+			if err := manager.Register(context.Background(), "code", pkce.S256, testCodeChallenge); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			form := url.Values{
+				"grant_type":           {tt.grantType},
+				"code":                 {"code"},
+				pkce.ParamCodeVerifier: {tt.codeVerifier},
+			}
+
+			headers := map[string]string{}
+			if tt.contentType != "" {
+				headers["content-type"] = tt.contentType
+			}
+
+			req := &authv3.CheckRequest{
+				Attributes: &authv3.AttributeContext{
+					Request: &authv3.AttributeContext_Request{
+						Http: &authv3.AttributeContext_HttpRequest{
+							Method:  "POST",
+							Path:    tt.path,
+							Host:    "as.example.com",
+							Headers: headers,
+							Body:    form.Encode(),
+						},
+					},
+				},
+			}
+
+			resp, err := NewServer(manager).Check(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+
+			if got := codes.Code(resp.GetStatus().GetCode()); got != tt.wantCode {
+				t.Errorf("Check() code = %v, want %v", got, tt.wantCode)
+			}
+
+			if tt.wantCode == codes.OK {
+				if resp.GetOkResponse() == nil {
+					t.Error("Check() should respond with an OK response")
+				}
+				return
+			}
+
+			deniedResp := resp.GetDeniedResponse()
+			if got := int(deniedResp.GetStatus().GetCode()); got != tt.wantStatus {
+				t.Errorf("Check() status = %v, want %v", got, tt.wantStatus)
+			}
+			if !strings.Contains(deniedResp.GetBody(), tt.wantBody) {
+				t.Errorf("Check() body = %v, want %v", deniedResp.GetBody(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package sidecar provides an embeddable HTTP verification service, for API
+// gateways and proxies which can not link Go code into the token path, such as
+// gateway plugins written in other languages.
+//
+// The service exposes a single endpoint, VerifyPath, which accepts a JSON
+// encoded Request and responds with a JSON encoded Verdict.
+package sidecar
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	// VerifyPath provides the path of the verification endpoint.
+	VerifyPath = "/verify"
+
+	// maxRequestSize limits the size of verification requests. A request is
+	// a few hundred bytes at most.
+	maxRequestSize = 4096
+)
+
+// Request provides a verification request.
+type Request struct {
+	// Method provides the code challenge method of the authorization
+	// request. Defaults to "plain" if empty, as specified in RFC 7636, 4.3.
+	Method pkce.Method `json:"method"`
+	// CodeVerifier provides the code verifier of the token request.
+	CodeVerifier string `json:"code_verifier"`
+	// CodeChallenge provides the code challenge of the authorization request.
+	CodeChallenge string `json:"code_challenge"`
+}
+
+// Verdict provides the outcome of a verification request.
+type Verdict struct {
+	// Verified reports whether the code verifier proves the code challenge.
+	Verified bool `json:"verified"`
+	// Reason classifies why verification failed.
+	Reason pkce.FailureReason `json:"reason,omitempty"`
+	// Error describes why verification failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Handler returns a handler serving the verification endpoint at VerifyPath.
+func Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(VerifyPath, verify)
+
+	return mux
+}
+
+// verify handles verification requests.
+func verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeVerdict(w, http.StatusMethodNotAllowed, Verdict{
+			Reason: pkce.FailureMalformed,
+			Error:  "verification requests must be POSTed",
+		})
+		return
+	}
+
+	var req Request
+	// decoded as a plain string, so an unsupported method is reported as a
+	// verdict rather than a decoding error.
+	doc := struct {
+		*Request
+		Method string `json:"method"`
+	}{Request: &req}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&doc); err != nil {
+		writeVerdict(w, http.StatusBadRequest, Verdict{
+			Reason: pkce.FailureMalformed,
+			Error:  "the request body must be a JSON encoded verification request",
+		})
+		return
+	}
+	req.Method = pkce.Method(doc.Method)
+
+	result := pkce.VerifyBatch([]pkce.VerificationInput{{
+		Method:        req.Method,
+		CodeChallenge: req.CodeChallenge,
+		CodeVerifier:  req.CodeVerifier,
+	}})[0]
+
+	verdict := Verdict{
+		Verified: result.Verified,
+		Reason:   result.Reason,
+	}
+	if result.Err != nil {
+		verdict.Error = result.Err.Error()
+	}
+
+	writeVerdict(w, http.StatusOK, verdict)
+}
+
+// writeVerdict writes the verdict as a JSON response.
+func writeVerdict(w http.ResponseWriter, status int, verdict Verdict) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(verdict)
+}
@@ -0,0 +1,93 @@
+package sidecar
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+const (
+	testCodeVerifier  = "6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj"
+	testCodeChallenge = "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		want       Verdict
+	}{
+		{
+			name:       "should verify a S256 code verifier",
+			method:     http.MethodPost,
+			body:       `{"method":"S256","code_verifier":"` + testCodeVerifier + `","code_challenge":"` + testCodeChallenge + `"}`,
+			wantStatus: http.StatusOK,
+			want:       Verdict{Verified: true},
+		},
+		{
+			name:       "should report a downgraded code verifier",
+			method:     http.MethodPost,
+			body:       `{"method":"S256","code_verifier":"` + testCodeChallenge + `","code_challenge":"` + testCodeChallenge + `"}`,
+			wantStatus: http.StatusOK,
+			want: Verdict{
+				Reason: pkce.FailureDowngrade,
+				Error:  pkce.ErrMethodDowngrade.Error(),
+			},
+		},
+		{
+			name:       "should report an unsupported method",
+			method:     http.MethodPost,
+			body:       `{"method":"yolo","code_verifier":"` + testCodeVerifier + `","code_challenge":"` + testCodeChallenge + `"}`,
+			wantStatus: http.StatusOK,
+			want: Verdict{
+				Reason: pkce.FailureMalformed,
+				Error:  pkce.ErrMethodNotSupported.Error(),
+			},
+		},
+		{
+			name:       "should error on a malformed request",
+			method:     http.MethodPost,
+			body:       `yolo`,
+			wantStatus: http.StatusBadRequest,
+			want: Verdict{
+				Reason: pkce.FailureMalformed,
+				Error:  "the request body must be a JSON encoded verification request",
+			},
+		},
+		{
+			name:       "should error on a GET request",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			want: Verdict{
+				Reason: pkce.FailureMalformed,
+				Error:  "verification requests must be POSTed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler().ServeHTTP(w, httptest.NewRequest(tt.method, VerifyPath, strings.NewReader(tt.body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			var got Verdict
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("ServeHTTP() wrote an invalid verdict: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("ServeHTTP() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store provides persistence of encoded proof keys, enabling a key created at
+// the authorization request to be retrieved at the token request.
+//
+// Stores deal in encoded keys so that decorators, such as EncryptedStore, can
+// transform the payload before it is written to the backing datastore.
+//
+// The provided context enables callers to enforce deadlines and cancellation
+// on lookups against remote datastores.
+type Store interface {
+	// Put stores data under the given id, replacing any existing entry. A ttl
+	// of zero specifies that the entry does not expire.
+	Put(ctx context.Context, id string, data []byte, ttl time.Duration) error
+
+	// Get returns the data stored under the given id. If the entry does not
+	// exist, or has expired, ErrKeyNotFound is returned.
+	Get(ctx context.Context, id string) ([]byte, error)
+
+	// Delete removes the entry stored under the given id.
+	Delete(ctx context.Context, id string) error
+
+	// Consume atomically returns and removes the data stored under the given
+	// id, ensuring only one of any concurrent callers receives it, such as
+	// two token requests racing to exchange the same authorization code. If
+	// the entry does not exist, or has expired, ErrKeyNotFound is returned.
+	Consume(ctx context.Context, id string) ([]byte, error)
+}
+
+// PutKey encodes and stores the key under the given id.
+func PutKey(ctx context.Context, store Store, id string, key *Key, ttl time.Duration) error {
+	data, err := key.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return store.Put(ctx, id, data, ttl)
+}
+
+// GetKey retrieves and decodes the key stored under the given id.
+func GetKey(ctx context.Context, store Store, id string) (*Key, error) {
+	data, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{}
+	if err = key.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// TakeKey atomically retrieves, decodes and removes the key stored under the
+// given id, ensuring a key can only be used once, such as when the
+// authorization request and the token request are performed by separate
+// process invocations. If the key has expired, ErrKeyExpired is returned.
+func TakeKey(ctx context.Context, store Store, id string) (*Key, error) {
+	key, err := consumeKey(ctx, store, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Expired() {
+		return nil, ErrKeyExpired
+	}
+
+	return key, nil
+}
+
+// consumeKey atomically retrieves, decodes and removes the key stored under
+// the given id.
+func consumeKey(ctx context.Context, store Store, id string) (*Key, error) {
+	data, err := store.Consume(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{}
+	if err = key.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// StoreStats provides a snapshot of a store's usage, enabling operators to
+// size caches and detect leaks of flows that are never completed.
+type StoreStats struct {
+	// Entries provides the number of live entries held by the store.
+	Entries int
+	// Puts provides the number of entries written to the store.
+	Puts uint64
+	// Hits provides the number of successful lookups.
+	Hits uint64
+	// Misses provides the number of lookups for missing or expired entries.
+	Misses uint64
+	// Evictions provides the number of entries removed due to expiry.
+	Evictions uint64
+	// AverageTTLRemaining provides the mean time remaining before live
+	// entries with an expiry are evicted.
+	AverageTTLRemaining time.Duration
+}
+
+// StatsStore is implemented by stores which expose usage statistics.
+type StatsStore interface {
+	Store
+
+	// Stats returns a snapshot of the store's usage.
+	Stats() StoreStats
+}
+
+// StoreEvent identifies an operation observed by a store.
+type StoreEvent string
+
+const (
+	// StoreEventPut is emitted when an entry is written.
+	StoreEventPut StoreEvent = "put"
+	// StoreEventHit is emitted when a lookup returns an entry.
+	StoreEventHit StoreEvent = "hit"
+	// StoreEventMiss is emitted when a lookup finds no live entry.
+	StoreEventMiss StoreEvent = "miss"
+	// StoreEventEviction is emitted when an expired entry is removed.
+	StoreEventEviction StoreEvent = "eviction"
+	// StoreEventDuplicate is emitted by a DuplicateCache when a code
+	// challenge is registered for more than one authorization request.
+	StoreEventDuplicate StoreEvent = "duplicate"
+)
+
+// MetricsHook is called for each event observed by a store, enabling
+// integration with metrics systems. Hooks must be safe for concurrent use.
+type MetricsHook func(event StoreEvent)
+
+// StoreOption enables variadic store options to be configured.
+type StoreOption func(*storeConfig)
+
+// storeConfig provides configuration shared by the package's stores.
+type storeConfig struct {
+	clock   Clock
+	metrics MetricsHook
+	// secret provides the secret a FileStore seals entries with, if
+	// specified.
+	secret []byte
+}
+
+// newStoreConfig applies the store options over the default configuration.
+func newStoreConfig(opts []StoreOption) storeConfig {
+	config := storeConfig{
+		metrics: func(StoreEvent) {},
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+// WithMetricsHook enables receiving store events.
+func WithMetricsHook(hook MetricsHook) StoreOption {
+	return func(config *storeConfig) {
+		if hook != nil {
+			config.metrics = hook
+		}
+	}
+}
+
+// WithStoreClock enables specifying the clock used to compute entry expiry.
+// Defaults to the system clock.
+func WithStoreClock(clock Clock) StoreOption {
+	return func(config *storeConfig) {
+		config.clock = clock
+	}
+}
+
+// MemoryStore provides an in-memory Store, suitable for single instance
+// deployments and testing.
+type MemoryStore struct {
+	config storeConfig
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	stats   StoreStats
+}
+
+// memoryEntry provides a stored value and its expiry.
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// expired returns whether the entry has expired at the given time.
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore(opts ...StoreOption) *MemoryStore {
+	return &MemoryStore{
+		config:  newStoreConfig(opts),
+		entries: map[string]memoryEntry{},
+	}
+}
+
+// Put implements Store.
+func (s *MemoryStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entry := memoryEntry{
+		data: append([]byte(nil), data...),
+	}
+	if ttl > 0 {
+		entry.expiresAt = now(s.config.clock).Add(ttl)
+	}
+
+	s.mu.Lock()
+	s.entries[id] = entry
+	s.stats.Puts++
+	s.mu.Unlock()
+
+	s.config.metrics(StoreEventPut)
+
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(ctx context.Context, id string) ([]byte, error) {
+	return s.lookup(ctx, id, false)
+}
+
+// Consume implements Store.
+func (s *MemoryStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	return s.lookup(ctx, id, true)
+}
+
+// lookup returns the entry stored under id, removing it if consume is set.
+func (s *MemoryStore) lookup(ctx context.Context, id string, consume bool) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	entry, ok := s.entries[id]
+	evicted := ok && entry.expired(now(s.config.clock))
+	if evicted {
+		delete(s.entries, id)
+		s.stats.Evictions++
+	}
+
+	if !ok || evicted {
+		s.stats.Misses++
+		s.mu.Unlock()
+
+		if evicted {
+			s.config.metrics(StoreEventEviction)
+		}
+		s.config.metrics(StoreEventMiss)
+
+		return nil, ErrKeyNotFound
+	}
+
+	if consume {
+		delete(s.entries, id)
+	}
+	s.stats.Hits++
+	s.mu.Unlock()
+
+	s.config.metrics(StoreEventHit)
+
+	return append([]byte(nil), entry.data...), nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.entries, id)
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Prune evicts all expired entries, returning the number of entries removed.
+// Expired entries are otherwise only evicted when they are next looked up.
+func (s *MemoryStore) Prune() int {
+	at := now(s.config.clock)
+
+	s.mu.Lock()
+	evicted := 0
+	for id, entry := range s.entries {
+		if entry.expired(at) {
+			delete(s.entries, id)
+			evicted++
+		}
+	}
+	s.stats.Evictions += uint64(evicted)
+	s.mu.Unlock()
+
+	for i := 0; i < evicted; i++ {
+		s.config.metrics(StoreEventEviction)
+	}
+
+	return evicted
+}
+
+// Stats implements StatsStore.
+func (s *MemoryStore) Stats() StoreStats {
+	at := now(s.config.clock)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+
+	var remaining time.Duration
+	expiring := 0
+	for _, entry := range s.entries {
+		if entry.expired(at) {
+			continue
+		}
+		stats.Entries++
+
+		if !entry.expiresAt.IsZero() {
+			remaining += entry.expiresAt.Sub(at)
+			expiring++
+		}
+	}
+
+	if expiring > 0 {
+		stats.AverageTTLRemaining = remaining / time.Duration(expiring)
+	}
+
+	return stats
+}
@@ -0,0 +1,116 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BreakerStore provides a Store decorator implementing a circuit breaker,
+// failing fast with ErrStoreUnavailable once the wrapped store has failed
+// repeatedly, rather than holding every request for the store's timeout.
+//
+// After the cooldown has elapsed, requests are again passed through to the
+// wrapped store, closing the breaker on the first success.
+type BreakerStore struct {
+	store     Store
+	threshold int
+	cooldown  time.Duration
+	config    storeConfig
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewBreakerStore returns a store which opens the circuit for cooldown after
+// threshold consecutive failures of store. Missing entries and cancellation by
+// the caller are not considered failures.
+func NewBreakerStore(store Store, threshold int, cooldown time.Duration, opts ...StoreOption) *BreakerStore {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	return &BreakerStore{
+		store:     store,
+		threshold: threshold,
+		cooldown:  cooldown,
+		config:    newStoreConfig(opts),
+	}
+}
+
+// Put implements Store.
+func (s *BreakerStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if !s.allow() {
+		return ErrStoreUnavailable
+	}
+
+	return s.record(s.store.Put(ctx, id, data, ttl))
+}
+
+// Get implements Store.
+func (s *BreakerStore) Get(ctx context.Context, id string) ([]byte, error) {
+	if !s.allow() {
+		return nil, ErrStoreUnavailable
+	}
+
+	data, err := s.store.Get(ctx, id)
+
+	return data, s.record(err)
+}
+
+// Delete implements Store.
+func (s *BreakerStore) Delete(ctx context.Context, id string) error {
+	if !s.allow() {
+		return ErrStoreUnavailable
+	}
+
+	return s.record(s.store.Delete(ctx, id))
+}
+
+// Consume implements Store.
+func (s *BreakerStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	if !s.allow() {
+		return nil, ErrStoreUnavailable
+	}
+
+	data, err := s.store.Consume(ctx, id)
+
+	return data, s.record(err)
+}
+
+// Open returns whether the circuit is open, failing requests without passing
+// them to the wrapped store.
+func (s *BreakerStore) Open() bool {
+	return !s.allow()
+}
+
+// allow returns whether a request may be passed to the wrapped store.
+func (s *BreakerStore) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failures < s.threshold || !now(s.config.clock).Before(s.openUntil)
+}
+
+// record records the outcome of a request to the wrapped store, returning err.
+func (s *BreakerStore) record(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch err {
+	case context.Canceled:
+		// The caller gave up, which says nothing of the store's health.
+
+	case nil, ErrKeyNotFound:
+		s.failures = 0
+
+	default:
+		s.failures++
+		if s.failures >= s.threshold {
+			s.openUntil = now(s.config.clock).Add(s.cooldown)
+		}
+	}
+
+	return err
+}
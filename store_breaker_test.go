@@ -0,0 +1,187 @@
+package pkce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// errStoreDown provides the error returned by an unavailable store.
+var errStoreDown = errors.New("store is down")
+
+// unavailableStore provides a Store which fails while down is set.
+type unavailableStore struct {
+	Store
+	down  bool
+	calls int
+}
+
+func (s *unavailableStore) Get(ctx context.Context, id string) ([]byte, error) {
+	s.calls++
+	if s.down {
+		return nil, errStoreDown
+	}
+
+	return s.Store.Get(ctx, id)
+}
+
+func (s *unavailableStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	s.calls++
+	if s.down {
+		return nil, errStoreDown
+	}
+
+	return s.Store.Consume(ctx, id)
+}
+
+func TestBreakerStore(t *testing.T) {
+	ctx := context.Background()
+	clock := newTestClock()
+	backing := &unavailableStore{Store: NewMemoryStore(), down: true}
+	store := NewBreakerStore(backing, 2, time.Minute, WithStoreClock(clock))
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.Get(ctx, "id"); err != errStoreDown {
+			t.Fatalf("Get() error type not expected\ngot:  %v, want: %v\n", err, errStoreDown)
+		}
+	}
+
+	if !store.Open() {
+		t.Fatal("Open() should be open after reaching the threshold")
+	}
+	if _, err := store.Consume(ctx, "id"); err != ErrStoreUnavailable {
+		t.Errorf("Consume() error type not expected\ngot:  %v, want: %v\n", err, ErrStoreUnavailable)
+	}
+	if backing.calls != 2 {
+		t.Errorf("calls = %d, want %d, an open breaker must not call the store", backing.calls, 2)
+	}
+
+	backing.down = false
+	clock.Advance(time.Minute)
+	if _, err := store.Get(ctx, "id"); err != ErrKeyNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+
+	backing.down = true
+	if _, err := store.Get(ctx, "id"); err != errStoreDown {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, errStoreDown)
+	}
+	if store.Open() {
+		t.Error("Open() should close on success, resetting the failure count")
+	}
+}
+
+func TestWithFailOpen(t *testing.T) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {"code"},
+		"code_verifier": {testCodeVerifier},
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		err         error
+		managerOpts []ManagerOption
+		failOpen    bool
+		wantStatus  int
+		wantLogged  error
+	}{
+		{
+			name:       "should fail closed by default",
+			err:        errStoreDown,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "should fail closed as unavailable when the breaker is open",
+			err:        ErrStoreUnavailable,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "should fail open on store errors",
+			err:        errStoreDown,
+			failOpen:   true,
+			wantStatus: http.StatusOK,
+			wantLogged: errStoreDown,
+		},
+		{
+			name:       "should fail open when the breaker is open",
+			err:        ErrStoreUnavailable,
+			failOpen:   true,
+			wantStatus: http.StatusOK,
+			wantLogged: ErrStoreUnavailable,
+		},
+		{
+			name:       "should fail closed on tampered entries",
+			err:        ErrSealedPayload,
+			failOpen:   true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "should fail closed on wrapped tampered entries",
+			err:        fmt.Errorf("decrypting: %w", ErrSealedPayload),
+			failOpen:   true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "should fail closed on wrapped malformed entries",
+			err:        fmt.Errorf("decoding: %w", ErrKeyEncoding),
+			failOpen:   true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "should fail closed on a missing keyring key",
+			err:        ErrKeyringKeyNotFound,
+			failOpen:   true,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:        "should fail closed on a misconfigured session secret",
+			err:         errStoreDown,
+			managerOpts: []ManagerOption{WithManagerSessionBinding([]byte("short"))},
+			failOpen:    true,
+			wantStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &erroringStore{Store: NewMemoryStore(), err: tt.err}
+
+			var logged error
+			var opts []MiddlewareOption
+			if tt.failOpen {
+				opts = append(opts, WithFailOpen(func(r *http.Request, err error) {
+					logged = err
+				}))
+			}
+
+			rec := httptest.NewRecorder()
+			NewKeyManager(store, tt.managerOpts...).Middleware(next, opts...).ServeHTTP(rec, newTokenRequest(form))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.wantStatus)
+			}
+			if !errors.Is(logged, tt.wantLogged) {
+				t.Errorf("WithFailOpen() logged error not expected\ngot:  %v, want: %v\n", logged, tt.wantLogged)
+			}
+		})
+	}
+}
+
+// erroringStore provides a Store which fails to consume entries with err.
+type erroringStore struct {
+	Store
+	err error
+}
+
+func (s *erroringStore) Consume(context.Context, string) ([]byte, error) {
+	return nil, s.err
+}
@@ -0,0 +1,269 @@
+package pkce
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+const (
+	// envelopeVersion provides the current version of the sealed envelope
+	// format.
+	envelopeVersion = 1
+
+	// dataKeyLen provides the length of generated data encryption keys,
+	// selecting AES-256.
+	dataKeyLen = 32
+)
+
+// Keyring wraps and unwraps the data encryption keys used to seal persisted
+// proof keys. Implementations may be backed by a KMS, HSM or a local set of
+// key encryption keys.
+type Keyring interface {
+	// WrapKey encrypts the data encryption key using the current key
+	// encryption key, returning the id of the key encryption key used.
+	WrapKey(ctx context.Context, dek []byte) (kid string, wrapped []byte, err error)
+
+	// UnwrapKey decrypts a data encryption key previously wrapped by the key
+	// encryption key identified by kid.
+	UnwrapKey(ctx context.Context, kid string, wrapped []byte) (dek []byte, err error)
+}
+
+// AESKeyring provides a local Keyring which wraps data encryption keys using
+// AES-GCM.
+type AESKeyring struct {
+	mu      sync.RWMutex
+	current string
+	keks    map[string]cipher.AEAD
+}
+
+// NewAESKeyring returns a keyring which wraps data encryption keys with the
+// provided key encryption key. The key encryption key must be 16, 24 or 32
+// bytes long to select AES-128, AES-192 or AES-256.
+func NewAESKeyring(kid string, kek []byte) (*AESKeyring, error) {
+	k := &AESKeyring{
+		keks: map[string]cipher.AEAD{},
+	}
+	if err := k.Rotate(kid, kek); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Rotate adds a new key encryption key and uses it to wrap all subsequent data
+// encryption keys. Previous key encryption keys are retained in order to
+// unwrap existing entries.
+func (k *AESKeyring) Rotate(kid string, kek []byte) error {
+	aead, err := newGCM(kek)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.keks[kid] = aead
+	k.current = kid
+	k.mu.Unlock()
+
+	return nil
+}
+
+// WrapKey implements Keyring.
+func (k *AESKeyring) WrapKey(ctx context.Context, dek []byte) (kid string, wrapped []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return "", nil, err
+	}
+
+	k.mu.RLock()
+	kid, aead := k.current, k.keks[k.current]
+	k.mu.RUnlock()
+
+	wrapped, err = seal(aead, dek, []byte(kid))
+
+	return kid, wrapped, err
+}
+
+// UnwrapKey implements Keyring.
+func (k *AESKeyring) UnwrapKey(ctx context.Context, kid string, wrapped []byte) (dek []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	aead, ok := k.keks[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, ErrKeyringKeyNotFound
+	}
+
+	return open(aead, wrapped, []byte(kid))
+}
+
+// EncryptedStore provides a Store decorator which seals data using envelope
+// encryption before it reaches the wrapped store, ensuring code verifiers and
+// code challenges are never persisted in plaintext.
+//
+// Each entry is encrypted with a freshly generated AES-256-GCM data key, which
+// is in turn wrapped by the Keyring. The entry id is bound to the ciphertext,
+// preventing sealed entries from being swapped between ids.
+type EncryptedStore struct {
+	store   Store
+	keyring Keyring
+}
+
+// NewEncryptedStore returns a store which seals entries written to store
+// using keys wrapped by keyring.
+func NewEncryptedStore(store Store, keyring Keyring) *EncryptedStore {
+	return &EncryptedStore{
+		store:   store,
+		keyring: keyring,
+	}
+}
+
+// Put implements Store.
+func (s *EncryptedStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	dek := make([]byte, dataKeyLen)
+	if _, err := rand.Read(dek); err != nil {
+		return err
+	}
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := seal(aead, data, []byte(id))
+	if err != nil {
+		return err
+	}
+
+	kid, wrapped, err := s.keyring.WrapKey(ctx, dek)
+	if err != nil {
+		return err
+	}
+
+	if len(kid) > 0xff || len(wrapped) > 0xffff {
+		return ErrSealedPayload
+	}
+
+	envelope := make([]byte, 0, 4+len(kid)+len(wrapped)+len(ciphertext))
+	envelope = append(envelope, envelopeVersion, byte(len(kid)))
+	envelope = append(envelope, kid...)
+	envelope = append(envelope, byte(len(wrapped)>>8), byte(len(wrapped)))
+	envelope = append(envelope, wrapped...)
+	envelope = append(envelope, ciphertext...)
+
+	return s.store.Put(ctx, id, envelope, ttl)
+}
+
+// Get implements Store.
+func (s *EncryptedStore) Get(ctx context.Context, id string) ([]byte, error) {
+	envelope, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openEnvelope(ctx, id, envelope)
+}
+
+// Consume implements Store, consuming the entry from the wrapped store.
+func (s *EncryptedStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	envelope, err := s.store.Consume(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.openEnvelope(ctx, id, envelope)
+}
+
+// openEnvelope opens the envelope stored under id.
+func (s *EncryptedStore) openEnvelope(ctx context.Context, id string, envelope []byte) ([]byte, error) {
+	if len(envelope) < 2 {
+		return nil, ErrSealedPayload
+	}
+
+	if envelope[0] != envelopeVersion {
+		return nil, ErrFormatVersion
+	}
+
+	kidLen := int(envelope[1])
+	envelope = envelope[2:]
+	if len(envelope) < kidLen+2 {
+		return nil, ErrSealedPayload
+	}
+	kid := string(envelope[:kidLen])
+	envelope = envelope[kidLen:]
+
+	wrappedLen := int(binary.BigEndian.Uint16(envelope))
+	envelope = envelope[2:]
+	if len(envelope) < wrappedLen {
+		return nil, ErrSealedPayload
+	}
+	wrapped, ciphertext := envelope[:wrappedLen], envelope[wrappedLen:]
+
+	dek, err := s.keyring.UnwrapKey(ctx, kid, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return open(aead, ciphertext, []byte(id))
+}
+
+// Delete implements Store.
+func (s *EncryptedStore) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, id)
+}
+
+// Stats implements StatsStore, returning the wrapped store's statistics. If
+// the wrapped store does not expose statistics, empty statistics are returned.
+func (s *EncryptedStore) Stats() StoreStats {
+	if store, ok := s.store.(StatsStore); ok {
+		return store.Stats()
+	}
+
+	return StoreStats{}
+}
+
+// newGCM returns an AES-GCM AEAD for the given key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext, prefixing the output with a random nonce.
+func seal(aead cipher.AEAD, plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts a nonce prefixed ciphertext produced by seal.
+func open(aead cipher.AEAD, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrSealedPayload
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrSealedPayload
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,181 @@
+package pkce
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func newTestKeyring(t testing.TB, kid string) *AESKeyring {
+	keyring, err := NewAESKeyring(kid, bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatalf("NewAESKeyring() unexpected error: %v", err)
+	}
+
+	return keyring
+}
+
+func TestNewAESKeyring(t *testing.T) {
+	tests := []struct {
+		name      string
+		kek       []byte
+		shouldErr bool
+	}{
+		{
+			name:      "should accept an AES-128 key",
+			kek:       make([]byte, 16),
+			shouldErr: false,
+		},
+		{
+			name:      "should accept an AES-256 key",
+			kek:       make([]byte, 32),
+			shouldErr: false,
+		},
+		{
+			name:      "should error on an invalid key length",
+			kek:       make([]byte, 7),
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAESKeyring("kid", tt.kek)
+			if (err != nil) != tt.shouldErr {
+				t.Errorf("NewAESKeyring() error = %v, shouldErr = %v", err, tt.shouldErr)
+			}
+		})
+	}
+}
+
+func TestAESKeyring_Rotate(t *testing.T) {
+	keyring := newTestKeyring(t, "old")
+	kid, wrapped, err := keyring.WrapKey(context.Background(), []byte("dek"))
+	if err != nil {
+		t.Fatalf("WrapKey() unexpected error: %v", err)
+	}
+
+	if err = keyring.Rotate("new", bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatalf("Rotate() unexpected error: %v", err)
+	}
+
+	newKid, _, err := keyring.WrapKey(context.Background(), []byte("dek"))
+	if err != nil {
+		t.Fatalf("WrapKey() unexpected error: %v", err)
+	}
+	if newKid != "new" {
+		t.Errorf("WrapKey() kid = %v, want %v", newKid, "new")
+	}
+
+	got, err := keyring.UnwrapKey(context.Background(), kid, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey() unexpected error: %v", err)
+	}
+	if string(got) != "dek" {
+		t.Errorf("UnwrapKey() = %s, want %s", got, "dek")
+	}
+
+	if _, err = keyring.UnwrapKey(context.Background(), "unknown", wrapped); err != ErrKeyringKeyNotFound {
+		t.Errorf("UnwrapKey() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyringKeyNotFound)
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	backing := NewMemoryStore()
+	s := NewEncryptedStore(backing, newTestKeyring(t, "kid"))
+
+	want := []byte("6et_m_LBa_8A-lHGANCGR0a6KATHyhr~5RU_CskUaaj")
+	if err := s.Put(context.Background(), "id", want, 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	sealed, err := backing.Get(context.Background(), "id")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if bytes.Contains(sealed, want) {
+		t.Errorf("Put() should not persist plaintext\ngot: %s", sealed)
+	}
+
+	got, err := s.Get(context.Background(), "id")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %s, want %s", got, want)
+	}
+
+	if err = s.Delete(context.Background(), "id"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if _, err = s.Get(context.Background(), "id"); err != ErrKeyNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+}
+
+func TestEncryptedStore_Get(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(backing *MemoryStore)
+		wantErr error
+	}{
+		{
+			name: "should error on a flipped ciphertext bit",
+			tamper: func(backing *MemoryStore) {
+				data, _ := backing.Get(context.Background(), "id")
+				data[len(data)-1] ^= 1
+				_ = backing.Put(context.Background(), "id", data, 0)
+			},
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name: "should error on an unknown envelope version",
+			tamper: func(backing *MemoryStore) {
+				data, _ := backing.Get(context.Background(), "id")
+				data[0] = envelopeVersion + 1
+				_ = backing.Put(context.Background(), "id", data, 0)
+			},
+			wantErr: ErrFormatVersion,
+		},
+		{
+			name: "should error on a truncated envelope",
+			tamper: func(backing *MemoryStore) {
+				_ = backing.Put(context.Background(), "id", []byte{envelopeVersion, 3, 'k'}, 0)
+			},
+			wantErr: ErrSealedPayload,
+		},
+		{
+			name: "should error on an envelope moved to another id",
+			tamper: func(backing *MemoryStore) {
+				data, _ := backing.Get(context.Background(), "other")
+				_ = backing.Put(context.Background(), "id", data, 0)
+			},
+			wantErr: ErrSealedPayload,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backing := NewMemoryStore()
+			s := NewEncryptedStore(backing, newTestKeyring(t, "kid"))
+			_ = s.Put(context.Background(), "id", []byte("data"), 0)
+			_ = s.Put(context.Background(), "other", []byte("other"), 0)
+
+			tt.tamper(backing)
+
+			if _, err := s.Get(context.Background(), "id"); err != tt.wantErr {
+				t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncryptedStore_Stats(t *testing.T) {
+	s := NewEncryptedStore(NewMemoryStore(), newTestKeyring(t, "kid"))
+	_ = s.Put(context.Background(), "id", []byte("data"), 0)
+
+	if got := s.Stats(); got.Entries != 1 || got.Puts != 1 {
+		t.Errorf("Stats() should report the wrapped store's statistics\ngot: %+v", got)
+	}
+}
@@ -0,0 +1,371 @@
+package pkce
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// fileStoreDir provides the directory, within the user's cache directory,
+	// a FileStore writes entries to if a directory is not specified.
+	fileStoreDir = "pkce"
+
+	// fileStoreSecretFile provides the name of the file, within the store's
+	// directory, holding the generated secret entries are sealed with if a
+	// secret is not specified.
+	fileStoreSecretFile = ".secret"
+
+	// fileStoreSecretMinLen provides the minimum length of a file store
+	// secret.
+	fileStoreSecretMinLen = 32
+
+	// fileStoreVersion provides the current version of the entry format.
+	fileStoreVersion = 1
+
+	// fileStoreContext binds derived file store encryption keys to their
+	// purpose.
+	fileStoreContext = "github.com/matthewhartstonge/pkce file store v1"
+)
+
+// FileStore provides a Store persisting each entry to a file readable only by
+// the owner, enabling command line tools to split the authorization request
+// and the token request across process invocations:
+//
+//	store, err := pkce.NewFileStore("")
+//	err = pkce.PutKey(ctx, store, state, key, 5*time.Minute)
+//	...
+//	key, err := pkce.TakeKey(ctx, store, state)
+//
+// Entries are sealed using AES-256-GCM, bound to the id they were stored
+// under, so an entry which has been modified, or moved to another id, is
+// rejected with ErrSealedPayload. Unless a secret is specified with
+// WithFileStoreSecret, such as one held in the operating system's keychain,
+// entries are sealed with a secret generated within the store's directory.
+type FileStore struct {
+	config storeConfig
+	dir    string
+	aead   cipher.AEAD
+}
+
+// WithFileStoreSecret enables specifying the secret a FileStore seals entries
+// with, which must be at least 32 bytes. Defaults to a secret generated and
+// stored within the store's directory. Other stores ignore the secret.
+func WithFileStoreSecret(secret []byte) StoreOption {
+	return func(config *storeConfig) {
+		config.secret = append([]byte{}, secret...)
+	}
+}
+
+// NewFileStore returns a store writing entries to dir, which is created with
+// owner only permissions if it does not exist. If dir is empty, a directory
+// within os.UserCacheDir is used.
+//
+// ErrStoreInsecure is returned if dir, or the generated secret within it, is
+// not owned by the current user with owner only permissions, as another user
+// could otherwise plant or replace entries.
+func NewFileStore(dir string, opts ...StoreOption) (*FileStore, error) {
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(cache, fileStoreDir)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, ErrStoreInsecure
+	}
+	if err = validateFileStorePerms(info); err != nil {
+		return nil, err
+	}
+
+	config := newStoreConfig(opts)
+	secret := config.secret
+	if secret == nil {
+		if secret, err = loadFileStoreSecret(filepath.Join(dir, fileStoreSecretFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	aead, err := newFileStoreGCM(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileStore{
+		config: config,
+		dir:    dir,
+		aead:   aead,
+	}, nil
+}
+
+// loadFileStoreSecret returns the secret stored in the file at path,
+// generating it if it does not exist.
+func loadFileStoreSecret(path string) ([]byte, error) {
+	secret, err := readFileStoreSecret(path)
+	if !os.IsNotExist(err) {
+		return secret, err
+	}
+
+	secret = make([]byte, fileStoreSecretMinLen)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	// the secret is written to a temporary file which is linked into place,
+	// so concurrent processes never observe a partially written secret, and
+	// only one generated secret is ever used.
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if err = f.Chmod(0600); err == nil {
+		_, err = f.Write(secret)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Link(f.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return readFileStoreSecret(path)
+		}
+
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// readFileStoreSecret returns the secret stored in the file at path, ensuring
+// it can not have been read or planted by another user.
+func readFileStoreSecret(path string) ([]byte, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, ErrStoreInsecure
+	}
+	if err = validateFileStorePerms(info); err != nil {
+		return nil, err
+	}
+
+	secret, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) < fileStoreSecretMinLen {
+		return nil, ErrStoreSecret
+	}
+
+	return secret, nil
+}
+
+// newFileStoreGCM returns an AES-256-GCM AEAD keyed by a key derived from the
+// file store secret, so secrets longer than an AES key can be used.
+func newFileStoreGCM(secret []byte) (cipher.AEAD, error) {
+	if len(secret) < fileStoreSecretMinLen {
+		return nil, ErrStoreSecret
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(fileStoreContext))
+
+	return newGCM(mac.Sum(nil))
+}
+
+// name returns the name of the file an entry is stored in. Ids are hashed,
+// so that untrusted ids, such as state values, can not escape the directory.
+func (s *FileStore) name(id string) string {
+	sum := sha256.Sum256([]byte(id))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file an entry is stored in.
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, s.name(id))
+}
+
+// additionalData returns the data entries stored under name are
+// authenticated with, binding them to their id.
+func (s *FileStore) additionalData(name string) []byte {
+	return append([]byte{fileStoreVersion}, name...)
+}
+
+// Put implements Store.
+//
+// The entry is written to a temporary file which is renamed into place, so
+// concurrent readers never observe a partially written entry.
+func (s *FileStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = now(s.config.clock).Add(ttl).UnixNano()
+	}
+
+	plaintext := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(plaintext, uint64(expiresAt))
+	plaintext = append(plaintext, data...)
+
+	name := s.name(id)
+	sealed, err := seal(s.aead, plaintext, s.additionalData(name))
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(s.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	// ioutil.TempFile creates files with owner only permissions, which is
+	// enforced regardless, in case of a permissive umask on other platforms.
+	if err = f.Chmod(0600); err != nil {
+		return err
+	}
+
+	if _, err = f.Write(append([]byte{fileStoreVersion}, sealed...)); err != nil {
+		return err
+	}
+
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(f.Name(), filepath.Join(s.dir, name)); err != nil {
+		return err
+	}
+
+	s.config.metrics(StoreEventPut)
+
+	return nil
+}
+
+// Get implements Store. Expired entries are removed.
+func (s *FileStore) Get(ctx context.Context, id string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	name := s.name(id)
+
+	return s.read(filepath.Join(s.dir, name), name)
+}
+
+// Consume implements Store.
+//
+// The entry is renamed to a unique name before being read, so only one of any
+// concurrent callers, across processes, can consume it.
+func (s *FileStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+
+	name := s.name(id)
+	path := filepath.Join(s.dir, ".consumed-"+hex.EncodeToString(suffix))
+	if err := os.Rename(filepath.Join(s.dir, name), path); err != nil {
+		if os.IsNotExist(err) {
+			s.config.metrics(StoreEventMiss)
+
+			return nil, ErrKeyNotFound
+		}
+
+		return nil, err
+	}
+	defer os.Remove(path)
+
+	return s.read(path, name)
+}
+
+// read returns the entry stored under name in the file at path. Expired
+// entries are removed.
+func (s *FileStore) read(path string, name string) ([]byte, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.config.metrics(StoreEventMiss)
+
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(content) == 0 {
+		return nil, ErrSealedPayload
+	}
+	if content[0] != fileStoreVersion {
+		return nil, ErrFormatVersion
+	}
+
+	plaintext, err := open(s.aead, content[1:], s.additionalData(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) < 8 {
+		return nil, ErrSealedPayload
+	}
+
+	expiresAt := int64(binary.BigEndian.Uint64(plaintext[:8]))
+	if expiresAt != 0 && !now(s.config.clock).Before(time.Unix(0, expiresAt)) {
+		_ = os.Remove(path)
+		s.config.metrics(StoreEventEviction)
+		s.config.metrics(StoreEventMiss)
+
+		return nil, ErrKeyNotFound
+	}
+
+	s.config.metrics(StoreEventHit)
+
+	return plaintext[8:], nil
+}
+
+// Delete implements Store.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
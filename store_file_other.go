@@ -0,0 +1,15 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !illumos && !js && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!js,!linux,!netbsd,!openbsd,!solaris
+
+package pkce
+
+import (
+	"os"
+)
+
+// validateFileStorePerms is a no-op on platforms without unix ownership and
+// permissions, such as windows, where access is controlled by the ACLs
+// inherited from the user's cache directory.
+func validateFileStorePerms(os.FileInfo) error {
+	return nil
+}
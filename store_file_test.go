@@ -0,0 +1,364 @@
+package pkce
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// newTestFileStore returns a file store writing to a temporary directory,
+// and a function removing it.
+func newTestFileStore(t testing.TB, opts ...StoreOption) (*FileStore, func()) {
+	dir, err := ioutil.TempDir("", "pkce")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileStore(filepath.Join(dir, "store"), opts...)
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestFileStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wait    time.Duration
+		wantErr error
+	}{
+		{
+			name:    "should return stored data without expiry",
+			ttl:     0,
+			wantErr: nil,
+		},
+		{
+			name:    "should return stored data before expiry",
+			ttl:     time.Hour,
+			wantErr: nil,
+		},
+		{
+			name:    "should not return expired data",
+			ttl:     time.Minute,
+			wait:    time.Minute,
+			wantErr: ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newTestClock()
+			s, cleanup := newTestFileStore(t, WithStoreClock(clock))
+			defer cleanup()
+
+			want := []byte("data")
+			if err := s.Put(context.Background(), "id", want, tt.ttl); err != nil {
+				t.Fatalf("Put() unexpected error: %v", err)
+			}
+
+			clock.Advance(tt.wait)
+
+			got, err := s.Get(context.Background(), "id")
+			if err != tt.wantErr {
+				t.Fatalf("Get() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("Get() = %s, want %s", got, want)
+			}
+
+			if _, err = os.Stat(s.path("id")); tt.wantErr != nil && !os.IsNotExist(err) {
+				t.Errorf("Get() should remove expired entries, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestFileStore_permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions are not supported")
+	}
+
+	s, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	if err := s.Put(context.Background(), "../../id", []byte("data"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Put() should write the secret and a single entry within the directory, got %d files", len(files))
+	}
+	for _, file := range files {
+		if perm := file.Mode().Perm(); perm != 0600 {
+			t.Errorf("%s file permissions = %v, want %v", file.Name(), perm, os.FileMode(0600))
+		}
+	}
+
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("NewFileStore() directory permissions = %v, want %v", perm, os.FileMode(0700))
+	}
+}
+
+func TestNewFileStore_insecure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions are not supported")
+	}
+
+	dir, err := ioutil.TempDir("", "pkce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shared := filepath.Join(dir, "shared")
+	if err = os.Mkdir(shared, 0700); err != nil {
+		t.Fatal(err)
+	}
+	// chmod, as Mkdir permissions are subject to the umask.
+	if err = os.Chmod(shared, 0777); err != nil {
+		t.Fatal(err)
+	}
+
+	link := filepath.Join(dir, "link")
+	if err = os.Symlink(shared, link); err != nil {
+		t.Fatal(err)
+	}
+
+	planted := filepath.Join(dir, "planted")
+	if err = os.Mkdir(planted, 0700); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(planted, fileStoreSecretFile)
+	if err = ioutil.WriteFile(secret, make([]byte, fileStoreSecretMinLen), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chmod(secret, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{name: "should reject a directory accessible to other users", dir: shared},
+		{name: "should reject a symbolic link", dir: link},
+		{name: "should reject a secret readable by other users", dir: planted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFileStore(tt.dir); err != ErrStoreInsecure {
+				t.Errorf("NewFileStore() error type not expected\ngot:  %v, want: %v\n", err, ErrStoreInsecure)
+			}
+		})
+	}
+}
+
+func TestNewFileStore_defaultDir(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CACHE_HOME is only used on linux")
+	}
+
+	dir, err := ioutil.TempDir("", "pkce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cache, ok := os.LookupEnv("XDG_CACHE_HOME")
+	defer func() {
+		if ok {
+			_ = os.Setenv("XDG_CACHE_HOME", cache)
+		} else {
+			_ = os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	if err = os.Setenv("XDG_CACHE_HOME", dir); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewFileStore("")
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+	if want := filepath.Join(dir, fileStoreDir); s.dir != want {
+		t.Errorf("NewFileStore() dir = %v, want %v", s.dir, want)
+	}
+}
+
+func TestFileStore_sealed(t *testing.T) {
+	s, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	data := []byte("code verifier")
+	if err := s.Put(ctx, "id", data, 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(s.path("id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(content, data) {
+		t.Error("Put() should seal entries")
+	}
+
+	t.Run("should reject an entry moved to another id", func(t *testing.T) {
+		if err := ioutil.WriteFile(s.path("other"), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Get(ctx, "other"); err != ErrSealedPayload {
+			t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrSealedPayload)
+		}
+	})
+
+	t.Run("should reject a modified entry", func(t *testing.T) {
+		tampered := append([]byte{}, content...)
+		tampered[len(tampered)-1] ^= 1
+		if err := ioutil.WriteFile(s.path("id"), tampered, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Get(ctx, "id"); err != ErrSealedPayload {
+			t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrSealedPayload)
+		}
+	})
+
+	t.Run("should reuse the generated secret", func(t *testing.T) {
+		if err := s.Put(ctx, "id", data, 0); err != nil {
+			t.Fatalf("Put() unexpected error: %v", err)
+		}
+
+		reopened, err := NewFileStore(s.dir)
+		if err != nil {
+			t.Fatalf("NewFileStore() unexpected error: %v", err)
+		}
+		if got, err := reopened.Consume(ctx, "id"); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("Consume() = %s, %v, want %s", got, err, data)
+		}
+	})
+}
+
+func TestWithFileStoreSecret(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pkce")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err = NewFileStore(dir, WithFileStoreSecret([]byte("short"))); err != ErrStoreSecret {
+		t.Errorf("NewFileStore() error type not expected\ngot:  %v, want: %v\n", err, ErrStoreSecret)
+	}
+
+	secret := bytes.Repeat([]byte("a"), fileStoreSecretMinLen)
+	s, err := NewFileStore(dir, WithFileStoreSecret(secret))
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, fileStoreSecretFile)); !os.IsNotExist(err) {
+		t.Errorf("NewFileStore() should not generate a secret if one is specified, got: %v", err)
+	}
+
+	ctx := context.Background()
+	if err = s.Put(ctx, "id", []byte("data"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	other, err := NewFileStore(dir, WithFileStoreSecret(bytes.Repeat([]byte("b"), fileStoreSecretMinLen)))
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+	if _, err = other.Get(ctx, "id"); err != ErrSealedPayload {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrSealedPayload)
+	}
+}
+
+func TestFileStore_Delete(t *testing.T) {
+	s, cleanup := newTestFileStore(t)
+	defer cleanup()
+
+	if err := s.Put(context.Background(), "id", []byte("data"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if err := s.Delete(context.Background(), "id"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+
+	if _, err := s.Get(context.Background(), "id"); err != ErrKeyNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+
+	if err := s.Delete(context.Background(), "id"); err != nil {
+		t.Errorf("Delete() should not error on a missing entry, got: %v", err)
+	}
+}
+
+func TestTakeKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		expiry  time.Duration
+		wantErr error
+	}{
+		{
+			name:   "should take a sealed key",
+			expiry: time.Minute,
+		},
+		{
+			name:    "should error on an expired key",
+			expiry:  -time.Minute,
+			wantErr: ErrKeyExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileStore, cleanup := newTestFileStore(t)
+			defer cleanup()
+			store := NewEncryptedStore(fileStore, newTestKeyring(t, "kid"))
+
+			want, err := New()
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+			want.expiresAt = time.Now().Add(tt.expiry)
+			// the code challenge is sent in the authorization request before
+			// the key is stored.
+			_ = want.CodeChallenge()
+
+			if err = PutKey(context.Background(), store, "state", want, 0); err != nil {
+				t.Fatalf("PutKey() unexpected error: %v", err)
+			}
+
+			got, err := TakeKey(context.Background(), store, "state")
+			if err != tt.wantErr {
+				t.Fatalf("TakeKey() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if err == nil && got.CodeVerifier() != want.CodeVerifier() {
+				t.Errorf("TakeKey() code verifier = %v, want %v", got.CodeVerifier(), want.CodeVerifier())
+			}
+
+			if _, err = TakeKey(context.Background(), store, "state"); err != ErrKeyNotFound {
+				t.Errorf("TakeKey() should only allow a single use\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+			}
+		})
+	}
+}
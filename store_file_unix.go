@@ -0,0 +1,24 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || js || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos js linux netbsd openbsd solaris
+
+package pkce
+
+import (
+	"os"
+	"syscall"
+)
+
+// validateFileStorePerms ensures a file store directory or secret is owned by
+// the current user, and can not be accessed by the group or other users.
+func validateFileStorePerms(info os.FileInfo) error {
+	if info.Mode().Perm()&0077 != 0 {
+		return ErrStoreInsecure
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || int(stat.Uid) != os.Getuid() {
+		return ErrStoreInsecure
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+package pkce
+
+import (
+	"context"
+	"time"
+)
+
+// RetryPolicy configures how a RetryStore retries requests to a remote store.
+//
+// The zero value makes a single attempt without a timeout.
+type RetryPolicy struct {
+	// Timeout, if set, bounds the duration of each attempt, and of each
+	// hedged lookup.
+	Timeout time.Duration
+	// Attempts provides the maximum number of attempts made per request,
+	// including the first. Defaults to one.
+	Attempts int
+	// Backoff provides the delay before the first retry, which doubles on
+	// each subsequent retry.
+	Backoff time.Duration
+	// MaxBackoff, if set, caps the delay between retries.
+	MaxBackoff time.Duration
+	// HedgeAfter, if set, enables sending a second, hedged, lookup if the
+	// first has not completed within the duration, returning whichever
+	// succeeds first. Writes and consumes are never hedged.
+	HedgeAfter time.Duration
+}
+
+// RetryStore provides a Store decorator adding timeouts, retries with
+// exponential backoff and hedged lookups to a remote store, so that transient
+// network errors between the authorization request and the token request do
+// not fail the flow.
+//
+// Missing entries, entries which can not be decoded and cancellation by the
+// caller are never retried, nor is ErrStoreUnavailable, so that a RetryStore
+// can wrap a BreakerStore. Consume is neither retried nor hedged.
+type RetryStore struct {
+	store  Store
+	policy RetryPolicy
+}
+
+// NewRetryStore returns a store retrying requests to store per the policy.
+func NewRetryStore(store Store, policy RetryPolicy) *RetryStore {
+	if policy.Attempts < 1 {
+		policy.Attempts = 1
+	}
+
+	return &RetryStore{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Put implements Store.
+func (s *RetryStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.retry(ctx, s.bounded(func(ctx context.Context) error {
+		return s.store.Put(ctx, id, data, ttl)
+	}))
+}
+
+// Get implements Store.
+func (s *RetryStore) Get(ctx context.Context, id string) ([]byte, error) {
+	return s.read(ctx, func(ctx context.Context) ([]byte, error) {
+		return s.store.Get(ctx, id)
+	})
+}
+
+// Delete implements Store.
+func (s *RetryStore) Delete(ctx context.Context, id string) error {
+	return s.retry(ctx, s.bounded(func(ctx context.Context) error {
+		return s.store.Delete(ctx, id)
+	}))
+}
+
+// Consume implements Store.
+//
+// Consume makes a single attempt, bounded by the policy timeout. An attempt
+// which fails may still have consumed the entry from the wrapped store, so
+// a retried or hedged consume would report the entry missing instead of the
+// original error.
+func (s *RetryStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	err := s.attempt(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = s.store.Consume(ctx, id)
+
+		return err
+	})
+
+	return data, err
+}
+
+// read performs the lookup with retries and hedging, bounding each lookup by
+// the policy timeout.
+func (s *RetryStore) read(ctx context.Context, lookup func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	var data []byte
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		data, err = s.hedge(ctx, lookup)
+
+		return err
+	})
+
+	return data, err
+}
+
+// retry performs fn until it succeeds, fails with an error that can not be
+// retried, or the attempts are exhausted. fn is responsible for bounding its
+// duration, such as with bounded.
+func (s *RetryStore) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	backoff := s.policy.Backoff
+
+	var err error
+	for attempt := 0; attempt < s.policy.Attempts; attempt++ {
+		if attempt > 0 {
+			if sleepErr := sleep(ctx, backoff); sleepErr != nil {
+				return err
+			}
+
+			backoff *= 2
+			if s.policy.MaxBackoff > 0 && backoff > s.policy.MaxBackoff {
+				backoff = s.policy.MaxBackoff
+			}
+		}
+
+		err = fn(ctx)
+		if !retryable(ctx, err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// attempt performs fn, bounded by the policy timeout.
+func (s *RetryStore) attempt(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.policy.Timeout)
+		defer cancel()
+	}
+
+	return fn(ctx)
+}
+
+// bounded returns fn bounded by the policy timeout.
+func (s *RetryStore) bounded(fn func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return s.attempt(ctx, fn)
+	}
+}
+
+// lookupResult provides the outcome of a hedged lookup.
+type lookupResult struct {
+	data []byte
+	err  error
+}
+
+// hedge performs the lookup, sending a second lookup if the first has not
+// completed within the policy's hedging delay. Each lookup is bounded by the
+// policy timeout.
+func (s *RetryStore) hedge(ctx context.Context, lookup func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	run := func(ctx context.Context) lookupResult {
+		var res lookupResult
+		res.err = s.attempt(ctx, func(ctx context.Context) error {
+			var err error
+			res.data, err = lookup(ctx)
+
+			return err
+		})
+
+		return res
+	}
+
+	if s.policy.HedgeAfter <= 0 {
+		res := run(ctx)
+		return res.data, res.err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan lookupResult, 2)
+	send := func() {
+		results <- run(ctx)
+	}
+	go send()
+
+	timer := time.NewTimer(s.policy.HedgeAfter)
+	defer timer.Stop()
+	hedgeC := timer.C
+
+	var err error
+	for pending := 1; pending > 0; {
+		select {
+		case <-hedgeC:
+			hedgeC = nil
+			pending++
+			go send()
+
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.data, nil
+			}
+
+			// The lookup missing an entry is definitive, whereas the other
+			// lookup's error may have been transient.
+			if err == nil || res.err == ErrKeyNotFound {
+				err = res.err
+			}
+
+			if hedgeC != nil {
+				// The first lookup failed before hedging, so is retried
+				// instead.
+				return nil, err
+			}
+		}
+	}
+
+	return nil, err
+}
+
+// retryable returns whether a request failing with err should be retried.
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+
+	switch err {
+	case ErrKeyNotFound, ErrKeyEncoding, ErrFormatVersion, ErrSealedPayload, ErrStoreUnavailable:
+		return false
+	default:
+		return true
+	}
+}
+
+// sleep blocks for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
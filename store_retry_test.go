@@ -0,0 +1,226 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedStore provides a Store whose lookups are answered by a script,
+// called with the number of the attempt, starting at zero.
+type scriptedStore struct {
+	Store
+
+	mu       sync.Mutex
+	attempts int
+	script   func(ctx context.Context, attempt int) ([]byte, error)
+}
+
+func (s *scriptedStore) Get(ctx context.Context, _ string) ([]byte, error) {
+	s.mu.Lock()
+	attempt := s.attempts
+	s.attempts++
+	s.mu.Unlock()
+
+	return s.script(ctx, attempt)
+}
+
+func (s *scriptedStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	return s.Get(ctx, id)
+}
+
+// block waits until ctx is done, as a store which is not responding.
+func block(ctx context.Context) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRetryStore(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       RetryPolicy
+		script       func(ctx context.Context, attempt int) ([]byte, error)
+		want         string
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:   "should retry transient errors",
+			policy: RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				if attempt < 2 {
+					return nil, errStoreDown
+				}
+				return []byte("data"), nil
+			},
+			want:         "data",
+			wantAttempts: 3,
+		},
+		{
+			name:   "should return the last error once attempts are exhausted",
+			policy: RetryPolicy{Attempts: 2},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				return nil, errStoreDown
+			},
+			wantErr:      errStoreDown,
+			wantAttempts: 2,
+		},
+		{
+			name:   "should not retry missing entries",
+			policy: RetryPolicy{Attempts: 3},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				return nil, ErrKeyNotFound
+			},
+			wantErr:      ErrKeyNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:   "should time out and retry unresponsive attempts",
+			policy: RetryPolicy{Attempts: 2, Timeout: 10 * time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				if attempt == 0 {
+					return block(ctx)
+				}
+				return []byte("data"), nil
+			},
+			want:         "data",
+			wantAttempts: 2,
+		},
+		{
+			name:   "should hedge slow lookups",
+			policy: RetryPolicy{HedgeAfter: 10 * time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				if attempt == 0 {
+					return block(ctx)
+				}
+				return []byte("data"), nil
+			},
+			want:         "data",
+			wantAttempts: 2,
+		},
+		{
+			name:   "should prefer a hedged miss over a transient error",
+			policy: RetryPolicy{HedgeAfter: 10 * time.Millisecond, Attempts: 2},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				if attempt == 0 {
+					time.Sleep(20 * time.Millisecond)
+					return nil, errStoreDown
+				}
+				return nil, ErrKeyNotFound
+			},
+			wantErr:      ErrKeyNotFound,
+			wantAttempts: 2,
+		},
+		{
+			name:   "should time out each hedged lookup separately",
+			policy: RetryPolicy{Timeout: 20 * time.Millisecond, HedgeAfter: 15 * time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				if attempt == 0 {
+					return block(ctx)
+				}
+				time.Sleep(10 * time.Millisecond)
+				return []byte("data"), ctx.Err()
+			},
+			want:         "data",
+			wantAttempts: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backing := &scriptedStore{Store: NewMemoryStore(), script: tt.script}
+			store := NewRetryStore(backing, tt.policy)
+
+			got, err := store.Get(context.Background(), "id")
+			if err != tt.wantErr {
+				t.Fatalf("Get() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Get() = %q, want %q", got, tt.want)
+			}
+			if backing.attempts != tt.wantAttempts {
+				t.Errorf("Get() made %d attempts, want %d", backing.attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetryStore_Consume(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		script  func(ctx context.Context, attempt int) ([]byte, error)
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "should consume the entry",
+			policy: RetryPolicy{Attempts: 3},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				return []byte("data"), nil
+			},
+			want: "data",
+		},
+		{
+			name:   "should not retry transient errors",
+			policy: RetryPolicy{Attempts: 3},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				return nil, errStoreDown
+			},
+			wantErr: errStoreDown,
+		},
+		{
+			name:   "should not retry unresponsive attempts",
+			policy: RetryPolicy{Attempts: 3, Timeout: 10 * time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				return block(ctx)
+			},
+			wantErr: context.DeadlineExceeded,
+		},
+		{
+			name:   "should not hedge slow consumes",
+			policy: RetryPolicy{HedgeAfter: time.Millisecond},
+			script: func(ctx context.Context, attempt int) ([]byte, error) {
+				time.Sleep(10 * time.Millisecond)
+				return []byte("data"), nil
+			},
+			want: "data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backing := &scriptedStore{Store: NewMemoryStore(), script: tt.script}
+			store := NewRetryStore(backing, tt.policy)
+
+			got, err := store.Consume(context.Background(), "id")
+			if err != tt.wantErr {
+				t.Fatalf("Consume() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Consume() = %q, want %q", got, tt.want)
+			}
+			if backing.attempts != 1 {
+				t.Errorf("Consume() made %d attempts, want %d", backing.attempts, 1)
+			}
+		})
+	}
+}
+
+func TestRetryStore_cancelled(t *testing.T) {
+	backing := &scriptedStore{Store: NewMemoryStore(), script: func(ctx context.Context, attempt int) ([]byte, error) {
+		return nil, errStoreDown
+	}}
+	store := NewRetryStore(backing, RetryPolicy{Attempts: 3, Backoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := store.Get(ctx, "id"); err != errStoreDown {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, errStoreDown)
+	}
+	if backing.attempts != 1 {
+		t.Errorf("Get() made %d attempts, want %d", backing.attempts, 1)
+	}
+}
@@ -0,0 +1,146 @@
+package pkce
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wait    time.Duration
+		wantErr error
+	}{
+		{
+			name:    "should return stored data without expiry",
+			ttl:     0,
+			wantErr: nil,
+		},
+		{
+			name:    "should return stored data before expiry",
+			ttl:     time.Hour,
+			wantErr: nil,
+		},
+		{
+			name:    "should not return expired data",
+			ttl:     time.Millisecond,
+			wait:    5 * time.Millisecond,
+			wantErr: ErrKeyNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMemoryStore()
+			want := []byte("data")
+			if err := s.Put(context.Background(), "id", want, tt.ttl); err != nil {
+				t.Fatalf("Put() unexpected error: %v", err)
+			}
+
+			time.Sleep(tt.wait)
+
+			got, err := s.Get(context.Background(), "id")
+			if err != tt.wantErr {
+				t.Fatalf("Get() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if tt.wantErr == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("Get() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_Delete(t *testing.T) {
+	s := NewMemoryStore()
+	if err := s.Put(context.Background(), "id", []byte("data"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	if err := s.Delete(context.Background(), "id"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+
+	if _, err := s.Get(context.Background(), "id"); err != ErrKeyNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+}
+
+func TestPutKey(t *testing.T) {
+	want := &Key{
+		challengeMethod: S256,
+		codeVerifierLen: verifierMinLen,
+		codeVerifier:    []byte(strings.Repeat("a", verifierMinLen)),
+	}
+
+	s := NewMemoryStore()
+	if err := PutKey(context.Background(), s, "id", want, 0); err != nil {
+		t.Fatalf("PutKey(context.Background(), ) unexpected error: %v", err)
+	}
+
+	got, err := GetKey(context.Background(), s, "id")
+	if err != nil {
+		t.Fatalf("GetKey(context.Background(), ) unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetKey(context.Background(), ) key\ngot:  %v\nwant: %v\n", got, want)
+	}
+}
+
+func TestGetKey(t *testing.T) {
+	if _, err := GetKey(context.Background(), NewMemoryStore(), "id"); err != ErrKeyNotFound {
+		t.Errorf("GetKey(context.Background(), ) error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+}
+
+func TestMemoryStore_Stats(t *testing.T) {
+	events := map[StoreEvent]int{}
+	s := NewMemoryStore(WithMetricsHook(func(event StoreEvent) {
+		events[event]++
+	}))
+
+	_ = s.Put(context.Background(), "forever", []byte("data"), 0)
+	_ = s.Put(context.Background(), "live", []byte("data"), time.Hour)
+	_ = s.Put(context.Background(), "expiring", []byte("data"), time.Millisecond)
+	_ = s.Put(context.Background(), "leaked", []byte("data"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	_, _ = s.Get(context.Background(), "forever")
+	_, _ = s.Get(context.Background(), "missing")
+	_, _ = s.Get(context.Background(), "expiring")
+
+	if got := s.Prune(); got != 1 {
+		t.Errorf("Prune() = %v, want %v", got, 1)
+	}
+
+	got := s.Stats()
+	want := StoreStats{
+		Entries:   2,
+		Puts:      4,
+		Hits:      1,
+		Misses:    2,
+		Evictions: 2,
+	}
+	if got.AverageTTLRemaining <= 0 || got.AverageTTLRemaining > time.Hour {
+		t.Errorf("Stats() AverageTTLRemaining = %v, want (0, %v]", got.AverageTTLRemaining, time.Hour)
+	}
+	got.AverageTTLRemaining = 0
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Stats()\ngot:  %+v\nwant: %+v\n", got, want)
+	}
+
+	wantEvents := map[StoreEvent]int{
+		StoreEventPut:      4,
+		StoreEventHit:      1,
+		StoreEventMiss:     2,
+		StoreEventEviction: 2,
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("MetricsHook() events\ngot:  %v\nwant: %v\n", events, wantEvents)
+	}
+}
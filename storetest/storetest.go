@@ -0,0 +1,393 @@
+// Package storetest provides a conformance test suite for pkce.Store
+// implementations, enabling third party store backends to verify they meet
+// the contract relied upon by the pkce package.
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+// NewStoreFunc returns a new, empty store to be tested, and an optional
+// function releasing its resources.
+//
+// Stores computing expiry using a pkce.Clock, such as those configured with
+// pkce.WithStoreClock, should use the provided clock, enabling expiry to be
+// tested without waiting. Stores which can not, such as remote datastores
+// computing expiry server-side, should ignore it and be tested using
+// WithRealTime.
+type NewStoreFunc func(t *testing.T, clock pkce.Clock) (store pkce.Store, cleanup func())
+
+// Option enables variadic conformance suite options to be configured.
+type Option func(*config)
+
+// config provides the configuration of the suite.
+type config struct {
+	resolution  time.Duration
+	concurrency int
+}
+
+// WithRealTime enables testing stores which compute expiry using the system
+// clock, waiting for entries to expire rather than advancing the provided
+// clock. The resolution specifies the granularity of the store's expiry, such
+// as time.Second for datastores with second resolution TTLs.
+func WithRealTime(resolution time.Duration) Option {
+	return func(c *config) {
+		if resolution > 0 {
+			c.resolution = resolution
+		}
+	}
+}
+
+// WithConcurrency enables specifying the number of goroutines concurrently
+// accessing the store. Defaults to 16.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// clock provides a clock which can be advanced, or which waits in real time.
+type clock struct {
+	resolution time.Duration
+
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+// Now implements pkce.Clock.
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Now().Add(c.offset)
+}
+
+// Advance moves the clock forward by d, waiting instead in real time.
+func (c *clock) Advance(d time.Duration) {
+	if c.resolution > 0 {
+		time.Sleep(d)
+		return
+	}
+
+	c.mu.Lock()
+	c.offset += d
+	c.mu.Unlock()
+}
+
+// TestStore runs the conformance suite against stores returned by newStore,
+// as subtests of t. Each subtest is given a new store.
+//
+// The suite exercises expiry, single-use consumption, concurrent access and
+// round-tripping encoded keys.
+func TestStore(t *testing.T, newStore NewStoreFunc, opts ...Option) {
+	c := config{
+		concurrency: 16,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	// the ttl entries are written with, which must exceed the store's expiry
+	// resolution to be observably live before expiring.
+	ttl := time.Minute
+	if c.resolution > 0 {
+		ttl = 2 * c.resolution
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock)
+	}{
+		{
+			name: "should get a put entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				data := []byte("data")
+				mustPut(t, ctx, store, "id", data, ttl)
+
+				// mutating the caller's slice must not affect the store.
+				data[0] = 'D'
+				wantData(t, ctx, store, "id", []byte("data"))
+
+				got, _ := store.Get(ctx, "id")
+				got[0] = 'D'
+				wantData(t, ctx, store, "id", []byte("data"))
+			},
+		},
+		{
+			name: "should error on a missing entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				wantNotFound(t, ctx, store, "id")
+			},
+		},
+		{
+			name: "should replace an existing entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("old"), ttl)
+				mustPut(t, ctx, store, "id", []byte("new"), ttl)
+				wantData(t, ctx, store, "id", []byte("new"))
+			},
+		},
+		{
+			name: "should delete an entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+				mustPut(t, ctx, store, "other", []byte("other"), ttl)
+
+				if err := store.Delete(ctx, "id"); err != nil {
+					t.Fatalf("Delete() unexpected error: %v", err)
+				}
+				wantNotFound(t, ctx, store, "id")
+				wantData(t, ctx, store, "other", []byte("other"))
+
+				if err := store.Delete(ctx, "id"); err != nil {
+					t.Errorf("Delete() should not error on a missing entry, got: %v", err)
+				}
+			},
+		},
+		{
+			name: "should expire an entry after its ttl",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+				wantData(t, ctx, store, "id", []byte("data"))
+
+				clock.Advance(ttl + c.resolution)
+				wantNotFound(t, ctx, store, "id")
+			},
+		},
+		{
+			name: "should not expire an entry without a ttl",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), 0)
+
+				clock.Advance(ttl + c.resolution)
+				wantData(t, ctx, store, "id", []byte("data"))
+			},
+		},
+		{
+			name: "should consume an entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+
+				got, err := store.Consume(ctx, "id")
+				if err != nil {
+					t.Fatalf("Consume() unexpected error: %v", err)
+				}
+				if !bytes.Equal(got, []byte("data")) {
+					t.Errorf("Consume() = %q, want %q", got, "data")
+				}
+
+				wantNotFound(t, ctx, store, "id")
+				if _, err = store.Consume(ctx, "id"); err != pkce.ErrKeyNotFound {
+					t.Errorf("Consume() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrKeyNotFound)
+				}
+			},
+		},
+		{
+			name: "should not consume an expired entry",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+
+				clock.Advance(ttl + c.resolution)
+				if _, err := store.Consume(ctx, "id"); err != pkce.ErrKeyNotFound {
+					t.Errorf("Consume() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrKeyNotFound)
+				}
+			},
+		},
+		{
+			name: "should only consume an entry once under concurrent use",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+
+				var wg sync.WaitGroup
+				consumed := make(chan error, c.concurrency)
+				for i := 0; i < c.concurrency; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, err := store.Consume(ctx, "id")
+						consumed <- err
+					}()
+				}
+				wg.Wait()
+				close(consumed)
+
+				successes := 0
+				for err := range consumed {
+					switch err {
+					case nil:
+						successes++
+					case pkce.ErrKeyNotFound:
+					default:
+						t.Errorf("Consume() unexpected error: %v", err)
+					}
+				}
+
+				if successes != 1 {
+					t.Errorf("Consume() succeeded %d times, want exactly once", successes)
+				}
+			},
+		},
+		{
+			name: "should round trip an encoded key",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				key, err := pkce.New(pkce.WithExpiry(ttl), pkce.WithClock(clock))
+				if err != nil {
+					t.Fatalf("New() unexpected error: %v", err)
+				}
+				_ = key.CodeVerifier()
+
+				if err = pkce.PutKey(ctx, store, "id", key, ttl); err != nil {
+					t.Fatalf("PutKey() unexpected error: %v", err)
+				}
+
+				got, err := pkce.GetKey(ctx, store, "id")
+				if err != nil {
+					t.Fatalf("GetKey() unexpected error: %v", err)
+				}
+
+				if !got.Equal(key) {
+					t.Error("GetKey() should return the key that was put")
+				}
+			},
+		},
+		{
+			name: "should only take a key once",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				key, _ := pkce.New(pkce.WithCodeVerifierString(strings.Repeat("a", 43)))
+				if err := pkce.PutKey(ctx, store, "id", key, ttl); err != nil {
+					t.Fatalf("PutKey() unexpected error: %v", err)
+				}
+
+				if _, err := pkce.TakeKey(ctx, store, "id"); err != nil {
+					t.Fatalf("TakeKey() unexpected error: %v", err)
+				}
+
+				if _, err := pkce.TakeKey(ctx, store, "id"); err != pkce.ErrKeyNotFound {
+					t.Errorf("TakeKey() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrKeyNotFound)
+				}
+			},
+		},
+		{
+			name: "should error on a cancelled context",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				mustPut(t, ctx, store, "id", []byte("data"), ttl)
+
+				cancelled, cancel := context.WithCancel(ctx)
+				cancel()
+
+				if err := store.Put(cancelled, "other", []byte("data"), ttl); err == nil {
+					t.Error("Put() should error on a cancelled context")
+				}
+				if _, err := store.Get(cancelled, "id"); err == nil {
+					t.Error("Get() should error on a cancelled context")
+				}
+				if err := store.Delete(cancelled, "id"); err == nil {
+					t.Error("Delete() should error on a cancelled context")
+				}
+				if _, err := store.Consume(cancelled, "id"); err == nil {
+					t.Error("Consume() should error on a cancelled context")
+				}
+			},
+		},
+		{
+			name: "should be safe for concurrent use",
+			run: func(t *testing.T, ctx context.Context, store pkce.Store, clock *clock) {
+				var wg sync.WaitGroup
+				errs := make(chan error, c.concurrency)
+				for i := 0; i < c.concurrency; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						errs <- concurrentUse(ctx, store, fmt.Sprint("id-", i), ttl)
+					}(i)
+				}
+				wg.Wait()
+				close(errs)
+
+				for err := range errs {
+					if err != nil {
+						t.Error(err)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &clock{resolution: c.resolution}
+			store, cleanup := newStore(t, clock)
+			if cleanup != nil {
+				defer cleanup()
+			}
+
+			tt.run(t, context.Background(), store, clock)
+		})
+	}
+}
+
+// concurrentUse puts, gets and deletes entries under id.
+func concurrentUse(ctx context.Context, store pkce.Store, id string, ttl time.Duration) error {
+	for j := 0; j < 10; j++ {
+		data := []byte(fmt.Sprint(id, "-", j))
+		if err := store.Put(ctx, id, data, ttl); err != nil {
+			return fmt.Errorf("Put(%q) unexpected error: %v", id, err)
+		}
+
+		got, err := store.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("Get(%q) unexpected error: %v", id, err)
+		}
+		if !bytes.Equal(got, data) {
+			return fmt.Errorf("Get(%q) = %q, want %q", id, got, data)
+		}
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		return fmt.Errorf("Delete(%q) unexpected error: %v", id, err)
+	}
+
+	return nil
+}
+
+// mustPut puts the entry, failing the test if it errors.
+func mustPut(t *testing.T, ctx context.Context, store pkce.Store, id string, data []byte, ttl time.Duration) {
+	t.Helper()
+
+	if err := store.Put(ctx, id, data, ttl); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+}
+
+// wantData fails the test if the entry stored under id is not data.
+func wantData(t *testing.T, ctx context.Context, store pkce.Store, id string, data []byte) {
+	t.Helper()
+
+	got, err := store.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("Get() = %q, want %q", got, data)
+	}
+}
+
+// wantNotFound fails the test if an entry is stored under id.
+func wantNotFound(t *testing.T, ctx context.Context, store pkce.Store, id string) {
+	t.Helper()
+
+	if _, err := store.Get(ctx, id); err != pkce.ErrKeyNotFound {
+		t.Errorf("Get() error type not expected\ngot:  %v, want: %v\n", err, pkce.ErrKeyNotFound)
+	}
+}
@@ -0,0 +1,69 @@
+package storetest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matthewhartstonge/pkce"
+)
+
+func TestTestStore(t *testing.T) {
+	t.Run("MemoryStore", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+			return pkce.NewMemoryStore(pkce.WithStoreClock(clock)), nil
+		})
+	})
+
+	t.Run("MemoryStore in real time", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, _ pkce.Clock) (pkce.Store, func()) {
+			return pkce.NewMemoryStore(), nil
+		}, WithRealTime(10*time.Millisecond))
+	})
+
+	t.Run("FileStore", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+			dir, err := ioutil.TempDir("", "pkce")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := pkce.NewFileStore(filepath.Join(dir, "store"), pkce.WithStoreClock(clock))
+			if err != nil {
+				t.Fatalf("NewFileStore() unexpected error: %v", err)
+			}
+
+			return store, func() { os.RemoveAll(dir) }
+		})
+	})
+
+	t.Run("EncryptedStore", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+			keyring, err := pkce.NewAESKeyring("kid", bytes.Repeat([]byte{1}, 32))
+			if err != nil {
+				t.Fatalf("NewAESKeyring() unexpected error: %v", err)
+			}
+
+			return pkce.NewEncryptedStore(pkce.NewMemoryStore(pkce.WithStoreClock(clock)), keyring), nil
+		})
+	})
+
+	t.Run("NamespacedStore", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+			return pkce.NewNamespacedStore(pkce.NewMemoryStore(pkce.WithStoreClock(clock)), "tenant"), nil
+		})
+	})
+
+	t.Run("RetryStore", func(t *testing.T) {
+		TestStore(t, func(t *testing.T, clock pkce.Clock) (pkce.Store, func()) {
+			return pkce.NewRetryStore(pkce.NewMemoryStore(pkce.WithStoreClock(clock)), pkce.RetryPolicy{
+				Attempts:   3,
+				Timeout:    time.Second,
+				HedgeAfter: time.Millisecond,
+			}), nil
+		})
+	})
+}
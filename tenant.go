@@ -0,0 +1,178 @@
+package pkce
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// NamespacedStore provides a Store decorator isolating the entries of a
+// namespace, such as a tenant, within a shared backing store. Ids of different
+// namespaces never collide, regardless of their content.
+type NamespacedStore struct {
+	store  Store
+	prefix string
+	config storeConfig
+}
+
+// NewNamespacedStore returns a store persisting the entries of namespace to
+// store. Metrics hooks are given the events observed within the namespace.
+func NewNamespacedStore(store Store, namespace string, opts ...StoreOption) *NamespacedStore {
+	return &NamespacedStore{
+		store: store,
+		// Escaping the namespace prevents it containing the separator.
+		prefix: url.PathEscape(namespace) + "/",
+		config: newStoreConfig(opts),
+	}
+}
+
+// Put implements Store.
+func (s *NamespacedStore) Put(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if err := s.store.Put(ctx, s.prefix+id, data, ttl); err != nil {
+		return err
+	}
+
+	s.config.metrics(StoreEventPut)
+
+	return nil
+}
+
+// Get implements Store.
+func (s *NamespacedStore) Get(ctx context.Context, id string) ([]byte, error) {
+	return s.observe(s.store.Get(ctx, s.prefix+id))
+}
+
+// Delete implements Store.
+func (s *NamespacedStore) Delete(ctx context.Context, id string) error {
+	return s.store.Delete(ctx, s.prefix+id)
+}
+
+// Consume implements Store.
+func (s *NamespacedStore) Consume(ctx context.Context, id string) ([]byte, error) {
+	return s.observe(s.store.Consume(ctx, s.prefix+id))
+}
+
+// observe reports the outcome of a lookup to the metrics hook.
+func (s *NamespacedStore) observe(data []byte, err error) ([]byte, error) {
+	switch err {
+	case nil:
+		s.config.metrics(StoreEventHit)
+	case ErrKeyNotFound:
+		s.config.metrics(StoreEventMiss)
+	}
+
+	return data, err
+}
+
+// TenantMetricsHook is called for each store event observed within a tenant,
+// enabling per-tenant metrics. Hooks must be safe for concurrent use.
+type TenantMetricsHook func(tenant string, event StoreEvent)
+
+// TenantOption enables variadic TenantManager options to be configured.
+type TenantOption func(*TenantManager)
+
+// WithTenantDefaults enables specifying the options applied to the key
+// manager of every tenant, before the tenant's policy.
+func WithTenantDefaults(opts ...ManagerOption) TenantOption {
+	return func(t *TenantManager) {
+		t.defaults = append(t.defaults, opts...)
+	}
+}
+
+// WithTenantMetricsHook enables receiving the store events of each tenant.
+func WithTenantMetricsHook(hook TenantMetricsHook) TenantOption {
+	return func(t *TenantManager) {
+		t.metrics = hook
+	}
+}
+
+// TenantManager manages the proof keys of many tenants, such as the issuers
+// of a multi-tenant identity platform, within a shared store. The keys of each
+// tenant are isolated, and each tenant may be configured with its own policy.
+type TenantManager struct {
+	store    Store
+	defaults []ManagerOption
+	metrics  TenantMetricsHook
+
+	mu       sync.RWMutex
+	policies map[string]Policy
+	managers map[string]*KeyManager
+}
+
+// NewTenantManager returns a tenant manager persisting keys to store.
+func NewTenantManager(store Store, opts ...TenantOption) *TenantManager {
+	t := &TenantManager{
+		store:    store,
+		policies: map[string]Policy{},
+		managers: map[string]*KeyManager{},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// SetPolicy validates and applies the policy to the tenant's key manager,
+// replacing any previous policy. Keys already registered remain verifiable.
+func (t *TenantManager) SetPolicy(tenant string, policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.policies[tenant] = policy
+	t.managers[tenant] = t.newManager(tenant, policy)
+	t.mu.Unlock()
+
+	return nil
+}
+
+// Manager returns the key manager of the tenant, such as for mounting its
+// verification middleware.
+func (t *TenantManager) Manager(tenant string) *KeyManager {
+	t.mu.RLock()
+	m, ok := t.managers[tenant]
+	t.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if m, ok = t.managers[tenant]; !ok {
+		m = t.newManager(tenant, t.policies[tenant])
+		t.managers[tenant] = m
+	}
+
+	return m
+}
+
+// Register persists the code challenge as KeyManager.Register, within the
+// tenant.
+func (t *TenantManager) Register(ctx context.Context, tenant string, id string, method Method, codeChallenge string) error {
+	return t.Manager(tenant).Register(ctx, id, method, codeChallenge)
+}
+
+// Verify verifies the code verifier as KeyManager.Verify, within the tenant.
+func (t *TenantManager) Verify(ctx context.Context, tenant string, id string, codeVerifier string) error {
+	return t.Manager(tenant).Verify(ctx, id, codeVerifier)
+}
+
+// newManager returns a key manager for the tenant enforcing the policy.
+func (t *TenantManager) newManager(tenant string, policy Policy) *KeyManager {
+	var storeOpts []StoreOption
+	if t.metrics != nil {
+		hook := t.metrics
+		storeOpts = append(storeOpts, WithMetricsHook(func(event StoreEvent) {
+			hook(tenant, event)
+		}))
+	}
+
+	opts := append(append([]ManagerOption(nil), t.defaults...), policy.ManagerOptions()...)
+
+	return NewKeyManager(NewNamespacedStore(t.store, tenant, storeOpts...), opts...)
+}
@@ -0,0 +1,84 @@
+package pkce
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestNewNamespacedStore(t *testing.T) {
+	ctx := context.Background()
+	backing := NewMemoryStore()
+	a := NewNamespacedStore(backing, "a")
+	b := NewNamespacedStore(backing, "a/b")
+
+	if err := a.Put(ctx, "b/id", []byte("a"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if err := b.Put(ctx, "id", []byte("b"), 0); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+
+	got, err := a.Get(ctx, "b/id")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if string(got) != "a" {
+		t.Errorf("Get() = %q, want %q, namespaces must not collide", got, "a")
+	}
+
+	if _, err = a.Consume(ctx, "id"); err != ErrKeyNotFound {
+		t.Errorf("Consume() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+	}
+}
+
+func TestTenantManager(t *testing.T) {
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	events := map[string][]StoreEvent{}
+	tm := NewTenantManager(NewMemoryStore(), WithTenantMetricsHook(func(tenant string, event StoreEvent) {
+		mu.Lock()
+		events[tenant] = append(events[tenant], event)
+		mu.Unlock()
+	}))
+
+	if err := tm.SetPolicy("strict", Policy{RequiredMethod: S256}); err != nil {
+		t.Fatalf("SetPolicy() unexpected error: %v", err)
+	}
+	if err := tm.SetPolicy("invalid", Policy{RequiredMethod: "none"}); err != ErrMethodNotSupported {
+		t.Errorf("SetPolicy() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodNotSupported)
+	}
+
+	t.Run("should apply per-tenant policies", func(t *testing.T) {
+		if err := tm.Register(ctx, "strict", "plain", Plain, testCodeVerifier); err != ErrMethodNotAllowed {
+			t.Errorf("Register() error type not expected\ngot:  %v, want: %v\n", err, ErrMethodNotAllowed)
+		}
+		if err := tm.Register(ctx, "lenient", "plain", Plain, testCodeVerifier); err != nil {
+			t.Errorf("Register() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should isolate tenants", func(t *testing.T) {
+		if err := tm.Register(ctx, "strict", "code", S256, testCodeChallenge); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+
+		if err := tm.Verify(ctx, "lenient", "code", testCodeVerifier); err != ErrKeyNotFound {
+			t.Errorf("Verify() error type not expected\ngot:  %v, want: %v\n", err, ErrKeyNotFound)
+		}
+		if err := tm.Verify(ctx, "strict", "code", testCodeVerifier); err != nil {
+			t.Errorf("Verify() unexpected error: %v", err)
+		}
+	})
+
+	t.Run("should report per-tenant metrics", func(t *testing.T) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		want := []StoreEvent{StoreEventPut, StoreEventHit}
+		if got := events["strict"]; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+			t.Errorf("events[strict] = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package pkce
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// maxTokenErrorSize provides the maximum size of a token error response body
+// read by ParseTokenError.
+const maxTokenErrorSize = 64 << 10
+
+// TokenError is returned when a token response reports the token request
+// failed, as specified in RFC 6749, 5.2.
+//
+// If the rejection is consistent with a downgrade attack against the key, the
+// error wraps ErrPossibleDowngradeAttack, so apps can warn the user or alert:
+//
+//	if errors.Is(err, pkce.ErrPossibleDowngradeAttack) {
+//		// the authorization request may have been tampered with.
+//	}
+type TokenError struct {
+	// Code provides the error code, such as "invalid_grant".
+	Code string
+	// Description provides a description of the error, if provided.
+	Description string
+	// URI provides a uri describing the error, if provided.
+	URI string
+
+	// possibleDowngrade reports whether the rejection suggests a downgrade
+	// attack.
+	possibleDowngrade bool
+}
+
+func (e *TokenError) Error() string {
+	if e.Description == "" {
+		return "token request failed: " + e.Code
+	}
+
+	return "token request failed: " + e.Code + ": " + e.Description
+}
+
+// ID implements Identifier. The identifier is derived from the error code,
+// such as "pkce.token.invalid_grant".
+func (e *TokenError) ID() string {
+	return "pkce.token." + e.Code
+}
+
+// Unwrap returns ErrPossibleDowngradeAttack if the rejection suggests a
+// downgrade attack, otherwise nil.
+func (e *TokenError) Unwrap() error {
+	if e.possibleDowngrade {
+		return ErrPossibleDowngradeAttack
+	}
+
+	return nil
+}
+
+// ParseTokenError parses the error response of a failed token request made
+// with key's code verifier, returning a *TokenError. An error describing the
+// status is returned if the body is not an RFC 6749, 5.2 error response. The
+// response body is not closed.
+//
+// RFC 7636, 7.2. A MITM stripping the code challenge method from the
+// authorization request causes the authorization server to register the
+// key's S256 code challenge as "plain", so the code verifier is then rejected
+// with invalid_grant. When key uses a hashed method, an invalid_grant
+// attributed to the code verifier or code challenge method is reported as a
+// possible downgrade attack. key may be nil if unknown.
+func ParseTokenError(key *Key, res *http.Response) error {
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxTokenErrorSize))
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+		ErrorURI         string `json:"error_uri"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Error == "" {
+		return fmt.Errorf("token request failed with status %d", res.StatusCode)
+	}
+
+	tokenErr := &TokenError{
+		Code:        resp.Error,
+		Description: resp.ErrorDescription,
+		URI:         resp.ErrorURI,
+	}
+	if key != nil {
+		tokenErr.possibleDowngrade = suspectDowngrade(key.ChallengeMethod(), tokenErr.Code, tokenErr.Description)
+	}
+
+	return tokenErr
+}
+
+// downgradeHints provides the phrases of an invalid_grant description which
+// attribute the rejection to PKCE.
+var downgradeHints = []string{
+	"code_verifier",
+	"code verifier",
+	"code_challenge",
+	"code challenge",
+	"challenge method",
+	"downgrade",
+	"pkce",
+	"plain",
+}
+
+// suspectDowngrade returns whether a token request rejected with the error
+// code and description suggests a downgrade attack against a key using
+// method.
+func suspectDowngrade(method Method, code string, description string) bool {
+	if method == "" || method == Plain || code != errorCodeInvalidGrant {
+		return false
+	}
+
+	description = strings.ToLower(description)
+	for _, hint := range downgradeHints {
+		if strings.Contains(description, hint) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,108 @@
+package pkce
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseTokenError(t *testing.T) {
+	s256, _ := New(WithChallengeMethod(S256))
+	plain, _ := New(WithChallengeMethod(Plain))
+
+	tests := []struct {
+		name          string
+		key           *Key
+		status        int
+		body          string
+		wantTokenErr  *TokenError
+		wantDowngrade bool
+	}{
+		{
+			name:          "should suspect a downgrade of an S256 key",
+			key:           s256,
+			status:        http.StatusBadRequest,
+			body:          `{"error":"invalid_grant","error_description":"PKCE verification failed"}`,
+			wantTokenErr:  &TokenError{Code: "invalid_grant", Description: "PKCE verification failed"},
+			wantDowngrade: true,
+		},
+		{
+			name:          "should suspect a downgrade from the middleware's description",
+			key:           s256,
+			status:        http.StatusBadRequest,
+			body:          `{"error":"invalid_grant","error_description":"` + invalidGrantDescription + `"}`,
+			wantTokenErr:  &TokenError{Code: "invalid_grant", Description: invalidGrantDescription},
+			wantDowngrade: true,
+		},
+		{
+			name:         "should not suspect a downgrade of a plain key",
+			key:          plain,
+			status:       http.StatusBadRequest,
+			body:         `{"error":"invalid_grant","error_description":"invalid code_verifier"}`,
+			wantTokenErr: &TokenError{Code: "invalid_grant", Description: "invalid code_verifier"},
+		},
+		{
+			name:         "should not suspect a downgrade for an unrelated invalid_grant",
+			key:          s256,
+			status:       http.StatusBadRequest,
+			body:         `{"error":"invalid_grant","error_description":"authorization code expired","error_uri":"https://as.example.com/errors"}`,
+			wantTokenErr: &TokenError{Code: "invalid_grant", Description: "authorization code expired", URI: "https://as.example.com/errors"},
+		},
+		{
+			name:         "should not suspect a downgrade for other error codes",
+			key:          s256,
+			status:       http.StatusUnauthorized,
+			body:         `{"error":"invalid_client","error_description":"code_verifier"}`,
+			wantTokenErr: &TokenError{Code: "invalid_client", Description: "code_verifier"},
+		},
+		{
+			name:         "should not classify without a key",
+			status:       http.StatusBadRequest,
+			body:         `{"error":"invalid_grant","error_description":"bad code_verifier"}`,
+			wantTokenErr: &TokenError{Code: "invalid_grant", Description: "bad code_verifier"},
+		},
+		{
+			name:   "should error on a non-oauth error response",
+			key:    s256,
+			status: http.StatusBadGateway,
+			body:   `<html>bad gateway</html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseTokenError(tt.key, &http.Response{
+				StatusCode: tt.status,
+				Body:       ioutil.NopCloser(strings.NewReader(tt.body)),
+			})
+			if err == nil {
+				t.Fatal("ParseTokenError() expected error")
+			}
+
+			var tokenErr *TokenError
+			if got := errors.As(err, &tokenErr); got != (tt.wantTokenErr != nil) {
+				t.Fatalf("ParseTokenError() got = %v, want *TokenError %v", err, tt.wantTokenErr != nil)
+			}
+			if tt.wantTokenErr != nil &&
+				(tokenErr.Code != tt.wantTokenErr.Code || tokenErr.Description != tt.wantTokenErr.Description || tokenErr.URI != tt.wantTokenErr.URI) {
+				t.Errorf("ParseTokenError() got = %+v, want %+v", tokenErr, tt.wantTokenErr)
+			}
+
+			if got := errors.Is(err, ErrPossibleDowngradeAttack); got != tt.wantDowngrade {
+				t.Errorf("errors.Is(ErrPossibleDowngradeAttack) got = %v, want %v", got, tt.wantDowngrade)
+			}
+			if tt.wantDowngrade && !errors.Is(err, ErrSecurityPolicy) {
+				t.Error("ParseTokenError() downgrade should be categorised as a security policy error")
+			}
+		})
+	}
+}
+
+func TestTokenError_ID(t *testing.T) {
+	err := &TokenError{Code: "invalid_grant"}
+	if got := ErrorID(err); got != "pkce.token.invalid_grant" {
+		t.Errorf("ErrorID() got = %q, want %q", got, "pkce.token.invalid_grant")
+	}
+}
@@ -1,5 +1,10 @@
 package pkce
 
+import (
+	"encoding/base64"
+	"strings"
+)
+
 // validateCodeVerifier ensures that the provided code verifier is specification
 // compliant.
 func validateCodeVerifier(verifier []byte) error {
@@ -10,6 +15,66 @@ func validateCodeVerifier(verifier []byte) error {
 	return validateCodeVerifierCharacters(verifier)
 }
 
+// validateCodeChallenge ensures that the provided code challenge is
+// specification compliant.
+//
+// RFC 7636, 4.2. The ABNF for "code_challenge" is as follows.
+//
+//	code-challenge = 43*128unreserved
+func validateCodeChallenge(challenge string) error {
+	if validateVerifierLen(len(challenge)) != nil ||
+		validateCodeVerifierCharacters([]byte(challenge)) != nil {
+		return ErrChallengeInvalid
+	}
+
+	return nil
+}
+
+// validateMethodCodeChallenge ensures that the provided code challenge is
+// specification compliant for the method used to derive it.
+//
+// Hashed code challenges, such as S256, containing base64 padding, or
+// characters from the standard base64 alphabet, are rejected with a
+// descriptive error, as these are a common client encoding mistake. Hashed
+// code challenges must also decode to exactly one digest of the method's
+// hash, so truncated or over-long values are rejected with
+// ErrChallengeDigest on receipt, rather than failing to verify.
+func validateMethodCodeChallenge(method Method, challenge string) error {
+	size := methodDigestSize(method)
+	if size == 0 {
+		return validateCodeChallenge(challenge)
+	}
+
+	if strings.ContainsAny(challenge, "=+/") {
+		return ErrChallengeEncoding
+	}
+
+	if validateCodeVerifierCharacters([]byte(challenge)) != nil {
+		return ErrChallengeInvalid
+	}
+
+	return validateChallengeDigest(challenge, size)
+}
+
+// validateChallengeDigest ensures that a hashed code challenge is the
+// unpadded base64url encoding of a digest of size bytes, as specified for
+// S256 in RFC 7636, 4.2. As the encoded digest is between 43 and 86
+// characters, this also enforces the code challenge ABNF length.
+func validateChallengeDigest(challenge string, size int) error {
+	if base64.RawURLEncoding.EncodedLen(size) != len(challenge) {
+		return ErrChallengeDigest
+	}
+
+	// Strict ensures the unused trailing bits are zero, so that exactly one
+	// encoding is accepted for each digest.
+	digest, err := base64.RawURLEncoding.Strict().DecodeString(challenge)
+	if err != nil || len(digest) != size {
+		return ErrChallengeDigest
+	}
+
+	return nil
+}
+
 // validateVerifierLen ensures the length of the code verifier is within the
 // bounds of the specification's declared lengths.
 func validateVerifierLen(n int) error {
@@ -20,6 +85,22 @@ func validateVerifierLen(n int) error {
 	return nil
 }
 
+// validateCharset ensures a code verifier character set is a non-empty subset
+// of the unreserved characters, without duplicates.
+func validateCharset(charset string) error {
+	if charset == "" {
+		return ErrCharsetInvalid
+	}
+
+	for i := 0; i < len(charset); i++ {
+		if !validVerifierChar(charset[i]) || strings.IndexByte(charset[:i], charset[i]) != -1 {
+			return ErrCharsetInvalid
+		}
+	}
+
+	return nil
+}
+
 // validateCodeVerifier ensures all characters provided are in the set of
 // unreserved characters.
 func validateCodeVerifierCharacters(chars []byte) error {
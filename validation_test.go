@@ -286,3 +286,69 @@ func randomBytes(t *testing.T, n int) (randomBytes []byte) {
 
 	return
 }
+
+func Test_validateMethodCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    Method
+		challenge string
+		wantErr   error
+	}{
+		{
+			name:      "should accept a compliant S256 code challenge",
+			method:    S256,
+			challenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxw",
+			wantErr:   nil,
+		},
+		{
+			name:      "should reject a padded S256 code challenge",
+			method:    S256,
+			challenge: "1u1qURRaY4QPquG83Yu2fnyEYp4d0TLhXyj6AnaEcGQ=",
+			wantErr:   ErrChallengeEncoding,
+		},
+		{
+			name:      "should reject a standard alphabet S256 code challenge",
+			method:    S256,
+			challenge: "EF+/M9nkOE6p88FdlYXUHkBv96MeV56C/Dsqk9DGlxw",
+			wantErr:   ErrChallengeEncoding,
+		},
+		{
+			name:      "should reject a truncated S256 code challenge",
+			method:    S256,
+			challenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlx",
+			wantErr:   ErrChallengeDigest,
+		},
+		{
+			name:      "should reject an over-long S256 code challenge",
+			method:    S256,
+			challenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxwAAAA",
+			wantErr:   ErrChallengeDigest,
+		},
+		{
+			name:      "should reject an S256 code challenge with non-zero trailing bits",
+			method:    S256,
+			challenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlxx",
+			wantErr:   ErrChallengeDigest,
+		},
+		{
+			name:      "should reject an S256 code challenge with invalid characters",
+			method:    S256,
+			challenge: "EF-_M9nkOE6p88FdlYXUHkBv96MeV56C_Dsqk9DGlx!",
+			wantErr:   ErrChallengeInvalid,
+		},
+		{
+			name:      "should reject an invalid plain code challenge",
+			method:    Plain,
+			challenge: strings.Repeat("+", verifierMinLen),
+			wantErr:   ErrChallengeInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMethodCodeChallenge(tt.method, tt.challenge); err != tt.wantErr {
+				t.Errorf("validateMethodCodeChallenge() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
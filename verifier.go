@@ -0,0 +1,85 @@
+package pkce
+
+import (
+	"context"
+)
+
+// Verifier computes the transform of a code verifier and compares it to a
+// code challenge, enabling verification to be delegated to a hardware security
+// module or remote cryptographic service, such as in regulated deployments
+// requiring digests be computed within certified hardware.
+//
+// Code verifiers are validated as specification compliant before being passed
+// to a Verifier, and code challenges are always base64url encoded without
+// padding. Implementations must compare in constant time, and return an error
+// only if verification could not be performed, rather than for a mismatch.
+//
+// Implementations must be safe for concurrent use.
+type Verifier interface {
+	// Verify reports whether the code verifier, transformed by method,
+	// matches the code challenge.
+	Verify(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error)
+}
+
+// VerifierFunc provides an adapter to allow the use of ordinary functions as
+// a Verifier.
+type VerifierFunc func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error)
+
+// Verify implements Verifier.
+func (f VerifierFunc) Verify(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+	return f(ctx, method, codeVerifier, codeChallenge)
+}
+
+// InProcessVerifier returns the default Verifier, which computes the
+// transform in-process and compares in constant time.
+func InProcessVerifier() Verifier {
+	return inProcessVerifier{}
+}
+
+// inProcessVerifier computes the transform in-process.
+type inProcessVerifier struct{}
+
+// Verify implements Verifier.
+func (inProcessVerifier) Verify(_ context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+	if !isKeyMethod(method) {
+		return false, ErrMethodNotSupported
+	}
+
+	return verifyChallenge(method, Base64URL, codeVerifier, codeChallenge), nil
+}
+
+// WithManagerVerifier enables delegating the transform and comparison of code
+// verifiers to verifier, such as a hardware security module. Defaults to
+// verifying in-process.
+//
+// Errors returned by the verifier fail verification, and are returned as is,
+// so are reported by the middleware as a server error, or passed through if
+// failing open.
+func WithManagerVerifier(verifier Verifier) ManagerOption {
+	return func(m *KeyManager) {
+		m.verifier = verifier
+	}
+}
+
+// verifyCodeVerifier reports whether the code verifier proves the key's code
+// challenge, delegating to the configured verifier, if any.
+func (m *KeyManager) verifyCodeVerifier(ctx context.Context, key *Key, codeVerifier string) (bool, error) {
+	if m.verifier == nil {
+		return key.VerifyCodeVerifier(codeVerifier), nil
+	}
+
+	if err := validateCodeVerifier([]byte(codeVerifier)); err != nil {
+		return false, err
+	}
+
+	ok, err := m.verifier.Verify(ctx, key.ChallengeMethod(), codeVerifier, key.CodeChallenge())
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if key.verifiedAt.IsZero() {
+		key.verifiedAt = now(key.clock)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,141 @@
+package pkce
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInProcessVerifier(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        Method
+		codeVerifier  string
+		codeChallenge string
+		want          bool
+		wantErr       error
+	}{
+		{
+			name:          "should verify a S256 code verifier",
+			method:        S256,
+			codeVerifier:  testCodeVerifier,
+			codeChallenge: testCodeChallenge,
+			want:          true,
+		},
+		{
+			name:          "should verify a S512 code verifier",
+			method:        S512,
+			codeVerifier:  testCodeVerifier,
+			codeChallenge: testHashedChallenge(S512),
+			want:          true,
+		},
+		{
+			name:          "should verify a plain code verifier",
+			method:        Plain,
+			codeVerifier:  testCodeVerifier,
+			codeChallenge: testCodeVerifier,
+			want:          true,
+		},
+		{
+			name:          "should not verify a mismatched code verifier",
+			method:        S256,
+			codeVerifier:  strings.Repeat("a", verifierMinLen),
+			codeChallenge: testCodeChallenge,
+		},
+		{
+			name:          "should error on an unsupported method",
+			method:        "yolo",
+			codeVerifier:  testCodeVerifier,
+			codeChallenge: testCodeChallenge,
+			wantErr:       ErrMethodNotSupported,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InProcessVerifier().Verify(context.Background(), tt.method, tt.codeVerifier, tt.codeChallenge)
+			if err != tt.wantErr {
+				t.Fatalf("Verify() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithManagerVerifier(t *testing.T) {
+	errHSM := errors.New("hsm unavailable")
+
+	tests := []struct {
+		name         string
+		verifier     VerifierFunc
+		codeVerifier string
+		wantCalls    int
+		wantErr      error
+	}{
+		{
+			name: "should verify via the verifier",
+			verifier: func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+				return true, nil
+			},
+			codeVerifier: testCodeVerifier,
+			wantCalls:    1,
+		},
+		{
+			name: "should report a mismatch from the verifier",
+			verifier: func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+				return false, nil
+			},
+			codeVerifier: testCodeVerifier,
+			wantCalls:    1,
+			wantErr:      ErrVerifierMismatch,
+		},
+		{
+			name: "should return the verifier's error",
+			verifier: func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+				return false, errHSM
+			},
+			codeVerifier: testCodeVerifier,
+			wantCalls:    1,
+			wantErr:      errHSM,
+		},
+		{
+			name: "should not pass a non-compliant code verifier to the verifier",
+			verifier: func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+				return true, nil
+			},
+			codeVerifier: strings.Repeat("a", verifierMinLen-1) + "!",
+			wantErr:      ErrVerifierCharacters,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			verifier := VerifierFunc(func(ctx context.Context, method Method, codeVerifier string, codeChallenge string) (bool, error) {
+				calls++
+				if method != S256 || codeChallenge != testCodeChallenge {
+					t.Errorf("Verify() called with (%v, %v), want (%v, %v)", method, codeChallenge, S256, testCodeChallenge)
+				}
+
+				return tt.verifier(ctx, method, codeVerifier, codeChallenge)
+			})
+
+			m := NewKeyManager(NewMemoryStore(), WithManagerVerifier(verifier))
+			if err := m.Register(context.Background(), "code", S256, testCodeChallenge); err != nil {
+				t.Fatalf("Register() unexpected error: %v", err)
+			}
+
+			if err := m.Verify(context.Background(), "code", tt.codeVerifier); err != tt.wantErr {
+				t.Errorf("Verify() error type not expected\ngot:  %v, want: %v\n", err, tt.wantErr)
+			}
+
+			if calls != tt.wantCalls {
+				t.Errorf("Verify() called the verifier %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}